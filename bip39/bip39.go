@@ -8,17 +8,22 @@ package bip39
 
 import (
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"math/big"
 	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
 )
 
 var (
 	// Some bitwise operands for working with big.Ints.
+	last11BitsMask  = big.NewInt(2047)
 	shift11BitsMask = big.NewInt(2048)
 	bigOne          = big.NewInt(1)
+	bigTwo          = big.NewInt(2)
 
 	// Used to isolate the checksum bits from the entropy+checksum byte
 	// array.
@@ -49,26 +54,132 @@ var (
 	// ErrChecksumIncorrect is returned when entropy has the incorrect
 	// checksum.
 	ErrChecksumIncorrect = errors.New("checksum incorrect")
+
+	// ErrEntropyLengthInvalid is returned when trying to use an entropy set
+	// with an invalid size.
+	ErrEntropyLengthInvalid = errors.New("entropy length must be [128, " +
+		"256] and a multiple of 32")
 )
 
+// NewMnemonic will return a string consisting of the English mnemonic words
+// for the given entropy.
+// If the provide entropy is invalid, an error will be returned.
+func NewMnemonic(entropy []byte) (string, error) {
+	return NewMnemonicInLanguage(entropy, LanguageEnglish)
+}
+
+// NewMnemonicInLanguage will return a string consisting of the mnemonic words
+// of the given language for the given entropy. The words are joined with the
+// separator of the language.
+func NewMnemonicInLanguage(entropy []byte, language *Language) (string,
+	error) {
+
+	// Compute some lengths for convenience.
+	entropyBitLength := len(entropy) * 8
+	checksumBitLength := entropyBitLength / 32
+	sentenceLength := (entropyBitLength + checksumBitLength) / 11
+
+	// Validate that the requested size is supported.
+	err := validateEntropyBitSize(entropyBitLength)
+	if err != nil {
+		return "", err
+	}
+
+	// Add checksum to entropy.
+	entropy = addChecksum(entropy)
+
+	// Break entropy up into sentenceLength chunks of 11 bits.
+	// For each word AND mask the rightmost 11 bits and find the word at
+	// that index. Then bitshift entropy 11 bits right and repeat.
+	// Add to the last empty slot so we can work with LSBs instead of MSB.
+
+	// Entropy as an int so we can bitmask without worrying about bytes
+	// slices.
+	entropyInt := new(big.Int).SetBytes(entropy)
+
+	// Slice to hold words in.
+	words := make([]string, sentenceLength)
+
+	// Throw away big.Int for AND masking.
+	word := big.NewInt(0)
+
+	for i := sentenceLength - 1; i >= 0; i-- {
+		// Get 11 right most bits and bitshift 11 to the right for next
+		// time.
+		word.And(entropyInt, last11BitsMask)
+		entropyInt.Div(entropyInt, shift11BitsMask)
+
+		// Get the bytes representing the 11 bits as a 2 byte slice.
+		wordBytes := padByteSlice(word.Bytes(), 2)
+
+		// Convert bytes to an index and add that word to the list.
+		words[i] = language.Words[binary.BigEndian.Uint16(wordBytes)]
+	}
+
+	return strings.Join(words, language.Separator), nil
+}
+
+// NewSeed returns the seed of the given mnemonic and passphrase in any of the
+// known languages. Both are NFKD normalized before the seed is derived, as
+// required by BIP39. The mnemonic is not validated.
+func NewSeed(mnemonic, passphrase string) []byte {
+	return pbkdf2.Key(
+		[]byte(NormalizeMnemonic(mnemonic)),
+		[]byte("mnemonic"+NormalizeString(passphrase)), 2048, 64,
+		sha512.New,
+	)
+}
+
 // EntropyFromMnemonic takes a mnemonic generated by this library,
 // and returns the input entropy used to generate the given mnemonic.
 // An error is returned if the given mnemonic is invalid.
 func EntropyFromMnemonic(mnemonic string) ([]byte, error) {
-	mnemonicSlice, isValid := splitMnemonicWords(mnemonic)
+	entropy, _, err := DecodeMnemonic(mnemonic)
+	return entropy, err
+}
+
+// DecodeMnemonic returns the entropy and the detected language of the given
+// mnemonic in any of the known languages. If the words of the mnemonic are
+// part of multiple word lists, the first language the checksum is valid for
+// is returned.
+func DecodeMnemonic(mnemonic string) ([]byte, *Language, error) {
+	mnemonicSlice, isValid := splitMnemonicWords(
+		NormalizeMnemonic(mnemonic),
+	)
 	if !isValid {
-		return nil, ErrInvalidMnemonic
+		return nil, nil, ErrInvalidMnemonic
+	}
+
+	languages, err := DetectLanguage(mnemonicSlice)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	wordMap := make(map[string]int)
-	for i, v := range English {
-		wordMap[v] = i
+	for _, language := range languages {
+		entropy, err := entropyFromWords(mnemonicSlice, language)
+		if err == nil {
+			return entropy, language, nil
+		}
+
+		// Only a wrong checksum could be caused by choosing the wrong
+		// one of multiple candidate languages.
+		if !errors.Is(err, ErrChecksumIncorrect) {
+			return nil, nil, err
+		}
 	}
 
+	return nil, nil, ErrChecksumIncorrect
+}
+
+// entropyFromWords returns the entropy encoded in the given words of the given
+// language.
+func entropyFromWords(mnemonicSlice []string, language *Language) ([]byte,
+	error) {
+
 	// Decode the words into a big.Int.
 	b := big.NewInt(0)
 	for _, v := range mnemonicSlice {
-		index, found := wordMap[v]
+		index, found := language.WordIndex(v)
 		if !found {
 			return nil, fmt.Errorf("word `%v` not found in "+
 				"reverse map", v)
@@ -108,6 +219,42 @@ func EntropyFromMnemonic(mnemonic string) ([]byte, error) {
 	return entropy, nil
 }
 
+// addChecksum appends to data the first (len(data) / 32) bits of the result of
+// sha256(data).
+func addChecksum(data []byte) []byte {
+	// Get first byte of sha256.
+	hash := computeChecksum(data)
+	firstChecksumByte := hash[0]
+
+	// len() is in bytes so we divide by 4.
+	checksumBitLength := uint(len(data) / 4)
+
+	// For each bit of check sum we want we shift the data one the left
+	// and then set the (new) right most bit equal to checksum bit at that
+	// index staring from the left.
+	dataBigInt := new(big.Int).SetBytes(data)
+
+	for i := uint(0); i < checksumBitLength; i++ {
+		// Bitshift 1 left.
+		dataBigInt.Mul(dataBigInt, bigTwo)
+
+		// Set rightmost bit if leftmost checksum bit is set.
+		if firstChecksumByte&(1<<(7-i)) > 0 {
+			dataBigInt.Or(dataBigInt, bigOne)
+		}
+	}
+
+	return dataBigInt.Bytes()
+}
+
+func validateEntropyBitSize(bitSize int) error {
+	if (bitSize%32) != 0 || bitSize < 128 || bitSize > 256 {
+		return ErrEntropyLengthInvalid
+	}
+
+	return nil
+}
+
 func computeChecksum(data []byte) []byte {
 	hasher := sha256.New()
 	_, _ = hasher.Write(data)
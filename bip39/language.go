@@ -0,0 +1,183 @@
+package bip39
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Language is a BIP39 word list together with the separator that is used to
+// join its words to a mnemonic sentence.
+type Language struct {
+	// Name is the lower case name of the language, as it is used in the
+	// file names of the official word lists.
+	Name string
+
+	// Words are the 2048 words of the list, in their original form.
+	Words []string
+
+	// Separator is the string that separates the words of a mnemonic
+	// sentence in this language.
+	Separator string
+
+	// wordMap maps the NFKD normalized words to their index in the list.
+	wordMap map[string]int
+}
+
+var (
+	// LanguageEnglish is the English BIP39 word list.
+	LanguageEnglish = newLanguage("english", English, " ")
+
+	// LanguageChineseSimplified is the simplified Chinese BIP39 word list.
+	LanguageChineseSimplified = newLanguage(
+		"chinese_simplified", ChineseSimplified, " ",
+	)
+
+	// LanguageChineseTraditional is the traditional Chinese BIP39 word
+	// list.
+	LanguageChineseTraditional = newLanguage(
+		"chinese_traditional", ChineseTraditional, " ",
+	)
+
+	// LanguageCzech is the Czech BIP39 word list.
+	LanguageCzech = newLanguage("czech", Czech, " ")
+
+	// LanguageFrench is the French BIP39 word list.
+	LanguageFrench = newLanguage("french", French, " ")
+
+	// LanguageItalian is the Italian BIP39 word list.
+	LanguageItalian = newLanguage("italian", Italian, " ")
+
+	// LanguageJapanese is the Japanese BIP39 word list. Japanese mnemonics
+	// are separated by ideographic spaces.
+	LanguageJapanese = newLanguage("japanese", Japanese, "\u3000")
+
+	// LanguageKorean is the Korean BIP39 word list.
+	LanguageKorean = newLanguage("korean", Korean, " ")
+
+	// LanguagePortuguese is the Portuguese BIP39 word list.
+	LanguagePortuguese = newLanguage("portuguese", Portuguese, " ")
+
+	// LanguageSpanish is the Spanish BIP39 word list.
+	LanguageSpanish = newLanguage("spanish", Spanish, " ")
+
+	// Languages are all word lists known to this package. Mnemonics in
+	// any of them can be decoded, the language is detected automatically.
+	// English comes first, so it is chosen if the words of a mnemonic are
+	// part of multiple word lists.
+	Languages = []*Language{
+		LanguageEnglish,
+		LanguageChineseSimplified,
+		LanguageChineseTraditional,
+		LanguageCzech,
+		LanguageFrench,
+		LanguageItalian,
+		LanguageJapanese,
+		LanguageKorean,
+		LanguagePortuguese,
+		LanguageSpanish,
+	}
+)
+
+// newLanguage creates a language from the given word list.
+func newLanguage(name string, words []string, separator string) *Language {
+	if len(words) != 2048 {
+		panic(fmt.Sprintf("%s word list has %d words", name,
+			len(words)))
+	}
+
+	wordMap := make(map[string]int, len(words))
+	for i, word := range words {
+		wordMap[NormalizeString(word)] = i
+	}
+
+	return &Language{
+		Name:      name,
+		Words:     words,
+		Separator: separator,
+		wordMap:   wordMap,
+	}
+}
+
+// WordIndex returns the index of the given word in the list. The word is
+// normalized before it is looked up.
+func (l *Language) WordIndex(word string) (int, bool) {
+	index, ok := l.wordMap[NormalizeString(word)]
+	return index, ok
+}
+
+// LanguageByName returns the language with the given name.
+func LanguageByName(name string) (*Language, error) {
+	for _, language := range Languages {
+		if language.Name == strings.ToLower(name) {
+			return language, nil
+		}
+	}
+
+	names := make([]string, len(Languages))
+	for i, language := range Languages {
+		names[i] = language.Name
+	}
+
+	return nil, fmt.Errorf("unknown BIP39 language '%s', supported are: "+
+		"%s", name, strings.Join(names, ", "))
+}
+
+// NormalizeString returns the NFKD normal form of the given string, which is
+// the form BIP39 uses for mnemonics and passphrases.
+func NormalizeString(s string) string {
+	return norm.NFKD.String(s)
+}
+
+// NormalizeMnemonic returns the NFKD normal form of the given mnemonic with
+// its words separated by a single space. This also turns the ideographic
+// spaces that separate Japanese words into normal spaces.
+func NormalizeMnemonic(mnemonic string) string {
+	return strings.Join(strings.Fields(NormalizeString(mnemonic)), " ")
+}
+
+// DetectLanguage returns the languages that contain all the given words. Some
+// words are part of multiple word lists, so more than one language can be
+// returned.
+func DetectLanguage(words []string) ([]*Language, error) {
+	var languages []*Language
+	for _, language := range Languages {
+		containsAll := true
+		for _, word := range words {
+			if _, ok := language.WordIndex(word); !ok {
+				containsAll = false
+				break
+			}
+		}
+
+		if containsAll {
+			languages = append(languages, language)
+		}
+	}
+
+	if len(languages) == 0 {
+		for _, word := range words {
+			if !inAnyLanguage(word) {
+				return nil, fmt.Errorf("word `%v` not found "+
+					"in any word list", word)
+			}
+		}
+
+		return nil, fmt.Errorf("%w: words of different word lists",
+			ErrInvalidMnemonic)
+	}
+
+	return languages, nil
+}
+
+// inAnyLanguage returns true if the word is part of any known word list.
+func inAnyLanguage(word string) bool {
+	for _, language := range Languages {
+		if _, ok := language.WordIndex(word); ok {
+			return true
+		}
+	}
+
+	return false
+}
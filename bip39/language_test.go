@@ -0,0 +1,135 @@
+package bip39
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeMnemonic(t *testing.T) {
+	// Japanese mnemonics are separated by ideographic spaces and NFKD
+	// decomposes the voiced kana into the base kana and a separate mark.
+	require.Equal(
+		t, "\u304b\u3099 \u3042",
+		NormalizeMnemonic("\u304c\u3000\u3042"),
+	)
+	require.Equal(t, "abandon ability", NormalizeMnemonic(
+		"  abandon \t ability\n",
+	))
+}
+
+func TestDecodeMnemonic(t *testing.T) {
+	entropy := bytes.Repeat([]byte{0x7f}, 16)
+	mnemonic, err := NewMnemonicInLanguage(entropy, LanguageEnglish)
+	require.NoError(t, err)
+	require.Equal(t, "legal winner thank year wave sausage worth useful "+
+		"legal winner thank yellow", mnemonic)
+
+	decoded, language, err := DecodeMnemonic(" " + mnemonic + "\n")
+	require.NoError(t, err)
+	require.Equal(t, entropy, decoded)
+	require.Equal(t, LanguageEnglish, language)
+
+	_, _, err = DecodeMnemonic("legal winner thank year wave sausage " +
+		"worth useful legal winner thank xyz")
+	require.ErrorContains(t, err, "not found in any word list")
+
+	_, _, err = DecodeMnemonic("legal winner thank year wave sausage " +
+		"worth useful legal winner thank year")
+	require.ErrorIs(t, err, ErrChecksumIncorrect)
+}
+
+func TestLanguageByName(t *testing.T) {
+	language, err := LanguageByName("English")
+	require.NoError(t, err)
+	require.Equal(t, LanguageEnglish, language)
+
+	language, err = LanguageByName("japanese")
+	require.NoError(t, err)
+	require.Equal(t, LanguageJapanese, language)
+
+	_, err = LanguageByName("klingon")
+	require.ErrorContains(t, err, "supported are: english, "+
+		"chinese_simplified")
+}
+
+func TestLanguagesRoundTrip(t *testing.T) {
+	entropy := bytes.Repeat([]byte{0x80}, 32)
+	for _, language := range Languages {
+		t.Run(language.Name, func(t *testing.T) {
+			mnemonic, err := NewMnemonicInLanguage(
+				entropy, language,
+			)
+			require.NoError(t, err)
+			require.Len(
+				t, strings.Split(mnemonic, language.Separator),
+				24,
+			)
+
+			decoded, detected, err := DecodeMnemonic(mnemonic)
+			require.NoError(t, err)
+			require.Equal(t, entropy, decoded)
+			require.Equal(t, language, detected)
+		})
+	}
+}
+
+// japaneseVector is one of the official Japanese BIP39 test vectors of
+// https://github.com/bip32JP/bip32JP.github.io/blob/master/test_JP_BIP39.json
+type japaneseVector struct {
+	Entropy  string `json:"entropy"`
+	Mnemonic string `json:"mnemonic"`
+	Seed     string `json:"seed"`
+}
+
+func TestJapaneseVectors(t *testing.T) {
+	const (
+		// The passphrase of the vectors contains characters that are
+		// changed by the NFKD normalization.
+		passphrase           = "㍍ガバヴァぱばぐゞちぢ十人十色"
+		normalizedPassphrase = "メートルガバヴァぱばぐゞちぢ十人十色"
+	)
+
+	content, err := os.ReadFile("testdata/vectors_japanese.json")
+	require.NoError(t, err)
+
+	var vectors []japaneseVector
+	require.NoError(t, json.Unmarshal(content, &vectors))
+	require.Len(t, vectors, 24)
+
+	for _, vector := range vectors {
+		entropy, err := hex.DecodeString(vector.Entropy)
+		require.NoError(t, err)
+
+		// The words of a generated mnemonic are separated by
+		// ideographic spaces.
+		mnemonic, err := NewMnemonicInLanguage(
+			entropy, LanguageJapanese,
+		)
+		require.NoError(t, err)
+		require.Equal(t, vector.Mnemonic, mnemonic)
+
+		// The mnemonic can be decoded both with ideographic and with
+		// normal spaces.
+		decoded, language, err := DecodeMnemonic(vector.Mnemonic)
+		require.NoError(t, err)
+		require.Equal(t, entropy, decoded)
+		require.Equal(t, LanguageJapanese, language)
+
+		spaced := strings.ReplaceAll(vector.Mnemonic, "\u3000", " ")
+		decoded, _, err = DecodeMnemonic(spaced)
+		require.NoError(t, err)
+		require.Equal(t, entropy, decoded)
+
+		seed := hex.EncodeToString(NewSeed(vector.Mnemonic, passphrase))
+		require.Equal(t, vector.Seed, seed)
+		require.Equal(t, vector.Seed, hex.EncodeToString(
+			NewSeed(spaced, normalizedPassphrase),
+		))
+	}
+}
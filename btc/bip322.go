@@ -0,0 +1,205 @@
+package btc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var (
+	// bip322Tag is the tag of the tagged hash of a BIP-322 message.
+	bip322Tag = []byte("BIP0322-signed-message")
+)
+
+// BIP322MessageHash returns the tagged hash of the given message as defined in
+// BIP-322.
+func BIP322MessageHash(msg []byte) *chainhash.Hash {
+	return chainhash.TaggedHash(bip322Tag, msg)
+}
+
+// bip322ToSpend returns the virtual transaction that creates the output with
+// the given pk script that is spent by the BIP-322 signature of the message.
+func bip322ToSpend(msg, pkScript []byte) (*wire.MsgTx, error) {
+	sigScript, err := txscript.NewScriptBuilder().AddOp(
+		txscript.OP_0,
+	).AddData(BIP322MessageHash(msg)[:]).Script()
+	if err != nil {
+		return nil, fmt.Errorf("error creating sig script: %w", err)
+	}
+
+	tx := wire.NewMsgTx(0)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{
+			Index: wire.MaxPrevOutIndex,
+		},
+		SignatureScript: sigScript,
+		Sequence:        0,
+	})
+	tx.AddTxOut(wire.NewTxOut(0, pkScript))
+
+	return tx, nil
+}
+
+// bip322ToSign returns the virtual transaction that spends the output of the
+// to_spend transaction and contains the BIP-322 signature in its witness.
+func bip322ToSign(toSpend *wire.MsgTx) *wire.MsgTx {
+	tx := wire.NewMsgTx(0)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{
+			Hash:  toSpend.TxHash(),
+			Index: 0,
+		},
+		Sequence: 0,
+	})
+	tx.AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_RETURN}))
+
+	return tx
+}
+
+// SignBIP322Simple creates a BIP-322 "simple" signature of the message for the
+// given P2WKH or P2TR (BIP86 key spend) address with the given private key.
+// The signature is returned base64 encoded.
+func SignBIP322Simple(privKey *btcec.PrivateKey, addr btcutil.Address,
+	msg []byte) (string, error) {
+
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return "", fmt.Errorf("error creating pk script: %w", err)
+	}
+
+	toSpend, err := bip322ToSpend(msg, pkScript)
+	if err != nil {
+		return "", err
+	}
+	toSign := bip322ToSign(toSpend)
+
+	prevOutFetcher := txscript.NewCannedPrevOutputFetcher(pkScript, 0)
+	sigHashes := txscript.NewTxSigHashes(toSign, prevOutFetcher)
+
+	var witness wire.TxWitness
+	switch addr.(type) {
+	case *btcutil.AddressWitnessPubKeyHash:
+		witness, err = txscript.WitnessSignature(
+			toSign, sigHashes, 0, 0, pkScript, txscript.SigHashAll,
+			privKey, true,
+		)
+
+	case *btcutil.AddressTaproot:
+		witness, err = txscript.TaprootWitnessSignature(
+			toSign, sigHashes, 0, 0, pkScript,
+			txscript.SigHashDefault, privKey,
+		)
+
+	default:
+		return "", fmt.Errorf("unsupported address type %T, only "+
+			"P2WKH and P2TR addresses are supported", addr)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error signing message: %w", err)
+	}
+	toSign.TxIn[0].Witness = witness
+
+	// Make sure the private key actually belongs to the address.
+	if err := verifyBIP322(toSpend, toSign, prevOutFetcher); err != nil {
+		return "", fmt.Errorf("private key doesn't belong to address "+
+			"%v: %w", addr, err)
+	}
+
+	var witnessBuf bytes.Buffer
+	if err := psbt.WriteTxWitness(&witnessBuf, witness); err != nil {
+		return "", fmt.Errorf("error serializing witness: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(witnessBuf.Bytes()), nil
+}
+
+// VerifyBIP322Simple verifies the base64 encoded BIP-322 "simple" signature of
+// the message for the given address. Any address type that can be spent with
+// a witness alone is supported.
+func VerifyBIP322Simple(addr btcutil.Address, msg []byte,
+	signature string) error {
+
+	sigBytes, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("error decoding signature: %w", err)
+	}
+
+	witness, err := parseWitness(sigBytes)
+	if err != nil {
+		return fmt.Errorf("error parsing signature: %w", err)
+	}
+
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return fmt.Errorf("error creating pk script: %w", err)
+	}
+
+	toSpend, err := bip322ToSpend(msg, pkScript)
+	if err != nil {
+		return err
+	}
+	toSign := bip322ToSign(toSpend)
+	toSign.TxIn[0].Witness = witness
+
+	prevOutFetcher := txscript.NewCannedPrevOutputFetcher(pkScript, 0)
+	return verifyBIP322(toSpend, toSign, prevOutFetcher)
+}
+
+// verifyBIP322 executes the script of the to_sign transaction.
+func verifyBIP322(toSpend, toSign *wire.MsgTx,
+	prevOutFetcher txscript.PrevOutputFetcher) error {
+
+	vm, err := txscript.NewEngine(
+		toSpend.TxOut[0].PkScript, toSign, 0,
+		txscript.StandardVerifyFlags, nil,
+		txscript.NewTxSigHashes(toSign, prevOutFetcher), 0,
+		prevOutFetcher,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating script engine: %w", err)
+	}
+
+	if err := vm.Execute(); err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+
+	return nil
+}
+
+// parseWitness parses a witness stack in its consensus serialization.
+func parseWitness(witnessBytes []byte) (wire.TxWitness, error) {
+	r := bytes.NewReader(witnessBytes)
+	numItems, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each item needs at least one byte for its length.
+	if numItems > uint64(len(witnessBytes)) {
+		return nil, fmt.Errorf("invalid number of witness items %d",
+			numItems)
+	}
+
+	witness := make(wire.TxWitness, numItems)
+	for i := range witness {
+		witness[i], err = wire.ReadVarBytes(
+			r, 0, wire.MaxBlockPayload, "witness item",
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%d unexpected bytes after witness",
+			r.Len())
+	}
+
+	return witness, nil
+}
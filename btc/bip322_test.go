@@ -0,0 +1,85 @@
+package btc
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/stretchr/testify/require"
+)
+
+// The test vectors are taken from BIP-322.
+const (
+	bip322PrivKey = "L3VFeEujGtevx9w18HD1fhRbCH67Az2dpCymeRE1SoPK6XQtaN2k"
+
+	bip322AddrP2WKH = "bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l"
+	bip322AddrP2TR  = "bc1ppv609nr0vr25u07u95waq5lucwfm6tde4nydujnu8npg" +
+		"4q75mr5sxq8lt3"
+
+	bip322SigEmpty = "AkcwRAIgM2gBAQqvZX15ZiysmKmQpDrG83avLIT492QBzLnQIxY" +
+		"CIBaTpOaD20qRlEylyxFSeEA2ba9YOixpX8z46TSDtS40ASECx/EgAxlkQp" +
+		"Q9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI="
+	bip322SigHello = "AkcwRAIgZRfIY3p7/DoVTty6YZbWS71bc5Vct9p9Fia83eRmw2Q" +
+		"CICK/ENGfwLtptFluMGs2KsqoNSk89pO7F29zJLUx9a/sASECx/EgAxlkQp" +
+		"Q9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI="
+	bip322SigTaproot = "AUHd69PrJQEv+oKTfZ8l+WROBHuy9HKrbFCJu7U1iK2iiEy1v" +
+		"MU5EfMtjc+VSHM7aU0SDbak5IUZRVno2P5mjSafAQ=="
+)
+
+func TestBIP322MessageHash(t *testing.T) {
+	require.Equal(
+		t, "c90c269c4f8fcbe6880f72a721ddfbf1914268a794cbb21cfafee13"+
+			"770ae19f1",
+		hex.EncodeToString(BIP322MessageHash(nil)[:]),
+	)
+	require.Equal(
+		t, "f0eb03b1a75ac6d9847f55c624a99169b5dccba2a31f5b23bea77ba"+
+			"270de0a7a",
+		hex.EncodeToString(BIP322MessageHash([]byte("Hello World"))[:]),
+	)
+}
+
+func TestBIP322Simple(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	wif, err := btcutil.DecodeWIF(bip322PrivKey)
+	require.NoError(t, err)
+
+	addrP2WKH, err := btcutil.DecodeAddress(bip322AddrP2WKH, params)
+	require.NoError(t, err)
+	addrP2TR, err := btcutil.DecodeAddress(bip322AddrP2TR, params)
+	require.NoError(t, err)
+
+	// The signatures of the test vectors were created by Bitcoin Core,
+	// which grinds the nonce for a low R value. So we can't reproduce them
+	// exactly but only verify them.
+	require.NoError(t, VerifyBIP322Simple(addrP2WKH, nil, bip322SigEmpty))
+	require.NoError(t, VerifyBIP322Simple(
+		addrP2WKH, []byte("Hello World"), bip322SigHello,
+	))
+	require.Error(t, VerifyBIP322Simple(
+		addrP2WKH, []byte("Hello World"), bip322SigEmpty,
+	))
+	require.NoError(t, VerifyBIP322Simple(
+		addrP2TR, []byte("Hello World"), bip322SigTaproot,
+	))
+
+	sig, err := SignBIP322Simple(wif.PrivKey, addrP2WKH, []byte("foo"))
+	require.NoError(t, err)
+	require.NoError(t, VerifyBIP322Simple(addrP2WKH, []byte("foo"), sig))
+	require.Error(t, VerifyBIP322Simple(addrP2WKH, []byte("bar"), sig))
+
+	sig, err = SignBIP322Simple(wif.PrivKey, addrP2TR, []byte("foo"))
+	require.NoError(t, err)
+	require.NoError(t, VerifyBIP322Simple(addrP2TR, []byte("foo"), sig))
+	require.Error(t, VerifyBIP322Simple(addrP2TR, []byte("bar"), sig))
+
+	// Signing with a key that doesn't belong to the address must fail.
+	otherKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	_, err = SignBIP322Simple(otherKey, addrP2WKH, nil)
+	require.ErrorContains(t, err, "doesn't belong to address")
+	_, err = SignBIP322Simple(otherKey, addrP2TR, nil)
+	require.ErrorContains(t, err, "doesn't belong to address")
+}
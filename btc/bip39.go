@@ -45,9 +45,11 @@ func ReadMnemonicFromTerminal(params *chaincfg.Params) (*hdkeychain.ExtendedKey,
 	}
 
 	// We'll trim off extra spaces, and ensure the mnemonic is all
-	// lower case.
+	// lower case. BIP39 uses the NFKD normal form of the mnemonic, which
+	// also replaces the ideographic spaces between Japanese words.
 	mnemonicStr = strings.TrimSpace(mnemonicStr)
 	mnemonicStr = strings.ToLower(mnemonicStr)
+	mnemonicStr = bip39.NormalizeMnemonic(mnemonicStr)
 
 	mnemonicWords := strings.Split(mnemonicStr, " ")
 	if len(mnemonicWords) < 12 || len(mnemonicWords) > 24 {
@@ -91,10 +93,14 @@ func ReadMnemonicFromTerminal(params *chaincfg.Params) (*hdkeychain.ExtendedKey,
 		fmt.Println()
 
 		// Check that the mnemonic is valid.
-		_, err = bip39.EntropyFromMnemonic(mnemonicStr)
+		var language *bip39.Language
+		_, language, err = bip39.DecodeMnemonic(mnemonicStr)
 		if err != nil {
 			return nil, err
 		}
+		if language != bip39.LanguageEnglish {
+			fmt.Printf("Detected %s mnemonic\n\n", language.Name)
+		}
 
 		fmt.Printf("Please choose passphrase mode:\n" +
 			"  0 - Default BIP39\n" +
@@ -115,11 +121,7 @@ func ReadMnemonicFromTerminal(params *chaincfg.Params) (*hdkeychain.ExtendedKey,
 
 	switch strings.TrimSpace(choice) {
 	case "", "0":
-		seed = pbkdf2.Key(
-			[]byte(mnemonicStr), append(
-				[]byte("mnemonic"), passphraseBytes...,
-			), 2048, 64, sha512.New,
-		)
+		seed = bip39.NewSeed(mnemonicStr, string(passphraseBytes))
 
 	case "1":
 		p := []byte(hex.EncodeToString(passphraseBytes))
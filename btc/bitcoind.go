@@ -9,7 +9,6 @@ import (
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
 	"github.com/btcsuite/btcd/chaincfg"
-	"github.com/btcsuite/btcd/wire"
 	"github.com/lightninglabs/chantools/lnd"
 )
 
@@ -20,6 +19,10 @@ const (
 	FormatDescriptors  = "bitcoin-descriptors"
 	FormatElectrum     = "electrum"
 
+	// FormatDescriptorsRanged isn't a per-key format, the wallet is
+	// exported with ExportRangedDescriptors instead.
+	FormatDescriptorsRanged = "bitcoin-descriptors-ranged"
+
 	PasteString = "# Paste the following lines into a command line window."
 )
 
@@ -27,7 +30,7 @@ type KeyExporter interface {
 	Header() string
 	Format(hdKey *hdkeychain.ExtendedKey, params *chaincfg.Params,
 		path string, branch, index uint32) (string, error)
-	Trailer(birthdayBlock uint32) string
+	Trailer(params *chaincfg.Params, birthdayBlock uint32) string
 }
 
 // ParseFormat parses the given format name and returns its associated print
@@ -99,34 +102,83 @@ func ExportKeys(extendedKey *hdkeychain.ExtendedKey, strPaths []string,
 		}
 	}
 
-	_, _ = fmt.Fprintf(writer, "%s\n", exporter.Trailer(rescanFrom))
+	_, _ = fmt.Fprintf(writer, "%s\n", exporter.Trailer(params, rescanFrom))
 	return nil
 }
 
+// testnet4GenesisTimestamp is the timestamp of the testnet4 genesis block.
+// The chain parameters of testnet4 aren't part of the btcd version we use, so
+// we identify the network by its name only.
+var testnet4GenesisTimestamp = time.Unix(1714777860, 0)
+
+// SeedBirthdayToBlock estimates the height of the block that was mined at the
+// given birthday by assuming an average block time of ten minutes since the
+// genesis block. This is only a fallback if the block can't be looked up by
+// its timestamp.
 func SeedBirthdayToBlock(params *chaincfg.Params,
-	birthdayTimestamp time.Time) uint32 {
+	birthdayTimestamp time.Time) (uint32, error) {
 
 	var genesisTimestamp time.Time
 	switch params.Name {
-	case "mainnet":
+	case chaincfg.MainNetParams.Name:
 		genesisTimestamp =
 			chaincfg.MainNetParams.GenesisBlock.Header.Timestamp
 
-	case "testnet3":
+	case chaincfg.TestNet3Params.Name:
 		genesisTimestamp =
 			chaincfg.TestNet3Params.GenesisBlock.Header.Timestamp
 
-	case "regtest", "simnet":
-		return 0
+	case "testnet4":
+		genesisTimestamp = testnet4GenesisTimestamp
+
+	case chaincfg.SigNetParams.Name:
+		genesisTimestamp =
+			chaincfg.SigNetParams.GenesisBlock.Header.Timestamp
+
+	case chaincfg.RegressionNetParams.Name, chaincfg.SimNetParams.Name:
+		return 0, nil
 
 	default:
-		panic(fmt.Errorf("unimplemented network %v", params.Name))
+		return 0, fmt.Errorf("unimplemented network %v", params.Name)
+	}
+
+	if birthdayTimestamp.Before(genesisTimestamp) {
+		return 0, nil
 	}
 
 	// With the timestamps retrieved, we can estimate a block height by
 	// taking the difference between them and dividing by the average block
 	// time (10 minutes).
-	return uint32(birthdayTimestamp.Sub(genesisTimestamp).Seconds() / 600)
+	return uint32(birthdayTimestamp.Sub(genesisTimestamp).Seconds() / 600),
+		nil
+}
+
+// birthdayComment returns the comment line that documents the block height
+// the wallet should be rescanned from.
+func birthdayComment(birthdayBlock uint32) string {
+	return fmt.Sprintf("# Block height to rescan from (wallet birthday): "+
+		"%d", birthdayBlock)
+}
+
+// bitcoinCliFlags returns the bitcoin-cli flags that select the network of
+// the given chain parameters.
+func bitcoinCliFlags(params *chaincfg.Params) string {
+	switch params.Name {
+	case chaincfg.TestNet3Params.Name:
+		return " -testnet"
+
+	case "testnet4":
+		return " -testnet4"
+
+	case chaincfg.SigNetParams.Name:
+		return " -signet"
+
+	case chaincfg.RegressionNetParams.Name:
+		return " -regtest"
+
+	default:
+		return ""
+	}
 }
 
 type Cli struct{}
@@ -146,16 +198,13 @@ func (c *Cli) Format(hdKey *hdkeychain.ExtendedKey, params *chaincfg.Params,
 	if err != nil {
 		return "", fmt.Errorf("could not encode WIF: %w", err)
 	}
-	flags := ""
-	if params.Net == wire.TestNet || params.Net == wire.TestNet3 {
-		flags = " -testnet"
-	}
+	flags := bitcoinCliFlags(params)
 	return fmt.Sprintf("bitcoin-cli%s importprivkey %s \"%s/%d/%d/\" false",
 		flags, wif.String(), path, branch, index), nil
 }
 
-func (c *Cli) Trailer(birthdayBlock uint32) string {
-	return fmt.Sprintf("bitcoin-cli rescanblockchain %d\n", birthdayBlock)
+func (c *Cli) Trailer(params *chaincfg.Params, birthdayBlock uint32) string {
+	return rescanTrailer(params, birthdayBlock)
 }
 
 type CliWatchOnly struct{}
@@ -186,17 +235,16 @@ func (c *CliWatchOnly) Format(hdKey *hdkeychain.ExtendedKey,
 		return "", fmt.Errorf("could not create address: %w", err)
 	}
 
-	flags := ""
-	if params.Net == wire.TestNet || params.Net == wire.TestNet3 {
-		flags = " -testnet"
-	}
+	flags := bitcoinCliFlags(params)
 	return fmt.Sprintf("bitcoin-cli%s importpubkey %x \"%s/%d/%d/\" "+
 		"false # addr=%s,%s,%s", flags, pubKey.SerializeCompressed(),
 		path, branch, index, addrP2PKH, addrP2WKH, addrNP2WKH), nil
 }
 
-func (c *CliWatchOnly) Trailer(birthdayBlock uint32) string {
-	return fmt.Sprintf("bitcoin-cli rescanblockchain %d\n", birthdayBlock)
+func (c *CliWatchOnly) Trailer(params *chaincfg.Params,
+	birthdayBlock uint32) string {
+
+	return rescanTrailer(params, birthdayBlock)
 }
 
 type ImportWallet struct{}
@@ -242,15 +290,17 @@ func (i *ImportWallet) Format(hdKey *hdkeychain.ExtendedKey,
 	), nil
 }
 
-func (i *ImportWallet) Trailer(_ uint32) string {
-	return ""
+func (i *ImportWallet) Trailer(_ *chaincfg.Params,
+	birthdayBlock uint32) string {
+
+	return birthdayComment(birthdayBlock)
 }
 
 type Electrum struct{}
 
 func (p *Electrum) Header() string {
-	return "# Copy the content of this file (without this line) into " +
-		"Electrum."
+	return "# Copy the content of this file (without the lines starting " +
+		"with #) into Electrum."
 }
 
 func (p *Electrum) Format(hdKey *hdkeychain.ExtendedKey,
@@ -274,8 +324,8 @@ func (p *Electrum) Format(hdKey *hdkeychain.ExtendedKey,
 	return fmt.Sprintf("%s:%s", prefix, wif.String()), nil
 }
 
-func (p *Electrum) Trailer(_ uint32) string {
-	return ""
+func (p *Electrum) Trailer(_ *chaincfg.Params, birthdayBlock uint32) string {
+	return birthdayComment(birthdayBlock)
 }
 
 type Descriptors struct{}
@@ -317,8 +367,18 @@ func (d *Descriptors) Format(hdKey *hdkeychain.ExtendedKey,
 		np2wkh, p2wkh, p2tr), nil
 }
 
-func (d *Descriptors) Trailer(birthdayBlock uint32) string {
-	return fmt.Sprintf("bitcoin-cli rescanblockchain %d\n", birthdayBlock)
+func (d *Descriptors) Trailer(params *chaincfg.Params,
+	birthdayBlock uint32) string {
+
+	return rescanTrailer(params, birthdayBlock)
+}
+
+// rescanTrailer returns the trailer of the bitcoin-cli based formats that
+// rescans the chain from the wallet birthday.
+func rescanTrailer(params *chaincfg.Params, birthdayBlock uint32) string {
+	return fmt.Sprintf("%s\nbitcoin-cli%s rescanblockchain %d\n",
+		birthdayComment(birthdayBlock), bitcoinCliFlags(params),
+		birthdayBlock)
 }
 
 func makeDescriptor(format, wif string, address btcutil.Address) string {
@@ -0,0 +1,63 @@
+package btc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeedBirthdayToBlock(t *testing.T) {
+	// One day of blocks after the signet genesis block.
+	signetGenesis := chaincfg.SigNetParams.GenesisBlock.Header.Timestamp
+	height, err := SeedBirthdayToBlock(
+		&chaincfg.SigNetParams, signetGenesis.Add(24*time.Hour),
+	)
+	require.NoError(t, err)
+	require.EqualValues(t, 144, height)
+
+	// Testnet4 is only known by its name.
+	testnet4Params := chaincfg.TestNet3Params
+	testnet4Params.Name = "testnet4"
+	height, err = SeedBirthdayToBlock(
+		&testnet4Params, testnet4GenesisTimestamp.Add(time.Hour),
+	)
+	require.NoError(t, err)
+	require.EqualValues(t, 6, height)
+
+	// A birthday before the genesis block starts at the beginning.
+	height, err = SeedBirthdayToBlock(
+		&chaincfg.MainNetParams, signetGenesis.AddDate(-20, 0, 0),
+	)
+	require.NoError(t, err)
+	require.Zero(t, height)
+
+	// Local networks are always scanned from the start.
+	height, err = SeedBirthdayToBlock(
+		&chaincfg.RegressionNetParams, time.Now(),
+	)
+	require.NoError(t, err)
+	require.Zero(t, height)
+
+	unknownParams := chaincfg.MainNetParams
+	unknownParams.Name = "unknown"
+	_, err = SeedBirthdayToBlock(&unknownParams, time.Now())
+	require.Error(t, err)
+}
+
+func TestExporterTrailers(t *testing.T) {
+	exporters := []KeyExporter{
+		&Cli{}, &CliWatchOnly{}, &ImportWallet{}, &Electrum{},
+		&Descriptors{},
+	}
+	for _, exporter := range exporters {
+		trailer := exporter.Trailer(&chaincfg.SigNetParams, 123)
+		require.Contains(t, trailer, birthdayComment(123))
+	}
+
+	require.Contains(
+		t, (&Cli{}).Trailer(&chaincfg.SigNetParams, 123),
+		"bitcoin-cli -signet rescanblockchain 123",
+	)
+}
@@ -1,83 +1,238 @@
 package btc
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"strings"
-)
+	"time"
 
-var (
-	inputCharset = "0123456789()[],'/*abcdefgh@:$%{}IJKLMNOPQRSTUVWXYZ" +
-		"&+-.;<=>?!^_|~ijklmnopqrstuvwxyzABCDEFGH`#\\\"\\\\ "
-	checksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
-	generator       = []uint64{
-		0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a,
-		0x644d626ffd,
-	}
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcwallet/waddrmgr"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/keychain"
 )
 
-func descriptorSumPolymod(symbols []uint64) uint64 {
-	chk := uint64(1)
-	for _, value := range symbols {
-		top := chk >> 35
-		chk = (chk&0x7ffffffff)<<5 ^ value
-		for i := range 5 {
-			if (top>>i)&1 != 0 {
-				chk ^= generator[i]
-			}
-		}
+// DescriptorSumCreate appends the checksum to the given descriptor.
+func DescriptorSumCreate(s string) string {
+	return lnd.DescriptorSumCreate(s)
+}
+
+// DescriptorSumCheck verifies the checksum of the given descriptor. If require
+// is false, a descriptor without checksum is also accepted.
+func DescriptorSumCheck(s string, require bool) bool {
+	return lnd.DescriptorSumCheck(s, require)
+}
+
+// RangedDescriptor is one entry of the bitcoin-cli importdescriptors command
+// that covers a whole branch of an account.
+type RangedDescriptor struct {
+	Desc      string    `json:"desc"`
+	Timestamp int64     `json:"timestamp"`
+	Active    bool      `json:"active"`
+	Internal  bool      `json:"internal"`
+	Range     [2]uint32 `json:"range"`
+}
+
+// MasterFingerprint returns the BIP32 fingerprint of the given root key.
+func MasterFingerprint(extendedKey *hdkeychain.ExtendedKey) ([]byte, error) {
+	pubKey, err := extendedKey.ECPubKey()
+	if err != nil {
+		return nil, fmt.Errorf("could not derive public key: %w", err)
 	}
-	return chk
+
+	return btcutil.Hash160(pubKey.SerializeCompressed())[:4], nil
 }
 
-func descriptorSumExpand(s string) []uint64 {
-	groups := []uint64{}
-	symbols := []uint64{}
-	for _, c := range s {
-		v := strings.IndexRune(inputCharset, c)
-		if v < 0 {
-			return nil
-		}
-		symbols = append(symbols, uint64(v&31))
-		groups = append(groups, uint64(v>>5))
-		if len(groups) == 3 {
-			symbols = append(
-				symbols, groups[0]*9+groups[1]*3+groups[2],
+// DescriptorPath formats the given derivation path the way it is used in the
+// key origin of a descriptor. We use "h" instead of "'" for hardened levels so
+// the descriptors can be used within single quotes on the command line.
+func DescriptorPath(path []uint32) string {
+	parts := make([]string, len(path))
+	for idx, part := range path {
+		if part >= hdkeychain.HardenedKeyStart {
+			parts[idx] = fmt.Sprintf(
+				"%dh", part-hdkeychain.HardenedKeyStart,
 			)
-			groups = []uint64{}
+			continue
 		}
+		parts[idx] = fmt.Sprintf("%d", part)
+	}
+
+	return strings.Join(parts, "/")
+}
+
+// AccountKeyExpression derives the account level extended private key at the
+// given path and returns it as a descriptor key expression including the key
+// origin, for example [fingerprint/84h/0h/0h]xprv... The account key is
+// derived the same way lnd does, the non-hardened branch and index levels
+// below it are derived by the wallet that imports the descriptor.
+func AccountKeyExpression(extendedKey *hdkeychain.ExtendedKey,
+	params *chaincfg.Params, accountPath []uint32) (string, error) {
+
+	fingerprint, err := MasterFingerprint(extendedKey)
+	if err != nil {
+		return "", err
 	}
-	if len(groups) == 1 {
-		symbols = append(symbols, groups[0])
-	} else if len(groups) == 2 {
-		symbols = append(symbols, groups[0]*3+groups[1])
+	accountKey, err := lnd.DeriveChildren(extendedKey, accountPath)
+	if err != nil {
+		return "", fmt.Errorf("could not derive account key: %w", err)
 	}
-	return symbols
+	accountKey, err = accountKey.CloneWithVersion(params.HDPrivateKeyID[:])
+	if err != nil {
+		return "", fmt.Errorf("could not encode account key: %w", err)
+	}
+
+	return fmt.Sprintf("[%x/%s]%s", fingerprint,
+		DescriptorPath(accountPath), accountKey.String()), nil
 }
 
-func DescriptorSumCreate(s string) string {
-	symbols := append(descriptorSumExpand(s), 0, 0, 0, 0, 0, 0, 0, 0)
-	checksum := descriptorSumPolymod(symbols) ^ 1
-	builder := strings.Builder{}
-	for i := range 8 {
-		builder.WriteByte(checksumCharset[(checksum>>(5*(7-i)))&31])
+// accountPath returns the account level derivation path of the given wallet
+// account.
+func (a *WalletAccount) accountPath(params *chaincfg.Params) []uint32 {
+	return a.Path(params, 0, 0)[:3]
+}
+
+// descriptorFormat returns the descriptor script format for the given branch
+// of the account. lnd's BIP0049 account uses nested SegWit addresses for
+// receiving but native SegWit addresses for change.
+func (a *WalletAccount) descriptorFormat(branch uint32) string {
+	switch a.Purpose {
+	case waddrmgr.KeyScopeBIP0049Plus.Purpose:
+		if branch == 1 {
+			return "wpkh(%s)"
+		}
+		return "sh(wpkh(%s))"
+
+	case waddrmgr.KeyScopeBIP0086.Purpose:
+		return "tr(%s)"
+
+	default:
+		return "wpkh(%s)"
 	}
-	return s + "#" + builder.String()
 }
 
-func DescriptorSumCheck(s string, require bool) bool {
-	if !strings.Contains(s, "#") {
-		return !require
+// RangedDescriptors returns the ranged descriptors of the external and
+// internal branches of lnd's default wallet accounts. If lndFamilies isn't
+// empty, descriptors for those lnd internal key families are added as well.
+// The descriptors cover the first recoveryWindow keys of every branch and
+// are rescanned from the given birthday.
+func RangedDescriptors(extendedKey *hdkeychain.ExtendedKey,
+	params *chaincfg.Params, lndFamilies []keychain.KeyFamily,
+	recoveryWindow uint32, birthday time.Time) ([]*RangedDescriptor,
+	error) {
+
+	if recoveryWindow == 0 {
+		return nil, errors.New("recovery window must be positive")
 	}
-	if s[len(s)-9] != '#' {
-		return false
+
+	// A zero birthday means we don't know it, so we need to scan the
+	// whole chain.
+	if birthday.IsZero() {
+		birthday = time.Unix(0, 0)
+	}
+
+	var (
+		keyRange    = [2]uint32{0, recoveryWindow - 1}
+		descriptors []*RangedDescriptor
+	)
+	newDescriptor := func(format, keyExpr string, branch uint32,
+		active bool) *RangedDescriptor {
+
+		desc := fmt.Sprintf(
+			format, fmt.Sprintf("%s/%d/*", keyExpr, branch),
+		)
+		return &RangedDescriptor{
+			Desc:      DescriptorSumCreate(desc),
+			Timestamp: birthday.Unix(),
+			Active:    active,
+			Internal:  active && branch == 1,
+			Range:     keyRange,
+		}
+	}
+
+	for _, account := range DefaultWalletAccounts {
+		keyExpr, err := AccountKeyExpression(
+			extendedKey, params, account.accountPath(params),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, branch := range account.Branches {
+			descriptors = append(descriptors, newDescriptor(
+				account.descriptorFormat(branch), keyExpr,
+				branch, true,
+			))
+		}
 	}
-	for _, c := range s[len(s)-8:] {
-		if !strings.ContainsRune(checksumCharset, c) {
-			return false
+
+	// The keys of lnd's internal key families are all on the external
+	// branch. They aren't used for receiving, so the descriptors aren't
+	// marked as active.
+	for _, family := range lndFamilies {
+		familyPath := []uint32{
+			lnd.HardenedKey(uint32(keychain.BIP0043Purpose)),
+			lnd.HardenedKey(params.HDCoinType),
+			lnd.HardenedKey(uint32(family)),
+		}
+		keyExpr, err := AccountKeyExpression(
+			extendedKey, params, familyPath,
+		)
+		if err != nil {
+			return nil, err
 		}
+
+		descriptors = append(descriptors, newDescriptor(
+			"wpkh(%s)", keyExpr, 0, false,
+		))
 	}
-	symbols := append(
-		descriptorSumExpand(s[:len(s)-9]),
-		uint64(strings.Index(checksumCharset, s[len(s)-8:])),
+
+	return descriptors, nil
+}
+
+// ExportRangedDescriptors writes a bitcoin-cli importdescriptors command that
+// imports the ranged descriptors of the wallet into a descriptor wallet of
+// Bitcoin Core.
+func ExportRangedDescriptors(extendedKey *hdkeychain.ExtendedKey,
+	params *chaincfg.Params, lndFamilies []keychain.KeyFamily,
+	recoveryWindow uint32, birthday time.Time, birthdayBlock uint32,
+	writer io.Writer) error {
+
+	descriptors, err := RangedDescriptors(
+		extendedKey, params, lndFamilies, recoveryWindow, birthday,
 	)
-	return descriptorSumPolymod(symbols) == 1
+	if err != nil {
+		return err
+	}
+
+	lines := make([]string, len(descriptors))
+	for idx, descriptor := range descriptors {
+		descriptorJSON, err := json.Marshal(descriptor)
+		if err != nil {
+			return fmt.Errorf("could not encode descriptor: %w",
+				err)
+		}
+		lines[idx] = string(descriptorJSON)
+	}
+
+	_, _ = fmt.Fprintf(
+		writer, "# Wallet dump created by chantools on %s\n",
+		time.Now().UTC(),
+	)
+	_, _ = fmt.Fprintf(writer, "%s\n", PasteString)
+	_, _ = fmt.Fprintf(
+		writer, "bitcoin-cli%s importdescriptors '[\n%s\n]'\n",
+		bitcoinCliFlags(params), strings.Join(lines, ",\n"),
+	)
+	_, _ = fmt.Fprintf(
+		writer, "%s\n# The import rescans the chain from the wallet "+
+			"birthday, no separate rescan is required.\n",
+		birthdayComment(birthdayBlock),
+	)
+
+	return nil
 }
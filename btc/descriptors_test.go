@@ -1,8 +1,14 @@
 package btc
 
 import (
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/keychain"
 	"github.com/stretchr/testify/require"
 )
 
@@ -22,6 +28,69 @@ func TestDescriptorSum(t *testing.T) {
 		sum := DescriptorSumCreate(tc.descriptor)
 		require.Equal(t, tc.descriptor+tc.expectedSum, sum)
 
-		DescriptorSumCheck(sum, true)
+		require.True(t, DescriptorSumCheck(sum, true))
+		require.False(t, DescriptorSumCheck(sum[:len(sum)-1]+"q", true))
 	}
 }
+
+func TestRangedDescriptors(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	extendedKey, err := hdkeychain.NewKeyFromString(testRootKey)
+	require.NoError(t, err)
+
+	birthday := time.Unix(1700000000, 0)
+	descriptors, err := RangedDescriptors(
+		extendedKey, params, []keychain.KeyFamily{
+			keychain.KeyFamilyPaymentBase,
+		}, 100, birthday,
+	)
+	require.NoError(t, err)
+	require.Len(t, descriptors, 7)
+
+	for _, descriptor := range descriptors {
+		require.True(t, DescriptorSumCheck(descriptor.Desc, true))
+		require.Equal(t, [2]uint32{0, 99}, descriptor.Range)
+		require.Equal(t, birthday.Unix(), descriptor.Timestamp)
+	}
+
+	// lnd's nested SegWit account uses native SegWit change addresses.
+	require.True(t, strings.HasPrefix(descriptors[0].Desc, "sh(wpkh("))
+	require.True(t, strings.HasPrefix(descriptors[1].Desc, "wpkh("))
+	require.True(t, descriptors[1].Internal)
+	require.True(t, strings.HasPrefix(descriptors[4].Desc, "tr("))
+
+	// The lnd key family isn't used for receiving.
+	require.Contains(t, descriptors[6].Desc, "/1017h/1h/3h]")
+	require.False(t, descriptors[6].Active)
+
+	// The account key in the descriptor derives the same addresses as the
+	// wallet scanner does.
+	account := WalletAccountP2WKH
+	desc := descriptors[2].Desc
+	keyStart := strings.Index(desc, "]") + 1
+	keyEnd := strings.Index(desc[keyStart:], "/") + keyStart
+	accountKey, err := hdkeychain.NewKeyFromString(desc[keyStart:keyEnd])
+	require.NoError(t, err)
+	require.True(t, accountKey.IsPrivate())
+
+	branchKey, err := accountKey.Derive(0)
+	require.NoError(t, err)
+	indexKey, err := branchKey.Derive(5)
+	require.NoError(t, err)
+	pubKey, err := indexKey.ECPubKey()
+	require.NoError(t, err)
+
+	expectedKey, err := lnd.PrivKeyFromPath(
+		extendedKey, account.Path(params, 0, 5),
+	)
+	require.NoError(t, err)
+	require.Equal(t, expectedKey.PubKey(), pubKey)
+
+	// Without a birthday, the whole chain is rescanned.
+	descriptors, err = RangedDescriptors(
+		extendedKey, params, nil, 100, time.Time{},
+	)
+	require.NoError(t, err)
+	require.Len(t, descriptors, 6)
+	require.Zero(t, descriptors[0].Timestamp)
+}
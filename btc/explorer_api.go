@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -19,9 +20,13 @@ type ExplorerAPI struct {
 }
 
 type TX struct {
-	TXID string  `json:"txid"`
-	Vin  []*Vin  `json:"vin"`
-	Vout []*Vout `json:"vout"`
+	TXID    string  `json:"txid"`
+	Version int32   `json:"version"`
+	Weight  int64   `json:"weight"`
+	Fee     uint64  `json:"fee"`
+	Vin     []*Vin  `json:"vin"`
+	Vout    []*Vout `json:"vout"`
+	Status  *Status `json:"status"`
 }
 
 type Vin struct {
@@ -67,6 +72,13 @@ type AddressStats struct {
 	MempoolStats *Stats `json:"mempool_stats"`
 }
 
+type UTXO struct {
+	TXID   string  `json:"txid"`
+	Vout   uint32  `json:"vout"`
+	Status *Status `json:"status"`
+	Value  uint64  `json:"value"`
+}
+
 func (a *ExplorerAPI) Transaction(txid string) (*TX, error) {
 	tx := &TX{}
 	err := fetchJSON(fmt.Sprintf("%s/tx/%s", a.BaseURL, txid), tx)
@@ -187,6 +199,30 @@ func (a *ExplorerAPI) Unspent(addr string) ([]*Vout, error) {
 	return unspent, nil
 }
 
+// AddressStats returns the on-chain and mempool statistics of an address.
+func (a *ExplorerAPI) AddressStats(addr string) (*AddressStats, error) {
+	stats := &AddressStats{}
+	err := fetchJSON(fmt.Sprintf("%s/address/%s", a.BaseURL, addr), stats)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// UTXOs returns all unspent outputs of an address, including unconfirmed ones.
+func (a *ExplorerAPI) UTXOs(addr string) ([]*UTXO, error) {
+	var utxos []*UTXO
+	err := fetchJSON(
+		fmt.Sprintf("%s/address/%s/utxo", a.BaseURL, addr), &utxos,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return utxos, nil
+}
+
 func (a *ExplorerAPI) Address(outpoint string) (string, error) {
 	parts := strings.Split(outpoint, ":")
 
@@ -211,9 +247,95 @@ func (a *ExplorerAPI) Address(outpoint string) (string, error) {
 	return tx.Vout[vout].ScriptPubkeyAddr, nil
 }
 
+// BlockHeight returns the height of the current best block.
+func (a *ExplorerAPI) BlockHeight() (int, error) {
+	var height int
+	err := fetchJSON(a.BaseURL+"/blocks/tip/height", &height)
+	if err != nil {
+		return 0, err
+	}
+
+	return height, nil
+}
+
+// BlockHeightByTimestamp returns the height of the block that was mined
+// closest to but not after the given time. This uses the block-by-timestamp
+// lookup of the mempool.space API which isn't available on every esplora
+// compatible API.
+func (a *ExplorerAPI) BlockHeightByTimestamp(timestamp time.Time) (uint32,
+	error) {
+
+	var block struct {
+		Height uint32 `json:"height"`
+		Hash   string `json:"hash"`
+	}
+	url := fmt.Sprintf(
+		"%s/v1/mining/blocks/timestamp/%d", a.BaseURL, timestamp.Unix(),
+	)
+	if err := fetchJSON(url, &block); err != nil {
+		return 0, err
+	}
+	if block.Hash == "" {
+		return 0, fmt.Errorf("no block found for timestamp %v",
+			timestamp)
+	}
+
+	return block.Height, nil
+}
+
+// RawTransaction returns the hex encoded raw transaction with the given ID.
+func (a *ExplorerAPI) RawTransaction(txid string) (string, error) {
+	url := fmt.Sprintf("%s/tx/%s/hex", a.BaseURL, txid)
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("error fetching data from API '%s', "+
+			"server might be experiencing temporary issues, try "+
+			"again later; error details: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	body := new(bytes.Buffer)
+	_, err = body.ReadFrom(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error fetching data from API '%s', "+
+			"server might be experiencing temporary issues, try "+
+			"again later; error details: %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if body.String() == "Transaction not found" {
+			return "", ErrTxNotFound
+		}
+
+		return "", fmt.Errorf("error fetching raw transaction %s: %s",
+			txid, body.String())
+	}
+
+	return strings.TrimSpace(body.String()), nil
+}
+
+// SubmitPackage submits a package of raw transactions (all unconfirmed parents
+// followed by the child that spends them) to the API, which forwards them to
+// bitcoind's submitpackage RPC.
+func (a *ExplorerAPI) SubmitPackage(rawTxHexes []string) (string, error) {
+	payload, err := json.Marshal(rawTxHexes)
+	if err != nil {
+		return "", err
+	}
+
+	return a.post(
+		a.BaseURL+"/txs/package", "application/json",
+		string(payload),
+	)
+}
+
 func (a *ExplorerAPI) PublishTx(rawTxHex string) (string, error) {
-	url := a.BaseURL + "/tx"
-	resp, err := http.Post(url, "text/plain", strings.NewReader(rawTxHex))
+	return a.post(a.BaseURL+"/tx", "text/plain", rawTxHex)
+}
+
+func (a *ExplorerAPI) post(url, contentType, payload string) (string,
+	error) {
+
+	resp, err := http.Post(url, contentType, strings.NewReader(payload))
 	if err != nil {
 		return "", fmt.Errorf("error posting data to API '%s', "+
 			"server might be experiencing temporary issues, try "+
@@ -12,7 +12,10 @@ import (
 	"math/big"
 
 	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
 )
 
 const (
@@ -38,8 +41,37 @@ func (k *FastDerivation) PubKeyBytes() []byte {
 	return pubKey.SerializeCompressed()
 }
 
+// TaprootPubKeyBytes returns the x-only BIP86 taproot output key of the key,
+// which is the key tweaked with an empty script tree.
+func (k *FastDerivation) TaprootPubKeyBytes() []byte {
+	_, pubKey := btcec.PrivKeyFromBytes(k.key)
+	return schnorr.SerializePubKey(
+		txscript.ComputeTaprootKeyNoScript(pubKey),
+	)
+}
+
+// PrivKey returns the private key of the derived key.
+func (k *FastDerivation) PrivKey() *btcec.PrivateKey {
+	privKey, _ := btcec.PrivKeyFromBytes(k.key)
+	return privKey
+}
+
+// Clone returns an independent copy of the key that can be derived further
+// without changing the original.
+func (k *FastDerivation) Clone() *FastDerivation {
+	return &FastDerivation{
+		key:       append([]byte(nil), k.key...),
+		chainCode: append([]byte(nil), k.chainCode...),
+		version:   append([]byte(nil), k.version...),
+	}
+}
+
 func (k *FastDerivation) Child(i uint32) error {
 	isChildHardened := i >= HardenedKeyStart
+
+	// Just like hdkeychain's DeriveNonStandard that lnd uses, we don't pad
+	// private keys with leading zeros but start with a zeroed buffer.
+	k.scratch = [keyLen + 4]byte{}
 	if isChildHardened {
 		copy(k.scratch[1:], k.key)
 	} else {
@@ -103,3 +135,22 @@ func NewFastDerivation(seed []byte, net *chaincfg.Params) (*FastDerivation, erro
 		version:   net.HDPrivateKeyID[:],
 	}, nil
 }
+
+// NewFastDerivationFromKey creates a fast derivation helper from an existing
+// private extended key. This can be used to derive many children of a branch
+// that was derived with the slower but more flexible hdkeychain package.
+func NewFastDerivationFromKey(
+	extendedKey *hdkeychain.ExtendedKey) (*FastDerivation, error) {
+
+	privKey, err := extendedKey.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+
+	keyBytes := privKey.Key.Bytes()
+	return &FastDerivation{
+		key:       keyBytes[:],
+		chainCode: extendedKey.ChainCode(),
+		version:   extendedKey.Version(),
+	}, nil
+}
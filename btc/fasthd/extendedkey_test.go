@@ -0,0 +1,50 @@
+package fasthd
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFastDerivation(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	path := []uint32{
+		HardenedKeyStart + 86, HardenedKeyStart + 1, HardenedKeyStart,
+		0, 7,
+	}
+
+	// With enough random seeds, we also hit keys with leading zero bytes,
+	// which lnd derives in a non-standard way.
+	var seed [16]byte
+	for range 1000 {
+		_, err := rand.Read(seed[:])
+		require.NoError(t, err)
+
+		fast, err := NewFastDerivation(seed[:], params)
+		require.NoError(t, err)
+		require.NoError(t, fast.ChildPath(path))
+
+		key, err := hdkeychain.NewMaster(seed[:], params)
+		require.NoError(t, err)
+		for _, index := range path {
+			key, err = key.DeriveNonStandard(index)
+			require.NoError(t, err)
+		}
+		pubKey, err := key.ECPubKey()
+		require.NoError(t, err)
+
+		require.Equal(
+			t, pubKey.SerializeCompressed(), fast.PubKeyBytes(),
+		)
+		require.Equal(
+			t, schnorr.SerializePubKey(
+				txscript.ComputeTaprootKeyNoScript(pubKey),
+			), fast.TaprootPubKeyBytes(),
+		)
+	}
+}
@@ -0,0 +1,226 @@
+package btc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/lightninglabs/chantools/lnd"
+)
+
+const (
+	FormatElectrumWallet = "electrum-wallet"
+	FormatSparrow        = "sparrow"
+
+	// electrumSeedVersion is the version of the Electrum wallet file we
+	// create. Electrum upgrades the file to its current version when
+	// opening it.
+	electrumSeedVersion = 17
+)
+
+var (
+	// slip132P2WKHVersions are the SLIP-0132 version bytes Electrum uses
+	// to identify extended keys of native SegWit accounts, indexed by
+	// whether the key is private.
+	slip132P2WKHVersions = map[bool][4]byte{
+		true:  {0x04, 0xb2, 0x43, 0x0c}, // zprv
+		false: {0x04, 0xb2, 0x47, 0x46}, // zpub
+	}
+	slip132P2WKHTestVersions = map[bool][4]byte{
+		true:  {0x04, 0x5f, 0x18, 0xbc}, // vprv
+		false: {0x04, 0x5f, 0x1c, 0xf6}, // vpub
+	}
+)
+
+// ElectrumKeystore is the BIP32 keystore of an Electrum wallet file.
+type ElectrumKeystore struct {
+	Type            string `json:"type"`
+	Xpub            string `json:"xpub"`
+	Xprv            string `json:"xprv"`
+	Derivation      string `json:"derivation"`
+	RootFingerprint string `json:"root_fingerprint"`
+	PwHashVersion   int    `json:"pw_hash_version"`
+}
+
+// ElectrumWallet is an unencrypted Electrum wallet file.
+type ElectrumWallet struct {
+	Keystore      *ElectrumKeystore `json:"keystore"`
+	WalletType    string            `json:"wallet_type"`
+	UseEncryption bool              `json:"use_encryption"`
+	SeedVersion   int               `json:"seed_version"`
+	GapLimit      uint32            `json:"gap_limit"`
+}
+
+// slip132Key encodes the given account key with the SLIP-0132 version bytes
+// of a native SegWit account.
+func slip132Key(accountKey *hdkeychain.ExtendedKey,
+	params *chaincfg.Params) (string, error) {
+
+	versions := slip132P2WKHTestVersions
+	if params.Name == chaincfg.MainNetParams.Name {
+		versions = slip132P2WKHVersions
+	}
+	version := versions[accountKey.IsPrivate()]
+
+	key, err := accountKey.CloneWithVersion(version[:])
+	if err != nil {
+		return "", fmt.Errorf("could not encode account key: %w", err)
+	}
+
+	return key.String(), nil
+}
+
+// NewElectrumWallet creates an Electrum wallet with the BIP0084 account of
+// lnd's on-chain wallet as its keystore. Electrum derives the external and
+// internal addresses of the account itself, up to the given gap limit.
+func NewElectrumWallet(extendedKey *hdkeychain.ExtendedKey,
+	params *chaincfg.Params, gapLimit uint32) (*ElectrumWallet, error) {
+
+	fingerprint, err := MasterFingerprint(extendedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	accountPath := WalletAccountP2WKH.accountPath(params)
+	accountKey, err := lnd.DeriveChildren(extendedKey, accountPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not derive account key: %w", err)
+	}
+	accountPubKey, err := accountKey.Neuter()
+	if err != nil {
+		return nil, fmt.Errorf("could not neuter account key: %w", err)
+	}
+
+	xprv, err := slip132Key(accountKey, params)
+	if err != nil {
+		return nil, err
+	}
+	xpub, err := slip132Key(accountPubKey, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ElectrumWallet{
+		Keystore: &ElectrumKeystore{
+			Type: "bip32",
+			Xpub: xpub,
+			Xprv: xprv,
+			Derivation: "m/" + strings.ReplaceAll(
+				DescriptorPath(accountPath), "h", "'",
+			),
+			RootFingerprint: hex.EncodeToString(fingerprint),
+			PwHashVersion:   1,
+		},
+		WalletType:    "standard",
+		UseEncryption: false,
+		SeedVersion:   electrumSeedVersion,
+		GapLimit:      gapLimit,
+	}, nil
+}
+
+// ExportElectrumWallet writes an Electrum wallet file of the BIP0084 account
+// of lnd's on-chain wallet.
+func ExportElectrumWallet(extendedKey *hdkeychain.ExtendedKey,
+	params *chaincfg.Params, gapLimit uint32, writer io.Writer) error {
+
+	wallet, err := NewElectrumWallet(extendedKey, params, gapLimit)
+	if err != nil {
+		return err
+	}
+
+	walletJSON, err := json.MarshalIndent(wallet, "", "    ")
+	if err != nil {
+		return fmt.Errorf("could not encode wallet: %w", err)
+	}
+	_, _ = fmt.Fprintf(writer, "%s\n", walletJSON)
+
+	return nil
+}
+
+// SparrowDescriptor is an output descriptor that can be imported as a wallet
+// into Sparrow.
+type SparrowDescriptor struct {
+	// Name is a human-readable description of the descriptor.
+	Name string
+
+	// Desc is the output descriptor including its checksum.
+	Desc string
+}
+
+// SparrowDescriptors returns the output descriptors of lnd's default wallet
+// accounts in the format Sparrow imports them. Each descriptor contains the
+// BIP32 fingerprint and derivation path of the account and covers both the
+// external and internal branch, except for the nested SegWit account because
+// lnd uses native SegWit addresses for its change.
+func SparrowDescriptors(extendedKey *hdkeychain.ExtendedKey,
+	params *chaincfg.Params) ([]*SparrowDescriptor, error) {
+
+	var descriptors []*SparrowDescriptor
+	for _, account := range DefaultWalletAccounts {
+		keyExpr, err := AccountKeyExpression(
+			extendedKey, params, account.accountPath(params),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		external := account.descriptorFormat(0)
+		internal := account.descriptorFormat(1)
+		if external == internal {
+			descriptors = append(descriptors, &SparrowDescriptor{
+				Name: fmt.Sprintf("%s account", account.Name),
+				Desc: DescriptorSumCreate(fmt.Sprintf(
+					external, keyExpr+"/<0;1>/*",
+				)),
+			})
+			continue
+		}
+
+		descriptors = append(descriptors, &SparrowDescriptor{
+			Name: fmt.Sprintf("%s account, receiving addresses",
+				account.Name),
+			Desc: DescriptorSumCreate(fmt.Sprintf(
+				external, keyExpr+"/0/*",
+			)),
+		}, &SparrowDescriptor{
+			Name: fmt.Sprintf("%s account, change addresses",
+				account.Name),
+			Desc: DescriptorSumCreate(fmt.Sprintf(
+				internal, keyExpr+"/1/*",
+			)),
+		})
+	}
+
+	return descriptors, nil
+}
+
+// ExportSparrowDescriptors writes the output descriptors of lnd's default
+// wallet accounts in the format Sparrow imports them.
+func ExportSparrowDescriptors(extendedKey *hdkeychain.ExtendedKey,
+	params *chaincfg.Params, birthdayBlock uint32, writer io.Writer) error {
+
+	descriptors, err := SparrowDescriptors(extendedKey, params)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(
+		writer, "# Wallet dump created by chantools on %s\n",
+		time.Now().UTC(),
+	)
+	_, _ = fmt.Fprintf(writer, "# Import each of the following output "+
+		"descriptors as its own wallet into Sparrow\n# (File -> "+
+		"Import Wallet -> Output Descriptor).\n")
+	for _, descriptor := range descriptors {
+		_, _ = fmt.Fprintf(writer, "\n# %s:\n%s\n", descriptor.Name,
+			descriptor.Desc)
+	}
+	_, _ = fmt.Fprintf(writer, "\n%s\n", birthdayComment(birthdayBlock))
+
+	return nil
+}
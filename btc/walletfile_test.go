@@ -0,0 +1,59 @@
+package btc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewElectrumWallet(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	extendedKey, err := hdkeychain.NewKeyFromString(testRootKey)
+	require.NoError(t, err)
+
+	wallet, err := NewElectrumWallet(extendedKey, params, 50)
+	require.NoError(t, err)
+	require.Equal(t, "m/84'/1'/0'", wallet.Keystore.Derivation)
+	require.Equal(t, "45bc5593", wallet.Keystore.RootFingerprint)
+	require.True(t, strings.HasPrefix(wallet.Keystore.Xprv, "vprv"))
+	require.True(t, strings.HasPrefix(wallet.Keystore.Xpub, "vpub"))
+
+	// The keystore contains the BIP0084 account key of the wallet.
+	xprv, err := hdkeychain.NewKeyFromString(wallet.Keystore.Xprv)
+	require.NoError(t, err)
+	xprv, err = xprv.CloneWithVersion(params.HDPrivateKeyID[:])
+	require.NoError(t, err)
+
+	accountKey, err := lnd.DeriveChildren(
+		extendedKey, WalletAccountP2WKH.accountPath(params),
+	)
+	require.NoError(t, err)
+	require.Equal(t, accountKey.String(), xprv.String())
+}
+
+func TestSparrowDescriptors(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	extendedKey, err := hdkeychain.NewKeyFromString(testRootKey)
+	require.NoError(t, err)
+
+	descriptors, err := SparrowDescriptors(extendedKey, params)
+	require.NoError(t, err)
+	require.Len(t, descriptors, 4)
+
+	for _, descriptor := range descriptors {
+		require.True(t, DescriptorSumCheck(descriptor.Desc, true))
+		require.Contains(t, descriptor.Desc, "[45bc5593/")
+	}
+
+	// The nested SegWit account is split because of its native SegWit
+	// change addresses.
+	require.Contains(t, descriptors[0].Desc, "/49h/1h/0h]")
+	require.True(t, strings.HasPrefix(descriptors[1].Desc, "wpkh("))
+	require.Contains(t, descriptors[1].Desc, "/1/*)")
+	require.Contains(t, descriptors[3].Desc, "/<0;1>/*)")
+	require.True(t, strings.HasPrefix(descriptors[3].Desc, "tr("))
+}
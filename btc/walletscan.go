@@ -0,0 +1,437 @@
+package btc
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcwallet/waddrmgr"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/keychain"
+)
+
+const (
+	// DefaultGapLimit is the default number of consecutive unused
+	// addresses after which we stop scanning a branch.
+	DefaultGapLimit = 20
+
+	// DefaultScanWorkers is the default number of parallel requests
+	// made to the block explorer API while scanning.
+	DefaultScanWorkers = 4
+)
+
+// WalletAccount describes a group of addresses that are derived from the same
+// account level extended key.
+type WalletAccount struct {
+	// Name is a human-readable name of the account.
+	Name string
+
+	// Purpose is the BIP43 purpose of the account's derivation path.
+	Purpose uint32
+
+	// Account is the account index (or key family for lnd's internal
+	// keys).
+	Account uint32
+
+	// Branches are the branches that are scanned for this account.
+	Branches []uint32
+}
+
+var (
+	// WalletAccountNP2WKH is lnd's default nested SegWit account.
+	WalletAccountNP2WKH = &WalletAccount{
+		Name:     "np2wkh",
+		Purpose:  waddrmgr.KeyScopeBIP0049Plus.Purpose,
+		Branches: []uint32{0, 1},
+	}
+
+	// WalletAccountP2WKH is lnd's default native SegWit account.
+	WalletAccountP2WKH = &WalletAccount{
+		Name:     "p2wkh",
+		Purpose:  waddrmgr.KeyScopeBIP0084.Purpose,
+		Branches: []uint32{0, 1},
+	}
+
+	// WalletAccountP2TR is lnd's default taproot account.
+	WalletAccountP2TR = &WalletAccount{
+		Name:     "p2tr",
+		Purpose:  waddrmgr.KeyScopeBIP0086.Purpose,
+		Branches: []uint32{0, 1},
+	}
+
+	// DefaultWalletAccounts are the on-chain wallet accounts lnd creates
+	// by default.
+	DefaultWalletAccounts = []*WalletAccount{
+		WalletAccountNP2WKH, WalletAccountP2WKH, WalletAccountP2TR,
+	}
+)
+
+// NewLndFamilyAccount returns an account for the keys of one of lnd's internal
+// key families. Those keys are all derived on the external branch and are
+// looked for as native SegWit addresses.
+func NewLndFamilyAccount(name string,
+	family keychain.KeyFamily) *WalletAccount {
+
+	return &WalletAccount{
+		Name:     name,
+		Purpose:  keychain.BIP0043Purpose,
+		Account:  uint32(family),
+		Branches: []uint32{0},
+	}
+}
+
+// Path returns the full derivation path of the key at the given branch and
+// index of the account.
+func (a *WalletAccount) Path(params *chaincfg.Params, branch,
+	index uint32) []uint32 {
+
+	return []uint32{
+		lnd.HardenedKey(a.Purpose), lnd.HardenedKey(params.HDCoinType),
+		lnd.HardenedKey(a.Account), branch, index,
+	}
+}
+
+// Address returns the address of the given public key on the given branch of
+// this account. lnd's BIP0049 account uses nested SegWit addresses for
+// receiving but native SegWit addresses for change.
+func (a *WalletAccount) Address(pubKey *btcec.PublicKey, branch uint32,
+	params *chaincfg.Params) (btcutil.Address, error) {
+
+	switch a.Purpose {
+	case waddrmgr.KeyScopeBIP0049Plus.Purpose:
+		if branch == 1 {
+			return lnd.P2WKHAddr(pubKey, params)
+		}
+		return lnd.NP2WKHAddr(pubKey, params)
+
+	case waddrmgr.KeyScopeBIP0086.Purpose:
+		return lnd.P2TRAddr(pubKey, params)
+
+	default:
+		return lnd.P2WKHAddr(pubKey, params)
+	}
+}
+
+// WalletAddress is a single address derived from a wallet account.
+type WalletAddress struct {
+	Account  *WalletAccount
+	Branch   uint32
+	Index    uint32
+	Path     []uint32
+	PubKey   *btcec.PublicKey
+	Address  btcutil.Address
+	PkScript []byte
+}
+
+// PathString returns the derivation path of the address in the usual string
+// notation.
+func (a *WalletAddress) PathString() string {
+	return fmt.Sprintf("m/%d'/%d'/%d'/%d/%d",
+		a.Path[0]-lnd.HardenedKeyStart, a.Path[1]-lnd.HardenedKeyStart,
+		a.Path[2]-lnd.HardenedKeyStart, a.Branch, a.Index)
+}
+
+// AddInputWeight adds the weight of spending an output of the address to the
+// given estimator.
+func (a *WalletAddress) AddInputWeight(estimator *input.TxWeightEstimator) {
+	switch {
+	case a.Account.Purpose == waddrmgr.KeyScopeBIP0049Plus.Purpose &&
+		a.Branch == 0:
+
+		estimator.AddNestedP2WKHInput()
+
+	case a.Account.Purpose == waddrmgr.KeyScopeBIP0086.Purpose:
+		estimator.AddTaprootKeySpendInput(txscript.SigHashDefault)
+
+	default:
+		estimator.AddP2WKHInput()
+	}
+}
+
+// WalletUTXO is an unspent output that belongs to a wallet address.
+type WalletUTXO struct {
+	*WalletAddress
+
+	OutPoint    wire.OutPoint
+	Value       uint64
+	Confirmed   bool
+	BlockHeight int
+}
+
+// TxOut returns the UTXO as a transaction output.
+func (u *WalletUTXO) TxOut() *wire.TxOut {
+	return &wire.TxOut{
+		Value:    int64(u.Value),
+		PkScript: u.PkScript,
+	}
+}
+
+// WalletBranchResult is the result of scanning a single branch of an account.
+type WalletBranchResult struct {
+	Account *WalletAccount
+	Branch  uint32
+
+	// HighestUsedIndex is the highest index of an address that has any
+	// on-chain or mempool history. It is -1 if no address was used.
+	HighestUsedIndex int64
+
+	// NextUnused is the first address after the highest used one.
+	NextUnused *WalletAddress
+
+	// UTXOs are all unspent outputs found in the branch.
+	UTXOs []*WalletUTXO
+}
+
+// WalletScanner scans the addresses derived from a root key for history and
+// unspent outputs using a block explorer API. Each branch is scanned until a
+// number of consecutive unused addresses equal to the gap limit is found.
+type WalletScanner struct {
+	API         *ExplorerAPI
+	RootKey     *hdkeychain.ExtendedKey
+	ChainParams *chaincfg.Params
+	GapLimit    uint32
+	NumWorkers  int
+}
+
+// Scan scans all branches of the given accounts.
+func (s *WalletScanner) Scan(
+	accounts []*WalletAccount) ([]*WalletBranchResult, error) {
+
+	var results []*WalletBranchResult
+	for _, account := range accounts {
+		for _, branch := range account.Branches {
+			result, err := s.ScanBranch(account, branch)
+			if err != nil {
+				return nil, fmt.Errorf("error scanning %s "+
+					"branch %d: %w", account.Name, branch,
+					err)
+			}
+
+			results = append(results, result)
+		}
+	}
+
+	return results, nil
+}
+
+// ScanBranch scans a single branch of an account until the gap limit is
+// reached.
+func (s *WalletScanner) ScanBranch(account *WalletAccount,
+	branch uint32) (*WalletBranchResult, error) {
+
+	gapLimit := s.GapLimit
+	if gapLimit == 0 {
+		gapLimit = DefaultGapLimit
+	}
+
+	// We derive the branch key once to speed up the derivation of the
+	// individual addresses.
+	branchPath := account.Path(s.ChainParams, branch, 0)
+	branchKey, err := lnd.DeriveChildren(
+		s.RootKey, branchPath[:len(branchPath)-1],
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error deriving branch key: %w", err)
+	}
+
+	result := &WalletBranchResult{
+		Account:          account,
+		Branch:           branch,
+		HighestUsedIndex: -1,
+	}
+	var (
+		index uint32
+		gap   uint32
+	)
+	for gap < gapLimit {
+		// We only need to look at as many addresses as would be
+		// required to reach the gap limit if none of them is used.
+		batch := make([]*WalletAddress, gapLimit-gap)
+		for i := range batch {
+			batch[i], err = s.deriveAddress(
+				account, branchKey, branch, index+uint32(i),
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		used, utxos, err := s.queryBatch(batch)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, addr := range batch {
+			result.UTXOs = append(result.UTXOs, utxos[i]...)
+
+			if !used[i] {
+				gap++
+				continue
+			}
+
+			gap = 0
+			result.HighestUsedIndex = int64(addr.Index)
+		}
+
+		index += uint32(len(batch))
+	}
+
+	result.NextUnused, err = s.deriveAddress(
+		account, branchKey, branch,
+		uint32(result.HighestUsedIndex+1),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// deriveAddress derives the address at the given index of a branch.
+func (s *WalletScanner) deriveAddress(account *WalletAccount,
+	branchKey *hdkeychain.ExtendedKey, branch,
+	index uint32) (*WalletAddress, error) {
+
+	key, err := branchKey.DeriveNonStandard(index)
+	if err != nil {
+		return nil, fmt.Errorf("error deriving key: %w", err)
+	}
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		return nil, fmt.Errorf("error deriving public key: %w", err)
+	}
+	addr, err := account.Address(pubKey, branch, s.ChainParams)
+	if err != nil {
+		return nil, fmt.Errorf("error deriving address: %w", err)
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil, fmt.Errorf("error creating pk script: %w", err)
+	}
+
+	return &WalletAddress{
+		Account:  account,
+		Branch:   branch,
+		Index:    index,
+		Path:     account.Path(s.ChainParams, branch, index),
+		PubKey:   pubKey,
+		Address:  addr,
+		PkScript: pkScript,
+	}, nil
+}
+
+// queryBatch queries the history and unspent outputs of all given addresses
+// in parallel.
+func (s *WalletScanner) queryBatch(batch []*WalletAddress) ([]bool,
+	[][]*WalletUTXO, error) {
+
+	numWorkers := s.NumWorkers
+	if numWorkers <= 0 {
+		numWorkers = DefaultScanWorkers
+	}
+
+	var (
+		used    = make([]bool, len(batch))
+		utxos   = make([][]*WalletUTXO, len(batch))
+		errs    = make([]error, len(batch))
+		indices = make(chan int)
+		wg      sync.WaitGroup
+	)
+	for range numWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range indices {
+				used[i], utxos[i], errs[i] = s.queryAddress(
+					batch[i],
+				)
+			}
+		}()
+	}
+	for i := range batch {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return used, utxos, nil
+}
+
+// QueryAddress returns whether the given address has any on-chain or mempool
+// history and all its unspent outputs. The unspent outputs are only fetched if
+// the address has a balance.
+func QueryAddress(api *ExplorerAPI, addr string) (bool, []*UTXO, error) {
+	stats, err := api.AddressStats(addr)
+	if err != nil {
+		return false, nil, err
+	}
+
+	txCount := stats.ChainStats.TXCount + stats.MempoolStats.TXCount
+	if txCount == 0 {
+		return false, nil, nil
+	}
+
+	funded := stats.ChainStats.FundedTXOSum +
+		stats.MempoolStats.FundedTXOSum
+	spent := stats.ChainStats.SpentTXOSum + stats.MempoolStats.SpentTXOSum
+	if funded == spent {
+		return true, nil, nil
+	}
+
+	utxos, err := api.UTXOs(addr)
+	if err != nil {
+		return false, nil, err
+	}
+
+	return true, utxos, nil
+}
+
+// queryAddress returns whether the given address has any history and all its
+// unspent outputs.
+func (s *WalletScanner) queryAddress(addr *WalletAddress) (bool,
+	[]*WalletUTXO, error) {
+
+	used, apiUTXOs, err := QueryAddress(s.API, addr.Address.EncodeAddress())
+	if err != nil || !used {
+		return false, nil, err
+	}
+
+	utxos := make([]*WalletUTXO, 0, len(apiUTXOs))
+	for _, apiUTXO := range apiUTXOs {
+		txHash, err := chainhash.NewHashFromStr(apiUTXO.TXID)
+		if err != nil {
+			return false, nil, fmt.Errorf("error parsing txid: %w",
+				err)
+		}
+
+		utxo := &WalletUTXO{
+			WalletAddress: addr,
+			OutPoint: wire.OutPoint{
+				Hash:  *txHash,
+				Index: apiUTXO.Vout,
+			},
+			Value: apiUTXO.Value,
+		}
+		if apiUTXO.Status != nil {
+			utxo.Confirmed = apiUTXO.Status.Confirmed
+			utxo.BlockHeight = apiUTXO.Status.BlockHeight
+		}
+		utxos = append(utxos, utxo)
+	}
+
+	return true, utxos, nil
+}
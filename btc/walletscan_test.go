@@ -0,0 +1,168 @@
+package btc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRootKey = "tprv8ZgxMBicQKsPejNXQLJKe3dBBs9Zrt53EZrsBzVLQ8rZji3" +
+		"hVb3wcoRvgrjvTmjPG2ixoGUUkCyC6yBEy9T5gbLdvD2a5VmJbcFd5Q9pkAs"
+
+	testTxid = "01020304050607080910111213141516171819202122232425" +
+		"26272829303132"
+)
+
+// newTestExplorer creates a minimal esplora compatible API that knows about
+// the given used addresses. Addresses with a non-zero value also have a single
+// confirmed UTXO.
+func newTestExplorer(t *testing.T,
+	usedAddrs map[string]uint64) *httptest.Server {
+
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			parts := strings.Split(r.URL.Path, "/")
+			require.GreaterOrEqual(t, len(parts), 3)
+			addr := parts[2]
+			value, used := usedAddrs[addr]
+
+			var response any
+			switch {
+			case len(parts) == 4 && parts[3] == "utxo":
+				response = []*UTXO{{
+					TXID:   testTxid,
+					Vout:   1,
+					Status: &Status{Confirmed: true},
+					Value:  value,
+				}}
+
+			default:
+				stats := &AddressStats{
+					Address:      addr,
+					ChainStats:   &Stats{},
+					MempoolStats: &Stats{},
+				}
+				if used {
+					stats.ChainStats.TXCount = 1
+					stats.ChainStats.FundedTXOCount = 1
+					stats.ChainStats.FundedTXOSum = value
+				}
+				response = stats
+			}
+
+			require.NoError(t, json.NewEncoder(w).Encode(response))
+		},
+	))
+}
+
+func TestWalletScannerGapLimit(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	rootKey, err := hdkeychain.NewKeyFromString(testRootKey)
+	require.NoError(t, err)
+
+	addrAt := func(index uint32) string {
+		path := WalletAccountP2WKH.Path(params, 0, index)
+		key, err := lnd.DeriveChildren(rootKey, path)
+		require.NoError(t, err)
+		pubKey, err := key.ECPubKey()
+		require.NoError(t, err)
+		addr, err := WalletAccountP2WKH.Address(pubKey, 0, params)
+		require.NoError(t, err)
+
+		return addr.EncodeAddress()
+	}
+
+	testCases := []struct {
+		name            string
+		used            map[uint32]uint64
+		expectedHighest int64
+		expectedUTXOs   int
+	}{{
+		name:            "empty",
+		used:            map[uint32]uint64{},
+		expectedHighest: -1,
+	}, {
+		name: "gap too large",
+		used: map[uint32]uint64{
+			0: 0, 3: 50_000, 25: 10_000,
+		},
+		expectedHighest: 3,
+		expectedUTXOs:   1,
+	}, {
+		name: "gap extended",
+		used: map[uint32]uint64{
+			0: 0, 3: 50_000, 22: 0, 25: 10_000,
+		},
+		expectedHighest: 25,
+		expectedUTXOs:   2,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			usedAddrs := make(map[string]uint64, len(tc.used))
+			for index, value := range tc.used {
+				usedAddrs[addrAt(index)] = value
+			}
+
+			server := newTestExplorer(t, usedAddrs)
+			defer server.Close()
+
+			scanner := &WalletScanner{
+				API:         &ExplorerAPI{BaseURL: server.URL},
+				RootKey:     rootKey,
+				ChainParams: params,
+				GapLimit:    20,
+			}
+			result, err := scanner.ScanBranch(WalletAccountP2WKH, 0)
+			require.NoError(t, err)
+
+			require.Equal(
+				t, tc.expectedHighest, result.HighestUsedIndex,
+			)
+			require.Len(t, result.UTXOs, tc.expectedUTXOs)
+			require.Equal(
+				t, uint32(tc.expectedHighest+1),
+				result.NextUnused.Index,
+			)
+			require.Equal(
+				t, addrAt(uint32(tc.expectedHighest+1)),
+				result.NextUnused.Address.EncodeAddress(),
+			)
+		})
+	}
+}
+
+func TestWalletAccountAddress(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	rootKey, err := hdkeychain.NewKeyFromString(testRootKey)
+	require.NoError(t, err)
+	pubKey, err := rootKey.ECPubKey()
+	require.NoError(t, err)
+
+	// lnd's nested SegWit account uses native SegWit change addresses.
+	account := WalletAccountNP2WKH
+	addr, err := account.Address(pubKey, 0, params)
+	require.NoError(t, err)
+	require.IsType(t, &btcutil.AddressScriptHash{}, addr)
+
+	addr, err = account.Address(pubKey, 1, params)
+	require.NoError(t, err)
+	require.IsType(t, &btcutil.AddressWitnessPubKeyHash{}, addr)
+
+	var external, internal input.TxWeightEstimator
+	(&WalletAddress{Account: account}).AddInputWeight(&external)
+	(&WalletAddress{Account: account, Branch: 1}).AddInputWeight(&internal)
+	require.Greater(t, external.Weight(), internal.Weight())
+}
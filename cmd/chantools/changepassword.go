@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	_ "github.com/btcsuite/btcwallet/walletdb/bdb"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/lncfg"
+	"github.com/lightningnetwork/lnd/macaroons"
+	"github.com/spf13/cobra"
+)
+
+type changePasswordCommand struct {
+	WalletDB string
+
+	cmd *cobra.Command
+}
+
+func newChangePasswordCommand() *cobra.Command {
+	cc := &changePasswordCommand{}
+	cc.cmd = &cobra.Command{
+		Use:   "changepassword",
+		Short: "Change the password of an lnd wallet.db file",
+		Long: `Changes the password of an lnd wallet.db file offline.
+The wallet is opened with the current password and the keys that encrypt the
+wallet are re-encrypted with the new password. Both the public and the private
+passphrase of the wallet are changed, as lnd uses the same password for both.
+
+Before the password is changed, a backup copy of the wallet.db file is created
+next to it. After the change, the wallet is opened again with the new password
+to make sure it can still be unlocked and still contains the same root key.
+
+lnd encrypts the root keys of its macaroons with the wallet password as well.
+If a macaroons.db file is found next to the wallet.db file, its password is
+changed too, after creating a backup of it. Otherwise lnd would unlock the
+wallet but fail to open the macaroon store. If changing the password of the
+macaroons.db fails, it can be deleted together with all *.macaroon files, lnd
+then creates new macaroons on the next start.
+
+The current password is read from the WALLET_PASSWORD and the new one from the
+WALLET_NEW_PASSWORD environment variable, if set. Otherwise they are read from
+the terminal. In case lnd was started with "--noseedbackup=true" the wallet has
+the default password, which can be specified with a single dash (-) in the
+environment variable or by pressing <enter> without entering a password.`,
+		Example: `chantools changepassword \
+	--walletdb ~/.lnd/data/chain/bitcoin/mainnet/wallet.db`,
+		RunE: cc.Execute,
+	}
+	cc.cmd.Flags().StringVar(
+		&cc.WalletDB, "walletdb", "", "lnd wallet.db file to change "+
+			"the password of",
+	)
+
+	return cc.cmd
+}
+
+func (c *changePasswordCommand) Execute(_ *cobra.Command, _ []string) error {
+	// Check that we have a wallet DB.
+	if c.WalletDB == "" {
+		return errors.New("wallet DB is required")
+	}
+	walletDB := lncfg.CleanAndExpandPath(c.WalletDB)
+	if _, err := os.Stat(walletDB); err != nil {
+		return fmt.Errorf("error reading wallet DB: %w", err)
+	}
+
+	oldPublicPw, oldPrivatePw, err := lnd.ReadWalletPassword(
+		lnd.PasswordEnvName, "Input current wallet password: ",
+	)
+	if err != nil {
+		return err
+	}
+	newPublicPw, newPrivatePw, err := readNewWalletPassword()
+	if err != nil {
+		return err
+	}
+
+	// Make sure the current password is correct before we create the
+	// backup, so we don't leave any unnecessary copies of the wallet.
+	w, cleanup, err := lnd.OpenWalletWithPassphrases(
+		walletDB, chainParams, oldPublicPw, oldPrivatePw,
+	)
+	if err != nil {
+		return fmt.Errorf("error opening wallet with current "+
+			"password: %w", err)
+	}
+	rootKey, err := lnd.DecryptWalletRootKey(w.Database(), oldPrivatePw)
+	if err != nil {
+		_ = cleanup()
+		return fmt.Errorf("error decrypting root key: %w", err)
+	}
+	if err := cleanup(); err != nil {
+		return fmt.Errorf("error closing wallet: %w", err)
+	}
+
+	backupFile := fmt.Sprintf(
+		"%s.%s.backup", walletDB,
+		time.Now().Format("2006-01-02-15-04-05"),
+	)
+	if err := copyFile(walletDB, backupFile); err != nil {
+		return fmt.Errorf("error creating backup of wallet DB: %w", err)
+	}
+	log.Infof("Created backup of wallet DB at %s", backupFile)
+
+	// Now we can re-encrypt the wallet's crypto keys with the new password.
+	// This happens in a single database transaction, so either both
+	// passphrases are changed or none of them.
+	w, cleanup, err = lnd.OpenWalletWithPassphrases(
+		walletDB, chainParams, oldPublicPw, oldPrivatePw,
+	)
+	if err != nil {
+		return fmt.Errorf("error opening wallet with current "+
+			"password: %w", err)
+	}
+	err = w.ChangePassphrases(
+		oldPublicPw, newPublicPw, oldPrivatePw, newPrivatePw,
+	)
+	if err != nil {
+		_ = cleanup()
+		return fmt.Errorf("error changing wallet password: %w", err)
+	}
+	if err := cleanup(); err != nil {
+		return fmt.Errorf("error closing wallet: %w", err)
+	}
+
+	// As a last step, we make sure the wallet can be opened and unlocked
+	// with the new password and that it still contains the same root key.
+	w, cleanup, err = lnd.OpenWalletWithPassphrases(
+		walletDB, chainParams, newPublicPw, newPrivatePw,
+	)
+	if err != nil {
+		return fmt.Errorf("error opening wallet with new password, "+
+			"restore backup %s: %w", backupFile, err)
+	}
+	defer func() {
+		_ = cleanup()
+	}()
+
+	newRootKey, err := lnd.DecryptWalletRootKey(w.Database(), newPrivatePw)
+	if err != nil {
+		return fmt.Errorf("error decrypting root key with new "+
+			"password, restore backup %s: %w", backupFile, err)
+	}
+	if !bytes.Equal(rootKey, newRootKey) {
+		return fmt.Errorf("root key changed after changing password, "+
+			"restore backup %s", backupFile)
+	}
+
+	log.Infof("Wallet password of %s changed successfully", walletDB)
+
+	// lnd encrypts the macaroon root keys with the wallet password as
+	// well. If we didn't change that password too, lnd could unlock the
+	// wallet but not open its macaroon DB anymore.
+	macaroonDB := filepath.Join(
+		filepath.Dir(walletDB), lncfg.MacaroonDBName,
+	)
+	if _, err := os.Stat(macaroonDB); err != nil {
+		log.Infof("No %s found next to the wallet, not changing its "+
+			"password", lncfg.MacaroonDBName)
+		return nil
+	}
+
+	macaroonBackup := fmt.Sprintf(
+		"%s.%s.backup", macaroonDB,
+		time.Now().Format("2006-01-02-15-04-05"),
+	)
+	if err := copyFile(macaroonDB, macaroonBackup); err != nil {
+		return fmt.Errorf("error creating backup of macaroon DB: %w",
+			err)
+	}
+	log.Infof("Created backup of macaroon DB at %s", macaroonBackup)
+
+	err = changeMacaroonDBPassword(macaroonDB, oldPrivatePw, newPrivatePw)
+	if err != nil {
+		return fmt.Errorf("error changing password of macaroon DB "+
+			"%s, delete it and all *.macaroon files to let lnd "+
+			"create new macaroons: %w", macaroonDB, err)
+	}
+
+	log.Infof("Macaroon DB password of %s changed successfully",
+		macaroonDB)
+
+	return nil
+}
+
+// changeMacaroonDBPassword re-encrypts the root keys in the given lnd macaroon
+// DB with the new password.
+func changeMacaroonDBPassword(macaroonDB string, oldPw, newPw []byte) error {
+	db, err := walletdb.Open(
+		"bdb", macaroonDB, false, lnd.DefaultOpenTimeout,
+	)
+	if err != nil {
+		return fmt.Errorf("error opening macaroon DB: %w", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	rootKeyStore, err := macaroons.NewRootKeyStorage(db)
+	if err != nil {
+		return fmt.Errorf("error opening root key store: %w", err)
+	}
+	defer func() {
+		_ = rootKeyStore.Close()
+	}()
+
+	if err := rootKeyStore.CreateUnlock(&oldPw); err != nil {
+		return fmt.Errorf("error unlocking root key store: %w", err)
+	}
+
+	return rootKeyStore.ChangePassword(oldPw, newPw)
+}
+
+// readNewWalletPassword reads the new wallet password from the environment
+// or, if it isn't set there, asks for it twice on the terminal.
+func readNewWalletPassword() ([]byte, []byte, error) {
+	newPublicPw, newPrivatePw, err := lnd.ReadWalletPassword(
+		lnd.NewPasswordEnvName, "Input new wallet password: ",
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// There's no need to confirm a password we read from the environment.
+	if os.Getenv(lnd.NewPasswordEnvName) != "" {
+		return newPublicPw, newPrivatePw, nil
+	}
+
+	_, confirmPrivatePw, err := lnd.ReadWalletPassword(
+		lnd.NewPasswordEnvName, "Confirm new wallet password: ",
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !bytes.Equal(newPrivatePw, confirmPrivatePw) {
+		return nil, nil, errors.New("passwords don't match")
+	}
+
+	return newPublicPw, newPrivatePw, nil
+}
+
+// copyFile copies the source file to the given destination and makes sure the
+// copy is written to disk.
+func copyFile(src, dest string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = srcFile.Close()
+	}()
+
+	destFile, err := os.OpenFile(
+		dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600,
+	)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(destFile, srcFile); err != nil {
+		_ = destFile.Close()
+		return err
+	}
+	if err := destFile.Sync(); err != nil {
+		_ = destFile.Close()
+		return err
+	}
+
+	return destFile.Close()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/btcsuite/btcwallet/walletdb"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/macaroons"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangePassword(t *testing.T) {
+	h := newHarness(t)
+
+	walletDB := h.testdataFile("wallet.db")
+	changePassword := &changePasswordCommand{
+		WalletDB: walletDB,
+	}
+
+	// lnd keeps the macaroon DB next to the wallet, encrypted with the
+	// same password.
+	macaroonDB := filepath.Join(filepath.Dir(walletDB), "macaroons.db")
+	rootKey := createTestMacaroonDB(t, macaroonDB, testPassPhrase)
+
+	t.Setenv(lnd.PasswordEnvName, testPassPhrase)
+	t.Setenv(lnd.NewPasswordEnvName, "new-password")
+
+	err := changePassword.Execute(nil, nil)
+	require.NoError(t, err)
+
+	h.assertLogContains("changed successfully")
+
+	// The backup still uses the old password.
+	backups, err := filepath.Glob(walletDB + ".*.backup")
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+
+	_, cleanup, err := lnd.OpenWalletWithPassphrases(
+		backups[0], chainParams, []byte(testPassPhrase),
+		[]byte(testPassPhrase),
+	)
+	require.NoError(t, err)
+	require.NoError(t, cleanup())
+
+	// The wallet itself can only be opened with the new password.
+	_, _, err = lnd.OpenWalletWithPassphrases(
+		walletDB, chainParams, []byte(testPassPhrase),
+		[]byte(testPassPhrase),
+	)
+	require.Error(t, err)
+
+	_, cleanup, err = lnd.OpenWalletWithPassphrases(
+		walletDB, chainParams, []byte("new-password"),
+		[]byte("new-password"),
+	)
+	require.NoError(t, err)
+	require.NoError(t, cleanup())
+
+	// The macaroon DB must now be unlocked with the new password and still
+	// contain the same root key.
+	h.assertLogContains("Macaroon DB password")
+	newRootKey := readTestMacaroonRootKey(t, macaroonDB, "new-password")
+	require.Equal(t, rootKey, newRootKey)
+}
+
+// createTestMacaroonDB creates a macaroon DB encrypted with the given password
+// the way lnd does and returns its default root key.
+func createTestMacaroonDB(t *testing.T, macaroonDB, password string) []byte {
+	t.Helper()
+
+	db, err := walletdb.Create(
+		"bdb", macaroonDB, false, lnd.DefaultOpenTimeout,
+	)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	return readTestMacaroonRootKey(t, macaroonDB, password)
+}
+
+// readTestMacaroonRootKey unlocks the macaroon DB with the given password and
+// returns its default root key, creating it if necessary.
+func readTestMacaroonRootKey(t *testing.T, macaroonDB,
+	password string) []byte {
+
+	t.Helper()
+
+	db, err := walletdb.Open(
+		"bdb", macaroonDB, false, lnd.DefaultOpenTimeout,
+	)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	store, err := macaroons.NewRootKeyStorage(db)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, store.Close())
+	}()
+
+	pw := []byte(password)
+	require.NoError(t, store.CreateUnlock(&pw))
+
+	ctx := macaroons.ContextWithRootKeyID(
+		context.Background(), macaroons.DefaultRootKeyID,
+	)
+	rootKey, _, err := store.RootKey(ctx)
+	require.NoError(t, err)
+
+	return rootKey
+}
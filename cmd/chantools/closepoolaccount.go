@@ -41,7 +41,7 @@ type closePoolAccountCommand struct {
 	Outpoint      string
 	AuctioneerKey string
 	Publish       bool
-	SweepAddr     string
+	SweepAddrs    []string
 	FeeRate       uint32
 
 	MinExpiry       uint32
@@ -88,10 +88,8 @@ obtained by running 'pool accounts list' `,
 		&cc.Publish, "publish", false, "publish sweep TX to the chain "+
 			"API instead of just printing the TX",
 	)
-	cc.cmd.Flags().StringVar(
-		&cc.SweepAddr, "sweepaddr", "", "address to recover the funds "+
-			"to; specify '"+lnd.AddressDeriveFromWallet+"' to "+
-			"derive a new address from the seed automatically",
+	cc.cmd.Flags().StringArrayVar(
+		&cc.SweepAddrs, "sweepaddr", nil, sweepAddrFlagDesc,
 	)
 	cc.cmd.Flags().Uint32Var(
 		&cc.FeeRate, "feerate", defaultFeeSatPerVByte, "fee rate to "+
@@ -127,10 +125,7 @@ func (c *closePoolAccountCommand) Execute(_ *cobra.Command, _ []string) error {
 	}
 
 	// Make sure sweep addr is set.
-	err = lnd.CheckAddress(
-		c.SweepAddr, chainParams, true, "sweep", lnd.AddrTypeP2WKH,
-		lnd.AddrTypeP2TR,
-	)
+	sweepDests, err := parseSweepAddrs(c.SweepAddrs)
 	if err != nil {
 		return err
 	}
@@ -156,7 +151,7 @@ func (c *closePoolAccountCommand) Execute(_ *cobra.Command, _ []string) error {
 		c.FeeRate = defaultFeeSatPerVByte
 	}
 	return closePoolAccount(
-		extendedKey, c.APIURL, outpoint, auctioneerKey, c.SweepAddr,
+		extendedKey, c.APIURL, outpoint, auctioneerKey, sweepDests,
 		c.Publish, c.FeeRate, c.MinExpiry, c.MinExpiry+c.MaxNumBlocks,
 		c.MaxNumAccounts, c.MaxNumBatchKeys,
 	)
@@ -164,8 +159,9 @@ func (c *closePoolAccountCommand) Execute(_ *cobra.Command, _ []string) error {
 
 func closePoolAccount(extendedKey *hdkeychain.ExtendedKey, apiURL string,
 	outpoint *wire.OutPoint, auctioneerKey *btcec.PublicKey,
-	sweepAddr string, publish bool, feeRate uint32, minExpiry,
-	maxNumBlocks, maxNumAccounts, maxNumBatchKeys uint32) error {
+	sweepDests []*lnd.SweepDestination, publish bool, feeRate uint32,
+	minExpiry, maxNumBlocks, maxNumAccounts,
+	maxNumBatchKeys uint32) error {
 
 	var (
 		estimator input.TxWeightEstimator
@@ -176,8 +172,8 @@ func closePoolAccount(extendedKey *hdkeychain.ExtendedKey, apiURL string,
 		api = newExplorerAPI(apiURL)
 	)
 
-	sweepScript, err := lnd.PrepareWalletAddress(
-		sweepAddr, chainParams, &estimator, extendedKey, "sweep",
+	sweepOutputs, err := lnd.PrepareSweepOutputs(
+		sweepDests, chainParams, &estimator, extendedKey, "sweep",
 	)
 	if err != nil {
 		return err
@@ -295,11 +291,11 @@ func closePoolAccount(extendedKey *hdkeychain.ExtendedKey, apiURL string,
 	feeRateKWeight := chainfee.SatPerKVByte(1000 * feeRate).FeePerKWeight()
 	totalFee := feeRateKWeight.FeeForWeight(estimator.Weight())
 
-	// Add our sweep destination output.
-	sweepTx.TxOut = []*wire.TxOut{{
-		Value:    sweepValue - int64(totalFee),
-		PkScript: sweepScript,
-	}}
+	// Add our sweep destination outputs.
+	sweepTx.TxOut, err = sweepOutputs.TxOuts(sweepValue - int64(totalFee))
+	if err != nil {
+		return err
+	}
 
 	log.Infof("Fee %d sats of %d total amount (estimated weight %d)",
 		totalFee, sweepValue, estimator.Weight())
@@ -2,25 +2,59 @@ package main
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcwallet/waddrmgr"
+	"github.com/btcsuite/btcwallet/wallet"
 	_ "github.com/btcsuite/btcwallet/walletdb/bdb"
 	"github.com/lightninglabs/chantools/lnd"
 	"github.com/lightningnetwork/lnd/aezeed"
 	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/lncfg"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
 	"github.com/lightningnetwork/lnd/lnwallet"
 	"github.com/lightningnetwork/lnd/lnwallet/btcwallet"
 	"github.com/spf13/cobra"
 )
 
+const (
+	// lndMaxKeyFamily is the highest key family lnd creates an account
+	// for in its internal key scope. This includes all of lnd's own key
+	// families as well as some used by external liquidity tools.
+	lndMaxKeyFamily = 255
+)
+
+var (
+	// lndWatchOnlyScopes are the key scopes of lnd's on-chain wallet that
+	// need to be present in a watch-only wallet.
+	lndWatchOnlyScopes = []waddrmgr.KeyScope{
+		waddrmgr.KeyScopeBIP0049Plus,
+		waddrmgr.KeyScopeBIP0084,
+		waddrmgr.KeyScopeBIP0086,
+	}
+
+	// watchOnlyBirthday is the birthday lnd uses for watch-only wallets
+	// that are created from account xpubs without an explicit birthday.
+	// Since lnd only uses SegWit addresses, this is the date of the first
+	// block that contained SegWit transactions (481824).
+	watchOnlyBirthday = time.Date(
+		2017, time.August, 24, 1, 57, 37, 0, time.UTC,
+	)
+)
+
 type createWalletCommand struct {
 	WalletDBDir  string
 	GenerateSeed bool
+	WatchOnly    bool
+	AccountsFile string
 
 	rootKey *rootKey
 	cmd     *cobra.Command
@@ -34,8 +68,22 @@ func newCreateWalletCommand() *cobra.Command {
 			"existing seed or by generating a new one",
 		Long: `Creates a new wallet that can be used with lnd or with 
 chantools. The wallet can be created from an existing seed or a new one can be
-generated (use --generateseed).`,
+generated (use --generateseed).
+
+With --watchonly a watch-only wallet for an lnd node in remote signing mode is
+created instead. Such a wallet doesn't contain any private keys, only the
+extended public keys of all accounts lnd expects: the np2wkh, p2wkh and p2tr
+accounts of the on-chain wallet and one account for each key family (0 to 255)
+of lnd's internal key scope. The xpubs are either derived from the root key or
+read from a JSON file created with 'lncli wallet accounts list' on the remote
+signer (use --accountsfile). Because the xpubs don't contain a birthday, the
+wallet created from such a file uses the date of the first SegWit block as its
+birthday.`,
 		Example: `chantools createwallet \
+	--walletdbdir ~/.lnd/data/chain/bitcoin/mainnet
+
+chantools createwallet --watchonly \
+	--accountsfile accounts.json \
 	--walletdbdir ~/.lnd/data/chain/bitcoin/mainnet`,
 		RunE: cc.Execute,
 	}
@@ -47,6 +95,17 @@ generated (use --generateseed).`,
 		&cc.GenerateSeed, "generateseed", false, "generate a new "+
 			"seed instead of using an existing one",
 	)
+	cc.cmd.Flags().BoolVar(
+		&cc.WatchOnly, "watchonly", false, "create a watch-only "+
+			"wallet for a remote signing setup that only contains "+
+			"the account xpubs",
+	)
+	cc.cmd.Flags().StringVar(
+		&cc.AccountsFile, "accountsfile", "", "JSON file with the "+
+			"account xpubs as exported by 'lncli wallet accounts "+
+			"list' to create the watch-only wallet from, instead "+
+			"of deriving them from the root key",
+	)
 
 	cc.rootKey = newRootKey(cc.cmd, "creating the new wallet")
 
@@ -59,6 +118,8 @@ func (c *createWalletCommand) Execute(_ *cobra.Command, _ []string) error {
 		privateWalletPw = lnwallet.DefaultPrivatePassphrase
 		masterRootKey   *hdkeychain.ExtendedKey
 		birthday        time.Time
+		accounts        map[waddrmgr.ScopedIndex]*hdkeychain.ExtendedKey
+		masterFP        uint32
 		err             error
 	)
 
@@ -75,7 +136,48 @@ func (c *createWalletCommand) Execute(_ *cobra.Command, _ []string) error {
 
 	// Check if we should create a new seed or read if from the console or
 	// environment.
-	if c.GenerateSeed {
+	switch {
+	case c.WatchOnly && c.GenerateSeed:
+		return errors.New("cannot create a watch-only wallet from a " +
+			"newly generated seed")
+
+	case c.AccountsFile != "" && !c.WatchOnly:
+		return errors.New("accounts file can only be used for " +
+			"watch-only wallets")
+
+	// The xpubs were exported from the remote signer, we don't need any
+	// private key material.
+	case c.WatchOnly && c.AccountsFile != "":
+		accounts, masterFP, err = readWatchOnlyAccounts(
+			c.AccountsFile,
+		)
+		if err != nil {
+			return err
+		}
+		birthday = watchOnlyBirthday
+
+	// We derive the xpubs from the root key ourselves.
+	case c.WatchOnly:
+		rootKey, rootKeyBirthday, err := c.rootKey.readWithBirthday()
+		if err != nil {
+			return err
+		}
+
+		accounts, err = deriveWatchOnlyAccounts(rootKey)
+		if err != nil {
+			return err
+		}
+		masterFP, _, err = fingerprint(rootKey)
+		if err != nil {
+			return err
+		}
+
+		birthday = watchOnlyBirthday
+		if rootKeyBirthday.Unix() > 0 {
+			birthday = rootKeyBirthday
+		}
+
+	case c.GenerateSeed:
 		fmt.Printf("Generating new lnd compatible aezeed...\n")
 		seed, err := aezeed.New(
 			keychain.KeyDerivationVersionTaproot, nil, time.Now(),
@@ -107,7 +209,8 @@ func (c *createWalletCommand) Execute(_ *cobra.Command, _ []string) error {
 
 		fmt.Println("Generated new seed")
 		printCipherSeedWords(mnemonic[:])
-	} else {
+
+	default:
 		masterRootKey, birthday, err = c.rootKey.readWithBirthday()
 		if err != nil {
 			return err
@@ -168,11 +271,30 @@ func (c *createWalletCommand) Execute(_ *cobra.Command, _ []string) error {
 		return fmt.Errorf("error creating wallet loader: %w", err)
 	}
 
-	_, err = loader.CreateNewWalletExtendedKey(
-		publicWalletPw, privateWalletPw, masterRootKey, birthday,
-	)
-	if err != nil {
-		return fmt.Errorf("error creating new wallet: %w", err)
+	// A watch-only wallet is only encrypted with the public passphrase, as
+	// there is no private key material in it.
+	if c.WatchOnly {
+		w, err := loader.CreateNewWatchingOnlyWallet(
+			publicWalletPw, birthday,
+		)
+		if err != nil {
+			return fmt.Errorf("error creating new watch-only "+
+				"wallet: %w", err)
+		}
+
+		err = importWatchOnlyAccounts(w, accounts, masterFP)
+		if err != nil {
+			_ = loader.UnloadWallet()
+			return err
+		}
+	} else {
+		_, err = loader.CreateNewWalletExtendedKey(
+			publicWalletPw, privateWalletPw, masterRootKey,
+			birthday,
+		)
+		if err != nil {
+			return fmt.Errorf("error creating new wallet: %w", err)
+		}
 	}
 
 	if err := loader.UnloadWallet(); err != nil {
@@ -184,6 +306,198 @@ func (c *createWalletCommand) Execute(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
+// deriveWatchOnlyAccounts derives the xpubs of all accounts lnd expects in a
+// watch-only wallet from the given root key.
+func deriveWatchOnlyAccounts(rootKey *hdkeychain.ExtendedKey) (
+	map[waddrmgr.ScopedIndex]*hdkeychain.ExtendedKey, error) {
+
+	var scopedIndexes []waddrmgr.ScopedIndex
+	for _, scope := range lndWatchOnlyScopes {
+		scopedIndexes = append(scopedIndexes, waddrmgr.ScopedIndex{
+			Scope: scope,
+		})
+	}
+
+	// lnd derives the keys of its key families with the coin type of the
+	// current chain, unlike the scopes of the on-chain wallet.
+	lndScope := waddrmgr.KeyScope{
+		Purpose: keychain.BIP0043Purpose,
+		Coin:    chainParams.HDCoinType,
+	}
+	for family := uint32(0); family <= lndMaxKeyFamily; family++ {
+		scopedIndexes = append(scopedIndexes, waddrmgr.ScopedIndex{
+			Scope: lndScope,
+			Index: family,
+		})
+	}
+
+	accounts := make(
+		map[waddrmgr.ScopedIndex]*hdkeychain.ExtendedKey,
+		len(scopedIndexes),
+	)
+	for _, scopedIndex := range scopedIndexes {
+		accountKey, err := lnd.DeriveChildren(rootKey, []uint32{
+			lnd.HardenedKey(scopedIndex.Scope.Purpose),
+			lnd.HardenedKey(scopedIndex.Scope.Coin),
+			lnd.HardenedKey(scopedIndex.Index),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error deriving account "+
+				"%s/%d': %w", scopedIndex.Scope,
+				scopedIndex.Index, err)
+		}
+
+		accounts[scopedIndex], err = accountKey.Neuter()
+		if err != nil {
+			return nil, fmt.Errorf("error neutering account key: "+
+				"%w", err)
+		}
+	}
+
+	return accounts, nil
+}
+
+// readWatchOnlyAccounts reads the account xpubs from a JSON file in the format
+// of lnd's ListAccounts RPC, as created by 'lncli wallet accounts list'.
+func readWatchOnlyAccounts(fileName string) (
+	map[waddrmgr.ScopedIndex]*hdkeychain.ExtendedKey, uint32, error) {
+
+	jsonBytes, err := os.ReadFile(lncfg.CleanAndExpandPath(fileName))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error reading accounts file %s: %w",
+			fileName, err)
+	}
+
+	jsonAccounts := &walletrpc.ListAccountsResponse{}
+	err = lnrpc.ProtoJSONUnmarshalOpts.Unmarshal(jsonBytes, jsonAccounts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error parsing accounts file: %w",
+			err)
+	}
+
+	// The default imported account of each scope doesn't have an xpub, we
+	// can just skip those.
+	var (
+		exported    []*walletrpc.Account
+		fingerprint uint32
+	)
+	for _, account := range jsonAccounts.Accounts {
+		if account.ExtendedPublicKey == "" {
+			continue
+		}
+		exported = append(exported, account)
+
+		// We assume that all accounts were exported from the same
+		// master root key, just like lnd does.
+		if len(account.MasterKeyFingerprint) == 4 {
+			fingerprint = binary.BigEndian.Uint32(
+				account.MasterKeyFingerprint,
+			)
+		}
+	}
+	if len(exported) == 0 {
+		return nil, 0, errors.New("accounts file doesn't contain any " +
+			"account xpubs")
+	}
+
+	watchOnlyAccounts, err := walletrpc.AccountsToWatchOnly(exported)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	accounts := make(
+		map[waddrmgr.ScopedIndex]*hdkeychain.ExtendedKey,
+		len(watchOnlyAccounts),
+	)
+	for _, account := range watchOnlyAccounts {
+		accountKey, err := hdkeychain.NewKeyFromString(account.Xpub)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error parsing xpub %s: %w",
+				account.Xpub, err)
+		}
+
+		// Just to make sure the file contains what we expect, the key
+		// must be at the account level and must not contain any private
+		// key material.
+		if accountKey.Depth() != 3 {
+			return nil, 0, fmt.Errorf("xpub %s must be at depth 3",
+				account.Xpub)
+		}
+		if accountKey.IsPrivate() {
+			return nil, 0, fmt.Errorf("xpub %s contains private "+
+				"key", account.Xpub)
+		}
+
+		accounts[waddrmgr.ScopedIndex{
+			Scope: waddrmgr.KeyScope{
+				Purpose: account.Purpose,
+				Coin:    account.CoinType,
+			},
+			Index: account.Account,
+		}] = accountKey
+	}
+
+	return accounts, fingerprint, nil
+}
+
+// importWatchOnlyAccounts imports the given account xpubs into the watch-only
+// wallet the same way lnd does when initializing a watch-only wallet.
+func importWatchOnlyAccounts(w *wallet.Wallet,
+	accounts map[waddrmgr.ScopedIndex]*hdkeychain.ExtendedKey,
+	fingerprint uint32) error {
+
+	scopedIndexes := make([]waddrmgr.ScopedIndex, 0, len(accounts))
+	for scopedIndex := range accounts {
+		scopedIndexes = append(scopedIndexes, scopedIndex)
+	}
+
+	// The wallet assigns the account numbers in the order the accounts are
+	// imported, so they need to be imported sorted by their index.
+	sort.Slice(scopedIndexes, func(i, j int) bool {
+		a, b := scopedIndexes[i], scopedIndexes[j]
+		if a.Scope.Purpose != b.Scope.Purpose {
+			return a.Scope.Purpose < b.Scope.Purpose
+		}
+		if a.Scope.Coin != b.Scope.Coin {
+			return a.Scope.Coin < b.Scope.Coin
+		}
+
+		return a.Index < b.Index
+	})
+
+	for _, scopedIndex := range scopedIndexes {
+		// lnd uses native SegWit addresses for everything except its
+		// np2wkh and p2tr accounts.
+		addrSchema := waddrmgr.ScopeAddrMap[waddrmgr.KeyScopeBIP0084]
+		switch scopedIndex.Scope.Purpose {
+		case waddrmgr.KeyScopeBIP0049Plus.Purpose,
+			waddrmgr.KeyScopeBIP0086.Purpose:
+
+			addrSchema = waddrmgr.ScopeAddrMap[scopedIndex.Scope]
+		}
+
+		// The first account of each scope must be called "default" for
+		// lnd to find it.
+		name := fmt.Sprintf(
+			"%s/%d'", scopedIndex.Scope, scopedIndex.Index,
+		)
+		if scopedIndex.Index == 0 {
+			name = "default"
+		}
+
+		_, err := w.ImportAccountWithScope(
+			name, accounts[scopedIndex], fingerprint,
+			scopedIndex.Scope, addrSchema,
+		)
+		if err != nil {
+			return fmt.Errorf("error importing account %s: %w",
+				name, err)
+		}
+	}
+
+	return nil
+}
+
 func printCipherSeedWords(mnemonicWords []string) {
 	fmt.Println("!!!YOU MUST WRITE DOWN THIS SEED TO BE ABLE TO " +
 		"RESTORE THE WALLET!!!")
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcwallet/waddrmgr"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
+	"github.com/lightningnetwork/lnd/lnwallet/btcwallet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateWalletWatchOnly(t *testing.T) {
+	h := newHarness(t)
+
+	t.Setenv(lnd.PasswordEnvName, testPassPhrase)
+
+	// Create the watch-only wallet from the root key first.
+	walletDir := h.tempFile("rootkey")
+	create := &createWalletCommand{
+		WalletDBDir: walletDir,
+		WatchOnly:   true,
+		rootKey:     &rootKey{RootKey: rootKeyAezeed},
+	}
+
+	err := create.Execute(nil, nil)
+	require.NoError(t, err)
+
+	extendedKey, err := hdkeychain.NewKeyFromString(rootKeyAezeed)
+	require.NoError(t, err)
+	accounts, err := deriveWatchOnlyAccounts(extendedKey)
+	require.NoError(t, err)
+	masterFP, _, err := fingerprint(extendedKey)
+	require.NoError(t, err)
+
+	assertWatchOnlyWallet(t, walletDir, accounts, masterFP)
+
+	// Then export the same accounts in the format of lncli and create the
+	// wallet from that file.
+	exported := &walletrpc.ListAccountsResponse{}
+	var fpBytes [4]byte
+	binary.BigEndian.PutUint32(fpBytes[:], masterFP)
+	for scopedIndex, accountKey := range accounts {
+		account := &walletrpc.Account{
+			ExtendedPublicKey:    accountKey.String(),
+			MasterKeyFingerprint: fpBytes[:],
+			DerivationPath: fmt.Sprintf(
+				"%s/%d'", scopedIndex.Scope, scopedIndex.Index,
+			),
+		}
+		exported.Accounts = append(exported.Accounts, account)
+	}
+	jsonBytes, err := lnrpc.ProtoJSONMarshalOpts.Marshal(exported)
+	require.NoError(t, err)
+
+	accountsFile := h.tempFile("accounts.json")
+	require.NoError(t, os.WriteFile(accountsFile, jsonBytes, 0600))
+
+	walletDir = h.tempFile("accountsfile")
+	create = &createWalletCommand{
+		WalletDBDir:  walletDir,
+		WatchOnly:    true,
+		AccountsFile: accountsFile,
+	}
+
+	err = create.Execute(nil, nil)
+	require.NoError(t, err)
+
+	assertWatchOnlyWallet(t, walletDir, accounts, masterFP)
+}
+
+func assertWatchOnlyWallet(t *testing.T, walletDir string,
+	accounts map[waddrmgr.ScopedIndex]*hdkeychain.ExtendedKey,
+	masterFP uint32) {
+
+	t.Helper()
+
+	loader, err := btcwallet.NewWalletLoader(
+		chainParams, 0, btcwallet.LoaderWithLocalWalletDB(
+			walletDir, true, 0,
+		),
+	)
+	require.NoError(t, err)
+
+	w, err := loader.OpenExistingWallet([]byte(testPassPhrase), false)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, loader.UnloadWallet())
+	}()
+
+	require.True(t, w.Manager.WatchOnly())
+
+	// lnd expects all three on-chain scopes and all 256 key families.
+	require.Len(t, accounts, 3+lndMaxKeyFamily+1)
+	for scopedIndex, accountKey := range accounts {
+		props, err := w.AccountProperties(
+			scopedIndex.Scope, scopedIndex.Index,
+		)
+		require.NoError(t, err)
+		require.Equal(
+			t, accountKey.String(), props.AccountPubKey.String(),
+		)
+		require.Equal(t, masterFP, props.MasterKeyFingerprint)
+	}
+
+	props, err := w.AccountProperties(waddrmgr.KeyScope{
+		Purpose: keychain.BIP0043Purpose,
+		Coin:    chainParams.HDCoinType,
+	}, lndMaxKeyFamily)
+	require.NoError(t, err)
+	require.Equal(t, "m/1017'/1'/255'", props.AccountName)
+}
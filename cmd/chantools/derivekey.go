@@ -1,61 +1,132 @@
 package main
 
 import (
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"strings"
 
-	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
 	"github.com/lightninglabs/chantools/lnd"
 	"github.com/spf13/cobra"
 )
 
+const (
+	deriveKeyFormatText = "text"
+	deriveKeyFormatJSON = "json"
+	deriveKeyFormatCSV  = "csv"
+
+	// maxDeriveKeyPaths is the maximum number of keys that can be derived
+	// with a single path range.
+	maxDeriveKeyPaths = 100_000
+)
+
 const deriveKeyFormat = `
 Path:				%s
 Network: 			%s
 Master Fingerprint:             %x
-Public key: 			%x
-Extended public key (xpub): 	%v
-Address: 			%v
-Legacy address: 		%v
-Taproot address:                %v
+Public key: 			%s
+Extended public key (xpub): 	%s
+Address: 			%s
+Legacy address: 		%s
+Nested SegWit address:		%s
+Taproot address:                %s
+Anchor static remote address:	%s
+Taproot static remote address:	%s
 Private key (WIF): 		%s
 Extended private key (xprv):	%s
 `
 
+var deriveKeyCSVHeader = []string{
+	"path", "pubkey", "xpub", "address_p2wkh", "address_p2pkh",
+	"address_np2wkh", "address_p2tr", "address_anchor_static_remote",
+	"address_taproot_static_remote", "privkey", "xprv",
+}
+
 type deriveKeyCommand struct {
 	Path     string
 	Neuter   bool
 	Identity bool
+	Format   string
 
 	rootKey *rootKey
 	cmd     *cobra.Command
 }
 
+// derivedKey is a single key derived by the derivekey command.
+type derivedKey struct {
+	Path                    string `json:"path"`
+	PubKey                  string `json:"pubkey"`
+	XPub                    string `json:"xpub"`
+	AddrP2WKH               string `json:"address_p2wkh"`
+	AddrP2PKH               string `json:"address_p2pkh"`
+	AddrNP2WKH              string `json:"address_np2wkh"`
+	AddrP2TR                string `json:"address_p2tr"`
+	AddrAnchorStaticRemote  string `json:"address_anchor_static_remote"`
+	AddrTaprootStaticRemote string `json:"address_taproot_static_remote"`
+	PrivKey                 string `json:"privkey,omitempty"`
+	XPriv                   string `json:"xprv,omitempty"`
+}
+
+// csvRecord returns the key as a record of the CSV output.
+func (k *derivedKey) csvRecord() []string {
+	return []string{
+		k.Path, k.PubKey, k.XPub, k.AddrP2WKH, k.AddrP2PKH,
+		k.AddrNP2WKH, k.AddrP2TR, k.AddrAnchorStaticRemote,
+		k.AddrTaprootStaticRemote, k.PrivKey, k.XPriv,
+	}
+}
+
+// derivedKeys is the JSON output of the derivekey command.
+type derivedKeys struct {
+	Network           string        `json:"network"`
+	MasterFingerprint string        `json:"master_fingerprint"`
+	Keys              []*derivedKey `json:"keys"`
+}
+
 func newDeriveKeyCommand() *cobra.Command {
 	cc := &deriveKeyCommand{}
 	cc.cmd = &cobra.Command{
 		Use:   "derivekey",
 		Short: "Derive a key with a specific derivation path",
-		Long: `This command derives a single key with the given BIP32
-derivation path from the root key and prints it to the console.`,
+		Long: `This command derives one or more keys with the given
+BIP32 derivation path from the root key and prints them to the console,
+together with their addresses of all types.
+
+Any part of the path can be a range of indexes like 0-499 to derive multiple
+keys at once, for example the first 500 multisig keys of lnd with the path
+m/1017'/0'/0'/0/0-499. A hardened range is written as 0'-499'.
+
+Besides the default human readable text, the keys can be printed as JSON or CSV
+with the --format flag. If more than one key is derived, the private keys are
+only printed if --neuter=false is specified explicitly.`,
 		Example: `chantools derivekey --path "m/1017'/0'/5'/0/0'" \
 	--neuter
 
+chantools derivekey --path "m/1017'/0'/0'/0/0-499" --format csv
+
 chantools derivekey --identity`,
 		RunE: cc.Execute,
 	}
 	cc.cmd.Flags().StringVar(
 		&cc.Path, "path", "", "BIP32 derivation path to derive; must "+
-			"start with \"m/\"",
+			"start with \"m/\", each part can be a range like 0-9",
 	)
 	cc.cmd.Flags().BoolVar(
 		&cc.Neuter, "neuter", false, "don't output private key(s), "+
-			"only public key(s)",
+			"only public key(s); the default if more than one key "+
+			"is derived",
 	)
 	cc.cmd.Flags().BoolVar(
 		&cc.Identity, "identity", false, "derive the lnd "+
 			"identity_pubkey",
 	)
+	cc.cmd.Flags().StringVar(
+		&cc.Format, "format", deriveKeyFormatText, "output format, "+
+			"either '"+deriveKeyFormatText+"', '"+
+			deriveKeyFormatJSON+"' or '"+deriveKeyFormatCSV+"'",
+	)
 
 	cc.rootKey = newRootKey(cc.cmd, "decrypting the backup")
 
@@ -63,6 +134,12 @@ chantools derivekey --identity`,
 }
 
 func (c *deriveKeyCommand) Execute(_ *cobra.Command, _ []string) error {
+	switch c.Format {
+	case "", deriveKeyFormatText, deriveKeyFormatJSON, deriveKeyFormatCSV:
+	default:
+		return fmt.Errorf("invalid format '%s'", c.Format)
+	}
+
 	extendedKey, err := c.rootKey.read()
 	if err != nil {
 		return fmt.Errorf("error reading root key: %w", err)
@@ -73,58 +150,152 @@ func (c *deriveKeyCommand) Execute(_ *cobra.Command, _ []string) error {
 		c.Neuter = true
 	}
 
-	return deriveKey(extendedKey, c.Path, c.Neuter)
+	paths, err := lnd.ExpandPathRange(c.Path, maxDeriveKeyPaths)
+	if err != nil {
+		return fmt.Errorf("could not parse derivation path: %w", err)
+	}
+
+	// Bulk output is usually processed further or shared, so we only
+	// include the private keys if the user explicitly asked for them.
+	neuter := c.Neuter
+	if len(paths) > 1 &&
+		(c.cmd == nil || !c.cmd.Flags().Changed("neuter")) {
+
+		neuter = true
+	}
+
+	return deriveKeys(extendedKey, paths, neuter, c.Format)
+}
+
+func deriveKeys(extendedKey *hdkeychain.ExtendedKey, paths []string,
+	neuter bool, format string) error {
+
+	_, fingerPrintBytes, err := fingerprint(extendedKey)
+	if err != nil {
+		return fmt.Errorf("could not get fingerprint: %w", err)
+	}
+
+	keys := make([]*derivedKey, len(paths))
+	for i, path := range paths {
+		keys[i], err = deriveKey(extendedKey, path, neuter)
+		if err != nil {
+			return err
+		}
+	}
+
+	var result string
+	switch format {
+	case deriveKeyFormatJSON:
+		resultBytes, err := json.MarshalIndent(&derivedKeys{
+			Network:           chainParams.Name,
+			MasterFingerprint: hex.EncodeToString(fingerPrintBytes),
+			Keys:              keys,
+		}, "", " ")
+		if err != nil {
+			return fmt.Errorf("error encoding keys: %w", err)
+		}
+		result = string(resultBytes)
+
+	case deriveKeyFormatCSV:
+		var b strings.Builder
+		writer := csv.NewWriter(&b)
+		if err := writer.Write(deriveKeyCSVHeader); err != nil {
+			return fmt.Errorf("error encoding keys: %w", err)
+		}
+		for _, key := range keys {
+			err := writer.Write(key.csvRecord())
+			if err != nil {
+				return fmt.Errorf("error encoding keys: %w",
+					err)
+			}
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return fmt.Errorf("error encoding keys: %w", err)
+		}
+		result = b.String()
+
+	default:
+		var b strings.Builder
+		for _, key := range keys {
+			privKey, xPriv := na, na
+			if key.PrivKey != "" {
+				privKey, xPriv = key.PrivKey, key.XPriv
+			}
+
+			fmt.Fprintf(
+				&b, deriveKeyFormat, key.Path,
+				chainParams.Name, fingerPrintBytes, key.PubKey,
+				key.XPub, key.AddrP2WKH, key.AddrP2PKH,
+				key.AddrNP2WKH, key.AddrP2TR,
+				key.AddrAnchorStaticRemote,
+				key.AddrTaprootStaticRemote, privKey, xPriv,
+			)
+		}
+		result = b.String()
+	}
+
+	fmt.Println(result)
+
+	// For the tests, also log as trace level which is disabled by default.
+	log.Tracef(result)
+
+	return nil
 }
 
 func deriveKey(extendedKey *hdkeychain.ExtendedKey, path string,
-	neuter bool) error {
+	neuter bool) (*derivedKey, error) {
 
 	child, pubKey, wif, err := lnd.DeriveKey(extendedKey, path, chainParams)
 	if err != nil {
-		return fmt.Errorf("could not derive keys: %w", err)
+		return nil, fmt.Errorf("could not derive keys: %w", err)
 	}
 	neutered, err := child.Neuter()
 	if err != nil {
-		return fmt.Errorf("could not neuter child key: %w", err)
+		return nil, fmt.Errorf("could not neuter child key: %w", err)
 	}
 
-	// Print the address too.
-	hash160 := btcutil.Hash160(pubKey.SerializeCompressed())
-	addrP2PKH, err := btcutil.NewAddressPubKeyHash(hash160, chainParams)
+	// Print the addresses of all types too.
+	addrP2PKH, err := lnd.P2PKHAddr(pubKey, chainParams)
 	if err != nil {
-		return fmt.Errorf("could not create address: %w", err)
+		return nil, fmt.Errorf("could not create address: %w", err)
 	}
-	addrP2WKH, err := btcutil.NewAddressWitnessPubKeyHash(
-		hash160, chainParams,
-	)
+	addrP2WKH, err := lnd.P2WKHAddr(pubKey, chainParams)
 	if err != nil {
-		return fmt.Errorf("could not create address: %w", err)
+		return nil, fmt.Errorf("could not create address: %w", err)
+	}
+	addrNP2WKH, err := lnd.NP2WKHAddr(pubKey, chainParams)
+	if err != nil {
+		return nil, fmt.Errorf("could not create address: %w", err)
 	}
-
 	addrP2TR, err := lnd.P2TRAddr(pubKey, chainParams)
 	if err != nil {
-		return fmt.Errorf("could not create address: %w", err)
+		return nil, fmt.Errorf("could not create address: %w", err)
 	}
-
-	privKey, xPriv := na, na
-	if !neuter && wif != nil {
-		privKey, xPriv = wif.String(), child.String()
+	addrAnchor, _, err := lnd.P2AnchorStaticRemote(pubKey, chainParams)
+	if err != nil {
+		return nil, fmt.Errorf("could not create address: %w", err)
 	}
-
-	_, fingerPrintBytes, err := fingerprint(extendedKey)
+	addrTaproot, _, err := lnd.P2TaprootStaticRemote(pubKey, chainParams)
 	if err != nil {
-		return fmt.Errorf("could not get fingerprint: %w", err)
+		return nil, fmt.Errorf("could not create address: %w", err)
 	}
 
-	result := fmt.Sprintf(
-		deriveKeyFormat, path, chainParams.Name, fingerPrintBytes,
-		pubKey.SerializeCompressed(), neutered, addrP2WKH, addrP2PKH,
-		addrP2TR, privKey, xPriv,
-	)
-	fmt.Println(result)
-
-	// For the tests, also log as trace level which is disabled by default.
-	log.Tracef(result)
+	pubKeyBytes := pubKey.SerializeCompressed()
+	key := &derivedKey{
+		Path:                    path,
+		PubKey:                  hex.EncodeToString(pubKeyBytes),
+		XPub:                    neutered.String(),
+		AddrP2WKH:               addrP2WKH.String(),
+		AddrP2PKH:               addrP2PKH.String(),
+		AddrNP2WKH:              addrNP2WKH.String(),
+		AddrP2TR:                addrP2TR.String(),
+		AddrAnchorStaticRemote:  addrAnchor.String(),
+		AddrTaprootStaticRemote: addrTaproot.String(),
+	}
+	if !neuter && wif != nil {
+		key.PrivKey, key.XPriv = wif.String(), child.String()
+	}
 
-	return nil
+	return key, nil
 }
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/lightninglabs/chantools/btc"
@@ -143,3 +145,55 @@ func TestDeriveKeyXpubNoNeuter(t *testing.T) {
 	h.assertLogContains("03dc8655d58bd4fd4326863fe34bd5cdddbefaa3b042571" +
 		"05eb1ab99aa05e01c2a")
 }
+
+func TestDeriveKeyRange(t *testing.T) {
+	h := newHarness(t)
+
+	// Derive a range of keys as JSON, which must not contain any private
+	// keys by default.
+	derive := &deriveKeyCommand{
+		Path:    "m/123'/45'/67'/8/8-9",
+		Format:  deriveKeyFormatJSON,
+		rootKey: &rootKey{RootKey: rootKeyAezeed},
+	}
+
+	err := derive.Execute(nil, nil)
+	require.NoError(t, err)
+
+	h.assertLogContains(keyContent)
+
+	var keys derivedKeys
+	logContent := h.getLog()
+	jsonStart := strings.Index(logContent, "{")
+	jsonEnd := strings.LastIndex(logContent, "}")
+	require.NoError(t, json.Unmarshal(
+		[]byte(logContent[jsonStart:jsonEnd+1]), &keys,
+	))
+	require.Len(t, keys.Keys, 2)
+	require.Equal(t, "m/123'/45'/67'/8/8", keys.Keys[0].Path)
+	require.Equal(t, testPath, keys.Keys[1].Path)
+	require.Equal(t, keyContent, keys.Keys[1].AddrP2WKH)
+	require.Empty(t, keys.Keys[1].PrivKey)
+	require.Empty(t, keys.Keys[1].XPriv)
+}
+
+func TestDeriveKeyCSV(t *testing.T) {
+	h := newHarness(t)
+
+	derive := &deriveKeyCommand{
+		Path:    testPath,
+		Format:  deriveKeyFormatCSV,
+		rootKey: &rootKey{RootKey: rootKeyAezeed},
+	}
+
+	err := derive.Execute(nil, nil)
+	require.NoError(t, err)
+
+	h.assertLogContains(strings.Join(deriveKeyCSVHeader, ","))
+	h.assertLogContains(testPath + ",")
+	h.assertLogContains("," + keyContent + ",")
+
+	derive.Format = "yaml"
+	err = derive.Execute(nil, nil)
+	require.ErrorContains(t, err, "invalid format")
+}
@@ -25,7 +25,7 @@ type doubleSpendInputs struct {
 	APIURL         string
 	InputOutpoints []string
 	Publish        bool
-	SweepAddr      string
+	SweepAddrs     []string
 	FeeRate        uint32
 	RecoveryWindow uint32
 
@@ -56,10 +56,8 @@ only be used with inputs that belong to an lnd wallet.`,
 		&cc.InputOutpoints, "inputoutpoints", []string{},
 		"list of outpoints to double spend in the format txid:vout",
 	)
-	cc.cmd.Flags().StringVar(
-		&cc.SweepAddr, "sweepaddr", "", "address to recover the funds "+
-			"to; specify '"+lnd.AddressDeriveFromWallet+"' to "+
-			"derive a new address from the seed automatically",
+	cc.cmd.Flags().StringArrayVar(
+		&cc.SweepAddrs, "sweepaddr", nil, sweepAddrFlagDesc,
 	)
 	cc.cmd.Flags().Uint32Var(
 		&cc.FeeRate, "feerate", defaultFeeSatPerVByte, "fee rate to "+
@@ -87,10 +85,7 @@ func (c *doubleSpendInputs) Execute(_ *cobra.Command, _ []string) error {
 	}
 
 	// Make sure sweep addr is set.
-	err = lnd.CheckAddress(
-		c.SweepAddr, chainParams, true, "sweep", lnd.AddrTypeP2WKH,
-		lnd.AddrTypeP2TR,
-	)
+	sweepDests, err := parseSweepAddrs(c.SweepAddrs)
 	if err != nil {
 		return err
 	}
@@ -147,8 +142,8 @@ func (c *doubleSpendInputs) Execute(_ *cobra.Command, _ []string) error {
 
 	// Start with the txweight estimator.
 	var estimator input.TxWeightEstimator
-	sweepScript, err := lnd.PrepareWalletAddress(
-		c.SweepAddr, chainParams, &estimator, extendedKey, "sweep",
+	sweepOutputs, err := lnd.PrepareSweepOutputs(
+		sweepDests, chainParams, &estimator, extendedKey, "sweep",
 	)
 	if err != nil {
 		return err
@@ -240,7 +235,10 @@ func (c *doubleSpendInputs) Execute(_ *cobra.Command, _ []string) error {
 		})
 	}
 
-	tx.AddTxOut(wire.NewTxOut(int64(totalInput-totalFee), sweepScript))
+	tx.TxOut, err = sweepOutputs.TxOuts(int64(totalInput - totalFee))
+	if err != nil {
+		return err
+	}
 
 	// Calculate the signature hash.
 	prevOutFetcher := txscript.NewMultiPrevOutFetcher(prevOuts)
@@ -0,0 +1,1314 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightninglabs/chantools/btc"
+	"github.com/lightninglabs/chantools/dump"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/chanbackup"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultExplainNumKeys = 500
+
+	// commitLockTimeMarker and commitSequenceMarker are the upper bytes
+	// of the lock time and the sequence of a commitment transaction's
+	// input. The lower 24 bits of both contain the obscured commitment
+	// number.
+	commitLockTimeMarker = 0x20
+	commitSequenceMarker = 0x80
+)
+
+// explainOutputClass describes what an output of an explained transaction
+// is and therefore how it can be claimed.
+type explainOutputClass uint8
+
+const (
+	outputUnknown explainOutputClass = iota
+	outputToRemote
+	outputTweakedToRemote
+	outputToLocal
+	outputAnchor
+	outputWallet
+	outputHTLC
+	outputRemoteParty
+	outputToLocalOrHTLC
+)
+
+type explainTxCommand struct {
+	APIURL    string
+	TXID      string
+	RawTx     string
+	ChannelDB string
+	MultiFile string
+	NumKeys   uint32
+	SweepAddr string
+
+	rootKey *rootKey
+	cmd     *cobra.Command
+}
+
+func newExplainTxCommand() *cobra.Command {
+	cc := &explainTxCommand{}
+	cc.cmd = &cobra.Command{
+		Use: "explaintx",
+		Short: "Explain the inputs and outputs of a channel close " +
+			"transaction",
+		Long: `Decodes every input and output of the given transaction
+and explains what it is, with a focus on channel commitment (force close) and
+cooperative close transactions.
+
+Funding output spends, to_local and to_remote outputs, anchors and HTLCs are
+labeled and all keys that are derived from the seed are detected (multisig,
+base point and on-chain wallet keys up to --numkeys per key family and branch).
+For every output that can be claimed with the seed, the exact chantools command
+to do so is printed.
+
+Without any channel data, to_local and HTLC outputs can't be told apart. If the
+lnd channel.db (--channeldb) or channel.backup (--multi_file) file is given, the
+commitment number is decoded and the to_local and to_remote outputs of both
+parties are identified. The channel.db additionally allows HTLC outputs to be
+labeled if the transaction is the latest commitment of either party.`,
+		Example: `chantools explaintx \
+	--txid abcdef01234...
+
+chantools explaintx \
+	--rawtx 02000000000101... \
+	--channeldb ~/.lnd/data/graph/mainnet/channel.db \
+	--multi_file ~/.lnd/data/chain/bitcoin/mainnet/channel.backup`,
+		RunE: cc.Execute,
+	}
+	cc.cmd.Flags().StringVar(
+		&cc.APIURL, "apiurl", defaultAPIURL, "API URL to use (must "+
+			"be esplora compatible)",
+	)
+	cc.cmd.Flags().StringVar(
+		&cc.TXID, "txid", "", "the ID of the transaction to explain, "+
+			"the transaction is fetched from the API",
+	)
+	cc.cmd.Flags().StringVar(
+		&cc.RawTx, "rawtx", "", "the raw hex encoded transaction to "+
+			"explain, can be used instead of --txid for "+
+			"transactions that aren't published yet",
+	)
+	cc.cmd.Flags().StringVar(
+		&cc.ChannelDB, "channeldb", "", "optional lnd channel.db file "+
+			"to read the channel information from",
+	)
+	cc.cmd.Flags().StringVar(
+		&cc.MultiFile, "multi_file", "", "optional lnd channel.backup "+
+			"file to read the channel information from",
+	)
+	cc.cmd.Flags().Uint32Var(
+		&cc.NumKeys, "numkeys", defaultExplainNumKeys, "number of "+
+			"keys to derive per key family and wallet address "+
+			"branch when looking for keys of the seed",
+	)
+	cc.cmd.Flags().StringVar(
+		&cc.SweepAddr, "sweepaddr", lnd.AddressDeriveFromWallet,
+		"the sweep address to use in the printed claim commands",
+	)
+
+	cc.rootKey = newRootKey(cc.cmd, "deriving the keys")
+
+	return cc.cmd
+}
+
+func (c *explainTxCommand) Execute(_ *cobra.Command, _ []string) error {
+	extendedKey, err := c.rootKey.read()
+	if err != nil {
+		return fmt.Errorf("error reading root key: %w", err)
+	}
+
+	if (c.TXID == "") == (c.RawTx == "") {
+		return errors.New("exactly one of --txid or --rawtx must be " +
+			"specified")
+	}
+	if c.NumKeys == 0 {
+		return errors.New("--numkeys must be greater than zero")
+	}
+
+	api := newExplorerAPI(c.APIURL)
+	tx, chainData, err := fetchExplainTx(api, c.TXID, c.RawTx)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Deriving %d keys per key family and branch", c.NumKeys)
+	keys, err := newOwnKeyIndex(extendedKey, c.NumKeys)
+	if err != nil {
+		return err
+	}
+
+	channels := make(map[wire.OutPoint]*explainChannel)
+	if c.ChannelDB != "" {
+		db, err := lnd.OpenDB(c.ChannelDB, true)
+		if err != nil {
+			return fmt.Errorf("error opening channel DB: %w", err)
+		}
+		defer func() { _ = db.Close() }()
+
+		err = addChannelsFromDB(
+			db.ChannelStateDB(), c.ChannelDB, channels,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	if c.MultiFile != "" {
+		keyRing := &lnd.HDKeyRing{
+			ExtendedKey: extendedKey,
+			ChainParams: chainParams,
+		}
+		multiFile := chanbackup.NewMultiFile(c.MultiFile)
+		multi, err := multiFile.ExtractMulti(keyRing)
+		if err != nil {
+			return fmt.Errorf("could not extract multi file: %w",
+				err)
+		}
+
+		err = addChannelsFromBackup(
+			multi, c.MultiFile, extendedKey, keyRing, channels,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	explanation, err := explainTx(tx, chainData, keys, channels)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(explanation.report(c.SweepAddr))
+
+	return nil
+}
+
+// explainChainData is the additional information about a transaction that
+// is only known to the chain API.
+type explainChainData struct {
+	prevOuts  map[wire.OutPoint]*wire.TxOut
+	status    *btc.Status
+	outspends []*btc.Outspend
+}
+
+// fetchExplainTx fetches or decodes the transaction to explain and looks up
+// the outputs it spends. If the transaction isn't known to the API, the
+// previous outputs are looked up one by one on a best effort basis.
+func fetchExplainTx(api *btc.ExplorerAPI, txid, rawTx string) (*wire.MsgTx,
+	*explainChainData, error) {
+
+	var err error
+	if txid != "" {
+		rawTx, err = api.RawTransaction(txid)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error fetching tx %s: %w",
+				txid, err)
+		}
+	}
+
+	txBytes, err := hex.DecodeString(strings.TrimSpace(rawTx))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error decoding tx: %w", err)
+	}
+	tx := &wire.MsgTx{}
+	if err := tx.Deserialize(bytes.NewReader(txBytes)); err != nil {
+		return nil, nil, fmt.Errorf("error parsing tx: %w", err)
+	}
+
+	data := &explainChainData{
+		prevOuts: make(map[wire.OutPoint]*wire.TxOut),
+	}
+	apiTx, err := api.Transaction(tx.TxHash().String())
+	switch {
+	case err == nil:
+		data.status = apiTx.Status
+		for idx, vin := range apiTx.Vin {
+			if vin.Prevout == nil || idx >= len(tx.TxIn) {
+				continue
+			}
+
+			txOut, err := voutToTxOut(vin.Prevout)
+			if err != nil {
+				return nil, nil, err
+			}
+			data.prevOuts[tx.TxIn[idx].PreviousOutPoint] = txOut
+		}
+		for _, vout := range apiTx.Vout {
+			data.outspends = append(data.outspends, vout.Outspend)
+		}
+
+	case txid != "":
+		return nil, nil, fmt.Errorf("error fetching tx %s: %w", txid,
+			err)
+
+	default:
+		log.Infof("Transaction %v not found, looking up the outputs "+
+			"it spends", tx.TxHash())
+
+		for _, txIn := range tx.TxIn {
+			prevOut := txIn.PreviousOutPoint
+			prevTx, err := api.Transaction(prevOut.Hash.String())
+			if err != nil {
+				log.Warnf("Could not fetch previous output "+
+					"%v: %v", prevOut, err)
+				continue
+			}
+			if int(prevOut.Index) >= len(prevTx.Vout) {
+				return nil, nil, fmt.Errorf("invalid previous "+
+					"outpoint %v", prevOut)
+			}
+
+			txOut, err := voutToTxOut(prevTx.Vout[prevOut.Index])
+			if err != nil {
+				return nil, nil, err
+			}
+			data.prevOuts[prevOut] = txOut
+		}
+	}
+
+	return tx, data, nil
+}
+
+// voutToTxOut converts an output returned by the API into a wire output.
+func voutToTxOut(vout *btc.Vout) (*wire.TxOut, error) {
+	pkScript, err := hex.DecodeString(vout.ScriptPubkey)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding pk script: %w", err)
+	}
+
+	return &wire.TxOut{
+		Value:    int64(vout.Value),
+		PkScript: pkScript,
+	}, nil
+}
+
+// ownScript is an output script that can be spent with a key of the seed.
+type ownScript struct {
+	class explainOutputClass
+	label string
+	path  string
+	index uint32
+}
+
+// ownKeyIndex contains the public keys and output scripts derived from the
+// seed, keyed by their hex encoding.
+type ownKeyIndex struct {
+	pubKeys map[string]string
+	scripts map[string]*ownScript
+}
+
+// newOwnKeyIndex derives the first numKeys keys of each lnd key family that
+// is used in channels and of each branch of the default on-chain wallet
+// accounts.
+func newOwnKeyIndex(extendedKey *hdkeychain.ExtendedKey,
+	numKeys uint32) (*ownKeyIndex, error) {
+
+	keys := &ownKeyIndex{
+		pubKeys: make(map[string]string),
+		scripts: make(map[string]*ownScript),
+	}
+
+	for _, family := range lndKeyFamilies {
+		if !family.channel {
+			continue
+		}
+
+		branchKey, err := lnd.DeriveChildren(extendedKey, []uint32{
+			lnd.HardenedKey(uint32(keychain.BIP0043Purpose)),
+			lnd.HardenedKey(chainParams.HDCoinType),
+			lnd.HardenedKey(uint32(family.family)),
+			0,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not derive %s branch: "+
+				"%w", family.name, err)
+		}
+
+		for index := range numKeys {
+			key, err := branchKey.DeriveNonStandard(index)
+			if err != nil {
+				return nil, fmt.Errorf("error deriving child "+
+					"key: %w", err)
+			}
+			pubKey, err := key.ECPubKey()
+			if err != nil {
+				return nil, fmt.Errorf("error deriving public "+
+					"key: %w", err)
+			}
+
+			path := fmt.Sprintf(
+				lnd.LndDerivationPath+"/0/%d",
+				chainParams.HDCoinType, family.family, index,
+			)
+			keys.pubKeys[hexPubKey(pubKey)] = fmt.Sprintf(
+				"%s key %s", family.name, path,
+			)
+
+			err = keys.addChannelScripts(
+				family.family, index, path, pubKey,
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, account := range btc.DefaultWalletAccounts {
+		for _, branch := range account.Branches {
+			path := account.Path(chainParams, branch, 0)
+			branchKey, err := lnd.DeriveChildren(
+				extendedKey, path[:len(path)-1],
+			)
+			if err != nil {
+				return nil, fmt.Errorf("could not derive "+
+					"wallet branch: %w", err)
+			}
+
+			err = keys.addWalletScripts(
+				account, branch, branchKey, numKeys,
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return keys, nil
+}
+
+// addChannelScripts adds the channel output scripts that pay to the given key
+// of the given key family.
+func (k *ownKeyIndex) addChannelScripts(family keychain.KeyFamily,
+	index uint32, path string, pubKey *btcec.PublicKey) error {
+
+	addScript := func(pkScript []byte, class explainOutputClass,
+		label string) {
+
+		k.scripts[hex.EncodeToString(pkScript)] = &ownScript{
+			class: class,
+			label: label,
+			path:  path,
+			index: index,
+		}
+	}
+	addAddr := func(addr btcutil.Address, class explainOutputClass,
+		label string) error {
+
+		pkScript, err := txscript.PayToAddrScript(addr)
+		if err != nil {
+			return fmt.Errorf("error creating pk script: %w", err)
+		}
+		addScript(pkScript, class, label)
+
+		return nil
+	}
+
+	switch family {
+	// Anchor channels use the multisig key for the anchor output.
+	case keychain.KeyFamilyMultiSig:
+		script, err := input.CommitScriptAnchor(pubKey)
+		if err != nil {
+			return fmt.Errorf("error deriving anchor script: %w",
+				err)
+		}
+		pkScript, err := input.WitnessScriptHash(script)
+		if err != nil {
+			return fmt.Errorf("error deriving script hash: %w", err)
+		}
+		addScript(pkScript, outputAnchor, "anchor output")
+
+	// The payment base key is used for the to_remote output of channels
+	// with a static remote key and the anchor output of simple taproot
+	// channels.
+	case keychain.KeyFamilyPaymentBase:
+		p2wkh, err := lnd.P2WKHAddr(pubKey, chainParams)
+		if err != nil {
+			return fmt.Errorf("could not create address: %w", err)
+		}
+		err = addAddr(
+			p2wkh, outputToRemote, "to_remote output (static "+
+				"remote key)",
+		)
+		if err != nil {
+			return err
+		}
+
+		p2anchor, _, err := lnd.P2AnchorStaticRemote(
+			pubKey, chainParams,
+		)
+		if err != nil {
+			return fmt.Errorf("could not create address: %w", err)
+		}
+		err = addAddr(
+			p2anchor, outputToRemote, "to_remote output (anchor "+
+				"channel)",
+		)
+		if err != nil {
+			return err
+		}
+
+		p2tr, _, err := lnd.P2TaprootStaticRemote(pubKey, chainParams)
+		if err != nil {
+			return fmt.Errorf("could not create address: %w", err)
+		}
+		err = addAddr(
+			p2tr, outputToRemote, "to_remote output (simple "+
+				"taproot channel)",
+		)
+		if err != nil {
+			return err
+		}
+
+		scriptTree, err := input.NewAnchorScriptTree(pubKey)
+		if err != nil {
+			return fmt.Errorf("error deriving taproot anchor: %w",
+				err)
+		}
+		pkScript, err := input.PayToTaprootScript(scriptTree.TaprootKey)
+		if err != nil {
+			return fmt.Errorf("error deriving pk script: %w", err)
+		}
+		addScript(
+			pkScript, outputAnchor, "anchor output (simple "+
+				"taproot channel)",
+		)
+	}
+
+	return nil
+}
+
+// addWalletScripts adds the first numKeys addresses of the given wallet
+// account branch.
+func (k *ownKeyIndex) addWalletScripts(account *btc.WalletAccount,
+	branch uint32, branchKey *hdkeychain.ExtendedKey,
+	numKeys uint32) error {
+
+	for index := range numKeys {
+		key, err := branchKey.DeriveNonStandard(index)
+		if err != nil {
+			return fmt.Errorf("error deriving child key: %w", err)
+		}
+		pubKey, err := key.ECPubKey()
+		if err != nil {
+			return fmt.Errorf("error deriving public key: %w", err)
+		}
+		addr, err := account.Address(pubKey, branch, chainParams)
+		if err != nil {
+			return fmt.Errorf("error deriving address: %w", err)
+		}
+		pkScript, err := txscript.PayToAddrScript(addr)
+		if err != nil {
+			return fmt.Errorf("error creating pk script: %w", err)
+		}
+
+		walletAddr := &btc.WalletAddress{
+			Branch: branch,
+			Index:  index,
+			Path:   account.Path(chainParams, branch, index),
+		}
+		path := walletAddr.PathString()
+		k.pubKeys[hexPubKey(pubKey)] = "wallet key " + path
+		k.scripts[hex.EncodeToString(pkScript)] = &ownScript{
+			class: outputWallet,
+			label: fmt.Sprintf("on-chain wallet output (%s "+
+				"account)", account.Name),
+			path:  path,
+			index: index,
+		}
+	}
+
+	return nil
+}
+
+// explainChannel is a channel known from a channel.db or channel.backup
+// file.
+type explainChannel struct {
+	channel *channeldb.OpenChannel
+
+	// channelDB is the path of the channel.db file the channel was read
+	// from, if any.
+	channelDB string
+
+	// backupFile is the path of the channel.backup file the channel was
+	// read from, if any.
+	backupFile string
+}
+
+// addChannelsFromDB adds all open and historical channels of the given
+// channel DB.
+func addChannelsFromDB(chanDb *channeldb.ChannelStateDB, path string,
+	channels map[wire.OutPoint]*explainChannel) error {
+
+	openChannels, err := chanDb.FetchAllChannels()
+	if err != nil {
+		return fmt.Errorf("error fetching open channels: %w", err)
+	}
+	for _, channel := range openChannels {
+		channels[channel.FundingOutpoint] = &explainChannel{
+			channel:   channel,
+			channelDB: path,
+		}
+	}
+
+	closedChannels, err := chanDb.FetchClosedChannels(false)
+	if err != nil {
+		return fmt.Errorf("error fetching closed channels: %w", err)
+	}
+	for _, closedChan := range closedChannels {
+		if _, ok := channels[closedChan.ChanPoint]; ok {
+			continue
+		}
+
+		histChan, err := chanDb.FetchHistoricalChannel(
+			&closedChan.ChanPoint,
+		)
+		switch {
+		// The channel was closed in a pre-historic version of lnd.
+		// Ignore the error.
+		case errors.Is(err, channeldb.ErrNoHistoricalBucket):
+		case errors.Is(err, channeldb.ErrChannelNotFound):
+
+		case err == nil:
+			channels[closedChan.ChanPoint] = &explainChannel{
+				channel:   histChan,
+				channelDB: path,
+			}
+
+		default:
+			return fmt.Errorf("error fetching historical channel "+
+				"%v: %w", closedChan.ChanPoint, err)
+		}
+	}
+
+	return nil
+}
+
+// addChannelsFromBackup adds all channels of the given backup that aren't
+// known from a channel DB yet. Because a backup only contains the locators
+// of our keys, the keys and the revocation producer are re-derived from the
+// seed.
+func addChannelsFromBackup(multi *chanbackup.Multi, path string,
+	extendedKey *hdkeychain.ExtendedKey, keyRing *lnd.HDKeyRing,
+	channels map[wire.OutPoint]*explainChannel) error {
+
+	for _, single := range multi.StaticBackups {
+		if _, ok := channels[single.FundingOutpoint]; ok {
+			continue
+		}
+
+		channel, err := channelFromBackup(single, extendedKey, keyRing)
+		if err != nil {
+			return fmt.Errorf("error restoring channel %v from "+
+				"backup: %w", single.FundingOutpoint, err)
+		}
+		channels[single.FundingOutpoint] = &explainChannel{
+			channel:    channel,
+			backupFile: path,
+		}
+	}
+
+	return nil
+}
+
+// channelFromBackup creates a channel shell from a static channel backup, the
+// same way lnd does it when restoring a channel.
+func channelFromBackup(single chanbackup.Single,
+	extendedKey *hdkeychain.ExtendedKey,
+	keyRing *lnd.HDKeyRing) (*channeldb.OpenChannel, error) {
+
+	var chanType channeldb.ChannelType
+	switch single.Version {
+	case chanbackup.DefaultSingleVersion:
+		chanType = channeldb.SingleFunderBit
+
+	case chanbackup.TweaklessCommitVersion:
+		chanType = channeldb.SingleFunderTweaklessBit
+
+	case chanbackup.AnchorsCommitVersion:
+		chanType = channeldb.AnchorOutputsBit
+		chanType |= channeldb.SingleFunderTweaklessBit
+
+	case chanbackup.AnchorsZeroFeeHtlcTxCommitVersion:
+		chanType = channeldb.ZeroHtlcTxFeeBit
+		chanType |= channeldb.AnchorOutputsBit
+		chanType |= channeldb.SingleFunderTweaklessBit
+
+	case chanbackup.ScriptEnforcedLeaseVersion:
+		chanType = channeldb.LeaseExpirationBit
+		chanType |= channeldb.ZeroHtlcTxFeeBit
+		chanType |= channeldb.AnchorOutputsBit
+		chanType |= channeldb.SingleFunderTweaklessBit
+
+	case chanbackup.SimpleTaprootVersion:
+		chanType = channeldb.ZeroHtlcTxFeeBit
+		chanType |= channeldb.AnchorOutputsBit
+		chanType |= channeldb.SingleFunderTweaklessBit
+		chanType |= channeldb.SimpleTaprootFeatureBit
+
+	default:
+		return nil, fmt.Errorf("unknown backup version %d",
+			single.Version)
+	}
+
+	localCfg := single.LocalChanCfg
+	for _, keyDesc := range []*keychain.KeyDescriptor{
+		&localCfg.MultiSigKey, &localCfg.RevocationBasePoint,
+		&localCfg.PaymentBasePoint, &localCfg.DelayBasePoint,
+		&localCfg.HtlcBasePoint,
+	} {
+		derived, err := keyRing.DeriveKey(keyDesc.KeyLocator)
+		if err != nil {
+			return nil, fmt.Errorf("error deriving key: %w", err)
+		}
+		keyDesc.PubKey = derived.PubKey
+	}
+
+	// Older backups contain the public key of the shachain root, which
+	// means the private key itself was used as the root. Newer versions
+	// perform an ECDH with the multisig key.
+	var multiSigPubKey *btcec.PublicKey
+	if single.ShaChainRootDesc.PubKey == nil {
+		multiSigPubKey = localCfg.MultiSigKey.PubKey
+	}
+	shaChainLocator := single.ShaChainRootDesc.KeyLocator
+	producer, err := lnd.ShaChainFromPath(extendedKey, []uint32{
+		lnd.HardenedKey(uint32(keychain.BIP0043Purpose)),
+		lnd.HardenedKey(chainParams.HDCoinType),
+		lnd.HardenedKey(uint32(shaChainLocator.Family)),
+		0, shaChainLocator.Index,
+	}, multiSigPubKey)
+	if err != nil {
+		return nil, fmt.Errorf("error deriving revocation producer: "+
+			"%w", err)
+	}
+
+	return &channeldb.OpenChannel{
+		ChanType:           chanType,
+		ChainHash:          single.ChainHash,
+		IsInitiator:        single.IsInitiator,
+		Capacity:           single.Capacity,
+		FundingOutpoint:    single.FundingOutpoint,
+		ShortChannelID:     single.ShortChannelID,
+		IdentityPub:        single.RemoteNodePub,
+		LocalChanCfg:       localCfg,
+		RemoteChanCfg:      single.RemoteChanCfg,
+		RevocationProducer: producer,
+		ThawHeight:         single.LeaseExpiry,
+	}, nil
+}
+
+// stateNum returns the commitment number of the given commitment transaction
+// of the channel.
+func (c *explainChannel) stateNum(tx *wire.MsgTx) uint64 {
+	local := c.channel.LocalChanCfg.PaymentBasePoint.PubKey
+	remote := c.channel.RemoteChanCfg.PaymentBasePoint.PubKey
+
+	var obfuscator [lnwallet.StateHintSize]byte
+	if c.channel.IsInitiator {
+		obfuscator = lnwallet.DeriveStateHintObfuscator(local, remote)
+	} else {
+		obfuscator = lnwallet.DeriveStateHintObfuscator(remote, local)
+	}
+
+	return lnwallet.GetStateNumHint(tx, obfuscator)
+}
+
+// commitScripts returns the to_local and to_remote output scripts of both
+// parties' commitment at the given commitment number, as far as the known
+// commitment points allow.
+func (c *explainChannel) commitScripts(
+	stateNum uint64) (map[string]*ownScript, error) {
+
+	var (
+		channel = c.channel
+		scripts = make(map[string]*ownScript)
+	)
+	addScript := func(addrStr string, class explainOutputClass,
+		label string) error {
+
+		addr, err := btcutil.DecodeAddress(addrStr, chainParams)
+		if err != nil {
+			return fmt.Errorf("error decoding address: %w", err)
+		}
+		pkScript, err := txscript.PayToAddrScript(addr)
+		if err != nil {
+			return fmt.Errorf("error creating pk script: %w", err)
+		}
+		scripts[hex.EncodeToString(pkScript)] = &ownScript{
+			class: class,
+			label: label,
+		}
+
+		return nil
+	}
+
+	// Our own commitment point can be derived for any commitment number.
+	if channel.RevocationProducer != nil {
+		revPreimage, err := channel.RevocationProducer.AtIndex(stateNum)
+		if err != nil {
+			return nil, fmt.Errorf("error deriving commitment "+
+				"secret: %w", err)
+		}
+		commitPoint := input.ComputeCommitmentPoint(revPreimage[:])
+
+		debugInfo, err := dump.CollectDebugInfo(
+			channel, commitPoint, true, channel.IsInitiator,
+			chainParams,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error collecting local debug "+
+				"info: %w", err)
+		}
+
+		err = addScript(
+			debugInfo.ToLocalAddr, outputToLocal,
+			fmt.Sprintf("to_local output (ours, time locked by "+
+				"%d blocks)", channel.LocalChanCfg.CsvDelay),
+		)
+		if err != nil {
+			return nil, err
+		}
+		err = addScript(
+			debugInfo.ToRemoteAddr, outputRemoteParty,
+			"to_remote output of the remote party",
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// We only know the latest commitment points of the remote party.
+	for _, commitPoint := range []*btcec.PublicKey{
+		channel.RemoteCurrentRevocation, channel.RemoteNextRevocation,
+	} {
+		if commitPoint == nil {
+			continue
+		}
+
+		debugInfo, err := dump.CollectDebugInfo(
+			channel, commitPoint, false, !channel.IsInitiator,
+			chainParams,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error collecting remote "+
+				"debug info: %w", err)
+		}
+
+		err = addScript(
+			debugInfo.ToLocalAddr, outputRemoteParty,
+			"to_local output of the remote party (time locked)",
+		)
+		if err != nil {
+			return nil, err
+		}
+		err = addScript(
+			debugInfo.ToRemoteAddr, outputTweakedToRemote,
+			"to_remote output (ours, tweaked with the commitment "+
+				"point)",
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return scripts, nil
+}
+
+// explainedInput is a decoded input of an explained transaction.
+type explainedInput struct {
+	outpoint wire.OutPoint
+	prevOut  *wire.TxOut
+	label    string
+	ourKeys  []string
+
+	fundingSpend    bool
+	taprootKeySpend bool
+}
+
+// explainedOutput is a decoded output of an explained transaction.
+type explainedOutput struct {
+	index   int
+	value   int64
+	addr    string
+	label   string
+	class   explainOutputClass
+	path    string
+	keyIdx  uint32
+	spentBy string
+}
+
+// explainedTx is the result of explaining a transaction.
+type explainedTx struct {
+	tx        *wire.MsgTx
+	chainData *explainChainData
+	kind      string
+	channel   *explainChannel
+	notes     []string
+	inputs    []*explainedInput
+	outputs   []*explainedOutput
+
+	isCommitment bool
+	remoteCommit bool
+}
+
+// explainTx decodes all inputs and outputs of the given transaction and
+// labels them with the help of the keys derived from the seed and the given
+// channels.
+func explainTx(tx *wire.MsgTx, chainData *explainChainData,
+	keys *ownKeyIndex,
+	channels map[wire.OutPoint]*explainChannel) (*explainedTx, error) {
+
+	result := &explainedTx{
+		tx:        tx,
+		chainData: chainData,
+	}
+	for _, txIn := range tx.TxIn {
+		prevOut := chainData.prevOuts[txIn.PreviousOutPoint]
+		result.inputs = append(
+			result.inputs, explainInput(txIn, prevOut, keys),
+		)
+	}
+
+	// A channel close transaction always spends exactly the funding
+	// output.
+	var fundingSpend bool
+	obscuredStateNum, hasStateHint := commitStateHint(tx)
+	if len(tx.TxIn) == 1 {
+		fundingInput := result.inputs[0]
+		result.channel = channels[fundingInput.outpoint]
+		fundingSpend = fundingInput.fundingSpend ||
+			result.channel != nil ||
+			(fundingInput.taprootKeySpend && hasStateHint)
+	}
+
+	switch {
+	case !fundingSpend:
+		result.kind = "transaction that doesn't spend a channel " +
+			"funding output"
+
+	case hasStateHint:
+		result.kind = "commitment transaction (force close)"
+		result.isCommitment = true
+
+	default:
+		result.kind = "cooperative close transaction"
+	}
+
+	var (
+		chanScripts = make(map[string]*ownScript)
+		htlcs       = make(map[int32]channeldb.HTLC)
+	)
+	if result.channel != nil {
+		channel := result.channel.channel
+		result.notes = append(result.notes, fmt.Sprintf("Channel %v "+
+			"with remote node %x, capacity %v",
+			channel.FundingOutpoint,
+			channel.IdentityPub.SerializeCompressed(),
+			channel.Capacity))
+
+		if result.isCommitment {
+			stateNum := result.channel.stateNum(tx)
+			result.notes = append(result.notes, fmt.Sprintf(
+				"Commitment number %d", stateNum,
+			))
+
+			var err error
+			chanScripts, err = result.channel.commitScripts(
+				stateNum,
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			result.addCommitmentHTLCs(htlcs)
+		}
+	} else if result.isCommitment {
+		result.notes = append(result.notes, fmt.Sprintf("Obscured "+
+			"commitment number %d, specify --channeldb or "+
+			"--multi_file to decode it", obscuredStateNum))
+	}
+
+	for idx, txOut := range tx.TxOut {
+		out := result.explainOutput(
+			idx, txOut, keys, chanScripts, htlcs,
+		)
+		result.outputs = append(result.outputs, out)
+
+		switch out.class {
+		case outputToRemote, outputTweakedToRemote:
+			result.remoteCommit = result.isCommitment
+		}
+	}
+
+	// If we know that the remote party published their commitment, the
+	// unidentified outputs aren't ours.
+	if result.remoteCommit {
+		for _, out := range result.outputs {
+			if out.class == outputToLocalOrHTLC {
+				out.class = outputRemoteParty
+				out.label = "to_local output of the remote " +
+					"party or HTLC output"
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// addCommitmentHTLCs adds the HTLCs of the commitment that matches the
+// explained transaction, keyed by their output index. This is only possible
+// for the latest commitments of a channel read from a channel DB.
+func (e *explainedTx) addCommitmentHTLCs(htlcs map[int32]channeldb.HTLC) {
+	txHash := e.tx.TxHash()
+	channel := e.channel.channel
+
+	commitments := []*channeldb.ChannelCommitment{
+		&channel.LocalCommitment, &channel.RemoteCommitment,
+	}
+	for idx, commitment := range commitments {
+		if commitment.CommitTx == nil ||
+			commitment.CommitTx.TxHash() != txHash {
+
+			continue
+		}
+
+		if idx == 0 {
+			e.notes = append(e.notes, "This is our latest "+
+				"commitment transaction")
+		} else {
+			e.notes = append(e.notes, "This is the latest "+
+				"commitment transaction of the remote party")
+			e.remoteCommit = true
+		}
+
+		for _, htlc := range commitment.Htlcs {
+			if htlc.OutputIndex >= 0 {
+				htlcs[htlc.OutputIndex] = htlc
+			}
+		}
+	}
+}
+
+// explainInput decodes the given input by looking at its witness and the
+// output it spends.
+func explainInput(txIn *wire.TxIn, prevOut *wire.TxOut,
+	keys *ownKeyIndex) *explainedInput {
+
+	in := &explainedInput{
+		outpoint: txIn.PreviousOutPoint,
+		prevOut:  prevOut,
+	}
+	addKey := func(pubKey []byte) {
+		if desc, ok := keys.pubKeys[hex.EncodeToString(pubKey)]; ok {
+			in.ourKeys = append(in.ourKeys, desc)
+		}
+	}
+
+	witness := txIn.Witness
+	switch {
+	case isMultiSigWitness(witness):
+		in.fundingSpend = true
+		in.label = "channel funding output (2-of-2 multisig)"
+		script := witness[3]
+		addKey(script[2:35])
+		addKey(script[36:69])
+
+	case len(witness) == 2 && len(witness[1]) == 33:
+		in.label = "P2WKH output"
+		if len(txIn.SignatureScript) > 0 {
+			in.label = "NP2WKH output"
+		}
+		addKey(witness[1])
+
+	case len(witness) == 1 &&
+		(len(witness[0]) == 64 || len(witness[0]) == 65):
+
+		in.taprootKeySpend = true
+		in.label = "taproot key spend (wallet output or simple " +
+			"taproot channel funding output)"
+
+	case len(witness) == 0:
+		in.label = "non-SegWit output"
+
+	default:
+		in.label = fmt.Sprintf("script spend with %d witness elements",
+			len(witness))
+	}
+
+	if prevOut != nil {
+		script, ok := keys.scripts[hex.EncodeToString(prevOut.PkScript)]
+		if ok {
+			in.label = script.label
+			in.ourKeys = append(in.ourKeys, "key "+script.path)
+		}
+	}
+
+	return in
+}
+
+// isMultiSigWitness returns true if the given witness spends a 2-of-2
+// multisig P2WSH output, as used for the funding output of a channel.
+func isMultiSigWitness(witness wire.TxWitness) bool {
+	if len(witness) != 4 {
+		return false
+	}
+
+	script := witness[3]
+	return len(script) == input.MultiSigSize &&
+		script[0] == txscript.OP_2 &&
+		script[1] == txscript.OP_DATA_33 &&
+		script[35] == txscript.OP_DATA_33 &&
+		script[69] == txscript.OP_2 &&
+		script[70] == txscript.OP_CHECKMULTISIG
+}
+
+// commitStateHint returns the obscured commitment number of the given
+// transaction if its lock time and sequence carry the markers of a
+// commitment transaction.
+func commitStateHint(tx *wire.MsgTx) (uint64, bool) {
+	if len(tx.TxIn) != 1 {
+		return 0, false
+	}
+
+	sequence := tx.TxIn[0].Sequence
+	if tx.LockTime>>24 != commitLockTimeMarker ||
+		sequence>>24 != commitSequenceMarker {
+
+		return 0, false
+	}
+
+	return uint64(sequence&0xFFFFFF)<<24 | uint64(tx.LockTime&0xFFFFFF),
+		true
+}
+
+// explainOutput labels a single output of the explained transaction.
+func (e *explainedTx) explainOutput(idx int, txOut *wire.TxOut,
+	keys *ownKeyIndex, chanScripts map[string]*ownScript,
+	htlcs map[int32]channeldb.HTLC) *explainedOutput {
+
+	out := &explainedOutput{
+		index: idx,
+		value: txOut.Value,
+	}
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(
+		txOut.PkScript, chainParams,
+	)
+	if err == nil && len(addrs) == 1 {
+		out.addr = addrs[0].EncodeAddress()
+	}
+	if idx < len(e.chainData.outspends) {
+		outspend := e.chainData.outspends[idx]
+		if outspend != nil && outspend.Spent {
+			out.spentBy = outspend.Txid
+		}
+	}
+
+	scriptHex := hex.EncodeToString(txOut.PkScript)
+	ownScript, isOwn := keys.scripts[scriptHex]
+	chanScript, isChan := chanScripts[scriptHex]
+	htlc, isHTLC := htlcs[int32(idx)]
+	isScript := txscript.IsPayToWitnessScriptHash(txOut.PkScript) ||
+		txscript.IsPayToTaproot(txOut.PkScript)
+
+	switch {
+	case isOwn:
+		out.class = ownScript.class
+		out.label = ownScript.label
+		out.path = ownScript.path
+		out.keyIdx = ownScript.index
+
+	case isChan:
+		out.class = chanScript.class
+		out.label = chanScript.label
+
+	case isHTLC:
+		direction := "outgoing"
+		if htlc.Incoming {
+			direction = "incoming"
+		}
+		out.class = outputHTLC
+		out.label = fmt.Sprintf("%s HTLC of %v, payment hash %x, "+
+			"expires at height %d", direction,
+			htlc.Amt.ToSatoshis(), htlc.RHash[:],
+			htlc.RefundTimeout)
+
+	case e.isCommitment &&
+		isAnchorCandidate(txOut.Value, txOut.PkScript):
+
+		out.class = outputRemoteParty
+		out.label = "anchor output of the remote party"
+
+	case e.isCommitment && isScript:
+		out.class = outputToLocalOrHTLC
+		out.label = "time locked to_local output or HTLC output"
+
+	case e.isCommitment:
+		out.class = outputRemoteParty
+		out.label = "to_remote output of the remote party"
+
+	default:
+		out.label = "output not derived from the seed"
+	}
+
+	return out
+}
+
+// claimCommand returns the chantools command to claim the given output or
+// an explanation why there is none.
+func (e *explainedTx) claimCommand(out *explainedOutput,
+	sweepAddr string) string {
+
+	txid := e.tx.TxHash().String()
+	confirmed := e.chainData.status != nil && e.chainData.status.Confirmed
+
+	if out.spentBy != "" {
+		return fmt.Sprintf("already spent by %s", out.spentBy)
+	}
+
+	switch out.class {
+	case outputToRemote:
+		return fmt.Sprintf("chantools sweepremoteclosed --startindex "+
+			"%d --gaplimit 1 --sweepaddr %s --publish", out.keyIdx,
+			sweepAddr)
+
+	case outputTweakedToRemote:
+		return "can only be swept by lnd since the key is tweaked " +
+			"with the commitment point"
+
+	case outputAnchor:
+		switch {
+		case confirmed:
+			return "not worth claiming on its own, the " +
+				"transaction is confirmed"
+
+		case e.chainData.status != nil:
+			return fmt.Sprintf("chantools pullanchor --closetxid "+
+				"%s --sponsorinput %s --publish", txid,
+				lnd.AddressDeriveFromWallet)
+
+		default:
+			return fmt.Sprintf("chantools pullanchor --closetx "+
+				"<raw tx> --sponsorinput %s --publish",
+				lnd.AddressDeriveFromWallet)
+		}
+
+	case outputWallet:
+		return fmt.Sprintf("spendable by the lnd wallet or with "+
+			"chantools sweepall --skip %s,%s,%s --sweepaddr %s "+
+			"--publish", sweepClassRemoteClosed,
+			sweepClassTimeLocked, sweepClassAnchor, sweepAddr)
+
+	case outputToLocal:
+		command := e.toLocalCommand(out, sweepAddr)
+		if confirmed {
+			csvDelay := e.channel.channel.LocalChanCfg.CsvDelay
+			command = fmt.Sprintf("%s (spendable after block %d)",
+				command,
+				e.chainData.status.BlockHeight+int(csvDelay))
+		}
+
+		return command
+
+	case outputToLocalOrHTLC:
+		return fmt.Sprintf("if this is our to_local output: "+
+			"chantools sweeptimelockmanual --timelockaddr %s "+
+			"--remoterevbasepoint <remote revocation base point> "+
+			"--sweepaddr %s --publish", out.addr, sweepAddr)
+
+	case outputHTLC:
+		return "claimed by lnd through the second level HTLC " +
+			"transactions"
+
+	default:
+		return ""
+	}
+}
+
+// toLocalCommand returns the command to sweep our time locked to_local
+// output.
+func (e *explainedTx) toLocalCommand(out *explainedOutput,
+	sweepAddr string) string {
+
+	channel := e.channel
+	if channel.backupFile != "" {
+		return fmt.Sprintf("chantools sweeptimelockmanual "+
+			"--frombackup %s --channelpoint %v --timelockaddr %s "+
+			"--sweepaddr %s --publish", channel.backupFile,
+			channel.channel.FundingOutpoint, out.addr, sweepAddr)
+	}
+
+	remoteRevBase := channel.channel.RemoteChanCfg.RevocationBasePoint
+	return fmt.Sprintf("chantools sweeptimelockmanual --timelockaddr %s "+
+		"--remoterevbasepoint %s --sweepaddr %s --publish", out.addr,
+		hexPubKey(remoteRevBase.PubKey), sweepAddr)
+}
+
+// report returns a human readable report of the explained transaction.
+func (e *explainedTx) report(sweepAddr string) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Transaction %v: %s\n", e.tx.TxHash(), e.kind)
+	if status := e.chainData.status; status != nil {
+		if status.Confirmed {
+			fmt.Fprintf(&b, "Confirmed in block %d\n",
+				status.BlockHeight)
+		} else {
+			fmt.Fprintf(&b, "Unconfirmed\n")
+		}
+	}
+	for _, note := range e.notes {
+		fmt.Fprintf(&b, "%s\n", note)
+	}
+
+	fmt.Fprintf(&b, "\nInputs:\n")
+	for idx, in := range e.inputs {
+		value := "unknown amount"
+		if in.prevOut != nil {
+			value = btcutil.Amount(in.prevOut.Value).String()
+		}
+		fmt.Fprintf(&b, "  #%d %v (%s): %s\n", idx, in.outpoint, value,
+			in.label)
+
+		sort.Strings(in.ourKeys)
+		for _, key := range in.ourKeys {
+			fmt.Fprintf(&b, "      our %s\n", key)
+		}
+	}
+
+	fmt.Fprintf(&b, "\nOutputs:\n")
+	for _, out := range e.outputs {
+		fmt.Fprintf(&b, "  #%d %s (%v): %s\n", out.index, out.addr,
+			btcutil.Amount(out.value), out.label)
+		if out.path != "" {
+			fmt.Fprintf(&b, "      our key %s\n", out.path)
+		}
+		if claim := e.claimCommand(out, sweepAddr); claim != "" {
+			fmt.Fprintf(&b, "      claim: %s\n", claim)
+		}
+	}
+
+	return b.String()
+}
+
+// hexPubKey returns the hex encoded compressed public key.
+func hexPubKey(pubKey *btcec.PublicKey) string {
+	return hex.EncodeToString(pubKey.SerializeCompressed())
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightninglabs/chantools/btc"
+	"github.com/lightninglabs/chantools/dump"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/lightningnetwork/lnd/shachain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainTx(t *testing.T) {
+	_ = newHarness(t)
+
+	extendedKey, err := hdkeychain.NewKeyFromString(rootKeyAezeed)
+	require.NoError(t, err)
+	otherKey, err := hdkeychain.NewKeyFromString(rootKeyBip39)
+	require.NoError(t, err)
+
+	keys, err := newOwnKeyIndex(extendedKey, 5)
+	require.NoError(t, err)
+
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	otherKeyRing := &lnd.HDKeyRing{
+		ExtendedKey: otherKey,
+		ChainParams: chainParams,
+	}
+	deriveKey := func(keyRing *lnd.HDKeyRing, family keychain.KeyFamily,
+		index uint32) keychain.KeyDescriptor {
+
+		keyDesc, err := keyRing.DeriveKey(keychain.KeyLocator{
+			Family: family,
+			Index:  index,
+		})
+		require.NoError(t, err)
+
+		return keyDesc
+	}
+	pkScript := func(addr btcutil.Address) []byte {
+		script, err := txscript.PayToAddrScript(addr)
+		require.NoError(t, err)
+
+		return script
+	}
+
+	// The funding output is spent with a 2-of-2 multisig witness that
+	// contains one of our multisig keys.
+	localMultiSig := deriveKey(keyRing, keychain.KeyFamilyMultiSig, 1)
+	remoteMultiSig := deriveKey(otherKeyRing, keychain.KeyFamilyMultiSig, 1)
+	fundingScript, err := input.GenMultiSigScript(
+		localMultiSig.PubKey.SerializeCompressed(),
+		remoteMultiSig.PubKey.SerializeCompressed(),
+	)
+	require.NoError(t, err)
+	fundingOutpoint := wire.OutPoint{Hash: chainhash.Hash{1, 2, 3}}
+
+	anchorScript, err := input.CommitScriptAnchor(localMultiSig.PubKey)
+	require.NoError(t, err)
+	anchorPkScript, err := input.WitnessScriptHash(anchorScript)
+	require.NoError(t, err)
+	remoteAnchorScript, err := input.CommitScriptAnchor(
+		remoteMultiSig.PubKey,
+	)
+	require.NoError(t, err)
+	remoteAnchorPkScript, err := input.WitnessScriptHash(
+		remoteAnchorScript,
+	)
+	require.NoError(t, err)
+
+	paymentDesc := deriveKey(keyRing, keychain.KeyFamilyPaymentBase, 2)
+	toRemoteAddr, _, err := lnd.P2AnchorStaticRemote(
+		paymentDesc.PubKey, chainParams,
+	)
+	require.NoError(t, err)
+	htlcPkScript, err := input.WitnessScriptHash([]byte{txscript.OP_1})
+	require.NoError(t, err)
+
+	commitTx := wire.NewMsgTx(2)
+	commitTx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: fundingOutpoint,
+		Witness: wire.TxWitness{
+			nil, {1}, {2}, fundingScript,
+		},
+	})
+	commitTx.AddTxOut(&wire.TxOut{
+		Value: anchorOutputValue, PkScript: anchorPkScript,
+	})
+	commitTx.AddTxOut(&wire.TxOut{
+		Value: anchorOutputValue, PkScript: remoteAnchorPkScript,
+	})
+	commitTx.AddTxOut(&wire.TxOut{
+		Value: 100_000, PkScript: pkScript(toRemoteAddr),
+	})
+	commitTx.AddTxOut(&wire.TxOut{Value: 20_000, PkScript: htlcPkScript})
+
+	// Without the commitment markers, this is a cooperative close.
+	chainData := &explainChainData{
+		status: &btc.Status{Confirmed: false},
+	}
+	explanation, err := explainTx(commitTx, chainData, keys, nil)
+	require.NoError(t, err)
+	require.Equal(t, "cooperative close transaction", explanation.kind)
+	require.Len(t, explanation.inputs[0].ourKeys, 1)
+
+	// With the markers of a commitment transaction, the outputs are
+	// labeled as such. Because our to_remote output is in there, this is
+	// the remote party's commitment.
+	commitTx.LockTime = commitLockTimeMarker<<24 | 5
+	commitTx.TxIn[0].Sequence = commitSequenceMarker << 24
+	explanation, err = explainTx(commitTx, chainData, keys, nil)
+	require.NoError(t, err)
+	require.True(t, explanation.isCommitment)
+	require.True(t, explanation.remoteCommit)
+
+	outputs := explanation.outputs
+	require.Equal(t, outputAnchor, outputs[0].class)
+	require.Equal(t, outputRemoteParty, outputs[1].class)
+	require.Equal(t, outputToRemote, outputs[2].class)
+	require.EqualValues(t, 2, outputs[2].keyIdx)
+	require.Equal(t, outputRemoteParty, outputs[3].class)
+
+	require.Contains(
+		t, explanation.claimCommand(outputs[0], "fromseed"),
+		"chantools pullanchor --closetxid "+commitTx.TxHash().String(),
+	)
+	require.Contains(
+		t, explanation.claimCommand(outputs[2], "fromseed"),
+		"chantools sweepremoteclosed --startindex 2 --gaplimit 1",
+	)
+	require.Empty(t, explanation.claimCommand(outputs[3], "fromseed"))
+
+	// Our own commitment can be fully decoded with the channel data.
+	remoteRevBase := deriveKey(
+		otherKeyRing, keychain.KeyFamilyRevocationBase, 0,
+	)
+	channel := &channeldb.OpenChannel{
+		ChanType: channeldb.SingleFunderTweaklessBit |
+			channeldb.AnchorOutputsBit,
+		IsInitiator:     true,
+		FundingOutpoint: fundingOutpoint,
+		IdentityPub:     remoteMultiSig.PubKey,
+		LocalChanCfg: channeldb.ChannelConfig{
+			CommitmentParams: channeldb.CommitmentParams{
+				CsvDelay: 144,
+			},
+			MultiSigKey: localMultiSig,
+			RevocationBasePoint: deriveKey(
+				keyRing, keychain.KeyFamilyRevocationBase, 0,
+			),
+			PaymentBasePoint: deriveKey(
+				keyRing, keychain.KeyFamilyPaymentBase, 0,
+			),
+			DelayBasePoint: deriveKey(
+				keyRing, keychain.KeyFamilyDelayBase, 0,
+			),
+			HtlcBasePoint: deriveKey(
+				keyRing, keychain.KeyFamilyHtlcBase, 0,
+			),
+		},
+		RemoteChanCfg: channeldb.ChannelConfig{
+			CommitmentParams: channeldb.CommitmentParams{
+				CsvDelay: 144,
+			},
+			MultiSigKey:         remoteMultiSig,
+			RevocationBasePoint: remoteRevBase,
+			PaymentBasePoint: deriveKey(
+				otherKeyRing, keychain.KeyFamilyPaymentBase, 0,
+			),
+			DelayBasePoint: deriveKey(
+				otherKeyRing, keychain.KeyFamilyDelayBase, 0,
+			),
+			HtlcBasePoint: deriveKey(
+				otherKeyRing, keychain.KeyFamilyHtlcBase, 0,
+			),
+		},
+		RevocationProducer: shachain.NewRevocationProducer(
+			chainhash.Hash{4, 5, 6},
+		),
+	}
+	channels := map[wire.OutPoint]*explainChannel{
+		fundingOutpoint: {
+			channel:   channel,
+			channelDB: "channel.db",
+		},
+	}
+
+	const stateNum = 42
+	revPreimage, err := channel.RevocationProducer.AtIndex(stateNum)
+	require.NoError(t, err)
+	debugInfo, err := dump.CollectDebugInfo(
+		channel, input.ComputeCommitmentPoint(revPreimage[:]), true,
+		true, chainParams,
+	)
+	require.NoError(t, err)
+	toLocalAddr, err := btcutil.DecodeAddress(
+		debugInfo.ToLocalAddr, chainParams,
+	)
+	require.NoError(t, err)
+
+	ourCommitTx := wire.NewMsgTx(2)
+	ourCommitTx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: fundingOutpoint,
+		Witness:          commitTx.TxIn[0].Witness,
+	})
+	ourCommitTx.AddTxOut(&wire.TxOut{
+		Value: 200_000, PkScript: pkScript(toLocalAddr),
+	})
+	ourCommitTx.AddTxOut(&wire.TxOut{Value: 20_000, PkScript: htlcPkScript})
+	err = lnwallet.SetStateNumHint(
+		ourCommitTx, stateNum, lnwallet.DeriveStateHintObfuscator(
+			channel.LocalChanCfg.PaymentBasePoint.PubKey,
+			channel.RemoteChanCfg.PaymentBasePoint.PubKey,
+		),
+	)
+	require.NoError(t, err)
+
+	chainData.status = &btc.Status{Confirmed: true, BlockHeight: 1000}
+	explanation, err = explainTx(ourCommitTx, chainData, keys, channels)
+	require.NoError(t, err)
+	require.True(t, explanation.isCommitment)
+	require.False(t, explanation.remoteCommit)
+	require.Contains(t, explanation.notes, "Commitment number 42")
+
+	outputs = explanation.outputs
+	require.Equal(t, outputToLocal, outputs[0].class)
+	require.Equal(t, outputToLocalOrHTLC, outputs[1].class)
+	require.Equal(
+		t, "chantools sweeptimelockmanual --timelockaddr "+
+			toLocalAddr.String()+" --remoterevbasepoint "+
+			hexPubKey(remoteRevBase.PubKey)+" --sweepaddr "+
+			"fromseed --publish (spendable after block 1144)",
+		explanation.claimCommand(outputs[0], "fromseed"),
+	)
+}
@@ -0,0 +1,583 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/lightninglabs/chantools/btc"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultFindKeyNumKeys = 1000
+
+	// findKeyChunkSize is the number of indices of a branch a worker
+	// derives in one go.
+	findKeyChunkSize = 500
+
+	matchDirect         = "direct"
+	matchSingleTweaked  = "single-tweaked"
+	matchTaprootTweaked = "taproot-tweaked"
+)
+
+// lndKeyFamilies are all key families lnd derives keys from. The channel
+// flag marks the families whose keys are used in channel scripts.
+var lndKeyFamilies = []struct {
+	family  keychain.KeyFamily
+	name    string
+	channel bool
+}{
+	{keychain.KeyFamilyMultiSig, "multisig", true},
+	{keychain.KeyFamilyRevocationBase, "revocation base", true},
+	{keychain.KeyFamilyHtlcBase, "HTLC base", true},
+	{keychain.KeyFamilyPaymentBase, "payment base", true},
+	{keychain.KeyFamilyDelayBase, "delay base", true},
+	{keychain.KeyFamilyRevocationRoot, "revocation root", false},
+	{keychain.KeyFamilyNodeKey, "node", false},
+	{keychain.KeyFamilyBaseEncryption, "base encryption", false},
+	{keychain.KeyFamilyTowerSession, "tower session", false},
+	{keychain.KeyFamilyTowerID, "tower ID", false},
+}
+
+type findKeyCommand struct {
+	Targets      []string
+	CommitPoints []string
+	NumKeys      uint32
+	Workers      uint32
+
+	rootKey *rootKey
+	cmd     *cobra.Command
+}
+
+func newFindKeyCommand() *cobra.Command {
+	cc := &findKeyCommand{}
+	cc.cmd = &cobra.Command{
+		Use: "findkey",
+		Short: "Find out if an address or key belongs to the seed " +
+			"and how it was derived",
+		Long: `Searches all lnd key families
+(m/1017'/coin'/family'/0/index) and the BIP49, BIP84 and BIP86 on-chain wallet
+branches up to the given number of keys for the given targets.
+
+A target can be an address, a public key, a script hash (20 or 32 bytes), a
+taproot output key (32 bytes x-only) or an output script, all hex encoded except
+for the address.
+
+For every match the derivation path and the type of match are reported:
+- direct: the key itself, its hash or a script that contains the untweaked key
+  (for example the anchor or to_remote output script of a channel).
+- single-tweaked: the key tweaked with one of the commitment points given with
+  --commitpoint, as used for the to_local, HTLC and legacy to_remote keys of
+  channel commitments.
+- taproot-tweaked: the taproot output key of the BIP86 key spend path or of one
+  of the taproot channel scripts (to_remote and anchor outputs) of the key.
+
+The derivation is split across all CPU cores.`,
+		Example: `chantools findkey \
+	--target bc1q..... \
+	--target 03xxxxxxx \
+	--numkeys 5000
+
+chantools findkey \
+	--target bc1q..... \
+	--commitpoint 02xxxxxxx`,
+		RunE: cc.Execute,
+	}
+	cc.cmd.Flags().StringArrayVar(
+		&cc.Targets, "target", nil, "address, public key, script "+
+			"hash, taproot output key or output script to look "+
+			"for; can be specified multiple times",
+	)
+	cc.cmd.Flags().StringArrayVar(
+		&cc.CommitPoints, "commitpoint", nil, "commitment point to "+
+			"tweak the keys of the channel related key families "+
+			"with; can be specified multiple times",
+	)
+	cc.cmd.Flags().Uint32Var(
+		&cc.NumKeys, "numkeys", defaultFindKeyNumKeys, "number of "+
+			"keys to derive per key family and wallet branch",
+	)
+	cc.cmd.Flags().Uint32Var(
+		&cc.Workers, "workers", 0, "number of parallel workers to "+
+			"derive keys with; defaults to the number of CPUs",
+	)
+
+	cc.rootKey = newRootKey(cc.cmd, "deriving the keys")
+
+	return cc.cmd
+}
+
+func (c *findKeyCommand) Execute(_ *cobra.Command, _ []string) error {
+	extendedKey, err := c.rootKey.read()
+	if err != nil {
+		return fmt.Errorf("error reading root key: %w", err)
+	}
+
+	if len(c.Targets) == 0 {
+		return errors.New("at least one target must be specified")
+	}
+	if c.NumKeys == 0 {
+		return errors.New("--numkeys must be greater than zero")
+	}
+	if c.Workers == 0 {
+		c.Workers = uint32(runtime.NumCPU())
+	}
+
+	targets := make([]*findKeyTarget, len(c.Targets))
+	for idx, target := range c.Targets {
+		targets[idx], err = parseFindKeyTarget(target)
+		if err != nil {
+			return err
+		}
+	}
+
+	commitPoints := make([]*btcec.PublicKey, len(c.CommitPoints))
+	for idx, commitPoint := range c.CommitPoints {
+		commitPoints[idx], err = pubKeyFromHex(commitPoint)
+		if err != nil {
+			return fmt.Errorf("error parsing commit point: %w", err)
+		}
+	}
+
+	log.Infof("Searching %d keys per branch on %d workers", c.NumKeys,
+		c.Workers)
+	matches, err := findKeys(
+		extendedKey, targets, commitPoints, c.NumKeys, c.Workers,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		var targetMatches []*findKeyMatch
+		for _, match := range matches {
+			if match.target == target {
+				targetMatches = append(targetMatches, match)
+			}
+		}
+
+		if len(targetMatches) == 0 {
+			fmt.Printf("%s: not found\n", target.input)
+			continue
+		}
+
+		fmt.Printf("%s:\n", target.input)
+		for _, match := range targetMatches {
+			fmt.Printf("  %s\n", match)
+		}
+	}
+
+	return nil
+}
+
+// findKeyTarget is a key, hash or script the user is looking for.
+type findKeyTarget struct {
+	input  string
+	needle []byte
+}
+
+// parseFindKeyTarget parses an address, a hex encoded public key, hash,
+// taproot output key or output script.
+func parseFindKeyTarget(target string) (*findKeyTarget, error) {
+	target = strings.TrimSpace(target)
+
+	raw, err := hex.DecodeString(target)
+	if err == nil {
+		switch {
+		case len(raw) == btcec.PubKeyBytesLenCompressed:
+			if _, err := btcec.ParsePubKey(raw); err != nil {
+				return nil, fmt.Errorf("invalid public key "+
+					"%s: %w", target, err)
+			}
+
+			return &findKeyTarget{input: target, needle: raw}, nil
+
+		case len(raw) == 20 || len(raw) == 32:
+			return &findKeyTarget{input: target, needle: raw}, nil
+		}
+
+		// Anything else we can only understand as an output script.
+		_, addrs, _, err := txscript.ExtractPkScriptAddrs(
+			raw, chainParams,
+		)
+		if err != nil || len(addrs) != 1 {
+			return nil, fmt.Errorf("unsupported target %s", target)
+		}
+
+		return &findKeyTarget{
+			input:  target,
+			needle: addrs[0].ScriptAddress(),
+		}, nil
+	}
+
+	addr, err := btcutil.DecodeAddress(target, chainParams)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported target %s: %w", target,
+			err)
+	}
+	if !addr.IsForNet(chainParams) {
+		return nil, fmt.Errorf("address %s is not valid for network %s",
+			target, chainParams.Name)
+	}
+
+	// The script address is the key hash, the script hash or the
+	// taproot output key, depending on the address type.
+	return &findKeyTarget{
+		input:  target,
+		needle: addr.ScriptAddress(),
+	}, nil
+}
+
+// findKeyMatch is a key of the seed that matches a target.
+type findKeyMatch struct {
+	target *findKeyTarget
+	branch *findKeyBranch
+	path   string
+	index  uint32
+	kind   string
+	desc   string
+}
+
+// String returns a human readable description of the match.
+func (m *findKeyMatch) String() string {
+	return fmt.Sprintf("%s (%s key), %s: %s", m.path, m.branch.name,
+		m.kind, m.desc)
+}
+
+// findKeyBranch is a branch of keys that is searched.
+type findKeyBranch struct {
+	order   int
+	name    string
+	family  keychain.KeyFamily
+	channel bool
+	key     *hdkeychain.ExtendedKey
+	path    func(index uint32) string
+}
+
+// findKeyBranches derives the public extended keys of all branches that are
+// searched.
+func findKeyBranches(
+	extendedKey *hdkeychain.ExtendedKey) ([]*findKeyBranch, error) {
+
+	var branches []*findKeyBranch
+	for _, family := range lndKeyFamilies {
+		branchKey, err := lnd.DeriveChildren(extendedKey, []uint32{
+			lnd.HardenedKey(uint32(keychain.BIP0043Purpose)),
+			lnd.HardenedKey(chainParams.HDCoinType),
+			lnd.HardenedKey(uint32(family.family)),
+			0,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not derive %s branch: "+
+				"%w", family.name, err)
+		}
+
+		branches = append(branches, &findKeyBranch{
+			name:    family.name,
+			family:  family.family,
+			channel: family.channel,
+			key:     branchKey,
+			path: func(index uint32) string {
+				return fmt.Sprintf(
+					lnd.LndDerivationPath+"/0/%d",
+					chainParams.HDCoinType, family.family,
+					index,
+				)
+			},
+		})
+	}
+
+	for _, account := range btc.DefaultWalletAccounts {
+		for _, branch := range account.Branches {
+			path := account.Path(chainParams, branch, 0)
+			branchKey, err := lnd.DeriveChildren(
+				extendedKey, path[:len(path)-1],
+			)
+			if err != nil {
+				return nil, fmt.Errorf("could not derive "+
+					"wallet branch: %w", err)
+			}
+
+			branches = append(branches, &findKeyBranch{
+				name: fmt.Sprintf("%s wallet", account.Name),
+				key:  branchKey,
+				path: func(index uint32) string {
+					addr := &btc.WalletAddress{
+						Branch: branch,
+						Index:  index,
+						Path: account.Path(
+							chainParams, branch,
+							index,
+						),
+					}
+
+					return addr.PathString()
+				},
+			})
+		}
+	}
+
+	// We only need the public keys, so we can use the much faster public
+	// derivation for the individual children.
+	for idx, branch := range branches {
+		var err error
+		branch.order = idx
+		branch.key, err = branch.key.Neuter()
+		if err != nil {
+			return nil, fmt.Errorf("error neutering key: %w", err)
+		}
+	}
+
+	return branches, nil
+}
+
+// findKeys searches the first numKeys keys of all branches for the given
+// targets. The derivation is split into chunks that are processed by the
+// given number of workers in parallel.
+func findKeys(extendedKey *hdkeychain.ExtendedKey, targets []*findKeyTarget,
+	commitPoints []*btcec.PublicKey, numKeys,
+	numWorkers uint32) ([]*findKeyMatch, error) {
+
+	branches, err := findKeyBranches(extendedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	needles := make(map[string][]*findKeyTarget, len(targets))
+	for _, target := range targets {
+		needles[string(target.needle)] = append(
+			needles[string(target.needle)], target,
+		)
+	}
+
+	type chunk struct {
+		branch     *findKeyBranch
+		start, end uint32
+	}
+	chunks := make(chan chunk)
+	go func() {
+		defer close(chunks)
+
+		for _, branch := range branches {
+			for start := uint32(0); start < numKeys; {
+				end := min(start+findKeyChunkSize, numKeys)
+				chunks <- chunk{
+					branch: branch,
+					start:  start,
+					end:    end,
+				}
+				start = end
+			}
+		}
+	}()
+
+	var (
+		matches []*findKeyMatch
+		mtx     sync.Mutex
+		wg      sync.WaitGroup
+		errChan = make(chan error, numWorkers)
+	)
+	for range numWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for chunk := range chunks {
+				found, err := findKeysInRange(
+					chunk.branch, chunk.start, chunk.end,
+					needles, commitPoints,
+				)
+				if err != nil {
+					errChan <- err
+
+					// Drain the remaining chunks so the
+					// producer doesn't block.
+					for range chunks {
+					}
+					return
+				}
+
+				mtx.Lock()
+				matches = append(matches, found...)
+				mtx.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case err := <-errChan:
+		return nil, err
+	default:
+	}
+
+	// The workers finish in random order, so we sort the matches by
+	// branch and index to get a stable result.
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].branch.order != matches[j].branch.order {
+			return matches[i].branch.order < matches[j].branch.order
+		}
+		if matches[i].index != matches[j].index {
+			return matches[i].index < matches[j].index
+		}
+
+		return matches[i].desc < matches[j].desc
+	})
+
+	return matches, nil
+}
+
+// findKeysInRange searches the keys of the branch from the start index up to
+// but excluding the end index.
+func findKeysInRange(branch *findKeyBranch, start, end uint32,
+	needles map[string][]*findKeyTarget,
+	commitPoints []*btcec.PublicKey) ([]*findKeyMatch, error) {
+
+	var matches []*findKeyMatch
+	for index := start; index < end; index++ {
+		found, err := findKeyAtIndex(
+			branch, index, needles, commitPoints,
+		)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, found...)
+	}
+
+	return matches, nil
+}
+
+// findKeyAtIndex derives the key at the given index of the branch and checks
+// all representations of it against the needles.
+func findKeyAtIndex(branch *findKeyBranch, index uint32,
+	needles map[string][]*findKeyTarget,
+	commitPoints []*btcec.PublicKey) ([]*findKeyMatch, error) {
+
+	key, err := branch.key.Derive(index)
+	if err != nil {
+		return nil, fmt.Errorf("error deriving child key: %w", err)
+	}
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		return nil, fmt.Errorf("error deriving public key: %w", err)
+	}
+
+	var matches []*findKeyMatch
+	check := func(data []byte, kind, desc string) {
+		for _, target := range needles[string(data)] {
+			matches = append(matches, &findKeyMatch{
+				target: target,
+				branch: branch,
+				path:   branch.path(index),
+				index:  index,
+				kind:   kind,
+				desc:   desc,
+			})
+		}
+	}
+	checkKey := func(pubKey *btcec.PublicKey, kind, prefix string) error {
+		compressed := pubKey.SerializeCompressed()
+		keyHash := btcutil.Hash160(compressed)
+		check(compressed, kind, prefix+"public key")
+		check(schnorr.SerializePubKey(pubKey), kind,
+			prefix+"x-only public key")
+		check(keyHash, kind, prefix+"key hash (P2PKH or P2WKH)")
+
+		witnessProgram, err := txscript.NewScriptBuilder().
+			AddOp(txscript.OP_0).AddData(keyHash).Script()
+		if err != nil {
+			return fmt.Errorf("error creating script: %w", err)
+		}
+		check(
+			btcutil.Hash160(witnessProgram), kind,
+			prefix+"NP2WKH script hash",
+		)
+
+		return nil
+	}
+
+	if err := checkKey(pubKey, matchDirect, ""); err != nil {
+		return nil, err
+	}
+	check(
+		schnorr.SerializePubKey(txscript.ComputeTaprootKeyNoScript(
+			pubKey,
+		)), matchTaprootTweaked, "BIP86 taproot output key",
+	)
+
+	if !branch.channel {
+		return matches, nil
+	}
+
+	for _, commitPoint := range commitPoints {
+		err := checkKey(
+			input.TweakPubKey(pubKey, commitPoint),
+			matchSingleTweaked, fmt.Sprintf("with commit point %x "+
+				"tweaked ", commitPoint.SerializeCompressed()),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch branch.family {
+	case keychain.KeyFamilyMultiSig:
+		script, err := input.CommitScriptAnchor(pubKey)
+		if err != nil {
+			return nil, fmt.Errorf("error deriving anchor script: "+
+				"%w", err)
+		}
+		scriptHash := sha256.Sum256(script)
+		check(scriptHash[:], matchDirect, "anchor output script hash")
+
+	case keychain.KeyFamilyPaymentBase:
+		_, script, err := lnd.P2AnchorStaticRemote(pubKey, chainParams)
+		if err != nil {
+			return nil, err
+		}
+		scriptHash := sha256.Sum256(script)
+		check(
+			scriptHash[:], matchDirect, "to_remote output script "+
+				"hash (anchor channel)",
+		)
+
+		_, scriptTree, err := lnd.P2TaprootStaticRemote(
+			pubKey, chainParams,
+		)
+		if err != nil {
+			return nil, err
+		}
+		check(
+			schnorr.SerializePubKey(scriptTree.TaprootKey),
+			matchTaprootTweaked, "to_remote output key (simple "+
+				"taproot channel)",
+		)
+
+		anchorTree, err := input.NewAnchorScriptTree(pubKey)
+		if err != nil {
+			return nil, fmt.Errorf("error deriving taproot "+
+				"anchor: %w", err)
+		}
+		check(
+			schnorr.SerializePubKey(anchorTree.TaprootKey),
+			matchTaprootTweaked, "anchor output key (simple "+
+				"taproot channel)",
+		)
+	}
+
+	return matches, nil
+}
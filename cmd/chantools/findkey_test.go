@@ -0,0 +1,130 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/lightninglabs/chantools/btc"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindKeys(t *testing.T) {
+	_ = newHarness(t)
+
+	extendedKey, err := hdkeychain.NewKeyFromString(rootKeyAezeed)
+	require.NoError(t, err)
+	otherKey, err := hdkeychain.NewKeyFromString(rootKeyBip39)
+	require.NoError(t, err)
+
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	deriveKey := func(family keychain.KeyFamily,
+		index uint32) keychain.KeyDescriptor {
+
+		keyDesc, err := keyRing.DeriveKey(keychain.KeyLocator{
+			Family: family,
+			Index:  index,
+		})
+		require.NoError(t, err)
+
+		return keyDesc
+	}
+	parseTarget := func(target string) *findKeyTarget {
+		parsed, err := parseFindKeyTarget(target)
+		require.NoError(t, err)
+
+		return parsed
+	}
+
+	// A taproot address of the internal branch of the on-chain wallet.
+	walletPath := btc.WalletAccountP2TR.Path(chainParams, 1, 7)
+	walletKey, err := lnd.PrivKeyFromPath(extendedKey, walletPath)
+	require.NoError(t, err)
+	walletAddr, err := lnd.P2TRAddr(walletKey.PubKey(), chainParams)
+	require.NoError(t, err)
+	walletTarget := parseTarget(walletAddr.String())
+
+	// The node key by its public key.
+	nodeKey := deriveKey(keychain.KeyFamilyNodeKey, 0)
+	nodeTarget := parseTarget(hex.EncodeToString(
+		nodeKey.PubKey.SerializeCompressed(),
+	))
+
+	// The anchor output of a channel by its script hash.
+	multiSigKey := deriveKey(keychain.KeyFamilyMultiSig, 2)
+	anchorScript, err := input.CommitScriptAnchor(multiSigKey.PubKey)
+	require.NoError(t, err)
+	anchorHash := sha256.Sum256(anchorScript)
+	anchorTarget := parseTarget(hex.EncodeToString(anchorHash[:]))
+
+	// The to_remote output key of a simple taproot channel.
+	paymentKey := deriveKey(keychain.KeyFamilyPaymentBase, 3)
+	_, scriptTree, err := lnd.P2TaprootStaticRemote(
+		paymentKey.PubKey, chainParams,
+	)
+	require.NoError(t, err)
+	toRemoteTarget := parseTarget(hex.EncodeToString(
+		schnorr.SerializePubKey(scriptTree.TaprootKey),
+	))
+
+	// A to_local key tweaked with a commitment point, as output script.
+	commitPoint, err := otherKey.ECPubKey()
+	require.NoError(t, err)
+	delayKey := deriveKey(keychain.KeyFamilyDelayBase, 4)
+	tweakedAddr, err := lnd.P2WKHAddr(
+		input.TweakPubKey(delayKey.PubKey, commitPoint), chainParams,
+	)
+	require.NoError(t, err)
+	tweakedScript, err := txscript.PayToAddrScript(tweakedAddr)
+	require.NoError(t, err)
+	tweakedTarget := parseTarget(hex.EncodeToString(tweakedScript))
+
+	// A key that isn't derived from the seed.
+	unknownTarget := parseTarget(hex.EncodeToString(
+		commitPoint.SerializeCompressed(),
+	))
+
+	targets := []*findKeyTarget{
+		walletTarget, nodeTarget, anchorTarget, toRemoteTarget,
+		tweakedTarget, unknownTarget,
+	}
+	matches, err := findKeys(
+		extendedKey, targets, []*btcec.PublicKey{commitPoint}, 10, 3,
+	)
+	require.NoError(t, err)
+
+	byTarget := make(map[*findKeyTarget][]*findKeyMatch)
+	for _, match := range matches {
+		byTarget[match.target] = append(byTarget[match.target], match)
+	}
+	require.Len(t, byTarget, 5)
+
+	expectMatch := func(target *findKeyTarget, path, kind string) {
+		t.Helper()
+
+		require.Len(t, byTarget[target], 1)
+		require.Equal(t, path, byTarget[target][0].path)
+		require.Equal(t, kind, byTarget[target][0].kind)
+	}
+	expectMatch(walletTarget, "m/86'/1'/0'/1/7", matchTaprootTweaked)
+	expectMatch(nodeTarget, "m/1017'/1'/6'/0/0", matchDirect)
+	expectMatch(anchorTarget, "m/1017'/1'/0'/0/2", matchDirect)
+	expectMatch(toRemoteTarget, "m/1017'/1'/3'/0/3", matchTaprootTweaked)
+	expectMatch(tweakedTarget, "m/1017'/1'/4'/0/4", matchSingleTweaked)
+
+	// Invalid targets are rejected.
+	_, err = parseFindKeyTarget("02abcd")
+	require.Error(t, err)
+	_, err = parseFindKeyTarget("bc1qnotanaddress")
+	require.Error(t, err)
+}
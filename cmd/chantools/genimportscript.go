@@ -3,11 +3,14 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"time"
 
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
 	"github.com/lightninglabs/chantools/btc"
 	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/keychain"
 	"github.com/spf13/cobra"
 )
 
@@ -17,6 +20,7 @@ const (
 )
 
 type genImportScriptCommand struct {
+	APIURL         string
 	Format         string
 	LndPaths       bool
 	DerivationPath string
@@ -57,17 +61,44 @@ The following script formats are currently supported:
   that can be used in combination with a bitcoind full node that has a
   descriptor wallet to recover the funds locked in those private keys.
   NOTE: This will only work for descriptor wallets and only for
-  p2sh-segwit, bech32 and bech32m (np2wkh, p2wkh and p2tr) addresses.`,
+  p2sh-segwit, bech32 and bech32m (np2wkh, p2wkh and p2tr) addresses.
+* bitcoin-descriptors-ranged: Creates a single bitcoin-cli importdescriptors
+  command with one ranged descriptor per account and branch of lnd's on-chain
+  wallet (np2wkh, p2wkh and p2tr) instead of one descriptor per key. The
+  wallet can then derive new addresses itself. With --lndpaths, descriptors
+  for all of lnd's internal key families are added as well. The import
+  rescans the chain from the wallet birthday by itself.
+* electrum-wallet: Creates an Electrum wallet file with the native SegWit
+  (p2wkh) account of lnd's on-chain wallet as its keystore. The file can be
+  opened in Electrum directly and the wallet can derive new addresses.
+* sparrow: Creates one output descriptor for each account of lnd's on-chain
+  wallet, including the BIP32 fingerprint and derivation path, that can each
+  be imported into Sparrow as a wallet.
+
+If an lnd 24 word aezeed is used, the block height of the wallet birthday is
+looked up through the API and added to the end of the output as the height to
+rescan from. If the API doesn't support that lookup, the height is estimated
+from the birthday instead. Mainnet, testnet, testnet4 and signet are
+supported. The default API only serves mainnet and testnet, so on signet the
+height is always estimated unless a signet --apiurl is specified.`,
 		Example: `chantools genimportscript --format bitcoin-cli \
 	--recoverywindow 5000`,
 		RunE: cc.Execute,
 	}
+	cc.cmd.Flags().StringVar(
+		&cc.APIURL, "apiurl", defaultAPIURL, "API URL to use for "+
+			"looking up the block height of the wallet birthday "+
+			"(must be esplora compatible and support the "+
+			"block-by-timestamp lookup, the height is estimated "+
+			"otherwise)",
+	)
 	cc.cmd.Flags().StringVar(
 		&cc.Format, "format", "bitcoin-importwallet", "format of the "+
 			"generated import script; currently supported are: "+
 			"bitcoin-importwallet, bitcoin-cli, "+
-			"bitcoin-cli-watchonly, bitcoin-descriptors and "+
-			"electrum",
+			"bitcoin-cli-watchonly, bitcoin-descriptors, "+
+			"bitcoin-descriptors-ranged, electrum, "+
+			"electrum-wallet and sparrow",
 	)
 	cc.cmd.Flags().BoolVar(
 		&cc.LndPaths, "lndpaths", false, "use all derivation paths "+
@@ -111,12 +142,19 @@ func (c *genImportScriptCommand) Execute(_ *cobra.Command, _ []string) error {
 		return fmt.Errorf("error reading root key: %w", err)
 	}
 
-	// The btcwallet gives the birthday a slack of 48 hours, let's do the
-	// same.
-	if !birthday.IsZero() {
-		c.RescanFrom = btc.SeedBirthdayToBlock(
-			chainParams, birthday.Add(-48*time.Hour),
-		)
+	// Only the aezeed contains a birthday, for all other root keys it is
+	// unknown. The btcwallet gives the birthday a slack of 48 hours, let's
+	// do the same.
+	if birthday.Unix() > 0 {
+		birthday = birthday.Add(-48 * time.Hour)
+	} else {
+		birthday = time.Time{}
+	}
+	if !birthday.IsZero() && !c.cmd.Flags().Changed("rescanfrom") {
+		c.RescanFrom, err = birthdayToBlock(c.APIURL, birthday)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Set default values.
@@ -127,6 +165,20 @@ func (c *genImportScriptCommand) Execute(_ *cobra.Command, _ []string) error {
 		c.RescanFrom = defaultRescanFrom
 	}
 
+	// Some formats export whole accounts of lnd's wallet instead of
+	// individual keys.
+	accountFormat := false
+	switch c.Format {
+	case btc.FormatDescriptorsRanged, btc.FormatElectrumWallet,
+		btc.FormatSparrow:
+
+		accountFormat = true
+	}
+	if accountFormat && c.DerivationPath != "" {
+		return fmt.Errorf("cannot use --derivationpath with the %s "+
+			"format", c.Format)
+	}
+
 	// Decide what derivation path(s) to use.
 	switch {
 	default:
@@ -158,8 +210,12 @@ func (c *genImportScriptCommand) Execute(_ *cobra.Command, _ []string) error {
 
 	writer := os.Stdout
 	if !c.Stdout {
-		fileName := fmt.Sprintf("results/genimportscript-%s.txt",
-			time.Now().Format("2006-01-02-15-04-05"))
+		extension := "txt"
+		if c.Format == btc.FormatElectrumWallet {
+			extension = "json"
+		}
+		fileName := fmt.Sprintf("results/genimportscript-%s.%s",
+			time.Now().Format("2006-01-02-15-04-05"), extension)
 		log.Infof("Writing import script with format '%s' to %s",
 			c.Format, fileName)
 
@@ -171,6 +227,10 @@ func (c *genImportScriptCommand) Execute(_ *cobra.Command, _ []string) error {
 		}
 	}
 
+	if accountFormat {
+		return c.exportAccounts(extendedKey, birthday, writer)
+	}
+
 	exporter, err := btc.ParseFormat(c.Format)
 	if err != nil {
 		return fmt.Errorf("error parsing format: %w", err)
@@ -186,3 +246,71 @@ func (c *genImportScriptCommand) Execute(_ *cobra.Command, _ []string) error {
 
 	return nil
 }
+
+// exportAccounts exports whole accounts of lnd's wallet in one of the account
+// level formats.
+func (c *genImportScriptCommand) exportAccounts(
+	extendedKey *hdkeychain.ExtendedKey, birthday time.Time,
+	writer io.Writer) error {
+
+	var err error
+	switch c.Format {
+	case btc.FormatDescriptorsRanged:
+		var families []keychain.KeyFamily
+		if c.LndPaths {
+			for _, family := range lndKeyFamilies {
+				families = append(families, family.family)
+			}
+		}
+
+		err = btc.ExportRangedDescriptors(
+			extendedKey, chainParams, families, c.RecoveryWindow,
+			birthday, c.RescanFrom, writer,
+		)
+
+	case btc.FormatElectrumWallet:
+		err = btc.ExportElectrumWallet(
+			extendedKey, chainParams, c.RecoveryWindow, writer,
+		)
+
+	case btc.FormatSparrow:
+		err = btc.ExportSparrowDescriptors(
+			extendedKey, chainParams, c.RescanFrom, writer,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("error exporting wallet: %w", err)
+	}
+
+	return nil
+}
+
+// birthdayToBlock returns the height of the block that was mined at the given
+// wallet birthday. The block is looked up by its timestamp if the API serves
+// the selected network and supports the lookup, otherwise its height is
+// estimated.
+func birthdayToBlock(apiURL string, birthday time.Time) (uint32, error) {
+	// The default API answers with the block of the main chain for any
+	// network it doesn't know about, so we can't use it for those.
+	if explorerAPIServesNetwork(apiURL) {
+		api := newExplorerAPI(apiURL)
+		height, err := api.BlockHeightByTimestamp(birthday)
+		if err == nil {
+			log.Infof("Wallet birthday %v is at block %d", birthday,
+				height)
+			return height, nil
+		}
+
+		log.Warnf("Could not look up block of wallet birthday, "+
+			"estimating the height instead: %v", err)
+	}
+
+	height, err := btc.SeedBirthdayToBlock(chainParams, birthday)
+	if err != nil {
+		return 0, fmt.Errorf("error estimating birthday block: %w", err)
+	}
+	log.Infof("Wallet birthday %v is at estimated block %d", birthday,
+		height)
+
+	return height, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/lightninglabs/chantools/btc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBirthdayToBlock(t *testing.T) {
+	_ = newHarness(t)
+
+	var numRequests int
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, _ *http.Request) {
+			numRequests++
+			err := json.NewEncoder(w).Encode(map[string]any{
+				"height": 123_456,
+				"hash":   "00000000000000000001",
+			})
+			require.NoError(t, err)
+		},
+	))
+	defer server.Close()
+
+	oldParams := chainParams
+	chainParams = &chaincfg.SigNetParams
+	defer func() {
+		chainParams = oldParams
+	}()
+
+	birthday := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	estimate, err := btc.SeedBirthdayToBlock(chainParams, birthday)
+	require.NoError(t, err)
+
+	// The default API only knows the main chain on signet, so the height
+	// must be estimated without asking it.
+	require.False(t, explorerAPIServesNetwork(defaultAPIURL))
+	height, err := birthdayToBlock(defaultAPIURL, birthday)
+	require.NoError(t, err)
+	require.Equal(t, estimate, height)
+
+	// An explicitly specified API is asked for the block.
+	height, err = birthdayToBlock(server.URL, birthday)
+	require.NoError(t, err)
+	require.EqualValues(t, 123_456, height)
+	require.Equal(t, 1, numRequests)
+
+	// On mainnet, the default API is used.
+	chainParams = &chaincfg.MainNetParams
+	require.True(t, explorerAPIServesNetwork(defaultAPIURL))
+}
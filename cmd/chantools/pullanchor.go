@@ -5,8 +5,11 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"math"
+	"slices"
+	"sort"
+	"strings"
 
+	"github.com/btcsuite/btcd/blockchain"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
 	"github.com/btcsuite/btcd/btcutil/psbt"
@@ -14,20 +17,40 @@ import (
 	"github.com/btcsuite/btcd/mempool"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcwallet/wallet"
 	"github.com/lightninglabs/chantools/btc"
 	"github.com/lightninglabs/chantools/lnd"
 	"github.com/lightningnetwork/lnd/input"
 	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/lntypes"
 	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
 	"github.com/spf13/cobra"
 )
 
+const (
+	anchorOutputValue = 330
+
+	// defaultAnchorNumKeys is the default number of keys per key family
+	// that are derived when looking for the keys of anchor outputs.
+	defaultAnchorNumKeys = 2500
+
+	// trucChildMaxVSize is the maximum virtual size of a TRUC (v3)
+	// transaction that spends an unconfirmed TRUC parent.
+	trucChildMaxVSize = 1000
+)
+
 type pullAnchorCommand struct {
 	APIURL       string
 	SponsorInput string
 	AnchorAddrs  []string
+	CloseTXIDs   []string
+	CloseTXs     []string
+	FromSummary  string
+	GapLimit     uint32
+	NumKeys      uint32
 	ChangeAddr   string
 	FeeRate      uint32
+	Publish      bool
 
 	rootKey *rootKey
 	cmd     *cobra.Command
@@ -40,12 +63,57 @@ func newPullAnchorCommand() *cobra.Command {
 		Short: "Attempt to CPFP an anchor output of a channel",
 		Long: `Use this command to confirm a channel force close
 transaction of an anchor output channel type. This will attempt to CPFP the
-330 byte anchor output created for your node.`,
+330 byte anchor output created for your node.
+
+Instead of specifying the anchor addresses manually, the --closetxid flag can be
+used to specify the force close (commitment) transaction(s) directly. The tool
+then fetches the transaction(s), identifies the anchor output that belongs to
+the node by scanning the keys derived from the seed and pulls all anchors found
+in a single transaction. The --fromsummary flag can be used to read the
+transaction IDs of all force closed channels from a summary file instead.
+
+If no suitable sponsor input owned by the lnd wallet can be found manually,
+specify --sponsorinput=fromseed to let the tool scan the on-chain wallet
+addresses (NP2WKH, P2WKH and P2TR, external and internal) derived from the seed
+up to the given gap limit. Confirmed UTXOs are then selected to pay for the
+fee and the fully signed transaction is printed. The change is sent to a fresh
+internal address of the wallet unless --changeaddr is specified.
+
+If a force close transaction is not yet confirmed, the CPFP transaction is
+created as a package together with it: The fee of the CPFP transaction is then
+chosen so that the whole package (close transaction(s) and CPFP transaction)
+reaches the given fee rate. If the close transaction pays less than the minimum
+mempool fee, it might not be known to the block explorer at all. In that case
+the raw close transaction can be given with the --closetx flag instead. If the
+close transaction is a TRUC (version 3) transaction, the CPFP transaction is
+created as a TRUC transaction as well.
+Packages need to be submitted with bitcoind's submitpackage RPC, the tool prints
+the corresponding bitcoin-cli command. If the sponsor inputs are selected from
+the seed, the --publish flag can be used to submit the transaction (or package)
+to the API directly.`,
 		Example: `chantools pullanchor \
 	--sponsorinput txid:vout \
 	--anchoraddr bc1q..... \
 	--changeaddr bc1q..... \
-	--feerate 30`,
+	--feerate 30
+
+chantools pullanchor \
+	--sponsorinput txid:vout \
+	--closetxid abcd..... \
+	--closetxid ef01..... \
+	--changeaddr bc1q..... \
+	--feerate 30
+
+chantools pullanchor \
+	--sponsorinput fromseed \
+	--closetxid abcd..... \
+	--feerate 30
+
+chantools pullanchor \
+	--sponsorinput fromseed \
+	--closetx 0200000001..... \
+	--feerate 10 \
+	--publish`,
 		RunE: cc.Execute,
 	}
 	cc.cmd.Flags().StringVar(
@@ -55,7 +123,16 @@ transaction of an anchor output channel type. This will attempt to CPFP the
 	cc.cmd.Flags().StringVar(
 		&cc.SponsorInput, "sponsorinput", "", "the input to use to "+
 			"sponsor the CPFP transaction; must be owned by the "+
-			"lnd node that owns the anchor output",
+			"lnd node that owns the anchor output; specify '"+
+			lnd.AddressDeriveFromWallet+"' to automatically "+
+			"select confirmed UTXOs of the on-chain wallet "+
+			"derived from the seed",
+	)
+	cc.cmd.Flags().Uint32Var(
+		&cc.GapLimit, "gaplimit", btc.DefaultGapLimit, "number of "+
+			"consecutive unused addresses after which the wallet "+
+			"scan stops when selecting sponsor inputs from the "+
+			"seed",
 	)
 	cc.cmd.Flags().StringArrayVar(
 		&cc.AnchorAddrs, "anchoraddr", nil, "the address of the "+
@@ -64,15 +141,51 @@ transaction of an anchor output channel type. This will attempt to CPFP the
 			"multiple times per command to pull multiple anchors "+
 			"with a single transaction",
 	)
+	cc.cmd.Flags().StringArrayVar(
+		&cc.CloseTXIDs, "closetxid", nil, "the transaction ID of a "+
+			"force close (commitment) transaction of which the "+
+			"anchor output belonging to the node should be "+
+			"pulled; the anchor is detected automatically; can "+
+			"be specified multiple times",
+	)
+	cc.cmd.Flags().StringArrayVar(
+		&cc.CloseTXs, "closetx", nil, "the raw hex encoded force "+
+			"close (commitment) transaction of which the anchor "+
+			"output belonging to the node should be pulled; use "+
+			"this instead of --closetxid if the transaction is "+
+			"not known to the API because its fee is too low; "+
+			"can be specified multiple times",
+	)
+	cc.cmd.Flags().StringVar(
+		&cc.FromSummary, "fromsummary", "", "read the transaction "+
+			"IDs of all force closed channels from a chantool's "+
+			"channel summary file and pull their anchors; "+
+			"specify '-' to read from stdin",
+	)
+	cc.cmd.Flags().Uint32Var(
+		&cc.NumKeys, "numkeys", defaultAnchorNumKeys, "number of "+
+			"multisig and payment base keys to derive when "+
+			"looking for the keys of the anchor outputs",
+	)
 	cc.cmd.Flags().StringVar(
 		&cc.ChangeAddr, "changeaddr", "", "the change address to "+
 			"send the remaining funds back to; specify '"+
 			lnd.AddressDeriveFromWallet+"' to derive a new "+
-			"address from the seed automatically",
+			"address from the seed automatically; can be left "+
+			"empty when selecting the sponsor inputs from the "+
+			"seed, in which case a fresh change address of the "+
+			"wallet is used",
 	)
 	cc.cmd.Flags().Uint32Var(
 		&cc.FeeRate, "feerate", defaultFeeSatPerVByte, "fee rate to "+
-			"use for the sweep transaction in sat/vByte",
+			"use for the sweep transaction in sat/vByte; if the "+
+			"close transaction is unconfirmed, this is the fee "+
+			"rate of the whole package",
+	)
+	cc.cmd.Flags().BoolVar(
+		&cc.Publish, "publish", false, "publish the transaction (or "+
+			"package) to the API; only possible if the sponsor "+
+			"inputs are selected from the seed",
 	)
 
 	cc.rootKey = newRootKey(cc.cmd, "deriving keys")
@@ -90,8 +203,38 @@ func (c *pullAnchorCommand) Execute(_ *cobra.Command, _ []string) error {
 	if c.SponsorInput == "" {
 		return errors.New("sponsor input is required")
 	}
-	if len(c.AnchorAddrs) == 0 {
-		return errors.New("at least one anchor addr is required")
+	if c.NumKeys == 0 {
+		return errors.New("--numkeys must be greater than zero")
+	}
+	sponsorFromWallet := c.SponsorInput == lnd.AddressDeriveFromWallet
+	closeTXIDs := c.CloseTXIDs
+	if c.FromSummary != "" {
+		inputs := &inputFlags{FromSummary: c.FromSummary}
+		entries, err := inputs.parseInputType()
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if entry.ClosingTX == nil ||
+				!entry.ClosingTX.ForceClose ||
+				entry.ClosingTX.AllOutsSpent {
+
+				continue
+			}
+
+			closeTXIDs = append(closeTXIDs, entry.ClosingTX.TXID)
+		}
+	}
+	if len(c.AnchorAddrs) == 0 && len(closeTXIDs) == 0 &&
+		len(c.CloseTXs) == 0 {
+
+		return errors.New("at least one anchor addr, close " +
+			"transaction ID or close transaction is required")
+	}
+	if c.Publish && !sponsorFromWallet {
+		return errors.New("publishing is only possible if the " +
+			"sponsor inputs are selected from the seed")
 	}
 	for _, anchorAddr := range c.AnchorAddrs {
 		err = lnd.CheckAddress(
@@ -102,27 +245,34 @@ func (c *pullAnchorCommand) Execute(_ *cobra.Command, _ []string) error {
 			return err
 		}
 	}
-	err = lnd.CheckAddress(
-		c.ChangeAddr, chainParams, true, "change", lnd.AddrTypeP2WKH,
-		lnd.AddrTypeP2TR,
-	)
-	if err != nil {
-		return err
+
+	// If the sponsor inputs are selected from the wallet, a fresh change
+	// address is derived from the wallet if none is specified.
+	if !sponsorFromWallet || c.ChangeAddr != "" {
+		err = lnd.CheckAddress(
+			c.ChangeAddr, chainParams, true, "change",
+			lnd.AddrTypeP2WKH, lnd.AddrTypeP2TR,
+		)
+		if err != nil {
+			return err
+		}
 	}
 
-	outpoint, err := lnd.ParseOutpoint(c.SponsorInput)
-	if err != nil {
-		return fmt.Errorf("error parsing sponsor input outpoint: %w",
-			err)
+	var outpoint *wire.OutPoint
+	if !sponsorFromWallet {
+		outpoint, err = lnd.ParseOutpoint(c.SponsorInput)
+		if err != nil {
+			return fmt.Errorf("error parsing sponsor input "+
+				"outpoint: %w", err)
+		}
 	}
 
 	// Set default values.
 	if c.FeeRate == 0 {
 		c.FeeRate = defaultFeeSatPerVByte
 	}
-	return createPullTransactionTemplate(
-		extendedKey, c.APIURL, outpoint, c.AnchorAddrs, c.ChangeAddr,
-		c.FeeRate,
+	return c.createPullTransactionTemplate(
+		extendedKey, outpoint, closeTXIDs,
 	)
 }
 
@@ -135,109 +285,165 @@ type targetAnchor struct {
 	scriptTree *input.AnchorScriptTree
 }
 
-func createPullTransactionTemplate(rootKey *hdkeychain.ExtendedKey,
-	apiURL string, sponsorOutpoint *wire.OutPoint, anchorAddrs []string,
-	changeAddr string, feeRate uint32) error {
+// packageParent is an unconfirmed force close transaction that needs to be
+// submitted together with the CPFP transaction as a package.
+type packageParent struct {
+	txid    string
+	rawTx   string
+	version int32
+	weight  lntypes.WeightUnit
+	fee     btcutil.Amount
+}
+
+func (c *pullAnchorCommand) createPullTransactionTemplate(
+	rootKey *hdkeychain.ExtendedKey, sponsorOutpoint *wire.OutPoint,
+	closeTXIDs []string) error {
 
 	var (
 		signer = &lnd.Signer{
 			ExtendedKey: rootKey,
 			ChainParams: chainParams,
 		}
-		api       = newExplorerAPI(apiURL)
-		estimator input.TxWeightEstimator
+		api            = newExplorerAPI(c.APIURL)
+		estimator      input.TxWeightEstimator
+		feeRateKWeight = chainfee.SatPerKVByte(
+			1000 * c.FeeRate,
+		).FeePerKWeight()
 	)
 
-	changeScript, err := lnd.PrepareWalletAddress(
-		changeAddr, chainParams, &estimator, rootKey, "change",
+	// We add the anchor inputs first, the sponsor input(s) follow after.
+	targets, parents, err := findAnchors(
+		c.AnchorAddrs, closeTXIDs, c.CloseTXs, api, rootKey, c.NumKeys,
 	)
 	if err != nil {
-		return err
+		return fmt.Errorf("error finding anchors: %w", err)
 	}
 
-	// Make sure the sponsor input is a P2WPKH or P2TR input and is known
-	// to the block explorer, so we can fetch the witness utxo.
-	sponsorTx, err := api.Transaction(sponsorOutpoint.Hash.String())
+	// If any of the close transactions is unconfirmed, we need to create
+	// a package. The version of the CPFP transaction depends on the
+	// version of the parents.
+	version, err := packageVersion(parents)
 	if err != nil {
-		return fmt.Errorf("error fetching sponsor tx: %w", err)
+		return err
 	}
-	sponsorTxOut := sponsorTx.Vout[sponsorOutpoint.Index]
-	sponsorPkScript, err := hex.DecodeString(sponsorTxOut.ScriptPubkey)
-	if err != nil {
-		return fmt.Errorf("error decoding sponsor pkscript: %w", err)
+	for _, parent := range parents {
+		log.Infof("Close transaction %s is unconfirmed (version %d, "+
+			"weight %d, fee %d sats), creating package",
+			parent.txid, parent.version, parent.weight, parent.fee)
 	}
 
-	sponsorType, err := txscript.ParsePkScript(sponsorPkScript)
+	tx := wire.NewMsgTx(version)
+	packet, err := psbt.NewFromUnsignedTx(tx)
 	if err != nil {
-		return fmt.Errorf("error parsing sponsor pkscript: %w", err)
+		return fmt.Errorf("error creating PSBT: %w", err)
 	}
-	var sponsorSigHashType txscript.SigHashType
-	switch sponsorType.Class() {
-	case txscript.WitnessV0PubKeyHashTy:
-		estimator.AddP2WKHInput()
-		sponsorSigHashType = txscript.SigHashAll
 
-	case txscript.WitnessV1TaprootTy:
-		sponsorSigHashType = txscript.SigHashDefault
-		estimator.AddTaprootKeySpendInput(sponsorSigHashType)
+	addAnchorInputs(targets, packet, &estimator)
+	anchorAmt := btcutil.Amount(len(targets) * anchorOutputValue)
 
-	default:
-		return fmt.Errorf("unsupported sponsor input type: %v",
-			sponsorType.Class())
-	}
+	// If no sponsor input was specified, we scan the wallet addresses
+	// derived from the seed for UTXOs we can use.
+	var (
+		walletUtxos  []*btc.WalletUTXO
+		changeScript []byte
+	)
+	if sponsorOutpoint == nil {
+		scanner := &btc.WalletScanner{
+			API:         api,
+			RootKey:     rootKey,
+			ChainParams: chainParams,
+			GapLimit:    c.GapLimit,
+		}
+		results, err := scanner.Scan(btc.DefaultWalletAccounts)
+		if err != nil {
+			return fmt.Errorf("error scanning wallet: %w", err)
+		}
 
-	tx := wire.NewMsgTx(2)
-	packet, err := psbt.NewFromUnsignedTx(tx)
-	if err != nil {
-		return fmt.Errorf("error creating PSBT: %w", err)
+		for _, result := range results {
+			walletUtxos = append(walletUtxos, result.UTXOs...)
+
+			// Unless the user specified a change address, we send
+			// the change to the next unused internal P2WKH
+			// address of the wallet.
+			if c.ChangeAddr == "" && result.Branch == 1 &&
+				result.Account == btc.WalletAccountP2WKH {
+
+				changeScript = result.NextUnused.PkScript
+				estimator.AddP2WKHOutput()
+
+				log.Infof("Using fresh change address %v (%s)",
+					result.NextUnused.Address,
+					result.NextUnused.PathString())
+			}
+		}
 	}
 
-	// Let's add the sponsor input to the PSBT.
-	sponsorUtxo := &wire.TxOut{
-		Value:    int64(sponsorTxOut.Value),
-		PkScript: sponsorPkScript,
+	if changeScript == nil {
+		changeScript, err = lnd.PrepareWalletAddress(
+			c.ChangeAddr, chainParams, &estimator, rootKey,
+			"change",
+		)
+		if err != nil {
+			return err
+		}
 	}
-	packet.UnsignedTx.TxIn = append(packet.UnsignedTx.TxIn, &wire.TxIn{
-		PreviousOutPoint: *sponsorOutpoint,
-		Sequence:         mempool.MaxRBFSequence,
-	})
-	packet.Inputs = append(packet.Inputs, psbt.PInput{
-		WitnessUtxo: sponsorUtxo,
-		SighashType: sponsorSigHashType,
-	})
 
-	targets, err := addAnchorInputs(
-		anchorAddrs, packet, api, &estimator, rootKey,
-	)
-	if err != nil {
-		return fmt.Errorf("error adding anchor inputs: %w", err)
+	var sponsorAmt btcutil.Amount
+	if sponsorOutpoint != nil {
+		sponsorAmt, err = addSponsorInput(
+			sponsorOutpoint, packet, api, &estimator,
+		)
+		if err != nil {
+			return err
+		}
+	} else {
+		walletUtxos, err = selectSponsorInputs(
+			walletUtxos, &estimator, feeRateKWeight, parents,
+			anchorAmt,
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, utxo := range walletUtxos {
+			sponsorAmt += btcutil.Amount(utxo.Value)
+
+			packet.UnsignedTx.AddTxIn(&wire.TxIn{
+				PreviousOutPoint: utxo.OutPoint,
+				Sequence:         mempool.MaxRBFSequence,
+			})
+			packet.Inputs = append(packet.Inputs, psbt.PInput{
+				WitnessUtxo: utxo.TxOut(),
+			})
+
+			log.Infof("Using wallet UTXO %v (%d sats) of address "+
+				"%v (%s) as sponsor input", utxo.OutPoint,
+				utxo.Value, utxo.Address, utxo.PathString())
+		}
 	}
 
 	// Now we can calculate the fee and add the change output.
-	anchorAmt := uint64(len(anchorAddrs)) * 330
-	totalOutputValue := btcutil.Amount(sponsorTxOut.Value + anchorAmt)
-	feeRateKWeight := chainfee.SatPerKVByte(1000 * feeRate).FeePerKWeight()
-	totalFee := feeRateKWeight.FeeForWeight(estimator.Weight())
+	totalOutputValue := sponsorAmt + anchorAmt
+	totalFee := packageFee(feeRateKWeight, estimator.Weight(), parents)
 
 	log.Infof("Fee %d sats of %d total amount (estimated weight %d)",
 		totalFee, totalOutputValue, estimator.Weight())
 
+	if version == 3 && estimator.VSize() > trucChildMaxVSize {
+		return fmt.Errorf("estimated size of %d vbytes exceeds the "+
+			"maximum size of %d vbytes for a TRUC child "+
+			"transaction, try pulling fewer anchors or using "+
+			"fewer sponsor inputs", estimator.VSize(),
+			trucChildMaxVSize)
+	}
+
 	packet.UnsignedTx.TxOut = append(packet.UnsignedTx.TxOut, &wire.TxOut{
 		Value:    int64(totalOutputValue - totalFee),
 		PkScript: changeScript,
 	})
 	packet.Outputs = append(packet.Outputs, psbt.POutput{})
 
-	prevOutFetcher := txscript.NewMultiPrevOutFetcher(
-		map[wire.OutPoint]*wire.TxOut{
-			*sponsorOutpoint: sponsorUtxo,
-		},
-	)
-	for idx := range targets {
-		prevOutFetcher.AddPrevOut(
-			targets[idx].outpoint, targets[idx].utxo,
-		)
-	}
+	prevOutFetcher := wallet.PsbtPrevOutputFetcher(packet)
 
 	// And now we sign the anchor inputs.
 	for idx := range targets {
@@ -247,7 +453,7 @@ func createPullTransactionTemplate(rootKey *hdkeychain.ExtendedKey,
 			WitnessScript:     target.script,
 			Output:            target.utxo,
 			PrevOutputFetcher: prevOutFetcher,
-			InputIndex:        idx + 1,
+			InputIndex:        idx,
 		}
 
 		var anchorWitness wire.TxWitness
@@ -297,235 +503,674 @@ func createPullTransactionTemplate(rootKey *hdkeychain.ExtendedKey,
 			return fmt.Errorf("error serializing witness: %w", err)
 		}
 
-		packet.Inputs[idx+1].FinalScriptWitness = witnessBuf.Bytes()
+		packet.Inputs[idx].FinalScriptWitness = witnessBuf.Bytes()
 	}
 
-	packetBase64, err := packet.B64Encode()
+	// If the sponsor input was specified manually, it is owned by the lnd
+	// node and needs to be signed by it.
+	if sponsorOutpoint != nil {
+		packetBase64, err := packet.B64Encode()
+		if err != nil {
+			return fmt.Errorf("error encoding PSBT: %w", err)
+		}
+
+		if len(parents) > 0 {
+			log.Infof("Prepared PSBT follows, please now call\n" +
+				"'lncli wallet psbt finalize <psbt>' to " +
+				"finalize the\ntransaction, then submit it " +
+				"together with the unconfirmed close\n" +
+				"transaction(s) as a package by using\n" +
+				submitPackageCommand(parents, "<final_tx>") +
+				"\n\n" + packetBase64 + "\n")
+
+			return nil
+		}
+
+		log.Infof("Prepared PSBT follows, please now call\n" +
+			"'lncli wallet psbt finalize <psbt>' to finalize " +
+			"the\ntransaction, then publish it manually or by " +
+			"using\n'lncli wallet publishtx <final_tx>':\n\n" +
+			packetBase64 + "\n")
+
+		return nil
+	}
+
+	// Otherwise we can sign the wallet inputs ourselves.
+	for idx, utxo := range walletUtxos {
+		privKey, err := lnd.PrivKeyFromPath(rootKey, utxo.Path)
+		if err != nil {
+			return fmt.Errorf("error deriving private key: %w", err)
+		}
+
+		err = signer.SignWalletInput(packet, len(targets)+idx, privKey)
+		if err != nil {
+			return fmt.Errorf("error signing sponsor input: %w",
+				err)
+		}
+	}
+
+	finalTx, err := psbt.Extract(packet)
 	if err != nil {
-		return fmt.Errorf("error encoding PSBT: %w", err)
+		return fmt.Errorf("error extracting final TX: %w", err)
 	}
 
-	log.Infof("Prepared PSBT follows, please now call\n" +
-		"'lncli wallet psbt finalize <psbt>' to finalize the\n" +
-		"transaction, then publish it manually or by using\n" +
-		"'lncli wallet publishtx <final_tx>':\n\n" + packetBase64 +
-		"\n")
+	var buf bytes.Buffer
+	err = finalTx.Serialize(&buf)
+	if err != nil {
+		return err
+	}
+
+	finalTxHex := hex.EncodeToString(buf.Bytes())
+
+	switch {
+	case c.Publish && len(parents) > 0:
+		rawTxs := make([]string, 0, len(parents)+1)
+		for _, parent := range parents {
+			rawTxs = append(rawTxs, parent.rawTx)
+		}
+		response, err := api.SubmitPackage(append(rawTxs, finalTxHex))
+		if err != nil {
+			return fmt.Errorf("error submitting package: %w", err)
+		}
+
+		log.Infof("Submitted package with transaction %v, response: "+
+			"%s", finalTx.TxHash(), response)
+
+	case c.Publish:
+		response, err := api.PublishTx(finalTxHex)
+		if err != nil {
+			return fmt.Errorf("error publishing transaction: %w",
+				err)
+		}
+
+		log.Infof("Published transaction %v, response: %s",
+			finalTx.TxHash(), response)
+
+	case len(parents) > 0:
+		log.Infof("Fully signed transaction %v created, please "+
+			"submit it together with the unconfirmed close "+
+			"transaction(s) as a package by using:\n\n%s\n",
+			finalTx.TxHash(),
+			submitPackageCommand(parents, finalTxHex))
+
+	default:
+		log.Infof("Fully signed transaction %v follows, please "+
+			"publish it manually:\n\n%s\n", finalTx.TxHash(),
+			finalTxHex)
+	}
 
 	return nil
 }
 
-func addAnchorInputs(anchorAddrs []string, packet *psbt.Packet,
-	api *btc.ExplorerAPI, estimator *input.TxWeightEstimator,
-	rootKey *hdkeychain.ExtendedKey) ([]targetAnchor, error) {
+// packageVersion returns the transaction version of the CPFP transaction. If
+// the unconfirmed parents are TRUC (version 3) transactions, the child must be
+// one as well. A TRUC transaction can only have a single unconfirmed parent.
+func packageVersion(parents []*packageParent) (int32, error) {
+	numTRUC := 0
+	for _, parent := range parents {
+		if parent.version == 3 {
+			numTRUC++
+		}
+	}
+
+	switch {
+	case numTRUC == 0:
+		return 2, nil
+
+	case len(parents) > 1:
+		return 0, errors.New("a TRUC (version 3) close transaction " +
+			"can't be pulled together with other unconfirmed " +
+			"close transactions, please pull its anchor " +
+			"separately")
+
+	default:
+		return 3, nil
+	}
+}
+
+// packageFee returns the fee the CPFP transaction with the given weight needs
+// to pay so the whole package, consisting of the unconfirmed parents and the
+// CPFP transaction itself, reaches the given fee rate. The CPFP transaction
+// always pays at least the fee for its own weight.
+func packageFee(feeRate chainfee.SatPerKWeight, childWeight lntypes.WeightUnit,
+	parents []*packageParent) btcutil.Amount {
+
+	var (
+		childFee      = feeRate.FeeForWeight(childWeight)
+		packageWeight = childWeight
+		parentFees    btcutil.Amount
+	)
+	for _, parent := range parents {
+		packageWeight += parent.weight
+		parentFees += parent.fee
+	}
+
+	fee := feeRate.FeeForWeight(packageWeight) - parentFees
+	if fee < childFee {
+		return childFee
+	}
+
+	return fee
+}
+
+// submitPackageCommand returns the bitcoin-cli command to submit the given
+// parents and the child transaction as a package.
+func submitPackageCommand(parents []*packageParent, childTx string) string {
+	rawTxs := make([]string, 0, len(parents)+1)
+	for _, parent := range parents {
+		rawTxs = append(rawTxs, fmt.Sprintf("%q", parent.rawTx))
+	}
+	rawTxs = append(rawTxs, fmt.Sprintf("%q", childTx))
+
+	return fmt.Sprintf("bitcoin-cli submitpackage '[%s]'",
+		strings.Join(rawTxs, ","))
+}
+
+// addSponsorInput adds the given sponsor input that is owned by the lnd node to
+// the packet. The input must be a P2WKH or P2TR input and must be known to the
+// block explorer, so we can fetch the witness UTXO.
+func addSponsorInput(sponsorOutpoint *wire.OutPoint, packet *psbt.Packet,
+	api *btc.ExplorerAPI, estimator *input.TxWeightEstimator) (
+	btcutil.Amount, error) {
 
-	// Fetch the additional info we need for the anchor output as well.
-	results := make([]targetAnchor, len(anchorAddrs))
-	for idx, anchorAddr := range anchorAddrs {
+	sponsorTx, err := api.Transaction(sponsorOutpoint.Hash.String())
+	if err != nil {
+		return 0, fmt.Errorf("error fetching sponsor tx: %w", err)
+	}
+	sponsorTxOut := sponsorTx.Vout[sponsorOutpoint.Index]
+	sponsorPkScript, err := hex.DecodeString(sponsorTxOut.ScriptPubkey)
+	if err != nil {
+		return 0, fmt.Errorf("error decoding sponsor pkscript: %w", err)
+	}
+
+	sponsorType, err := txscript.ParsePkScript(sponsorPkScript)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing sponsor pkscript: %w", err)
+	}
+	var sponsorSigHashType txscript.SigHashType
+	switch sponsorType.Class() {
+	case txscript.WitnessV0PubKeyHashTy:
+		estimator.AddP2WKHInput()
+		sponsorSigHashType = txscript.SigHashAll
+
+	case txscript.WitnessV1TaprootTy:
+		sponsorSigHashType = txscript.SigHashDefault
+		estimator.AddTaprootKeySpendInput(sponsorSigHashType)
+
+	default:
+		return 0, fmt.Errorf("unsupported sponsor input type: %v",
+			sponsorType.Class())
+	}
+
+	// Let's add the sponsor input to the PSBT.
+	packet.UnsignedTx.TxIn = append(packet.UnsignedTx.TxIn, &wire.TxIn{
+		PreviousOutPoint: *sponsorOutpoint,
+		Sequence:         mempool.MaxRBFSequence,
+	})
+	packet.Inputs = append(packet.Inputs, psbt.PInput{
+		WitnessUtxo: &wire.TxOut{
+			Value:    int64(sponsorTxOut.Value),
+			PkScript: sponsorPkScript,
+		},
+		SighashType: sponsorSigHashType,
+	})
+
+	return btcutil.Amount(sponsorTxOut.Value), nil
+}
+
+// selectSponsorInputs selects confirmed wallet UTXOs, largest first, until
+// their value covers the fee of the transaction (or package, if there are
+// unconfirmed parents) and leaves a change output above the dust limit. The
+// weight of the selected inputs is added to the estimator.
+func selectSponsorInputs(utxos []*btc.WalletUTXO,
+	estimator *input.TxWeightEstimator, feeRate chainfee.SatPerKWeight,
+	parents []*packageParent,
+	anchorAmt btcutil.Amount) ([]*btc.WalletUTXO, error) {
+
+	confirmed := make([]*btc.WalletUTXO, 0, len(utxos))
+	for _, utxo := range utxos {
+		if utxo.Confirmed {
+			confirmed = append(confirmed, utxo)
+		}
+	}
+	sort.Slice(confirmed, func(i, j int) bool {
+		return confirmed[i].Value > confirmed[j].Value
+	})
+
+	var (
+		selected []*btc.WalletUTXO
+		total    = anchorAmt
+	)
+	for _, utxo := range confirmed {
+		selected = append(selected, utxo)
+		total += btcutil.Amount(utxo.Value)
+		utxo.AddInputWeight(estimator)
+
+		fee := packageFee(feeRate, estimator.Weight(), parents)
+		if total-fee >= sweepDustLimit {
+			return selected, nil
+		}
+	}
+
+	return nil, fmt.Errorf("not enough confirmed wallet funds to pay for "+
+		"the fee, found %d confirmed UTXOs with a total value of %d "+
+		"sats", len(confirmed), total-anchorAmt)
+}
+
+// anchorCandidates are outputs that could be one of our anchor outputs. They
+// are keyed by their outpoint, so an anchor that is specified multiple times,
+// for example by its address and its close transaction, is only spent once.
+type anchorCandidates map[wire.OutPoint]targetAnchor
+
+// add adds the output with the given pk script as a candidate.
+func (a anchorCandidates) add(outpoint wire.OutPoint, pkScript []byte) {
+	addr := ""
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(
+		pkScript, chainParams,
+	)
+	if err == nil && len(addrs) == 1 {
+		addr = addrs[0].EncodeAddress()
+	}
+
+	a[outpoint] = targetAnchor{
+		addr: addr,
+		utxo: &wire.TxOut{
+			Value:    anchorOutputValue,
+			PkScript: pkScript,
+		},
+		outpoint: outpoint,
+	}
+}
+
+// numTxs returns the number of distinct transactions the candidates belong to.
+// Each of them contains at most one anchor output of our node.
+func (a anchorCandidates) numTxs() int {
+	txids := make(map[chainhash.Hash]struct{}, len(a))
+	for outpoint := range a {
+		txids[outpoint.Hash] = struct{}{}
+	}
+
+	return len(txids)
+}
+
+// findAnchors identifies the anchor outputs that belong to our node, given by
+// their address or by the force close transaction they're part of. The close
+// transactions can also be given in their raw form, in case they aren't known
+// to the API. Anchor outputs of close transactions that are already spent are
+// ignored. All close transactions that aren't confirmed yet are returned as
+// package parents. The keys of the anchors are searched in the first numKeys
+// indexes of the multisig and payment base key families.
+func findAnchors(anchorAddrs, closeTXIDs, rawCloseTxs []string,
+	api *btc.ExplorerAPI, rootKey *hdkeychain.ExtendedKey,
+	numKeys uint32) ([]targetAnchor, []*packageParent, error) {
+
+	var (
+		candidates = make(anchorCandidates)
+		byAddr     = make(map[wire.OutPoint]string)
+		seenTxs    = make(map[string]struct{})
+		parents    []*packageParent
+	)
+	for _, anchorAddr := range anchorAddrs {
 		anchorTx, anchorIndex, err := api.Outpoint(anchorAddr)
 		if err != nil {
-			return nil, fmt.Errorf("error fetching anchor "+
+			return nil, nil, fmt.Errorf("error fetching anchor "+
 				"outpoint: %w", err)
 		}
 		anchorTxHash, err := chainhash.NewHashFromStr(anchorTx.TXID)
 		if err != nil {
-			return nil, fmt.Errorf("error decoding anchor txid: %w",
-				err)
+			return nil, nil, fmt.Errorf("error decoding anchor "+
+				"txid: %w", err)
 		}
 
 		addr, err := btcutil.DecodeAddress(anchorAddr, chainParams)
 		if err != nil {
-			return nil, fmt.Errorf("error decoding address: %w",
-				err)
+			return nil, nil, fmt.Errorf("error decoding address: "+
+				"%w", err)
 		}
-
 		anchorPkScript, err := txscript.PayToAddrScript(addr)
 		if err != nil {
-			return nil, fmt.Errorf("error creating pk script: %w",
-				err)
+			return nil, nil, fmt.Errorf("error creating pk "+
+				"script: %w", err)
 		}
 
-		target := targetAnchor{
-			addr: anchorAddr,
-			utxo: &wire.TxOut{
-				Value:    330,
-				PkScript: anchorPkScript,
-			},
-			outpoint: wire.OutPoint{
-				Hash:  *anchorTxHash,
-				Index: uint32(anchorIndex),
-			},
+		outpoint := wire.OutPoint{
+			Hash:  *anchorTxHash,
+			Index: uint32(anchorIndex),
 		}
-		switch addr.(type) {
-		case *btcutil.AddressWitnessScriptHash:
-			estimator.AddWitnessInput(input.AnchorWitnessSize)
+		candidates.add(outpoint, anchorPkScript)
+		byAddr[outpoint] = anchorAddr
+	}
 
-			anchorKeyDesc, anchorWitnessScript, err := findAnchorKey(
-				rootKey, anchorPkScript,
-			)
+	for _, closeTXID := range closeTXIDs {
+		if _, ok := seenTxs[closeTXID]; ok {
+			continue
+		}
+		seenTxs[closeTXID] = struct{}{}
+
+		closeTx, err := api.Transaction(closeTXID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error fetching close tx "+
+				"%s: %w", closeTXID, err)
+		}
+		closeTxHash, err := chainhash.NewHashFromStr(closeTx.TXID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error decoding close "+
+				"txid: %w", err)
+		}
+
+		for idx, vout := range closeTx.Vout {
+			pkScript, err := hex.DecodeString(vout.ScriptPubkey)
 			if err != nil {
-				return nil, fmt.Errorf("could not find "+
-					"key for anchor address %v: %w",
-					anchorAddr, err)
+				return nil, nil, fmt.Errorf("error decoding "+
+					"pk script: %w", err)
+			}
+			if !isAnchorCandidate(int64(vout.Value), pkScript) {
+				continue
+			}
+			if vout.Outspend != nil && vout.Outspend.Spent {
+				log.Infof("Anchor output %s:%d already spent",
+					closeTx.TXID, idx)
+				continue
 			}
 
-			target.keyDesc = anchorKeyDesc
-			target.script = anchorWitnessScript
-
-		case *btcutil.AddressTaproot:
-			estimator.AddTaprootKeySpendInput(
-				txscript.SigHashDefault,
-			)
+			candidates.add(wire.OutPoint{
+				Hash:  *closeTxHash,
+				Index: uint32(idx),
+			}, pkScript)
+		}
 
-			anchorKeyDesc, scriptTree, err := findTaprootAnchorKey(
-				rootKey, anchorPkScript,
-			)
+		if closeTx.Status != nil && !closeTx.Status.Confirmed {
+			rawTx, err := api.RawTransaction(closeTx.TXID)
 			if err != nil {
-				return nil, fmt.Errorf("could not find "+
-					"key for anchor address %v: %w",
-					anchorAddr, err)
+				return nil, nil, fmt.Errorf("error fetching "+
+					"raw close tx %s: %w", closeTx.TXID,
+					err)
 			}
 
-			target.keyDesc = anchorKeyDesc
-			target.scriptTree = scriptTree
+			parents = append(parents, &packageParent{
+				txid:    closeTx.TXID,
+				rawTx:   rawTx,
+				version: closeTx.Version,
+				weight:  lntypes.WeightUnit(closeTx.Weight),
+				fee:     btcutil.Amount(closeTx.Fee),
+			})
+		}
+	}
 
-		default:
-			return nil, fmt.Errorf("unsupported address type: %T",
-				addr)
+	for _, rawCloseTx := range rawCloseTxs {
+		parent, closeTx, err := decodeRawCloseTx(rawCloseTx, api)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, ok := seenTxs[parent.txid]; ok {
+			continue
 		}
+		seenTxs[parent.txid] = struct{}{}
 
-		log.Infof("Found multisig key %x for anchor pk script %x",
-			target.keyDesc.PubKey.SerializeCompressed(),
-			anchorPkScript)
+		for idx, txOut := range closeTx.TxOut {
+			if !isAnchorCandidate(txOut.Value, txOut.PkScript) {
+				continue
+			}
 
-		packet.UnsignedTx.TxIn = append(
-			packet.UnsignedTx.TxIn, &wire.TxIn{
-				PreviousOutPoint: target.outpoint,
-				Sequence:         mempool.MaxRBFSequence,
+			candidates.add(wire.OutPoint{
+				Hash:  closeTx.TxHash(),
+				Index: uint32(idx),
+			}, txOut.PkScript)
+		}
+
+		parents = append(parents, parent)
+	}
+
+	if len(candidates) == 0 {
+		return nil, nil, errors.New("no unspent anchor outputs found " +
+			"in close transactions")
+	}
+
+	numTxs := candidates.numTxs()
+	targets, err := findAnchorKeys(rootKey, candidates, numTxs, numKeys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Anchors that were given by their address must belong to us, the
+	// close transactions also contain the anchor of the remote party.
+	for outpoint, anchorAddr := range byAddr {
+		found := slices.ContainsFunc(
+			targets, func(target targetAnchor) bool {
+				return target.outpoint == outpoint
 			},
 		)
-		packet.Inputs = append(packet.Inputs, psbt.PInput{
-			WitnessUtxo:   target.utxo,
-			WitnessScript: target.script,
-		})
+		if !found {
+			return nil, nil, fmt.Errorf("could not find key for "+
+				"anchor address %v in the first %d keys",
+				anchorAddr, numKeys)
+		}
+	}
 
-		results[idx] = target
+	if len(targets) < numTxs {
+		log.Warnf("Only found %d of our anchor outputs for %d close "+
+			"transactions", len(targets), numTxs)
+	}
+	if len(targets) == 0 {
+		return nil, nil, errors.New("none of the anchor outputs " +
+			"belong to the given seed")
 	}
 
-	return results, nil
+	return targets, parents, nil
 }
 
-func findAnchorKey(rootKey *hdkeychain.ExtendedKey,
-	targetScript []byte) (*keychain.KeyDescriptor, []byte, error) {
-
-	family := keychain.KeyFamilyMultiSig
-	localMultisig, err := lnd.DeriveChildren(rootKey, []uint32{
-		lnd.HardenedKeyStart + uint32(keychain.BIP0043Purpose),
-		lnd.HardenedKeyStart + chainParams.HDCoinType,
-		lnd.HardenedKeyStart + uint32(family),
-		0,
-	})
-	if err != nil {
-		return nil, nil, fmt.Errorf("could not derive local "+
-			"multisig key: %w", err)
+// isAnchorCandidate returns true if the output with the given value and pk
+// script could be an anchor output.
+func isAnchorCandidate(value int64, pkScript []byte) bool {
+	if value != anchorOutputValue {
+		return false
 	}
 
-	// Loop through the local multisig keys to find the target anchor
-	// script.
-	for index := range uint32(math.MaxInt16) {
-		currentKey, err := localMultisig.DeriveNonStandard(index)
-		if err != nil {
-			return nil, nil, fmt.Errorf("error deriving child "+
-				"key: %w", err)
-		}
+	return txscript.IsPayToWitnessScriptHash(pkScript) ||
+		txscript.IsPayToTaproot(pkScript)
+}
 
-		currentPubkey, err := currentKey.ECPubKey()
-		if err != nil {
-			return nil, nil, fmt.Errorf("error deriving public "+
-				"key: %w", err)
-		}
+// decodeRawCloseTx decodes the given raw close transaction and calculates its
+// fee by looking up the outputs it spends.
+func decodeRawCloseTx(rawTx string, api *btc.ExplorerAPI) (*packageParent,
+	*wire.MsgTx, error) {
 
-		script, err := input.CommitScriptAnchor(currentPubkey)
-		if err != nil {
-			return nil, nil, fmt.Errorf("error deriving script: "+
-				"%w", err)
-		}
+	txBytes, err := hex.DecodeString(strings.TrimSpace(rawTx))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error decoding close tx: %w", err)
+	}
+	closeTx := &wire.MsgTx{}
+	if err := closeTx.Deserialize(bytes.NewReader(txBytes)); err != nil {
+		return nil, nil, fmt.Errorf("error parsing close tx: %w", err)
+	}
 
-		pkScript, err := input.WitnessScriptHash(script)
+	var inputValue int64
+	for _, txIn := range closeTx.TxIn {
+		prevOut := txIn.PreviousOutPoint
+		prevTx, err := api.Transaction(prevOut.Hash.String())
 		if err != nil {
-			return nil, nil, fmt.Errorf("error deriving script "+
-				"hash: %w", err)
+			return nil, nil, fmt.Errorf("error fetching funding "+
+				"tx %v: %w", prevOut.Hash, err)
 		}
-
-		if !bytes.Equal(pkScript, targetScript) {
-			continue
+		if int(prevOut.Index) >= len(prevTx.Vout) {
+			return nil, nil, fmt.Errorf("invalid funding outpoint "+
+				"%v", prevOut)
 		}
 
-		return &keychain.KeyDescriptor{
-			PubKey: currentPubkey,
-			KeyLocator: keychain.KeyLocator{
-				Family: family,
-				Index:  index,
-			},
-		}, script, nil
+		inputValue += int64(prevTx.Vout[prevOut.Index].Value)
+	}
+
+	var outputValue int64
+	for _, txOut := range closeTx.TxOut {
+		outputValue += txOut.Value
+	}
+	if outputValue > inputValue {
+		return nil, nil, fmt.Errorf("close tx %v spends more than its "+
+			"inputs", closeTx.TxHash())
 	}
 
-	return nil, nil, errors.New("no matching pubkeys found")
+	weight := blockchain.GetTransactionWeight(btcutil.NewTx(closeTx))
+
+	return &packageParent{
+		txid:    closeTx.TxHash().String(),
+		rawTx:   hex.EncodeToString(txBytes),
+		version: closeTx.Version,
+		weight:  lntypes.WeightUnit(weight),
+		fee:     btcutil.Amount(inputValue - outputValue),
+	}, closeTx, nil
 }
 
-func findTaprootAnchorKey(rootKey *hdkeychain.ExtendedKey,
-	targetScript []byte) (*keychain.KeyDescriptor, *input.AnchorScriptTree,
-	error) {
+// findAnchorKeys scans the first numKeys keys of the multisig and payment base
+// key families of the given root key for keys that produce one of the
+// candidate anchor pk scripts. The scan stops early once the given number of
+// anchors was found.
+func findAnchorKeys(rootKey *hdkeychain.ExtendedKey,
+	candidates anchorCandidates, maxResults int,
+	numKeys uint32) ([]targetAnchor, error) {
+
+	// The same anchor pk script can be part of multiple candidates, for
+	// example our anchor on the local and the remote commitment.
+	byScript := make(map[string][]targetAnchor, len(candidates))
+	for _, target := range candidates {
+		script := hex.EncodeToString(target.utxo.PkScript)
+		byScript[script] = append(byScript[script], target)
+	}
+	for _, targets := range byScript {
+		slices.SortFunc(targets, func(a, b targetAnchor) int {
+			return strings.Compare(
+				a.outpoint.String(), b.outpoint.String(),
+			)
+		})
+	}
 
-	family := keychain.KeyFamilyPaymentBase
-	localPayment, err := lnd.DeriveChildren(rootKey, []uint32{
-		lnd.HardenedKeyStart + uint32(keychain.BIP0043Purpose),
-		lnd.HardenedKeyStart + chainParams.HDCoinType,
-		lnd.HardenedKeyStart + uint32(family),
-		0,
-	})
+	deriveFamily := func(family keychain.KeyFamily) (
+		*hdkeychain.ExtendedKey, error) {
+
+		return lnd.DeriveChildren(rootKey, []uint32{
+			lnd.HardenedKeyStart + uint32(keychain.BIP0043Purpose),
+			lnd.HardenedKeyStart + chainParams.HDCoinType,
+			lnd.HardenedKeyStart + uint32(family),
+			0,
+		})
+	}
+	localMultisig, err := deriveFamily(keychain.KeyFamilyMultiSig)
+	if err != nil {
+		return nil, fmt.Errorf("could not derive local multisig key: "+
+			"%w", err)
+	}
+	localPayment, err := deriveFamily(keychain.KeyFamilyPaymentBase)
 	if err != nil {
-		return nil, nil, fmt.Errorf("could not derive local "+
-			"multisig key: %w", err)
+		return nil, fmt.Errorf("could not derive local payment key: "+
+			"%w", err)
 	}
 
-	// Loop through the local multisig keys to find the target anchor
-	// script.
-	for index := range uint32(math.MaxInt16) {
-		currentKey, err := localPayment.DeriveNonStandard(index)
+	var results []targetAnchor
+	for index := range numKeys {
+		// Anchor channels use the multisig key for the anchor output.
+		multisigKey, err := localMultisig.DeriveNonStandard(index)
 		if err != nil {
-			return nil, nil, fmt.Errorf("error deriving child "+
-				"key: %w", err)
+			return nil, fmt.Errorf("error deriving child key: %w",
+				err)
 		}
-
-		currentPubkey, err := currentKey.ECPubKey()
+		multisigPubKey, err := multisigKey.ECPubKey()
 		if err != nil {
-			return nil, nil, fmt.Errorf("error deriving public "+
-				"key: %w", err)
+			return nil, fmt.Errorf("error deriving public key: %w",
+				err)
 		}
-
-		scriptTree, err := input.NewAnchorScriptTree(currentPubkey)
+		script, err := input.CommitScriptAnchor(multisigPubKey)
 		if err != nil {
-			return nil, nil, fmt.Errorf("error deriving taproot "+
-				"key: %w", err)
+			return nil, fmt.Errorf("error deriving script: %w", err)
+		}
+		pkScript, err := input.WitnessScriptHash(script)
+		if err != nil {
+			return nil, fmt.Errorf("error deriving script hash: %w",
+				err)
+		}
+		for _, target := range byScript[hex.EncodeToString(pkScript)] {
+			target.keyDesc = &keychain.KeyDescriptor{
+				PubKey: multisigPubKey,
+				KeyLocator: keychain.KeyLocator{
+					Family: keychain.KeyFamilyMultiSig,
+					Index:  index,
+				},
+			}
+			target.script = script
+			results = append(results, target)
+
+			log.Infof("Found multisig key %x for anchor output %v",
+				multisigPubKey.SerializeCompressed(),
+				target.outpoint)
 		}
 
-		pkScript, err := input.PayToTaprootScript(scriptTree.TaprootKey)
+		// Simple taproot channels use the payment base key instead.
+		paymentKey, err := localPayment.DeriveNonStandard(index)
 		if err != nil {
-			return nil, nil, fmt.Errorf("error deriving pk "+
-				"script: %w", err)
+			return nil, fmt.Errorf("error deriving child key: %w",
+				err)
 		}
-		if !bytes.Equal(pkScript, targetScript) {
-			continue
+		paymentPubKey, err := paymentKey.ECPubKey()
+		if err != nil {
+			return nil, fmt.Errorf("error deriving public key: %w",
+				err)
+		}
+		scriptTree, err := input.NewAnchorScriptTree(paymentPubKey)
+		if err != nil {
+			return nil, fmt.Errorf("error deriving taproot key: %w",
+				err)
 		}
+		pkScript, err = input.PayToTaprootScript(scriptTree.TaprootKey)
+		if err != nil {
+			return nil, fmt.Errorf("error deriving pk script: %w",
+				err)
+		}
+		for _, target := range byScript[hex.EncodeToString(pkScript)] {
+			target.keyDesc = &keychain.KeyDescriptor{
+				PubKey: paymentPubKey,
+				KeyLocator: keychain.KeyLocator{
+					Family: keychain.KeyFamilyPaymentBase,
+					Index:  index,
+				},
+			}
+			target.scriptTree = scriptTree
+			results = append(results, target)
 
-		return &keychain.KeyDescriptor{
-			PubKey: currentPubkey,
-			KeyLocator: keychain.KeyLocator{
-				Family: family,
-				Index:  index,
-			},
-		}, scriptTree, nil
+			log.Infof("Found payment base key %x for anchor "+
+				"output %v",
+				paymentPubKey.SerializeCompressed(),
+				target.outpoint)
+		}
+
+		if len(results) >= maxResults {
+			break
+		}
 	}
 
-	return nil, nil, errors.New("no matching pubkeys found")
+	return results, nil
+}
+
+func addAnchorInputs(targets []targetAnchor, packet *psbt.Packet,
+	estimator *input.TxWeightEstimator) {
+
+	for _, target := range targets {
+		switch {
+		case target.scriptTree != nil:
+			estimator.AddTaprootKeySpendInput(
+				txscript.SigHashDefault,
+			)
+
+		default:
+			estimator.AddWitnessInput(input.AnchorWitnessSize)
+		}
+
+		packet.UnsignedTx.TxIn = append(
+			packet.UnsignedTx.TxIn, &wire.TxIn{
+				PreviousOutPoint: target.outpoint,
+				Sequence:         mempool.MaxRBFSequence,
+			},
+		)
+		packet.Inputs = append(packet.Inputs, psbt.PInput{
+			WitnessUtxo:   target.utxo,
+			WitnessScript: target.script,
+		})
+	}
 }
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightninglabs/chantools/btc"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindAnchorKeys(t *testing.T) {
+	_ = newHarness(t)
+
+	extendedKey, err := hdkeychain.NewKeyFromString(rootKeyAezeed)
+	require.NoError(t, err)
+	otherKey, err := hdkeychain.NewKeyFromString(rootKeyBip39)
+	require.NoError(t, err)
+
+	deriveKey := func(root *hdkeychain.ExtendedKey,
+		loc keychain.KeyLocator) keychain.KeyDescriptor {
+
+		keyRing := &lnd.HDKeyRing{
+			ExtendedKey: root,
+			ChainParams: chainParams,
+		}
+		keyDesc, err := keyRing.DeriveKey(loc)
+		require.NoError(t, err)
+
+		return keyDesc
+	}
+
+	candidates := make(anchorCandidates)
+	addCandidate := func(pkScript []byte, index uint32) {
+		candidates.add(wire.OutPoint{Index: index}, pkScript)
+	}
+
+	// Our anchor output of an anchor channel.
+	multisigKey := deriveKey(extendedKey, keychain.KeyLocator{
+		Family: keychain.KeyFamilyMultiSig,
+		Index:  17,
+	})
+	script, err := input.CommitScriptAnchor(multisigKey.PubKey)
+	require.NoError(t, err)
+	pkScript, err := input.WitnessScriptHash(script)
+	require.NoError(t, err)
+	addCandidate(pkScript, 0)
+
+	// The remote party's anchor output of the same channel.
+	remoteKey := deriveKey(otherKey, keychain.KeyLocator{
+		Family: keychain.KeyFamilyMultiSig,
+		Index:  17,
+	})
+	remoteScript, err := input.CommitScriptAnchor(remoteKey.PubKey)
+	require.NoError(t, err)
+	remotePkScript, err := input.WitnessScriptHash(remoteScript)
+	require.NoError(t, err)
+	addCandidate(remotePkScript, 1)
+
+	// Our anchor output of a simple taproot channel.
+	paymentKey := deriveKey(extendedKey, keychain.KeyLocator{
+		Family: keychain.KeyFamilyPaymentBase,
+		Index:  42,
+	})
+	scriptTree, err := input.NewAnchorScriptTree(paymentKey.PubKey)
+	require.NoError(t, err)
+	trPkScript, err := input.PayToTaprootScript(scriptTree.TaprootKey)
+	require.NoError(t, err)
+	addCandidate(trPkScript, 2)
+
+	// Adding the same anchor twice, for example by its address and its
+	// close transaction, must not result in a duplicate input.
+	addCandidate(trPkScript, 2)
+	require.Len(t, candidates, 3)
+	require.Equal(t, 1, candidates.numTxs())
+
+	targets, err := findAnchorKeys(
+		extendedKey, candidates, 2, defaultAnchorNumKeys,
+	)
+	require.NoError(t, err)
+	require.Len(t, targets, 2)
+
+	require.Equal(t, uint32(0), targets[0].outpoint.Index)
+	require.Equal(t, multisigKey.KeyLocator, targets[0].keyDesc.KeyLocator)
+	require.Equal(t, script, targets[0].script)
+	require.Nil(t, targets[0].scriptTree)
+
+	require.Equal(t, uint32(2), targets[1].outpoint.Index)
+	require.Equal(t, paymentKey.KeyLocator, targets[1].keyDesc.KeyLocator)
+	require.NotNil(t, targets[1].scriptTree)
+
+	// Only the given number of keys is derived per key family.
+	targets, err = findAnchorKeys(extendedKey, candidates, 2, 20)
+	require.NoError(t, err)
+	require.Len(t, targets, 1)
+	require.Equal(t, uint32(0), targets[0].outpoint.Index)
+}
+
+func TestSelectSponsorInputs(t *testing.T) {
+	utxos := []*btc.WalletUTXO{{
+		WalletAddress: &btc.WalletAddress{
+			Account: btc.WalletAccountP2WKH,
+		},
+		Value:     15_000,
+		Confirmed: true,
+	}, {
+		WalletAddress: &btc.WalletAddress{
+			Account: btc.WalletAccountP2TR,
+		},
+		Value:     100_000,
+		Confirmed: false,
+	}, {
+		WalletAddress: &btc.WalletAddress{
+			Account: btc.WalletAccountNP2WKH,
+		},
+		Value:     20_000,
+		Confirmed: true,
+	}}
+	feeRate := chainfee.SatPerKVByte(1000 * 10).FeePerKWeight()
+
+	// A low fee only requires the largest confirmed UTXO, the unconfirmed
+	// one must never be selected.
+	var estimator input.TxWeightEstimator
+	estimator.AddWitnessInput(input.AnchorWitnessSize)
+	estimator.AddP2WKHOutput()
+	selected, err := selectSponsorInputs(
+		utxos, &estimator, feeRate, nil, anchorOutputValue,
+	)
+	require.NoError(t, err)
+	require.Len(t, selected, 1)
+	require.Equal(t, uint64(20_000), selected[0].Value)
+
+	// A very high fee rate requires both confirmed UTXOs.
+	estimator = input.TxWeightEstimator{}
+	estimator.AddWitnessInput(input.AnchorWitnessSize)
+	estimator.AddP2WKHOutput()
+	feeRate = chainfee.SatPerKVByte(1000 * 100).FeePerKWeight()
+	selected, err = selectSponsorInputs(
+		utxos, &estimator, feeRate, nil, anchorOutputValue,
+	)
+	require.NoError(t, err)
+	require.Len(t, selected, 2)
+
+	// And an even higher one can't be paid for.
+	estimator = input.TxWeightEstimator{}
+	feeRate = chainfee.SatPerKVByte(1000 * 500).FeePerKWeight()
+	_, err = selectSponsorInputs(
+		utxos, &estimator, feeRate, nil, anchorOutputValue,
+	)
+	require.ErrorContains(t, err, "not enough confirmed wallet funds")
+
+	// A low fee rate for the transaction alone isn't enough if it also
+	// needs to pay for a large parent that pays no fee at all.
+	estimator = input.TxWeightEstimator{}
+	estimator.AddWitnessInput(input.AnchorWitnessSize)
+	estimator.AddP2WKHOutput()
+	feeRate = chainfee.SatPerKVByte(1000 * 20).FeePerKWeight()
+	parents := []*packageParent{{
+		weight: 4 * 1_000,
+	}}
+	selected, err = selectSponsorInputs(
+		utxos, &estimator, feeRate, parents, anchorOutputValue,
+	)
+	require.NoError(t, err)
+	require.Len(t, selected, 2)
+}
+
+func TestPackageFee(t *testing.T) {
+	feeRate := chainfee.SatPerKVByte(1000 * 10).FeePerKWeight()
+
+	// Without parents, only the child's weight counts.
+	require.Equal(t, btcutil.Amount(1_500), packageFee(feeRate, 600, nil))
+
+	// A parent that pays nothing needs to be paid for by the child.
+	parents := []*packageParent{{
+		weight: 1_000,
+		fee:    0,
+	}}
+	require.Equal(
+		t, btcutil.Amount(4_000), packageFee(feeRate, 600, parents),
+	)
+
+	// Parent fees are subtracted from the package fee.
+	parents = append(parents, &packageParent{
+		weight: 400,
+		fee:    500,
+	})
+	require.Equal(
+		t, btcutil.Amount(4_500), packageFee(feeRate, 600, parents),
+	)
+
+	// But the child never pays less than its own fee.
+	parents = []*packageParent{{
+		weight: 400,
+		fee:    10_000,
+	}}
+	require.Equal(
+		t, btcutil.Amount(1_500), packageFee(feeRate, 600, parents),
+	)
+}
+
+func TestPackageVersion(t *testing.T) {
+	testCases := []struct {
+		name            string
+		parents         []*packageParent
+		expectedVersion int32
+		expectedErr     string
+	}{{
+		name:            "no parents",
+		expectedVersion: 2,
+	}, {
+		name: "v2 parents",
+		parents: []*packageParent{
+			{version: 2}, {version: 2},
+		},
+		expectedVersion: 2,
+	}, {
+		name: "single TRUC parent",
+		parents: []*packageParent{
+			{version: 3},
+		},
+		expectedVersion: 3,
+	}, {
+		name: "TRUC and other parents",
+		parents: []*packageParent{
+			{version: 3}, {version: 2},
+		},
+		expectedErr: "can't be pulled together",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			version, err := packageVersion(tc.parents)
+			if tc.expectedErr != "" {
+				require.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedVersion, version)
+		})
+	}
+}
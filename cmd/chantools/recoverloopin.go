@@ -32,7 +32,7 @@ type recoverLoopInCommand struct {
 	TxID          string
 	Vout          uint32
 	SwapHash      string
-	SweepAddr     string
+	SweepAddrs    []string
 	OutputAmt     uint64
 	FeeRate       uint32
 	StartKeyIndex int
@@ -79,10 +79,8 @@ func newRecoverLoopInCommand() *cobra.Command {
 		&cc.LoopDbDir, "loop_db_dir", "", "path to the loop "+
 			"database directory, where the loop.db file is located",
 	)
-	cc.cmd.Flags().StringVar(
-		&cc.SweepAddr, "sweepaddr", "", "address to recover the funds "+
-			"to; specify '"+lnd.AddressDeriveFromWallet+"' to "+
-			"derive a new address from the seed automatically",
+	cc.cmd.Flags().StringArrayVar(
+		&cc.SweepAddrs, "sweepaddr", nil, sweepAddrFlagDesc,
 	)
 	cc.cmd.Flags().Uint32Var(
 		&cc.FeeRate, "feerate", 0, "fee rate to "+
@@ -136,10 +134,7 @@ func (c *recoverLoopInCommand) Execute(_ *cobra.Command, _ []string) error {
 		return errors.New("loop_db_dir is required")
 	}
 
-	err = lnd.CheckAddress(
-		c.SweepAddr, chainParams, true, "sweep", lnd.AddrTypeP2WKH,
-		lnd.AddrTypeP2TR,
-	)
+	sweepDests, err := parseSweepAddrs(c.SweepAddrs)
 	if err != nil {
 		return err
 	}
@@ -227,8 +222,8 @@ func (c *recoverLoopInCommand) Execute(_ *cobra.Command, _ []string) error {
 
 	// Get the destination address.
 	var estimator input.TxWeightEstimator
-	sweepScript, err := lnd.PrepareWalletAddress(
-		c.SweepAddr, chainParams, &estimator, extendedKey, "sweep",
+	sweepOutputs, err := lnd.PrepareSweepOutputs(
+		sweepDests, chainParams, &estimator, extendedKey, "sweep",
 	)
 	if err != nil {
 		return err
@@ -267,11 +262,13 @@ func (c *recoverLoopInCommand) Execute(_ *cobra.Command, _ []string) error {
 		Sequence:         0,
 	})
 
-	// Add output for the destination address.
-	sweepTx.AddTxOut(&wire.TxOut{
-		PkScript: sweepScript,
-		Value:    int64(outputValue) - int64(fee),
-	})
+	// Add the outputs for the destination addresses.
+	sweepTx.TxOut, err = sweepOutputs.TxOuts(
+		int64(outputValue) - int64(fee),
+	)
+	if err != nil {
+		return err
+	}
 
 	// If the htlc is version 2, we need to brute force the key locator, as
 	// it is not stored in the database.
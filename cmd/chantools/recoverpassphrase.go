@@ -0,0 +1,350 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/aezeed"
+	"github.com/lightningnetwork/lnd/lncfg"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// recoverPassphraseLogInterval is the minimum time between two progress
+	// log messages.
+	recoverPassphraseLogInterval = 10 * time.Second
+)
+
+type recoverPassphraseCommand struct {
+	Wordlist   string
+	Mutate     bool
+	Mask       string
+	Guesses    []string
+	Workers    int
+	Checkpoint string
+
+	cmd *cobra.Command
+}
+
+// passphraseCheckpoint is the content of the checkpoint file of a passphrase
+// recovery.
+type passphraseCheckpoint struct {
+	// Source is the hash of the description of the candidate source and of
+	// the mnemonic, so we don't resume with a different source or seed by
+	// accident. We don't store the description itself as it can contain
+	// passphrase guesses.
+	Source string `json:"source"`
+
+	// Tested is the number of candidates that were already tested.
+	Tested uint64 `json:"tested"`
+}
+
+func newRecoverPassphraseCommand() *cobra.Command {
+	cc := &recoverPassphraseCommand{}
+	cc.cmd = &cobra.Command{
+		Use:   "recoverpassphrase",
+		Short: "Recover the forgotten passphrase of an lnd aezeed",
+		Long: `Tries to find the passphrase of an lnd aezeed (cipher
+seed) by testing a list of candidates. The words of the seed must be correct,
+use the recoverseed command first if some of them are unknown.
+
+The candidates are created by exactly one of the following sources:
+  --wordlist: A file with one candidate per line.
+  --mask: All passphrases matching a mask like the ones of hashcat. Every
+    position is either a literal character or one of the placeholders ?l
+    (lower case letter), ?u (upper case letter), ?d (digit), ?s (special
+    character) or ?a (any of those). Use ?? for a literal question mark.
+  --guess: One or more passphrases that are almost right. Common case and leet
+    variations (for example "P4ssw0rd" for "password") of each guess are tested
+    as well.
+With --mutate, the case and leet variations of each line of the word list are
+tested too.
+
+Deciphering an aezeed is slow on purpose, only a few passphrases per second and
+CPU can be tested. The progress is logged regularly together with an estimate
+of the remaining time. With --checkpoint, the number of tested candidates is
+written to the given file, so an interrupted recovery can be resumed by running
+the same command again with the same seed.`,
+		Example: `chantools recoverpassphrase --mask "satoshi?d?d?d?d" \
+	--checkpoint recoverpassphrase.json
+
+chantools recoverpassphrase --guess correcthorse --guess batterystaple`,
+		RunE: cc.Execute,
+	}
+	cc.cmd.Flags().StringVar(
+		&cc.Wordlist, "wordlist", "", "file with one passphrase "+
+			"candidate per line",
+	)
+	cc.cmd.Flags().BoolVar(
+		&cc.Mutate, "mutate", false, "also test the case and leet "+
+			"variations of each line of the word list",
+	)
+	cc.cmd.Flags().StringVar(
+		&cc.Mask, "mask", "", "mask the passphrase candidates are "+
+			"created from, for example 'pass?d?d'",
+	)
+	cc.cmd.Flags().StringArrayVar(
+		&cc.Guesses, "guess", nil, "passphrase guess of which the "+
+			"case and leet variations are tested; can be "+
+			"specified multiple times",
+	)
+	cc.cmd.Flags().IntVar(
+		&cc.Workers, "workers", 0, "number of passphrases that are "+
+			"tested in parallel; leave at 0 to use one per CPU",
+	)
+	cc.cmd.Flags().StringVar(
+		&cc.Checkpoint, "checkpoint", "", "file to store the progress "+
+			"in, to be able to resume an interrupted recovery",
+	)
+
+	return cc.cmd
+}
+
+func (c *recoverPassphraseCommand) Execute(_ *cobra.Command, _ []string) error {
+	source, description, cleanup, err := c.passphraseSource()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	words, err := lnd.ReadMnemonicWords()
+	if err != nil {
+		return err
+	}
+	if len(words) != aezeed.NumMnemonicWords {
+		return fmt.Errorf("wrong cipher seed mnemonic length: got %d "+
+			"words, expecting %d words", len(words),
+			aezeed.NumMnemonicWords)
+	}
+
+	var mnemonic aezeed.Mnemonic
+	copy(mnemonic[:], words)
+	checkpointKey := passphraseCheckpointKey(description, mnemonic)
+
+	// If there is a checkpoint of a previous run with the same source and
+	// seed, we continue where it stopped.
+	checkpoint := &passphraseCheckpoint{
+		Source: checkpointKey,
+	}
+	if c.Checkpoint != "" {
+		checkpoint, err = readPassphraseCheckpoint(
+			lncfg.CleanAndExpandPath(c.Checkpoint), checkpointKey,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	total := source.Total()
+	if checkpoint.Tested > 0 {
+		log.Infof("Resuming from checkpoint, skipping %d of %d "+
+			"candidates", checkpoint.Tested, total)
+	} else {
+		log.Infof("Testing %d passphrase candidates", total)
+	}
+
+	var (
+		start   = time.Now()
+		lastLog = start
+	)
+	recovery := &lnd.PassphraseRecovery{
+		Mnemonic:   mnemonic,
+		Source:     source,
+		NumWorkers: c.Workers,
+		Skip:       checkpoint.Tested,
+		Progress: func(tested uint64) error {
+			now := time.Now()
+			if now.Sub(lastLog) >= recoverPassphraseLogInterval {
+				logPassphraseProgress(
+					tested, checkpoint.Tested, total,
+					now.Sub(start),
+				)
+				lastLog = now
+			}
+
+			if c.Checkpoint == "" {
+				return nil
+			}
+
+			return writePassphraseCheckpoint(
+				lncfg.CleanAndExpandPath(c.Checkpoint),
+				&passphraseCheckpoint{
+					Source: checkpointKey,
+					Tested: tested,
+				},
+			)
+		},
+	}
+
+	passphrase, cipherSeed, err := recovery.Run()
+	if err != nil {
+		return fmt.Errorf("error recovering passphrase: %w", err)
+	}
+	if cipherSeed == nil {
+		return errors.New("passphrase not found, none of the " +
+			"candidates deciphers the seed")
+	}
+
+	if passphrase == "" {
+		fmt.Println("\nThe seed doesn't have a passphrase.")
+	} else {
+		fmt.Printf("\nFound passphrase: %s\n", passphrase)
+	}
+	fmt.Printf("Seed birthday: %s\n",
+		cipherSeed.BirthdayTime().Format("2006-01-02"))
+
+	// For the tests, also log as trace level which is disabled by default.
+	log.Tracef("Recovered passphrase: '%s'", passphrase)
+
+	return nil
+}
+
+// passphraseSource creates the passphrase source selected by the flags. Next to
+// the source, it returns its description for the checkpoint and a cleanup
+// function.
+func (c *recoverPassphraseCommand) passphraseSource() (lnd.PassphraseSource,
+	string, func(), error) {
+
+	numSources := 0
+	for _, isSet := range []bool{
+		c.Wordlist != "", c.Mask != "", len(c.Guesses) > 0,
+	} {
+		if isSet {
+			numSources++
+		}
+	}
+	if numSources != 1 {
+		return nil, "", nil, errors.New("exactly one of --wordlist, " +
+			"--mask or --guess must be specified")
+	}
+	if c.Mutate && c.Wordlist == "" {
+		return nil, "", nil, errors.New("--mutate can only be used " +
+			"with --wordlist")
+	}
+
+	var (
+		source      lnd.PassphraseSource
+		description string
+		cleanup     = func() {}
+	)
+	switch {
+	case c.Wordlist != "":
+		wordlistSource, err := lnd.NewWordlistSource(
+			lncfg.CleanAndExpandPath(c.Wordlist), c.Mutate,
+		)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error reading word "+
+				"list: %w", err)
+		}
+		source = wordlistSource
+		description = fmt.Sprintf("wordlist:%s:%v:%d", c.Wordlist,
+			c.Mutate, wordlistSource.Total())
+		cleanup = func() {
+			_ = wordlistSource.Close()
+		}
+
+	case c.Mask != "":
+		maskSource, err := lnd.NewMaskSource(c.Mask)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error parsing mask: "+
+				"%w", err)
+		}
+		source = maskSource
+		description = "mask:" + c.Mask
+
+	default:
+		mutationSource, err := lnd.NewMutationSource(c.Guesses)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error mutating "+
+				"guesses: %w", err)
+		}
+		source = mutationSource
+		description = "guess:" + strings.Join(c.Guesses, "\x00")
+	}
+
+	return source, description, cleanup, nil
+}
+
+// passphraseCheckpointKey returns the hash of the source description and the
+// mnemonic that identifies the checkpoint of a passphrase recovery.
+func passphraseCheckpointKey(description string,
+	mnemonic aezeed.Mnemonic) string {
+
+	hash := sha256.Sum256([]byte(
+		description + "\x00" + strings.Join(mnemonic[:], " "),
+	))
+	return hex.EncodeToString(hash[:])
+}
+
+// logPassphraseProgress logs the number of tested candidates, the current rate
+// and the estimated remaining time.
+func logPassphraseProgress(tested, skipped, total uint64,
+	elapsed time.Duration) {
+
+	rate := float64(tested-skipped) / elapsed.Seconds()
+	if rate <= 0 {
+		log.Infof("Tested %d of %d candidates", tested, total)
+		return
+	}
+
+	remaining := time.Duration(
+		float64(total-tested) / rate * float64(time.Second),
+	)
+	log.Infof("Tested %d of %d candidates (%.2f%%), %.1f candidates/s, "+
+		"ETA %v", tested, total, float64(tested)*100/float64(total),
+		rate, remaining.Round(time.Second))
+}
+
+// readPassphraseCheckpoint reads the checkpoint from the given file. If the
+// file doesn't exist, an empty checkpoint is returned.
+func readPassphraseCheckpoint(fileName,
+	source string) (*passphraseCheckpoint, error) {
+
+	checkpoint := &passphraseCheckpoint{
+		Source: source,
+	}
+
+	content, err := os.ReadFile(fileName)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return checkpoint, nil
+
+	case err != nil:
+		return nil, fmt.Errorf("error reading checkpoint: %w", err)
+	}
+
+	if err := json.Unmarshal(content, checkpoint); err != nil {
+		return nil, fmt.Errorf("error parsing checkpoint: %w", err)
+	}
+	if checkpoint.Source != source {
+		return nil, fmt.Errorf("checkpoint %s belongs to different "+
+			"passphrase candidates, use a different file", fileName)
+	}
+
+	return checkpoint, nil
+}
+
+// writePassphraseCheckpoint writes the checkpoint to the given file. The file
+// is replaced atomically, so an interruption never leaves a broken checkpoint.
+func writePassphraseCheckpoint(fileName string,
+	checkpoint *passphraseCheckpoint) error {
+
+	content, err := json.MarshalIndent(checkpoint, "", " ")
+	if err != nil {
+		return err
+	}
+
+	tempFile := fileName + ".tmp"
+	if err := os.WriteFile(tempFile, content, 0600); err != nil {
+		return fmt.Errorf("error writing checkpoint: %w", err)
+	}
+
+	return os.Rename(tempFile, fileName)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/aezeed"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverPassphrase(t *testing.T) {
+	h := newHarness(t)
+
+	checkpoint := h.tempFile("checkpoint.json")
+	recoverPassphrase := &recoverPassphraseCommand{
+		Mask:       "testnet?d",
+		Workers:    1,
+		Checkpoint: checkpoint,
+	}
+
+	t.Setenv(lnd.MnemonicEnvName, seedAezeedWithPassphrase)
+
+	// The first three candidates were already tested in a previous run.
+	err := writePassphraseCheckpoint(checkpoint, &passphraseCheckpoint{
+		Source: "wrong",
+		Tested: 3,
+	})
+	require.NoError(t, err)
+	err = recoverPassphrase.Execute(nil, nil)
+	require.ErrorContains(t, err, "belongs to different")
+
+	// The checkpoint of a different seed must not be used either.
+	var mnemonic, otherMnemonic aezeed.Mnemonic
+	copy(mnemonic[:], strings.Fields(seedAezeedWithPassphrase))
+	copy(otherMnemonic[:], strings.Fields(seedAezeedNoPassphrase))
+
+	_, description, _, err := recoverPassphrase.passphraseSource()
+	require.NoError(t, err)
+	err = writePassphraseCheckpoint(checkpoint, &passphraseCheckpoint{
+		Source: passphraseCheckpointKey(description, otherMnemonic),
+		Tested: 3,
+	})
+	require.NoError(t, err)
+	err = recoverPassphrase.Execute(nil, nil)
+	require.ErrorContains(t, err, "belongs to different")
+
+	checkpointKey := passphraseCheckpointKey(description, mnemonic)
+	err = writePassphraseCheckpoint(checkpoint, &passphraseCheckpoint{
+		Source: checkpointKey,
+		Tested: 3,
+	})
+	require.NoError(t, err)
+
+	err = recoverPassphrase.Execute(nil, nil)
+	require.NoError(t, err)
+
+	h.assertLogContains("Recovered passphrase: '" + testPassPhrase + "'")
+
+	// None of the guesses is right, but the checkpoint is updated.
+	require.NoError(t, os.Remove(checkpoint))
+	recoverPassphrase = &recoverPassphraseCommand{
+		Guesses:    []string{"xyz"},
+		Workers:    1,
+		Checkpoint: checkpoint,
+	}
+	err = recoverPassphrase.Execute(nil, nil)
+	require.ErrorContains(t, err, "passphrase not found")
+
+	resumed, err := readPassphraseCheckpoint(checkpoint, checkpointKey)
+	require.ErrorContains(t, err, "belongs to different")
+	require.Nil(t, resumed)
+
+	recoverPassphrase.Mask = "x"
+	err = recoverPassphrase.Execute(nil, nil)
+	require.ErrorContains(t, err, "exactly one of")
+}
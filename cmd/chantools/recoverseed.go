@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/aezeed"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultRecoverSeedMaxDistance = 2
+	defaultRecoverSeedNumAddrs    = 100
+)
+
+type recoverSeedCommand struct {
+	MaxDistance int
+	Workers     int
+	NodePubKey  string
+	Address     string
+	NumAddrs    uint32
+
+	cmd *cobra.Command
+}
+
+func newRecoverSeedCommand() *cobra.Command {
+	cc := &recoverSeedCommand{}
+	cc.cmd = &cobra.Command{
+		Use: "recoverseed",
+		Short: "Recover missing or misspelled words of an lnd " +
+			"aezeed",
+		Long: `Tries to recover an lnd aezeed (cipher seed) of which one
+or two words are unknown or were written down incorrectly.
+
+Enter the 24 words of the seed as usual but use a question mark (?) for every
+word that is unknown. A word that is not in the word list is assumed to be
+misspelled and is replaced by all words of the list that are at most
+--maxdistance edits (added, removed or changed letters) away from it. To also
+test other candidates for a word that is in the word list, replace it with a
+question mark.
+
+Every combination of candidate words is first checked against the version and
+the checksum encoded in the seed, which rules out almost all wrong combinations
+very quickly. Only the remaining ones are deciphered with the passphrase, which
+is slow on purpose. Two unknown words can be recovered in a few seconds to
+minutes, depending on the number of CPUs. Each additional unknown word
+multiplies the time by 2048.
+
+If multiple seeds are found or to make sure the right seed was found, the
+identity pubkey of the node (--nodepubkey) or an address of the on-chain wallet
+(--address) can be specified. Only seeds that derive that key or address are
+shown then.`,
+		Example: `chantools recoverseed \
+	--nodepubkey 03xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx`,
+		RunE: cc.Execute,
+	}
+	cc.cmd.Flags().IntVar(
+		&cc.MaxDistance, "maxdistance", defaultRecoverSeedMaxDistance,
+		"maximum number of edits between a misspelled word and its "+
+			"candidates from the word list",
+	)
+	cc.cmd.Flags().IntVar(
+		&cc.Workers, "workers", 0, "number of word combinations that "+
+			"are tested in parallel; leave at 0 to use one per CPU",
+	)
+	cc.cmd.Flags().StringVar(
+		&cc.NodePubKey, "nodepubkey", "", "identity pubkey of the "+
+			"node the seed belongs to, to confirm a recovered seed",
+	)
+	cc.cmd.Flags().StringVar(
+		&cc.Address, "address", "", "address of the on-chain wallet "+
+			"of the seed, to confirm a recovered seed",
+	)
+	cc.cmd.Flags().Uint32Var(
+		&cc.NumAddrs, "numaddrs", defaultRecoverSeedNumAddrs, "number "+
+			"of addresses per branch of each wallet account that "+
+			"are derived to find the address given with --address",
+	)
+
+	return cc.cmd
+}
+
+func (c *recoverSeedCommand) Execute(_ *cobra.Command, _ []string) error {
+	var (
+		nodePubKey []byte
+		address    btcutil.Address
+		err        error
+	)
+	if c.NodePubKey != "" {
+		nodePubKey, err = hex.DecodeString(c.NodePubKey)
+		if err != nil {
+			return fmt.Errorf("error decoding node pubkey: %w", err)
+		}
+	}
+	if c.Address != "" {
+		address, err = btcutil.DecodeAddress(c.Address, chainParams)
+		if err != nil {
+			return fmt.Errorf("error decoding address: %w", err)
+		}
+	}
+
+	words, err := lnd.ReadMnemonicWords()
+	if err != nil {
+		return err
+	}
+	if len(words) != aezeed.NumMnemonicWords {
+		return fmt.Errorf("wrong cipher seed mnemonic length: got %d "+
+			"words, expecting %d words, use '%s' for unknown words",
+			len(words), aezeed.NumMnemonicWords,
+			lnd.UnknownWordPlaceholder)
+	}
+
+	candidates := make([][]string, len(words))
+	for i, word := range words {
+		candidates[i], err = lnd.MnemonicWordCandidates(
+			word, c.MaxDistance,
+		)
+		if err != nil {
+			return fmt.Errorf("error finding candidates for word "+
+				"%d: %w", i+1, err)
+		}
+
+		if len(candidates[i]) > 1 {
+			log.Infof("Word %d (%s): %d candidates", i+1, word,
+				len(candidates[i]))
+		}
+	}
+
+	passphrase, err := lnd.ReadPassphrase("doesn't have")
+	if err != nil {
+		return err
+	}
+
+	recovery := &lnd.SeedRecovery{
+		Candidates: candidates,
+		Passphrase: passphrase,
+		NumWorkers: c.Workers,
+	}
+	if nodePubKey != nil || address != nil {
+		recovery.Confirm = func(seed *aezeed.CipherSeed) (bool, error) {
+			return c.confirmSeed(seed, nodePubKey, address)
+		}
+	}
+
+	numCombinations, err := recovery.NumCombinations()
+	if err != nil {
+		return err
+	}
+	log.Infof("Testing %d word combinations", numCombinations)
+
+	results, err := recovery.Run()
+	if err != nil {
+		return fmt.Errorf("error recovering seed: %w", err)
+	}
+	if len(results) == 0 {
+		return errors.New("no matching seed found, check the known " +
+			"words and the passphrase or increase --maxdistance")
+	}
+
+	log.Infof("Found %d matching seed(s)", len(results))
+	for _, result := range results {
+		fmt.Printf("\nSeed with birthday %s:\n",
+			result.CipherSeed.BirthdayTime().Format("2006-01-02"))
+		printCipherSeedWords(result.Mnemonic[:])
+
+		// For the tests, also log as trace level which is disabled by
+		// default.
+		log.Tracef("Recovered mnemonic: %s",
+			strings.Join(result.Mnemonic[:], " "))
+	}
+
+	return nil
+}
+
+// confirmSeed returns true if the given seed derives the given node pubkey and
+// address, if they are set.
+func (c *recoverSeedCommand) confirmSeed(seed *aezeed.CipherSeed,
+	nodePubKey []byte, address btcutil.Address) (bool, error) {
+
+	extendedKey, err := hdkeychain.NewMaster(seed.Entropy[:], chainParams)
+	if err != nil {
+		return false, fmt.Errorf("error deriving master extended key: "+
+			"%w", err)
+	}
+
+	if nodePubKey != nil {
+		keyRing := &lnd.HDKeyRing{
+			ExtendedKey: extendedKey,
+			ChainParams: chainParams,
+		}
+		identityKey, err := keyRing.NodePubKey()
+		if err != nil {
+			return false, fmt.Errorf("error deriving identity "+
+				"key: %w", err)
+		}
+
+		if !bytes.Equal(identityKey.SerializeCompressed(), nodePubKey) {
+			return false, nil
+		}
+	}
+
+	if address == nil {
+		return true, nil
+	}
+
+	// We only need to know whether the address belongs to the seed, the
+	// private key itself isn't needed.
+	privKey, err := findWalletAddressKey(extendedKey, address, c.NumAddrs)
+	if err != nil {
+		return false, err
+	}
+
+	return privKey != nil, nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverSeed(t *testing.T) {
+	h := newHarness(t)
+
+	// We don't know the 3rd word and misspelled the 10th.
+	words := strings.Split(seedAezeedWithPassphrase, " ")
+	words[2] = lnd.UnknownWordPlaceholder
+	words[9] = words[9][1:]
+
+	recoverSeed := &recoverSeedCommand{
+		MaxDistance: 1,
+		NodePubKey:  walletContent,
+	}
+
+	t.Setenv(lnd.MnemonicEnvName, strings.Join(words, " "))
+	t.Setenv(lnd.PassphraseEnvName, testPassPhrase)
+
+	err := recoverSeed.Execute(nil, nil)
+	require.NoError(t, err)
+
+	h.assertLogContains(seedAezeedWithPassphrase)
+
+	// A seed that doesn't belong to the node must not be found.
+	recoverSeed.NodePubKey = strings.Replace(walletContent, "03", "02", 1)
+	err = recoverSeed.Execute(nil, nil)
+	require.ErrorContains(t, err, "no matching seed found")
+}
@@ -1,29 +1,36 @@
 package main
 
 import (
+	"bytes"
 	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"regexp"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/lightninglabs/chantools/btc/fasthd"
 	"github.com/lightninglabs/chantools/dataformat"
 	"github.com/lightninglabs/chantools/lnd"
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/input"
 	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/lnencrypt"
 	"github.com/spf13/cobra"
 )
 
 var (
 	defaultNumKeys uint32 = 5000
-	cache          []*cacheEntry
+	cache          *keyCache
 
 	errAddrNotFound = errors.New("addr not found")
 
@@ -41,6 +48,7 @@ type rescueClosedCommand struct {
 	CommitPoint string
 	LndLog      string
 	NumKeys     uint32
+	CacheFile   string
 
 	rootKey *rootKey
 	inputs  *inputFlags
@@ -76,7 +84,13 @@ specify the --fromsummary and --lnd_log flags to automatically look for force
 close addresses in the summary and the corresponding commit points in the
 lnd log file. This only works if lnd is running the fund-recovery branch of my
 guggero/lnd (https://github.com/guggero/lnd/releases) fork and only if the
-debuglevel is set to debug (lnd.conf, set 'debuglevel=debug').`,
+debuglevel is set to debug (lnd.conf, set 'debuglevel=debug').
+
+The payment base point keys are derived and matched on all available CPU cores.
+When running the command multiple times (for example against new summaries or
+log files), the --cache_file flag can be used to store the derived public keys
+in a file that is encrypted with a key derived from the seed. Subsequent runs
+then only need to derive the keys that are not yet in the cache.`,
 		Example: `chantools rescueclosed \
 	--fromsummary results/summary-xxxxxx.json \
 	--channeldb ~/.lnd/data/graph/mainnet/channel.db
@@ -84,7 +98,8 @@ debuglevel is set to debug (lnd.conf, set 'debuglevel=debug').`,
 chantools rescueclosed --force_close_addr bc1q... --commit_point 03xxxx
 
 chantools rescueclosed --fromsummary results/summary-xxxxxx.json \
-	--lnd_log ~/.lnd/logs/bitcoin/mainnet/lnd.log`,
+	--lnd_log ~/.lnd/logs/bitcoin/mainnet/lnd.log \
+	--num_keys 100000 --cache_file results/rescueclosed-keys.cache`,
 		RunE: cc.Execute,
 	}
 	cc.cmd.Flags().StringVar(
@@ -110,6 +125,12 @@ chantools rescueclosed --fromsummary results/summary-xxxxxx.json \
 		&cc.NumKeys, "num_keys", defaultNumKeys, "the number of keys "+
 			"to derive for the brute force attack",
 	)
+	cc.cmd.Flags().StringVar(
+		&cc.CacheFile, "cache_file", "", "optional file to store the "+
+			"derived public keys in (encrypted with a key derived "+
+			"from the seed) so they don't need to be derived "+
+			"again in subsequent runs",
+	)
 	cc.rootKey = newRootKey(cc.cmd, "decrypting the backup")
 	cc.inputs = newInputFlags(cc.cmd)
 
@@ -143,7 +164,8 @@ func (c *rescueClosedCommand) Execute(_ *cobra.Command, _ []string) error {
 				"db: %w", err)
 		}
 		return rescueClosedChannels(
-			c.NumKeys, extendedKey, entries, commitPoints,
+			c.NumKeys, c.CacheFile, extendedKey, entries,
+			commitPoints,
 		)
 
 	case c.Addr != "":
@@ -165,7 +187,8 @@ func (c *rescueClosedCommand) Execute(_ *cobra.Command, _ []string) error {
 		}
 
 		return rescueClosedChannel(
-			c.NumKeys, extendedKey, targetAddr, commitPoint,
+			c.NumKeys, c.CacheFile, extendedKey, targetAddr,
+			commitPoint,
 		)
 
 	case c.LndLog != "":
@@ -181,7 +204,8 @@ func (c *rescueClosedCommand) Execute(_ *cobra.Command, _ []string) error {
 				"log file: %w", err)
 		}
 		return rescueClosedChannels(
-			c.NumKeys, extendedKey, entries, commitPoints,
+			c.NumKeys, c.CacheFile, extendedKey, entries,
+			commitPoints,
 		)
 
 	default:
@@ -253,11 +277,11 @@ func commitPointsFromLogFile(lndLog string) ([]*btcec.PublicKey, error) {
 	return result, nil
 }
 
-func rescueClosedChannels(numKeys uint32, extendedKey *hdkeychain.ExtendedKey,
-	entries []*dataformat.SummaryEntry,
+func rescueClosedChannels(numKeys uint32, cacheFile string,
+	extendedKey *hdkeychain.ExtendedKey, entries []*dataformat.SummaryEntry,
 	possibleCommitPoints []*btcec.PublicKey) error {
 
-	err := fillCache(numKeys, extendedKey)
+	err := fillCache(numKeys, extendedKey, cacheFile)
 	if err != nil {
 		return err
 	}
@@ -328,8 +352,9 @@ outer:
 	return os.WriteFile(fileName, summaryBytes, 0644)
 }
 
-func rescueClosedChannel(numKeys uint32, extendedKey *hdkeychain.ExtendedKey,
-	addr btcutil.Address, commitPoint *btcec.PublicKey) error {
+func rescueClosedChannel(numKeys uint32, cacheFile string,
+	extendedKey *hdkeychain.ExtendedKey, addr btcutil.Address,
+	commitPoint *btcec.PublicKey) error {
 
 	// Make the check on the decoded address according to the active
 	// network (testnet or mainnet only).
@@ -348,7 +373,7 @@ func rescueClosedChannel(numKeys uint32, extendedKey *hdkeychain.ExtendedKey,
 		return errors.New("address: must be a bech32 P2WPKH address")
 	}
 
-	err := fillCache(numKeys, extendedKey)
+	err := fillCache(numKeys, extendedKey, cacheFile)
 	if err != nil {
 		return err
 	}
@@ -397,100 +422,295 @@ func addrInCache(numKeys uint32, addr string,
 		return "", errors.New("address must be a P2WPKH address")
 	}
 
-	// If the commit point is nil, we try with plain private keys to match
-	// static_remote_key outputs.
-	if perCommitPoint == nil {
-		for i := range numKeys {
-			cacheEntry := cache[i]
-			hashedPubKey := btcutil.Hash160(
-				cacheEntry.pubKey.SerializeCompressed(),
-			)
-			equal := subtle.ConstantTimeCompare(
-				targetPubKeyHash, hashedPubKey,
-			)
-			if equal == 1 {
-				wif, err := btcutil.NewWIF(
-					cacheEntry.privKey, chainParams, true,
-				)
-				if err != nil {
-					return "", err
-				}
-				log.Infof("The private key for addr %s "+
-					"(static_remote_key) found after "+
-					"%d tries: %s", addr, i, wif.String(),
-				)
-				return wif.String(), nil
-			}
+	// If the commit point is nil, we try with plain public keys to match
+	// static_remote_key outputs. Otherwise we tweak each of the cached
+	// payment base point keys with the per_commit_point and see if the
+	// hashed public key corresponds to the target pubKeyHash of the given
+	// address.
+	matchFn := func(basePoint *btcec.PublicKey) bool {
+		pubKey := basePoint
+		if perCommitPoint != nil {
+			pubKey = input.TweakPubKey(basePoint, perCommitPoint)
 		}
+		hashedPubKey := btcutil.Hash160(pubKey.SerializeCompressed())
+		equal := subtle.ConstantTimeCompare(
+			targetPubKeyHash, hashedPubKey,
+		)
+		return equal == 1
+	}
 
+	index, found := cache.find(numKeys, matchFn)
+	if !found {
 		return "", errAddrNotFound
 	}
 
-	// Loop through all cached payment base point keys, tweak each of it
-	// with the per_commit_point and see if the hashed public key
-	// corresponds to the target pubKeyHash of the given address.
-	for i := range numKeys {
-		cacheEntry := cache[i]
-		basePoint := cacheEntry.pubKey
-		tweakedPubKey := input.TweakPubKey(basePoint, perCommitPoint)
-		tweakBytes := input.SingleTweakBytes(perCommitPoint, basePoint)
-		tweakedPrivKey := input.TweakPrivKey(
-			cacheEntry.privKey, tweakBytes,
-		)
-		hashedPubKey := btcutil.Hash160(
-			tweakedPubKey.SerializeCompressed(),
-		)
-		equal := subtle.ConstantTimeCompare(
-			targetPubKeyHash, hashedPubKey,
+	privKey, err := cache.privKey(index)
+	if err != nil {
+		return "", err
+	}
+
+	keyType := "static_remote_key"
+	if perCommitPoint != nil {
+		keyType = "tweaked"
+		tweakBytes := input.SingleTweakBytes(
+			perCommitPoint, cache.entries[index].pubKey,
 		)
-		if equal == 1 {
-			wif, err := btcutil.NewWIF(
-				tweakedPrivKey, chainParams, true,
-			)
-			if err != nil {
-				return "", err
+		privKey = input.TweakPrivKey(privKey, tweakBytes)
+	}
+
+	wif, err := btcutil.NewWIF(privKey, chainParams, true)
+	if err != nil {
+		return "", err
+	}
+	log.Infof("The private key for addr %s (%s) found after %d tries: %s",
+		addr, keyType, index, wif.String())
+
+	return wif.String(), nil
+}
+
+// keyCache holds all payment base point keys that are tried during the brute
+// force attack. The private keys are only held in memory for keys that were
+// derived in this run, keys loaded from a cache file are re-derived on demand.
+type keyCache struct {
+	branch  *fasthd.FastDerivation
+	entries []*cacheEntry
+}
+
+// find looks for the lowest index of the first numKeys entries that matches
+// the given function. The work is split across all available CPU cores.
+func (c *keyCache) find(numKeys uint32,
+	matchFn func(*btcec.PublicKey) bool) (uint32, bool) {
+
+	var (
+		numWorkers = uint32(runtime.NumCPU())
+		chunkSize  = (numKeys + numWorkers - 1) / numWorkers
+		wg         sync.WaitGroup
+		best       atomic.Uint32
+	)
+	best.Store(math.MaxUint32)
+	for start := uint32(0); start < numKeys; start += chunkSize {
+		end := min(start+chunkSize, numKeys)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := start; i < end; i++ {
+				// Another worker already found a match at a
+				// lower index, no need to continue.
+				if i > best.Load() {
+					return
+				}
+
+				if !matchFn(c.entries[i].pubKey) {
+					continue
+				}
+
+				// Each worker scans its chunk in ascending
+				// order, so the first match of a worker is
+				// its lowest one.
+				current := best.Load()
+				for i < current &&
+					!best.CompareAndSwap(current, i) {
+
+					current = best.Load()
+				}
+
+				return
 			}
-			log.Infof("The private key for addr %s found after "+
-				"%d tries: %s", addr, i, wif.String(),
-			)
-			return wif.String(), nil
-		}
+		}()
 	}
+	wg.Wait()
 
-	return "", errAddrNotFound
+	result := best.Load()
+	return result, result != math.MaxUint32
 }
 
-func fillCache(numKeys uint32, extendedKey *hdkeychain.ExtendedKey) error {
-	cache = make([]*cacheEntry, numKeys)
+// privKey returns the private key of the entry at the given index, deriving it
+// if necessary.
+func (c *keyCache) privKey(index uint32) (*btcec.PrivateKey, error) {
+	if c.entries[index].privKey != nil {
+		return c.entries[index].privKey, nil
+	}
 
-	for i := range numKeys {
-		key, err := lnd.DeriveChildren(extendedKey, []uint32{
-			lnd.HardenedKeyStart + uint32(keychain.BIP0043Purpose),
-			lnd.HardenedKeyStart + chainParams.HDCoinType,
-			lnd.HardenedKeyStart + uint32(
-				keychain.KeyFamilyPaymentBase,
-			), 0, i,
-		})
+	key := c.branch.Clone()
+	if err := key.Child(index); err != nil {
+		return nil, err
+	}
+
+	return key.PrivKey(), nil
+}
+
+func fillCache(numKeys uint32, extendedKey *hdkeychain.ExtendedKey,
+	cacheFile string) error {
+
+	// We derive the branch key the slow way to make sure we replicate
+	// lnd's derivation exactly, then use the fast derivation for the
+	// individual non-hardened children.
+	branchKey, err := lnd.DeriveChildren(extendedKey, []uint32{
+		lnd.HardenedKeyStart + uint32(keychain.BIP0043Purpose),
+		lnd.HardenedKeyStart + chainParams.HDCoinType,
+		lnd.HardenedKeyStart + uint32(keychain.KeyFamilyPaymentBase),
+		0,
+	})
+	if err != nil {
+		return err
+	}
+	branch, err := fasthd.NewFastDerivationFromKey(branchKey)
+	if err != nil {
+		return err
+	}
+
+	cache = &keyCache{
+		branch:  branch,
+		entries: make([]*cacheEntry, numKeys),
+	}
+
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	numLoaded := uint32(0)
+	if cacheFile != "" {
+		pubKeys, err := readCacheFile(cacheFile, keyRing)
 		if err != nil {
 			return err
 		}
-		privKey, err := key.ECPrivKey()
-		if err != nil {
-			return err
+
+		for numLoaded < numKeys && int(numLoaded) < len(pubKeys) {
+			cache.entries[numLoaded] = &cacheEntry{
+				pubKey: pubKeys[numLoaded],
+			}
+			numLoaded++
 		}
-		pubKey, err := key.ECPubKey()
+
+		log.Infof("Loaded %d keys from cache file %s", numLoaded,
+			cacheFile)
+	}
+
+	var (
+		numWorkers = uint32(runtime.NumCPU())
+		numDerived atomic.Uint32
+		errChan    = make(chan error, numWorkers)
+		wg         sync.WaitGroup
+	)
+	for worker := range numWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			i := numLoaded + worker
+			for ; i < numKeys; i += numWorkers {
+				key := branch.Clone()
+				if err := key.Child(i); err != nil {
+					errChan <- err
+					return
+				}
+				privKey := key.PrivKey()
+				cache.entries[i] = &cacheEntry{
+					privKey: privKey,
+					pubKey:  privKey.PubKey(),
+				}
+
+				count := numDerived.Add(1)
+				if count%10000 == 0 {
+					fmt.Printf("Filled cache with %d of "+
+						"%d keys.\n", numLoaded+count,
+						numKeys)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case err := <-errChan:
+		return fmt.Errorf("error deriving key: %w", err)
+	default:
+	}
+
+	// Only write the cache file if we actually derived new keys.
+	if cacheFile == "" || numDerived.Load() == 0 {
+		return nil
+	}
+
+	log.Infof("Writing %d keys to cache file %s", numKeys, cacheFile)
+	return writeCacheFile(cacheFile, keyRing, cache.entries)
+}
+
+// readCacheFile reads the encrypted list of public keys from the given cache
+// file. If the file doesn't exist, an empty list is returned.
+func readCacheFile(cacheFile string,
+	keyRing keychain.KeyRing) ([]*btcec.PublicKey, error) {
+
+	encrypted, err := os.ReadFile(cacheFile)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, nil
+
+	case err != nil:
+		return nil, fmt.Errorf("error reading cache file %s: %w",
+			cacheFile, err)
+	}
+
+	encrypter, err := lnencrypt.KeyRingEncrypter(keyRing)
+	if err != nil {
+		return nil, fmt.Errorf("error creating encrypter: %w", err)
+	}
+	plaintext, err := encrypter.DecryptPayloadFromReader(
+		bytes.NewReader(encrypted),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting cache file %s, was "+
+			"it created with a different seed? %w", cacheFile, err)
+	}
+
+	if len(plaintext)%btcec.PubKeyBytesLenCompressed != 0 {
+		return nil, fmt.Errorf("invalid cache file %s length %d",
+			cacheFile, len(plaintext))
+	}
+
+	const keyLen = btcec.PubKeyBytesLenCompressed
+	numKeys := len(plaintext) / keyLen
+	pubKeys := make([]*btcec.PublicKey, numKeys)
+	for i := range numKeys {
+		offset := i * keyLen
+		pubKeys[i], err = btcec.ParsePubKey(
+			plaintext[offset : offset+keyLen],
+		)
 		if err != nil {
-			return err
-		}
-		cache[i] = &cacheEntry{
-			privKey: privKey,
-			pubKey:  pubKey,
+			return nil, fmt.Errorf("error parsing cached key %d: "+
+				"%w", i, err)
 		}
+	}
 
-		if i > 0 && i%10000 == 0 {
-			fmt.Printf("Filled cache with %d of %d keys.\n",
-				i, numKeys)
-		}
+	return pubKeys, nil
+}
+
+// writeCacheFile writes the public keys of all given cache entries to the
+// cache file, encrypted with a key derived from the seed.
+func writeCacheFile(cacheFile string, keyRing keychain.KeyRing,
+	entries []*cacheEntry) error {
+
+	plaintext := make(
+		[]byte, 0, len(entries)*btcec.PubKeyBytesLenCompressed,
+	)
+	for _, entry := range entries {
+		plaintext = append(
+			plaintext, entry.pubKey.SerializeCompressed()...,
+		)
 	}
-	return nil
+
+	encrypter, err := lnencrypt.KeyRingEncrypter(keyRing)
+	if err != nil {
+		return fmt.Errorf("error creating encrypter: %w", err)
+	}
+
+	var b bytes.Buffer
+	err = encrypter.EncryptPayloadToWriter(plaintext, &b)
+	if err != nil {
+		return fmt.Errorf("error encrypting cache: %w", err)
+	}
+
+	return os.WriteFile(cacheFile, b.Bytes(), 0600)
 }
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testNumCacheKeys = 200
+	testCacheIndex   = 123
+)
+
+func TestFillCache(t *testing.T) {
+	h := newHarness(t)
+
+	extendedKey, err := hdkeychain.NewKeyFromString(rootKeyAezeed)
+	require.NoError(t, err)
+
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	keyDesc, err := keyRing.DeriveKey(keychain.KeyLocator{
+		Family: keychain.KeyFamilyPaymentBase,
+		Index:  testCacheIndex,
+	})
+	require.NoError(t, err)
+
+	// The keys derived in parallel must match the ones lnd would derive.
+	err = fillCache(testNumCacheKeys, extendedKey, "")
+	require.NoError(t, err)
+	require.Len(t, cache.entries, testNumCacheKeys)
+	require.True(t, cache.entries[testCacheIndex].pubKey.IsEqual(
+		keyDesc.PubKey,
+	))
+
+	// A static_remote_key address must be found.
+	addr, err := lnd.P2WKHAddr(keyDesc.PubKey, chainParams)
+	require.NoError(t, err)
+	wif, err := addrInCache(testNumCacheKeys, addr.String(), nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, wif)
+
+	// Now persist the cache and make sure the keys are read back, with the
+	// private keys being re-derived on demand.
+	cacheFile := h.tempFile("cache.bin")
+	err = fillCache(testNumCacheKeys/2, extendedKey, cacheFile)
+	require.NoError(t, err)
+	err = fillCache(testNumCacheKeys, extendedKey, cacheFile)
+	require.NoError(t, err)
+
+	h.clearLog()
+	err = fillCache(testNumCacheKeys, extendedKey, cacheFile)
+	require.NoError(t, err)
+	h.assertLogContains("Loaded 200 keys from cache file")
+	require.Nil(t, cache.entries[testCacheIndex].privKey)
+	require.True(t, cache.entries[testCacheIndex].pubKey.IsEqual(
+		keyDesc.PubKey,
+	))
+
+	// A tweaked key must also be found with the loaded cache.
+	commitPoint := cache.entries[0].pubKey
+	tweakedPubKey := input.TweakPubKey(keyDesc.PubKey, commitPoint)
+	tweakedAddr, err := lnd.P2WKHAddr(tweakedPubKey, chainParams)
+	require.NoError(t, err)
+	tweakedWif, err := addrInCache(
+		testNumCacheKeys, tweakedAddr.String(), commitPoint,
+	)
+	require.NoError(t, err)
+
+	decodedWif, err := btcutil.DecodeWIF(tweakedWif)
+	require.NoError(t, err)
+	require.True(t, decodedWif.PrivKey.PubKey().IsEqual(tweakedPubKey))
+
+	// A different seed must not be able to read the cache file.
+	otherKey, err := hdkeychain.NewKeyFromString(rootKeyBip39)
+	require.NoError(t, err)
+	err = fillCache(testNumCacheKeys, otherKey, cacheFile)
+	require.ErrorContains(t, err, "error decrypting cache file")
+}
+
+func TestKeyCacheFindLowestIndex(t *testing.T) {
+	_, pubKey := btcec.PrivKeyFromBytes([]byte{1})
+	_, otherPubKey := btcec.PrivKeyFromBytes([]byte{2})
+
+	// The key we look for is at several indexes, the lowest one must
+	// always be returned, no matter which worker finds a match first.
+	c := &keyCache{
+		entries: make([]*cacheEntry, testNumCacheKeys),
+	}
+	for i := range c.entries {
+		c.entries[i] = &cacheEntry{pubKey: otherPubKey}
+	}
+	for _, i := range []int{testCacheIndex, 150, testNumCacheKeys - 1} {
+		c.entries[i].pubKey = pubKey
+	}
+
+	for range 10 {
+		index, found := c.find(
+			testNumCacheKeys, func(key *btcec.PublicKey) bool {
+				return key.IsEqual(pubKey)
+			},
+		)
+		require.True(t, found)
+		require.EqualValues(t, testCacheIndex, index)
+	}
+
+	_, found := c.find(testCacheIndex, func(key *btcec.PublicKey) bool {
+		return key.IsEqual(pubKey)
+	})
+	require.False(t, found)
+}
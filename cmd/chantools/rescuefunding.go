@@ -44,9 +44,9 @@ type rescueFundingCommand struct {
 	LocalKeyIndex uint32
 	RemotePubKey  string
 
-	SweepAddr string
-	FeeRate   uint32
-	APIURL    string
+	SweepAddrs []string
+	FeeRate    uint32
+	APIURL     string
 
 	rootKey *rootKey
 	cmd     *cobra.Command
@@ -112,10 +112,8 @@ chantools rescuefunding \
 			"file), the remote multisig public key can be "+
 			"specified manually",
 	)
-	cc.cmd.Flags().StringVar(
-		&cc.SweepAddr, "sweepaddr", "", "address to recover the funds "+
-			"to; specify '"+lnd.AddressDeriveFromWallet+"' to "+
-			"derive a new address from the seed automatically",
+	cc.cmd.Flags().StringArrayVar(
+		&cc.SweepAddrs, "sweepaddr", nil, sweepAddrFlagDesc,
 	)
 	cc.cmd.Flags().Uint32Var(
 		&cc.FeeRate, "feerate", defaultFeeSatPerVByte, "fee rate to "+
@@ -229,31 +227,29 @@ func (c *rescueFundingCommand) Execute(_ *cobra.Command, _ []string) error {
 		}
 	}
 
-	err = lnd.CheckAddress(
-		c.SweepAddr, chainParams, true, "sweep", lnd.AddrTypeP2WKH,
-		lnd.AddrTypeP2TR,
-	)
+	sweepDests, err := parseSweepAddrs(c.SweepAddrs)
 	if err != nil {
 		return err
 	}
 
 	return rescueFunding(
-		localKeyDesc, remotePubKey, signer, chainOp, c.SweepAddr,
+		localKeyDesc, remotePubKey, signer, chainOp, sweepDests,
 		btcutil.Amount(c.FeeRate), c.APIURL,
 	)
 }
 
 func rescueFunding(localKeyDesc *keychain.KeyDescriptor,
 	remoteKey *btcec.PublicKey, signer *lnd.Signer,
-	chainPoint *wire.OutPoint, sweepAddr string, feeRate btcutil.Amount,
-	apiURL string) error {
+	chainPoint *wire.OutPoint, sweepDests []*lnd.SweepDestination,
+	feeRate btcutil.Amount, apiURL string) error {
 
 	var (
 		estimator input.TxWeightEstimator
 		api       = newExplorerAPI(apiURL)
 	)
-	sweepScript, err := lnd.PrepareWalletAddress(
-		sweepAddr, chainParams, &estimator, signer.ExtendedKey, "sweep",
+	sweepOutputs, err := lnd.PrepareSweepOutputs(
+		sweepDests, chainParams, &estimator, signer.ExtendedKey,
+		"sweep",
 	)
 	if err != nil {
 		return err
@@ -264,9 +260,6 @@ func rescueFunding(localKeyDesc *keychain.KeyDescriptor,
 		PreviousOutPoint: *chainPoint,
 		Sequence:         0,
 	}
-	txOut := &wire.TxOut{
-		PkScript: sweepScript,
-	}
 
 	// Locate the output in the funding TX.
 	tx, err := api.Transaction(chainPoint.Hash.String())
@@ -318,13 +311,16 @@ func rescueFunding(localKeyDesc *keychain.KeyDescriptor,
 	estimator.AddWitnessInput(MultiSigWitnessSize)
 	feeRateKWeight := chainfee.SatPerKVByte(1000 * feeRate).FeePerKWeight()
 	totalFee := feeRateKWeight.FeeForWeight(estimator.Weight())
-	txOut.Value = utxo.Value - int64(totalFee)
+	txOuts, err := sweepOutputs.TxOuts(utxo.Value - int64(totalFee))
+	if err != nil {
+		return err
+	}
 
 	// Let's now create the PSBT as we have everything we need so far.
 	wireTx := &wire.MsgTx{
 		Version: 2,
 		TxIn:    []*wire.TxIn{txIn},
-		TxOut:   []*wire.TxOut{txOut},
+		TxOut:   txOuts,
 	}
 	packet, err := psbt.NewFromUnsignedTx(wireTx)
 	if err != nil {
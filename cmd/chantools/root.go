@@ -52,6 +52,18 @@ var (
 	chainParams = &chaincfg.MainNetParams
 )
 
+// sweepAddrFlagDesc is the description of the --sweepaddr flag that is shared
+// by all commands that sweep funds.
+const sweepAddrFlagDesc = "address to recover the funds to; specify '" +
+	lnd.AddressDeriveFromWallet + "' to derive a new address from the " +
+	"seed automatically; an output descriptor such as " +
+	"wpkh(xpub.../0/*)@5 or wsh(sortedmulti(2,...)) can be used " +
+	"instead of an address, where the optional @ suffix sets the " +
+	"start index of a ranged descriptor; the flag can be specified " +
+	"multiple times to split the funds between several " +
+	"destinations, appending :<weight> to a destination sets its " +
+	"share of the funds (default 1)"
+
 var rootCmd = &cobra.Command{
 	Use:   "chantools",
 	Short: "Chantools helps recover funds from lightning channels",
@@ -99,6 +111,7 @@ func main() {
 
 	rootCmd.AddCommand(
 		newChanBackupCommand(),
+		newChangePasswordCommand(),
 		newClosePoolAccountCommand(),
 		newCreateWalletCommand(),
 		newCompactDBCommand(),
@@ -110,29 +123,39 @@ func main() {
 		newDumpBackupCommand(),
 		newDumpChannelsCommand(),
 		newDocCommand(),
+		newExplainTxCommand(),
 		newFakeChanBackupCommand(),
 		newFilterBackupCommand(),
+		newFindKeyCommand(),
 		newFixOldBackupCommand(),
 		newForceCloseCommand(),
 		newGenImportScriptCommand(),
 		newMigrateDBCommand(),
 		newPullAnchorCommand(),
 		newRecoverLoopInCommand(),
+		newRecoverPassphraseCommand(),
+		newRecoverSeedCommand(),
 		newRemoveChannelCommand(),
 		newRescueClosedCommand(),
 		newRescueFundingCommand(),
 		newRescueTweakedKeyCommand(),
+		newSeedToolCommand(),
 		newShowRootKeyCommand(),
 		newSignMessageCommand(),
 		newSignRescueFundingCommand(),
 		newSignPSBTCommand(),
 		newSummaryCommand(),
+		newSweepAllCommand(),
 		newSweepTimeLockCommand(),
 		newSweepTimeLockManualCommand(),
 		newSweepRemoteClosedCommand(),
+		newSweepWalletCommand(),
 		newTriggerForceCloseCommand(),
 		newVanityGenCommand(),
+		newVerifyMessageCommand(),
+		newWalletBalanceCommand(),
 		newWalletInfoCommand(),
+		newWatchCommand(),
 		newZombieRecoveryCommand(),
 	)
 
@@ -254,6 +277,12 @@ func newInputFlags(cmd *cobra.Command) *inputFlags {
 	return f
 }
 
+// isSet returns true if any of the channel input flags is set.
+func (f *inputFlags) isSet() bool {
+	return f.ListChannels != "" || f.PendingChannels != "" ||
+		f.FromSummary != "" || f.FromChannelDB != ""
+}
+
 func (f *inputFlags) parseInputType() ([]*dataformat.SummaryEntry, error) {
 	var (
 		content []byte
@@ -365,3 +394,31 @@ func newExplorerAPI(apiURL string) *btc.ExplorerAPI {
 	// Otherwise use the provided URL.
 	return &btc.ExplorerAPI{BaseURL: apiURL}
 }
+
+// explorerAPIServesNetwork returns true if the API that newExplorerAPI returns
+// for the given URL is known to serve the selected network. A custom URL is
+// assumed to be chosen for the right network, the default URL is only
+// replaced for testnet3 and regtest.
+func explorerAPIServesNetwork(apiURL string) bool {
+	if apiURL != defaultAPIURL {
+		return true
+	}
+
+	switch chainParams.Name {
+	case chaincfg.MainNetParams.Name, chaincfg.TestNet3Params.Name,
+		chaincfg.RegressionNetParams.Name:
+
+		return true
+
+	default:
+		return false
+	}
+}
+
+// parseSweepAddrs parses the destinations given with the --sweepaddr flag.
+func parseSweepAddrs(sweepAddrs []string) ([]*lnd.SweepDestination, error) {
+	return lnd.ParseSweepDestinations(
+		sweepAddrs, chainParams, true, "sweep", lnd.AddrTypeP2WKH,
+		lnd.AddrTypeP2WSH, lnd.AddrTypeP2TR,
+	)
+}
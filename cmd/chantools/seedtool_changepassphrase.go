@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/aezeed"
+	"github.com/spf13/cobra"
+)
+
+type seedToolChangePassphraseCommand struct {
+	cmd *cobra.Command
+}
+
+func newSeedToolChangePassphraseCommand() *cobra.Command {
+	cc := &seedToolChangePassphraseCommand{}
+	cc.cmd = &cobra.Command{
+		Use: "changepassphrase",
+		Short: "Re-encipher an lnd aezeed with a new passphrase, " +
+			"keeping its birthday",
+		Long: `Deciphers an lnd aezeed with its current passphrase and
+enciphers the same entropy with a new passphrase. This can be used to add,
+change or remove the passphrase of a seed. The new seed has a different
+mnemonic but the same birthday and derives exactly the same keys, so it can be
+used to restore the same lnd wallet.
+
+Before the new mnemonic is shown, it is deciphered again with the new
+passphrase to make sure it contains the same entropy and birthday as the
+original seed.
+
+The new passphrase is read from the AEZEED_NEW_PASSPHRASE environment
+variable, if set. Otherwise it is read from the terminal. To remove the
+passphrase, set the environment variable to a single dash (-) or press <enter>
+without entering a passphrase.`,
+		Example: `chantools seedtool changepassphrase`,
+		RunE:    cc.Execute,
+	}
+
+	return cc.cmd
+}
+
+func (c *seedToolChangePassphraseCommand) Execute(_ *cobra.Command,
+	_ []string) error {
+
+	cipherSeed, err := lnd.ReadCipherSeed()
+	if err != nil {
+		return err
+	}
+
+	newPassphrase, err := readNewSeedPassphrase(
+		"Input new cipher seed passphrase (press enter to not use a " +
+			"passphrase): ",
+	)
+	if err != nil {
+		return err
+	}
+
+	// A new cipher seed with the same entropy and birthday is enciphered
+	// with a new random salt, so the mnemonic is different even if the
+	// passphrase stays the same.
+	newCipherSeed, err := aezeed.New(
+		cipherSeed.InternalVersion, &cipherSeed.Entropy,
+		cipherSeed.BirthdayTime(),
+	)
+	if err != nil {
+		return fmt.Errorf("error creating new seed: %w", err)
+	}
+	mnemonic, err := newCipherSeed.ToMnemonic(newPassphrase)
+	if err != nil {
+		return fmt.Errorf("error converting seed to mnemonic: %w", err)
+	}
+
+	// Let's make sure the new mnemonic can actually be deciphered with the
+	// new passphrase and contains everything the old seed did.
+	decipheredSeed, err := mnemonic.ToCipherSeed(newPassphrase)
+	if err != nil {
+		return fmt.Errorf("error verifying new seed: %w", err)
+	}
+	if decipheredSeed.Entropy != cipherSeed.Entropy ||
+		decipheredSeed.Birthday != cipherSeed.Birthday ||
+		decipheredSeed.InternalVersion != cipherSeed.InternalVersion {
+
+		return errors.New("error verifying new seed, deciphered " +
+			"seed doesn't match original seed")
+	}
+
+	fmt.Println("Re-enciphered seed with new passphrase")
+	printCipherSeedWords(mnemonic[:])
+
+	// For the tests, also log as trace level which is disabled by default.
+	log.Tracef("New mnemonic: %s", strings.Join(mnemonic[:], " "))
+
+	return nil
+}
+
+// readNewSeedPassphrase reads the new passphrase of an aezeed from the
+// environment or, if it isn't set there, asks for it twice on the terminal.
+func readNewSeedPassphrase(userQuery string) ([]byte, error) {
+	// Because we cannot differentiate between an empty and a non-existent
+	// environment variable, a single dash (-) indicates that no passphrase
+	// should be used.
+	passphrase := strings.TrimSpace(os.Getenv(lnd.NewPassphraseEnvName))
+	switch passphrase {
+	case "-":
+		return nil, nil
+
+	case "":
+
+	default:
+		return []byte(passphrase), nil
+	}
+
+	passphraseBytes, err := lnd.PasswordFromConsole(userQuery)
+	if err != nil {
+		return nil, err
+	}
+	confirmBytes, err := lnd.PasswordFromConsole(
+		"Confirm new cipher seed passphrase: ",
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(passphraseBytes, confirmBytes) {
+		return nil, errors.New("passphrases don't match")
+	}
+
+	return passphraseBytes, nil
+}
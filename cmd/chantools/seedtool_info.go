@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/lightninglabs/chantools/btc"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/aezeed"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/spf13/cobra"
+)
+
+const seedInfoFormat = `
+Cipher seed version:		%d
+Key derivation version:		%d (%s)
+Birthday:			%s
+Estimated birthday block:	%d
+Identity pubkey:		%x
+`
+
+type seedToolInfoCommand struct {
+	cmd *cobra.Command
+}
+
+func newSeedToolInfoCommand() *cobra.Command {
+	cc := &seedToolInfoCommand{}
+	cc.cmd = &cobra.Command{
+		Use:   "info",
+		Short: "Show the information encoded in an lnd aezeed",
+		Long: `Deciphers an lnd aezeed and shows the version of the seed
+and of the key derivation scheme it was created for, its birthday, the
+estimated height of the block that was mined at the birthday and the identity
+public key of the lnd node that uses the seed.
+
+The block height is estimated from the birthday without any network access and
+can be off by a few hundred blocks.`,
+		Example: `chantools seedtool info`,
+		RunE:    cc.Execute,
+	}
+
+	return cc.cmd
+}
+
+func (c *seedToolInfoCommand) Execute(_ *cobra.Command, _ []string) error {
+	cipherSeed, err := lnd.ReadCipherSeed()
+	if err != nil {
+		return err
+	}
+
+	birthdayBlock, err := btc.SeedBirthdayToBlock(
+		chainParams, cipherSeed.BirthdayTime(),
+	)
+	if err != nil {
+		return fmt.Errorf("error estimating birthday block: %w", err)
+	}
+
+	extendedKey, err := hdkeychain.NewMaster(
+		cipherSeed.Entropy[:], chainParams,
+	)
+	if err != nil {
+		return fmt.Errorf("error deriving master extended key: %w", err)
+	}
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	identityKey, err := keyRing.NodePubKey()
+	if err != nil {
+		return fmt.Errorf("error deriving identity key: %w", err)
+	}
+
+	result := fmt.Sprintf(
+		seedInfoFormat, aezeed.CipherSeedVersion,
+		cipherSeed.InternalVersion,
+		keyDerivationVersionName(cipherSeed.InternalVersion),
+		cipherSeed.BirthdayTime().Format("2006-01-02"), birthdayBlock,
+		identityKey.SerializeCompressed(),
+	)
+	fmt.Println(result)
+
+	// For the tests, also log as trace level which is disabled by default.
+	log.Tracef(result)
+
+	return nil
+}
+
+// keyDerivationVersionName returns a human readable name of the key derivation
+// version of an aezeed.
+func keyDerivationVersionName(version uint8) string {
+	switch version {
+	case keychain.KeyDerivationVersionLegacy:
+		return "legacy"
+
+	case keychain.KeyDerivationVersionTaproot:
+		return "taproot"
+
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+type seedToolCommand struct {
+	cmd *cobra.Command
+}
+
+func newSeedToolCommand() *cobra.Command {
+	cc := &seedToolCommand{}
+	cc.cmd = &cobra.Command{
+		Use:   "seedtool",
+		Short: "Inspect or re-encipher an lnd aezeed",
+		Long: `A sub command that hosts a set of further sub commands
+to inspect an lnd aezeed (cipher seed), to add, change or remove its passphrase
+or to show the BIP39 mnemonic of its entropy.
+
+All sub commands only work on the seed itself and don't need lnd or any of its
+files.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				_ = cmd.Help()
+				os.Exit(0)
+			}
+		},
+	}
+
+	cc.cmd.AddCommand(
+		newSeedToolInfoCommand(),
+		newSeedToolChangePassphraseCommand(),
+		newSeedToolToBIP39Command(),
+	)
+
+	return cc.cmd
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/lightninglabs/chantools/bip39"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/aezeed"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	newMnemonicPattern   = regexp.MustCompile(`New mnemonic: ([a-z ]+)`)
+	bip39MnemonicPattern = regexp.MustCompile(
+		`BIP39 mnemonic of the aezeed entropy:\n([a-z ]+)\n`,
+	)
+)
+
+// decipherTestSeed deciphers the given aezeed with the given passphrase.
+func decipherTestSeed(t *testing.T, words,
+	passphrase string) *aezeed.CipherSeed {
+
+	t.Helper()
+
+	var mnemonic aezeed.Mnemonic
+	copy(mnemonic[:], strings.Split(words, " "))
+
+	cipherSeed, err := mnemonic.ToCipherSeed([]byte(passphrase))
+	require.NoError(t, err)
+
+	return cipherSeed
+}
+
+func TestSeedToolInfo(t *testing.T) {
+	h := newHarness(t)
+
+	info := &seedToolInfoCommand{}
+
+	t.Setenv(lnd.MnemonicEnvName, seedAezeedWithPassphrase)
+	t.Setenv(lnd.PassphraseEnvName, testPassPhrase)
+
+	err := info.Execute(nil, nil)
+	require.NoError(t, err)
+
+	h.assertLogContains("0 (legacy)")
+	h.assertLogContains("2020-12-30")
+	h.assertLogContains(walletContent)
+}
+
+func TestSeedToolChangePassphrase(t *testing.T) {
+	h := newHarness(t)
+
+	changePassphrase := &seedToolChangePassphraseCommand{}
+
+	// Remove the passphrase of the seed.
+	t.Setenv(lnd.MnemonicEnvName, seedAezeedWithPassphrase)
+	t.Setenv(lnd.PassphraseEnvName, testPassPhrase)
+	t.Setenv(lnd.NewPassphraseEnvName, "-")
+
+	err := changePassphrase.Execute(nil, nil)
+	require.NoError(t, err)
+
+	matches := newMnemonicPattern.FindStringSubmatch(h.getLog())
+	require.Len(t, matches, 2)
+
+	// The new seed must contain the same entropy and birthday.
+	oldSeed := decipherTestSeed(
+		t, seedAezeedWithPassphrase, testPassPhrase,
+	)
+	newSeed := decipherTestSeed(t, matches[1], "")
+	require.Equal(t, oldSeed.Entropy, newSeed.Entropy)
+	require.Equal(t, oldSeed.Birthday, newSeed.Birthday)
+	require.Equal(t, oldSeed.InternalVersion, newSeed.InternalVersion)
+}
+
+func TestSeedToolToBIP39(t *testing.T) {
+	h := newHarness(t)
+
+	toBIP39 := &seedToolToBIP39Command{
+		Language: "klingon",
+	}
+
+	t.Setenv(lnd.MnemonicEnvName, seedAezeedNoPassphrase)
+	t.Setenv(lnd.PassphraseEnvName, "-")
+
+	err := toBIP39.Execute(nil, nil)
+	require.ErrorContains(t, err, "unknown BIP39 language")
+
+	toBIP39.Language = "English"
+	err = toBIP39.Execute(nil, nil)
+	require.NoError(t, err)
+
+	matches := bip39MnemonicPattern.FindStringSubmatch(h.getLog())
+	require.Len(t, matches, 2)
+	require.Len(t, strings.Split(matches[1], " "), 12)
+
+	// The mnemonic must decode to the entropy of the aezeed.
+	entropy, err := bip39.EntropyFromMnemonic(matches[1])
+	require.NoError(t, err)
+
+	cipherSeed := decipherTestSeed(t, seedAezeedNoPassphrase, "")
+	require.Equal(t, cipherSeed.Entropy[:], entropy)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/lightninglabs/chantools/bip39"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/spf13/cobra"
+)
+
+const seedToBIP39Format = `
+!!!THIS IS NOT A BACKUP OF YOUR LND WALLET!!!
+
+BIP39 mnemonic of the aezeed entropy:
+%s
+
+The mnemonic above contains the same 128 bits of entropy as the aezeed but no
+birthday and no passphrase. lnd derives its keys directly from the entropy,
+while wallets that support BIP39 derive them from the mnemonic and an optional
+passphrase. Restoring this mnemonic in a BIP39 wallet therefore results in a
+DIFFERENT wallet that does not contain any of the funds of the lnd wallet. Keep
+the mnemonic as secret as the aezeed itself.
+`
+
+type seedToolToBIP39Command struct {
+	Language string
+
+	cmd *cobra.Command
+}
+
+func newSeedToolToBIP39Command() *cobra.Command {
+	cc := &seedToolToBIP39Command{}
+	cc.cmd = &cobra.Command{
+		Use:   "tobip39",
+		Short: "Show the BIP39 mnemonic of an lnd aezeed's entropy",
+		Long: `Deciphers an lnd aezeed and encodes its 128 bits of
+entropy as a 12 word BIP39 mnemonic. The words are taken from the English word
+list unless a different one is selected with --language. All official word
+lists of BIP39 are supported: english, chinese_simplified, chinese_traditional,
+czech, french, italian, japanese, korean, portuguese and spanish.
+
+This is only useful for tools that work on the raw entropy of a seed. Wallets
+that support BIP39 derive their keys differently than lnd, so restoring the
+mnemonic in such a wallet does NOT give access to the funds of the lnd wallet.
+The birthday and passphrase of the aezeed are not part of the mnemonic.`,
+		Example: `chantools seedtool tobip39 --language japanese`,
+		RunE:    cc.Execute,
+	}
+	cc.cmd.Flags().StringVar(
+		&cc.Language, "language", bip39.LanguageEnglish.Name,
+		"language of the BIP39 word list to use",
+	)
+
+	return cc.cmd
+}
+
+func (c *seedToolToBIP39Command) Execute(_ *cobra.Command, _ []string) error {
+	language, err := bip39.LanguageByName(c.Language)
+	if err != nil {
+		return err
+	}
+
+	cipherSeed, err := lnd.ReadCipherSeed()
+	if err != nil {
+		return err
+	}
+
+	mnemonic, err := bip39.NewMnemonicInLanguage(
+		cipherSeed.Entropy[:], language,
+	)
+	if err != nil {
+		return fmt.Errorf("error encoding entropy as BIP39 "+
+			"mnemonic: %w", err)
+	}
+
+	result := fmt.Sprintf(seedToBIP39Format, mnemonic)
+	fmt.Println(result)
+
+	// For the tests, also log as trace level which is disabled by default.
+	log.Tracef(result)
+
+	return nil
+}
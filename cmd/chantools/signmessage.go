@@ -4,18 +4,30 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/lightninglabs/chantools/btc"
 	chantools_lnd "github.com/lightninglabs/chantools/lnd"
 	"github.com/lightningnetwork/lnd/keychain"
 	"github.com/spf13/cobra"
 	"github.com/tv42/zbase32"
 )
 
+const (
+	defaultSignMessageNumAddrs = 1000
+)
+
 var (
 	signedMsgPrefix = []byte("Lightning Signed Message:")
 )
 
 type signMessageCommand struct {
-	Msg string
+	Msg      string
+	Family   uint32
+	Index    uint32
+	Address  string
+	NumAddrs uint32
 
 	rootKey *rootKey
 	cmd     *cobra.Command
@@ -27,13 +39,44 @@ func newSignMessageCommand() *cobra.Command {
 		Use:   "signmessage",
 		Short: "Sign a message with the node's private key.",
 		Long: `Sign msg with the resident node's private key.
-		Returns the signature as a zbase32 string.`,
-		Example: `chantools signmessage --msg=foobar`,
-		RunE:    cc.Execute,
+		Returns the signature as a zbase32 string.
+
+By default, the message is signed with the node's identity key in the same
+format lnd's signmessage RPC uses. A different key of lnd can be selected with
+--family and --index.
+
+If --address is specified, a BIP-322 "simple" signature for that P2WKH or P2TR
+address of the lnd on-chain wallet is created instead, which proves the
+ownership of the address. The signature is returned base64 encoded. The key of
+the address is searched in the first --numaddrs addresses of each of the
+wallet's accounts.`,
+		Example: `chantools signmessage --msg=foobar
+
+chantools signmessage --msg=foobar --family 0 --index 3
+
+chantools signmessage --msg=foobar --address bc1q.....`,
+		RunE: cc.Execute,
 	}
 	cc.cmd.Flags().StringVar(
 		&cc.Msg, "msg", "", "the message to sign",
 	)
+	cc.cmd.Flags().Uint32Var(
+		&cc.Family, "family", uint32(keychain.KeyFamilyNodeKey), "the "+
+			"key family of the key to sign with",
+	)
+	cc.cmd.Flags().Uint32Var(
+		&cc.Index, "index", 0, "the index of the key to sign with",
+	)
+	cc.cmd.Flags().StringVar(
+		&cc.Address, "address", "", "create a BIP-322 signature for "+
+			"this P2WKH or P2TR address of the wallet instead",
+	)
+	cc.cmd.Flags().Uint32Var(
+		&cc.NumAddrs, "numaddrs", defaultSignMessageNumAddrs, "number "+
+			"of addresses per branch of each wallet account that "+
+			"are derived to find the key of the address given "+
+			"with --address",
+	)
 
 	cc.rootKey = newRootKey(cc.cmd, "decrypting the backup")
 
@@ -50,15 +93,20 @@ func (c *signMessageCommand) Execute(_ *cobra.Command, _ []string) error {
 		return fmt.Errorf("error reading root key: %w", err)
 	}
 
+	if c.Address != "" {
+		return c.signBIP322(extendedKey)
+	}
+
 	signer := &chantools_lnd.Signer{
 		ExtendedKey: extendedKey,
 		ChainParams: chainParams,
 	}
 
-	// Create the key locator for the node key.
+	// Create the key locator for the selected key, the node key by
+	// default.
 	keyLocator := keychain.KeyLocator{
-		Family: keychain.KeyFamilyNodeKey,
-		Index:  0,
+		Family: keychain.KeyFamily(c.Family),
+		Index:  c.Index,
 	}
 
 	// Fetch the private key for node key.
@@ -87,5 +135,77 @@ func (c *signMessageCommand) Execute(_ *cobra.Command, _ []string) error {
 	sig := zbase32.EncodeToString(sigBytes)
 	fmt.Println(sig)
 
+	// For the tests, also log as trace level which is disabled by default.
+	log.Tracef("Signature: %s", sig)
+
+	return nil
+}
+
+// signBIP322 creates a BIP-322 simple signature of the message for the
+// address.
+func (c *signMessageCommand) signBIP322(
+	extendedKey *hdkeychain.ExtendedKey) error {
+
+	addr, err := btcutil.DecodeAddress(c.Address, chainParams)
+	if err != nil {
+		return fmt.Errorf("error decoding address: %w", err)
+	}
+
+	privKey, err := findWalletAddressKey(extendedKey, addr, c.NumAddrs)
+	if err != nil {
+		return err
+	}
+	if privKey == nil {
+		return fmt.Errorf("address %v not found in the first %d "+
+			"addresses of the wallet accounts", addr, c.NumAddrs)
+	}
+
+	sig, err := btc.SignBIP322Simple(privKey, addr, []byte(c.Msg))
+	if err != nil {
+		return err
+	}
+	fmt.Println(sig)
+
+	// For the tests, also log as trace level which is disabled by default.
+	log.Tracef("Signature: %s", sig)
+
 	return nil
 }
+
+// findWalletAddressKey derives the first numAddrs addresses of each branch of
+// lnd's default wallet accounts and returns the private key of the given
+// address. If the address isn't found, nil is returned.
+func findWalletAddressKey(extendedKey *hdkeychain.ExtendedKey,
+	address btcutil.Address, numAddrs uint32) (*btcec.PrivateKey, error) {
+
+	for _, account := range btc.DefaultWalletAccounts {
+		for _, branch := range account.Branches {
+			for idx := uint32(0); idx < numAddrs; idx++ {
+				key, err := chantools_lnd.DeriveChildren(
+					extendedKey, account.Path(
+						chainParams, branch, idx,
+					),
+				)
+				if err != nil {
+					return nil, err
+				}
+				pubKey, err := key.ECPubKey()
+				if err != nil {
+					return nil, err
+				}
+
+				addr, err := account.Address(
+					pubKey, branch, chainParams,
+				)
+				if err != nil {
+					return nil, err
+				}
+				if addr.String() == address.String() {
+					return key.ECPrivKey()
+				}
+			}
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/lightninglabs/chantools/btc"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	signaturePattern = regexp.MustCompile(`Signature: (\S+)`)
+)
+
+// walletAddress returns the address with the given index of the external
+// branch of the given wallet account of the test root key.
+func walletAddress(t *testing.T, account *btc.WalletAccount,
+	index uint32) string {
+
+	extendedKey, err := (&rootKey{RootKey: rootKeyAezeed}).read()
+	require.NoError(t, err)
+
+	key, err := lnd.DeriveChildren(
+		extendedKey, account.Path(chainParams, 0, index),
+	)
+	require.NoError(t, err)
+	pubKey, err := key.ECPubKey()
+	require.NoError(t, err)
+
+	addr, err := account.Address(pubKey, 0, chainParams)
+	require.NoError(t, err)
+
+	return addr.String()
+}
+
+func TestSignVerifyMessage(t *testing.T) {
+	h := newHarness(t)
+
+	sign := &signMessageCommand{
+		Msg:     "foobar",
+		Family:  6,
+		rootKey: &rootKey{RootKey: rootKeyAezeed},
+	}
+	err := sign.Execute(nil, nil)
+	require.NoError(t, err)
+
+	matches := signaturePattern.FindStringSubmatch(h.getLog())
+	require.Len(t, matches, 2)
+
+	verify := &verifyMessageCommand{
+		Msg:    "foobar",
+		Sig:    matches[1],
+		PubKey: walletContent,
+	}
+	err = verify.Execute(nil, nil)
+	require.NoError(t, err)
+	h.assertLogContains("Signature is valid for public key " +
+		walletContent)
+
+	// A different message or key must not verify.
+	verify.Msg = "foobaz"
+	err = verify.Execute(nil, nil)
+	require.ErrorContains(t, err, "signature was created by public key")
+
+	sign.Family, sign.Index = 0, 1
+	err = sign.Execute(nil, nil)
+	require.NoError(t, err)
+
+	verify.Msg = "foobar"
+	allMatches := signaturePattern.FindAllStringSubmatch(h.getLog(), -1)
+	require.Len(t, allMatches, 2)
+	verify.Sig = allMatches[1][1]
+	err = verify.Execute(nil, nil)
+	require.ErrorContains(t, err, "signature was created by public key")
+}
+
+func TestSignVerifyMessageBIP322(t *testing.T) {
+	for _, account := range []*btc.WalletAccount{
+		btc.WalletAccountP2WKH, btc.WalletAccountP2TR,
+	} {
+		h := newHarness(t)
+
+		address := walletAddress(t, account, 3)
+		sign := &signMessageCommand{
+			Msg:      "I own this address",
+			Address:  address,
+			NumAddrs: 5,
+			rootKey:  &rootKey{RootKey: rootKeyAezeed},
+		}
+		err := sign.Execute(nil, nil)
+		require.NoError(t, err)
+
+		matches := signaturePattern.FindStringSubmatch(h.getLog())
+		require.Len(t, matches, 2)
+
+		verify := &verifyMessageCommand{
+			Msg:     "I own this address",
+			Sig:     matches[1],
+			Address: address,
+		}
+		err = verify.Execute(nil, nil)
+		require.NoError(t, err)
+		h.assertLogContains("Signature is valid for address " + address)
+
+		verify.Msg = "I don't own this address"
+		err = verify.Execute(nil, nil)
+		require.ErrorContains(t, err, "invalid signature")
+
+		// The address can't be found if we don't derive enough keys.
+		sign.NumAddrs = 3
+		err = sign.Execute(nil, nil)
+		require.ErrorContains(t, err, "not found")
+	}
+}
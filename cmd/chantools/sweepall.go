@@ -0,0 +1,874 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcwallet/wallet"
+	"github.com/lightninglabs/chantools/btc"
+	"github.com/lightninglabs/chantools/dataformat"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// sweepClassRemoteClosed are to_remote outputs of channels that were
+	// force closed by the remote party.
+	sweepClassRemoteClosed = "remote_closed"
+
+	// sweepClassTimeLocked are matured to_local outputs of channels that
+	// were force closed by us.
+	sweepClassTimeLocked = "time_locked"
+
+	// sweepClassWallet are outputs of lnd's on-chain wallet.
+	sweepClassWallet = "wallet"
+
+	// sweepClassAnchor are our anchor outputs of confirmed force close
+	// transactions.
+	sweepClassAnchor = "anchor"
+
+	defaultSweepAllMaxInputs = 100
+)
+
+// sweepClasses are all classes of outputs sweepall knows about, in the order
+// they are added to the sweep transactions.
+var sweepClasses = []string{
+	sweepClassRemoteClosed, sweepClassTimeLocked, sweepClassWallet,
+	sweepClassAnchor,
+}
+
+type sweepAllCommand struct {
+	APIURL         string
+	Publish        bool
+	SweepAddrs     []string
+	FeeRate        uint32
+	MaxCsvLimit    uint16
+	RemoteGapLimit uint32
+	WalletGapLimit uint32
+	MaxInputs      uint32
+	Skip           []string
+
+	rootKey *rootKey
+	inputs  *inputFlags
+	cmd     *cobra.Command
+}
+
+func newSweepAllCommand() *cobra.Command {
+	cc := &sweepAllCommand{}
+	cc.cmd = &cobra.Command{
+		Use: "sweepall",
+		Short: "Sweep all outputs that can be claimed with the seed " +
+			"in one run",
+		Long: `This command combines the work of sweepremoteclosed,
+sweeptimelock, doublespendinputs and pullanchor. It gathers all outputs the
+seed can claim and sweeps them together:
+ - remote_closed: to_remote outputs of channels force closed by the remote
+   party, found by scanning the payment base keys (see sweepremoteclosed)
+ - time_locked: to_local outputs of channels force closed by us whose time
+   lock has expired; requires a channel input file (see sweeptimelock)
+ - wallet: unspent outputs of lnd's on-chain wallet and of the keys of lnd's
+   internal key families (see walletbalance)
+ - anchor: our anchor outputs of confirmed force close transactions of the
+   channels in the channel input file
+
+Outputs that are worth less than the fee required to spend them are skipped.
+A plan with the number of inputs, the total value and the fee of each class is
+shown before the transactions are created. If there are more inputs than
+--maxinputs, multiple transactions are created.
+
+Use --skip to leave out whole classes, for example to not touch the on-chain
+wallet or to not wait for the time consuming remote closed scan.`,
+		Example: `chantools sweepall \
+	--fromsummary results/summary-xxxx-yyyy.json \
+	--sweepaddr bc1q..... \
+	--feerate 10 \
+	--publish
+
+chantools sweepall \
+	--skip wallet,anchor \
+	--sweepaddr fromseed`,
+		RunE: cc.Execute,
+	}
+	cc.cmd.Flags().StringVar(
+		&cc.APIURL, "apiurl", defaultAPIURL, "API URL to use (must "+
+			"be esplora compatible)",
+	)
+	cc.cmd.Flags().BoolVar(
+		&cc.Publish, "publish", false, "publish sweep TXs to the "+
+			"chain API instead of just printing them",
+	)
+	cc.cmd.Flags().StringArrayVar(
+		&cc.SweepAddrs, "sweepaddr", nil, sweepAddrFlagDesc,
+	)
+	cc.cmd.Flags().Uint32Var(
+		&cc.FeeRate, "feerate", defaultFeeSatPerVByte, "fee rate to "+
+			"use for the sweep transactions in sat/vByte",
+	)
+	cc.cmd.Flags().Uint16Var(
+		&cc.MaxCsvLimit, "maxcsvlimit", defaultCsvLimit, "maximum CSV "+
+			"limit to use when sweeping time locked outputs",
+	)
+	cc.cmd.Flags().Uint32Var(
+		&cc.RemoteGapLimit, "remotegaplimit",
+		sweepRemoteClosedDefaultGapLimit, "number of consecutive "+
+			"payment base key indices without any on-chain "+
+			"history after which the remote closed scan stops",
+	)
+	cc.cmd.Flags().Uint32Var(
+		&cc.WalletGapLimit, "walletgaplimit", btc.DefaultGapLimit,
+		"number of consecutive unused addresses after which the "+
+			"on-chain wallet scan stops",
+	)
+	cc.cmd.Flags().Uint32Var(
+		&cc.MaxInputs, "maxinputs", defaultSweepAllMaxInputs,
+		"maximum number of inputs per sweep transaction",
+	)
+	cc.cmd.Flags().StringSliceVar(
+		&cc.Skip, "skip", nil, "comma separated list of output "+
+			"classes to skip; possible values: "+
+			strings.Join(sweepClasses, ", "),
+	)
+
+	cc.rootKey = newRootKey(cc.cmd, "sweeping the funds")
+	cc.inputs = newInputFlags(cc.cmd)
+
+	return cc.cmd
+}
+
+func (c *sweepAllCommand) Execute(_ *cobra.Command, _ []string) error {
+	extendedKey, err := c.rootKey.read()
+	if err != nil {
+		return fmt.Errorf("error reading root key: %w", err)
+	}
+
+	// Make sure sweep addr is set.
+	sweepDests, err := parseSweepAddrs(c.SweepAddrs)
+	if err != nil {
+		return err
+	}
+
+	for _, class := range c.Skip {
+		if !slices.Contains(sweepClasses, class) {
+			return fmt.Errorf("unknown output class %s, possible "+
+				"values: %s", class,
+				strings.Join(sweepClasses, ", "))
+		}
+	}
+	include := func(class string) bool {
+		return !slices.Contains(c.Skip, class)
+	}
+
+	// The channel input file is only needed for the time locked and anchor
+	// outputs.
+	var entries []*dataformat.SummaryEntry
+	if c.inputs.isSet() {
+		entries, err = c.inputs.parseInputType()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Set default values.
+	if c.FeeRate == 0 {
+		c.FeeRate = defaultFeeSatPerVByte
+	}
+	if c.MaxCsvLimit == 0 {
+		c.MaxCsvLimit = defaultCsvLimit
+	}
+	if c.RemoteGapLimit == 0 {
+		c.RemoteGapLimit = sweepRemoteClosedDefaultGapLimit
+	}
+	if c.MaxInputs == 0 {
+		c.MaxInputs = defaultSweepAllMaxInputs
+	}
+
+	var (
+		api    = newExplorerAPI(c.APIURL)
+		inputs []*sweepInput
+	)
+	if include(sweepClassRemoteClosed) {
+		log.Infof("Scanning for remote closed channel outputs")
+		targets, _, err := scanRemoteClosed(
+			extendedKey, api, 0, c.RemoteGapLimit,
+		)
+		if err != nil {
+			return err
+		}
+
+		remoteInputs, err := remoteClosedSweepInputs(targets)
+		if err != nil {
+			return err
+		}
+		inputs = append(inputs, remoteInputs...)
+	}
+
+	if include(sweepClassTimeLocked) && len(entries) > 0 {
+		log.Infof("Looking for matured time locked outputs")
+		targets, err := sweepTargetsFromSummary(entries)
+		if err != nil {
+			return err
+		}
+
+		timeLockedInputs, err := timeLockedSweepInputs(
+			targets, api, c.MaxCsvLimit,
+		)
+		if err != nil {
+			return err
+		}
+		inputs = append(inputs, timeLockedInputs...)
+	}
+
+	if include(sweepClassWallet) {
+		log.Infof("Scanning on-chain wallet")
+		scanner := &btc.WalletScanner{
+			API:         api,
+			RootKey:     extendedKey,
+			ChainParams: chainParams,
+			GapLimit:    c.WalletGapLimit,
+		}
+		results, err := scanner.Scan(walletBalanceAccounts())
+		if err != nil {
+			return fmt.Errorf("error scanning wallet: %w", err)
+		}
+
+		inputs = append(inputs, walletSweepInputs(results)...)
+	}
+
+	if include(sweepClassAnchor) && len(entries) > 0 {
+		log.Infof("Looking for anchor outputs")
+		anchorInputs, err := anchorSweepInputs(
+			closeTXIDsFromSummary(entries), api, extendedKey,
+		)
+		if err != nil {
+			return err
+		}
+		inputs = append(inputs, anchorInputs...)
+	}
+
+	feeRate := chainfee.SatPerKVByte(1000 * c.FeeRate).FeePerKWeight()
+	inputs, summaries := planSweep(inputs, feeRate)
+	logSweepPlan(summaries, feeRate)
+
+	if len(inputs) == 0 {
+		return errors.New("no outputs found that can be swept")
+	}
+
+	return sweepInBatches(
+		extendedKey, api, inputs, sweepDests, feeRate, c.MaxInputs,
+		c.Publish,
+	)
+}
+
+// sweepInBatches creates, logs and optionally publishes transactions that
+// sweep the given inputs to the sweep destinations, with at most maxInputs
+// inputs per transaction.
+func sweepInBatches(extendedKey *hdkeychain.ExtendedKey, api *btc.ExplorerAPI,
+	inputs []*sweepInput, sweepDests []*lnd.SweepDestination,
+	feeRate chainfee.SatPerKWeight, maxInputs uint32, publish bool) error {
+
+	signer := &lnd.Signer{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	for start := 0; start < len(inputs); start += int(maxInputs) {
+		end := min(start+int(maxInputs), len(inputs))
+
+		sweepTx, fee, err := createSweepAllTx(
+			extendedKey, signer, inputs[start:end], sweepDests,
+			feeRate,
+		)
+		if err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		if err := sweepTx.Serialize(&buf); err != nil {
+			return err
+		}
+
+		log.Infof("Transaction %v with %d inputs, fee %d sats "+
+			"(weight %d)", sweepTx.TxHash(), len(sweepTx.TxIn), fee,
+			blockchain.GetTransactionWeight(btcutil.NewTx(sweepTx)))
+
+		if publish {
+			response, err := api.PublishTx(
+				hex.EncodeToString(buf.Bytes()),
+			)
+			if err != nil {
+				return err
+			}
+			log.Infof("Published TX %s, response: %s",
+				sweepTx.TxHash().String(), response)
+		}
+
+		log.Infof("Transaction: %x", buf.Bytes())
+	}
+
+	return nil
+}
+
+// witnessFunc creates the witness for spending an input described by the given
+// sign descriptor.
+type witnessFunc func(input.Signer, *input.SignDescriptor,
+	*wire.MsgTx) (wire.TxWitness, error)
+
+// sweepInput is a single output of any of the sweep classes.
+type sweepInput struct {
+	class    string
+	outpoint wire.OutPoint
+	utxo     *wire.TxOut
+	sequence uint32
+
+	// addWeight adds the weight of spending the output to an estimator.
+	addWeight func(*input.TxWeightEstimator)
+
+	// signDesc and witness are used to sign all inputs except the ones
+	// of the on-chain wallet.
+	signDesc *input.SignDescriptor
+	witness  witnessFunc
+
+	// walletUTXO is set if the input spends an on-chain wallet output.
+	walletUTXO *btc.WalletUTXO
+}
+
+// weight returns the weight the input adds to a transaction.
+func (i *sweepInput) weight() lntypes.WeightUnit {
+	var empty, estimator input.TxWeightEstimator
+	i.addWeight(&estimator)
+
+	return estimator.Weight() - empty.Weight()
+}
+
+// witnessInputWeight returns a function that adds the weight of a witness input
+// with the given witness size to an estimator.
+func witnessInputWeight(
+	witnessSize lntypes.WeightUnit) func(*input.TxWeightEstimator) {
+
+	return func(estimator *input.TxWeightEstimator) {
+		estimator.AddWitnessInput(witnessSize)
+	}
+}
+
+// remoteClosedSweepInputs turns the unspent outputs found by the remote closed
+// scan into sweep inputs.
+func remoteClosedSweepInputs(targets []*targetAddr) ([]*sweepInput, error) {
+	var inputs []*sweepInput
+	for _, target := range targets {
+		pkScript, err := lnd.GetWitnessAddrScript(
+			target.addr, chainParams,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error getting pk script: %w",
+				err)
+		}
+
+		for _, utxo := range target.utxos {
+			txHash, err := chainhash.NewHashFromStr(utxo.TXID)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing tx "+
+					"hash: %w", err)
+			}
+
+			in := &sweepInput{
+				class: sweepClassRemoteClosed,
+				outpoint: wire.OutPoint{
+					Hash:  *txHash,
+					Index: utxo.Vout,
+				},
+				utxo: &wire.TxOut{
+					PkScript: pkScript,
+					Value:    int64(utxo.Value),
+				},
+				sequence: wire.MaxTxInSequenceNum,
+				signDesc: &input.SignDescriptor{
+					KeyDesc:  *target.keyDesc,
+					HashType: txscript.SigHashAll,
+				},
+			}
+
+			switch target.addr.(type) {
+			// Static Remote Key Channels.
+			case *btcutil.AddressWitnessPubKeyHash:
+				in.addWeight = func(
+					e *input.TxWeightEstimator) {
+
+					e.AddP2WKHInput()
+				}
+
+				// The txscript library expects the witness
+				// script of a P2WKH descriptor to be set to the
+				// pkScript of the output...
+				in.signDesc.WitnessScript = pkScript
+				in.witness = commitSpendNoDelayTweakless
+
+			// Anchor Channels.
+			case *btcutil.AddressWitnessScriptHash:
+				in.sequence = 1
+				in.addWeight = witnessInputWeight(
+					input.ToRemoteConfirmedWitnessSize,
+				)
+				in.signDesc.WitnessScript = target.script
+				in.witness = input.CommitSpendToRemoteConfirmed
+
+			// Simple Taproot Channels.
+			case *btcutil.AddressTaproot:
+				in.sequence = 1
+				in.addWeight = witnessInputWeight(
+					input.TaprootToRemoteWitnessSize,
+				)
+
+				tree := target.scriptTree
+				controlBlock, err := tree.CtrlBlockForPath(
+					input.ScriptPathSuccess,
+				)
+				if err != nil {
+					return nil, err
+				}
+				controlBlockBytes, err := controlBlock.ToBytes()
+				if err != nil {
+					return nil, err
+				}
+
+				signDesc := in.signDesc
+				signDesc.WitnessScript = tree.SettleLeaf.Script
+				signDesc.HashType = txscript.SigHashDefault
+				signDesc.ControlBlock = controlBlockBytes
+				signDesc.SignMethod =
+					input.TaprootScriptSpendSignMethod
+				signDesc.TapTweak = tree.TapscriptRoot
+				in.witness = taprootCommitSpendSuccess
+
+			default:
+				return nil, fmt.Errorf("unsupported address "+
+					"type %T", target.addr)
+			}
+
+			inputs = append(inputs, in)
+		}
+	}
+
+	return inputs, nil
+}
+
+// timeLockedSweepInputs turns all time locked outputs that are confirmed,
+// unspent and matured into sweep inputs.
+func timeLockedSweepInputs(targets []*sweepTarget, api *btc.ExplorerAPI,
+	maxCsvLimit uint16) ([]*sweepInput, error) {
+
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	height, err := api.BlockHeight()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching block height: %w", err)
+	}
+
+	var inputs []*sweepInput
+	for _, target := range targets {
+		csvTimeout, script, scriptHash, err := target.findDelay(
+			maxCsvLimit,
+		)
+		if err != nil {
+			log.Errorf("Could not create matching script for %s "+
+				"or csv too high: %v", target.channelPoint, err)
+			continue
+		}
+
+		tx, err := api.Transaction(target.txid.String())
+		switch {
+		case errors.Is(err, btc.ErrTxNotFound):
+			log.Infof("Force close transaction %v of %s not "+
+				"found", target.txid, target.channelPoint)
+			continue
+
+		case err != nil:
+			return nil, fmt.Errorf("error fetching force close "+
+				"transaction %v: %w", target.txid, err)
+		}
+
+		if tx.Status == nil || !tx.Status.Confirmed {
+			log.Infof("Force close transaction %v of %s not "+
+				"confirmed yet", target.txid,
+				target.channelPoint)
+			continue
+		}
+		if int(target.index) >= len(tx.Vout) {
+			return nil, fmt.Errorf("invalid output index %d for "+
+				"transaction %v", target.index, target.txid)
+		}
+		outspend := tx.Vout[target.index].Outspend
+		if outspend != nil && outspend.Spent {
+			log.Infof("Time locked output of %s already spent",
+				target.channelPoint)
+			continue
+		}
+
+		// The sweep can be included in the next block once the
+		// output has the required number of confirmations.
+		matureHeight := tx.Status.BlockHeight + int(csvTimeout)
+		if height+1 < matureHeight {
+			log.Infof("Time locked output of %s matures at height "+
+				"%d, current height is %d", target.channelPoint,
+				matureHeight, height)
+			continue
+		}
+
+		inputs = append(inputs, &sweepInput{
+			class: sweepClassTimeLocked,
+			outpoint: wire.OutPoint{
+				Hash:  target.txid,
+				Index: target.index,
+			},
+			utxo: &wire.TxOut{
+				PkScript: scriptHash,
+				Value:    target.value,
+			},
+			sequence: input.LockTimeToSequence(
+				false, uint32(csvTimeout),
+			),
+			addWeight: witnessInputWeight(
+				input.ToLocalTimeoutWitnessSize,
+			),
+			signDesc: &input.SignDescriptor{
+				KeyDesc: *target.delayBasePointDesc,
+				SingleTweak: input.SingleTweakBytes(
+					target.commitPoint,
+					target.delayBasePointDesc.PubKey,
+				),
+				WitnessScript: script,
+				HashType:      txscript.SigHashAll,
+			},
+			witness: input.CommitSpendTimeout,
+		})
+	}
+
+	return inputs, nil
+}
+
+// walletSweepInputs turns all confirmed UTXOs of the on-chain wallet into
+// sweep inputs.
+func walletSweepInputs(results []*btc.WalletBranchResult) []*sweepInput {
+	var inputs []*sweepInput
+	for _, result := range results {
+		for _, utxo := range result.UTXOs {
+			if !utxo.Confirmed {
+				log.Infof("Skipping unconfirmed wallet UTXO %v",
+					utxo.OutPoint)
+				continue
+			}
+
+			inputs = append(inputs, &sweepInput{
+				class:      sweepClassWallet,
+				outpoint:   utxo.OutPoint,
+				utxo:       utxo.TxOut(),
+				sequence:   mempool.MaxRBFSequence,
+				addWeight:  utxo.AddInputWeight,
+				walletUTXO: utxo,
+			})
+		}
+	}
+
+	return inputs
+}
+
+// closeTXIDsFromSummary returns the IDs of all force close transactions of the
+// given channels.
+func closeTXIDsFromSummary(entries []*dataformat.SummaryEntry) []string {
+	var txids []string
+	addTXID := func(txid string) {
+		if txid != "" && !slices.Contains(txids, txid) {
+			txids = append(txids, txid)
+		}
+	}
+	for _, entry := range entries {
+		if entry.ForceClose != nil {
+			addTXID(entry.ForceClose.TXID)
+		}
+		if entry.ClosingTX != nil && entry.ClosingTX.ForceClose {
+			addTXID(entry.ClosingTX.TXID)
+		}
+	}
+
+	return txids
+}
+
+// anchorSweepInputs turns our unspent anchor outputs of the given confirmed
+// force close transactions into sweep inputs. Anchors of unconfirmed close
+// transactions are left for pullanchor.
+func anchorSweepInputs(closeTXIDs []string, api *btc.ExplorerAPI,
+	rootKey *hdkeychain.ExtendedKey) ([]*sweepInput, error) {
+
+	if len(closeTXIDs) == 0 {
+		return nil, nil
+	}
+
+	targets, parents, err := findAnchors(
+		nil, closeTXIDs, nil, api, rootKey, defaultAnchorNumKeys,
+	)
+	if err != nil {
+		log.Infof("Not sweeping any anchors: %v", err)
+		return nil, nil
+	}
+
+	var inputs []*sweepInput
+	for _, target := range targets {
+		closeTXID := target.outpoint.Hash.String()
+		unconfirmed := slices.ContainsFunc(
+			parents, func(parent *packageParent) bool {
+				return parent.txid == closeTXID
+			},
+		)
+		if unconfirmed {
+			log.Infof("Skipping anchor %v of unconfirmed close "+
+				"transaction, use pullanchor instead",
+				target.outpoint)
+			continue
+		}
+
+		in := &sweepInput{
+			class:    sweepClassAnchor,
+			outpoint: target.outpoint,
+			utxo:     target.utxo,
+			sequence: mempool.MaxRBFSequence,
+			signDesc: &input.SignDescriptor{
+				KeyDesc:       *target.keyDesc,
+				WitnessScript: target.script,
+			},
+		}
+
+		switch {
+		// Simple Taproot Channel.
+		case target.scriptTree != nil:
+			in.addWeight = func(e *input.TxWeightEstimator) {
+				e.AddTaprootKeySpendInput(
+					txscript.SigHashDefault,
+				)
+			}
+			in.signDesc.SignMethod = input.TaprootKeySpendSignMethod
+			in.signDesc.HashType = txscript.SigHashDefault
+			in.signDesc.TapTweak = target.scriptTree.TapscriptRoot
+			in.witness = input.TaprootAnchorSpend
+
+		// Anchor Channel.
+		default:
+			in.addWeight = witnessInputWeight(
+				input.AnchorWitnessSize,
+			)
+			in.signDesc.SignMethod = input.WitnessV0SignMethod
+			in.signDesc.HashType = txscript.SigHashAll
+			in.witness = input.CommitSpendAnchor
+		}
+
+		inputs = append(inputs, in)
+	}
+
+	return inputs, nil
+}
+
+// sweepClassSummary summarizes the inputs of a single class.
+type sweepClassSummary struct {
+	numInputs  int
+	numSkipped int
+	value      btcutil.Amount
+	fee        btcutil.Amount
+}
+
+// planSweep removes all inputs that are worth less than the fee required to
+// spend them and summarizes the remaining inputs by class. The inputs are
+// returned in the order of their class.
+func planSweep(inputs []*sweepInput, feeRate chainfee.SatPerKWeight) (
+	[]*sweepInput, map[string]*sweepClassSummary) {
+
+	summaries := make(map[string]*sweepClassSummary, len(sweepClasses))
+	for _, class := range sweepClasses {
+		summaries[class] = &sweepClassSummary{}
+	}
+
+	selected := make([]*sweepInput, 0, len(inputs))
+	for _, class := range sweepClasses {
+		summary := summaries[class]
+		for _, in := range inputs {
+			if in.class != class {
+				continue
+			}
+
+			fee := feeRate.FeeForWeight(in.weight())
+			if btcutil.Amount(in.utxo.Value) <= fee {
+				log.Infof("Skipping uneconomical %s output "+
+					"%v with value %d sats, spending it "+
+					"costs %d sats", class, in.outpoint,
+					in.utxo.Value, fee)
+				summary.numSkipped++
+				continue
+			}
+
+			summary.numInputs++
+			summary.value += btcutil.Amount(in.utxo.Value)
+			summary.fee += fee
+			selected = append(selected, in)
+		}
+	}
+
+	return selected, summaries
+}
+
+// logSweepPlan logs the number of inputs, value and fee of each class.
+func logSweepPlan(summaries map[string]*sweepClassSummary,
+	feeRate chainfee.SatPerKWeight) {
+
+	var (
+		plan  strings.Builder
+		total sweepClassSummary
+	)
+	_, _ = fmt.Fprintf(&plan, "Sweep plan at %d sat/vByte:\n",
+		feeRate.FeePerKVByte()/1000)
+	for _, class := range sweepClasses {
+		summary := summaries[class]
+		_, _ = fmt.Fprintf(&plan, "  %-14s %4d inputs, %12d sats, "+
+			"fee %9d sats, %d skipped\n", class+":",
+			summary.numInputs, summary.value, summary.fee,
+			summary.numSkipped)
+
+		total.numInputs += summary.numInputs
+		total.numSkipped += summary.numSkipped
+		total.value += summary.value
+		total.fee += summary.fee
+	}
+	_, _ = fmt.Fprintf(&plan, "  %-14s %4d inputs, %12d sats, "+
+		"fee %9d sats, %d skipped\n", "total:", total.numInputs,
+		total.value, total.fee, total.numSkipped)
+	_, _ = fmt.Fprintf(&plan, "The fee of each class only covers its "+
+		"inputs, the transaction overhead and outputs are added on "+
+		"top.")
+
+	log.Info(plan.String())
+}
+
+// createSweepAllTx creates and signs a transaction that sweeps the given inputs
+// to the sweep destinations. The transaction and its fee are returned.
+func createSweepAllTx(rootKey *hdkeychain.ExtendedKey, signer *lnd.Signer,
+	inputs []*sweepInput, sweepDests []*lnd.SweepDestination,
+	feeRate chainfee.SatPerKWeight) (*wire.MsgTx, btcutil.Amount, error) {
+
+	var estimator input.TxWeightEstimator
+	sweepOutputs, err := lnd.PrepareSweepOutputs(
+		sweepDests, chainParams, &estimator, rootKey, "sweep",
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		sweepTx    = wire.NewMsgTx(2)
+		totalValue int64
+	)
+	for _, in := range inputs {
+		sweepTx.TxIn = append(sweepTx.TxIn, &wire.TxIn{
+			PreviousOutPoint: in.outpoint,
+			Sequence:         in.sequence,
+		})
+		in.addWeight(&estimator)
+		totalValue += in.utxo.Value
+	}
+
+	totalFee := feeRate.FeeForWeight(estimator.Weight())
+	sweepTx.TxOut, err = sweepOutputs.TxOuts(totalValue - int64(totalFee))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	packet, err := psbt.NewFromUnsignedTx(sweepTx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error creating PSBT: %w", err)
+	}
+	for idx, in := range inputs {
+		packet.Inputs[idx].WitnessUtxo = in.utxo
+	}
+
+	// Sign the transaction now.
+	var (
+		prevOutFetcher = wallet.PsbtPrevOutputFetcher(packet)
+		sigHashes      = txscript.NewTxSigHashes(
+			packet.UnsignedTx, prevOutFetcher,
+		)
+	)
+	for idx, in := range inputs {
+		if in.walletUTXO != nil {
+			privKey, err := lnd.PrivKeyFromPath(
+				rootKey, in.walletUTXO.Path,
+			)
+			if err != nil {
+				return nil, 0, fmt.Errorf("error deriving "+
+					"private key: %w", err)
+			}
+
+			err = signer.SignWalletInput(packet, idx, privKey)
+			if err != nil {
+				return nil, 0, fmt.Errorf("error signing "+
+					"wallet input: %w", err)
+			}
+
+			continue
+		}
+
+		signDesc := *in.signDesc
+		signDesc.Output = in.utxo
+		signDesc.InputIndex = idx
+		signDesc.SigHashes = sigHashes
+		signDesc.PrevOutputFetcher = prevOutFetcher
+		witness, err := in.witness(signer, &signDesc, packet.UnsignedTx)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error signing %s input "+
+				"%v: %w", in.class, in.outpoint, err)
+		}
+
+		var witnessBuf bytes.Buffer
+		err = psbt.WriteTxWitness(&witnessBuf, witness)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error serializing "+
+				"witness: %w", err)
+		}
+		packet.Inputs[idx].FinalScriptWitness = witnessBuf.Bytes()
+	}
+
+	finalTx, err := psbt.Extract(packet)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error extracting final TX: %w", err)
+	}
+
+	return finalTx, totalFee, nil
+}
+
+// commitSpendNoDelayTweakless creates the witness for spending the to_remote
+// output of a static remote key channel.
+func commitSpendNoDelayTweakless(signer input.Signer,
+	signDesc *input.SignDescriptor,
+	sweepTx *wire.MsgTx) (wire.TxWitness, error) {
+
+	return input.CommitSpendNoDelay(signer, signDesc, sweepTx, true)
+}
+
+// taprootCommitSpendSuccess creates the witness for spending the to_remote
+// output of a simple taproot channel.
+func taprootCommitSpendSuccess(signer input.Signer,
+	signDesc *input.SignDescriptor,
+	sweepTx *wire.MsgTx) (wire.TxWitness, error) {
+
+	return input.TaprootCommitSpendSuccess(signer, signDesc, sweepTx, nil)
+}
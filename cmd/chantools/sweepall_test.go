@@ -0,0 +1,227 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightninglabs/chantools/btc"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSweepAllTx(t *testing.T) {
+	_ = newHarness(t)
+
+	extendedKey, err := hdkeychain.NewKeyFromString(rootKeyAezeed)
+	require.NoError(t, err)
+	otherKey, err := hdkeychain.NewKeyFromString(rootKeyBip39)
+	require.NoError(t, err)
+
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	deriveKey := func(family keychain.KeyFamily,
+		index uint32) keychain.KeyDescriptor {
+
+		keyDesc, err := keyRing.DeriveKey(keychain.KeyLocator{
+			Family: family,
+			Index:  index,
+		})
+		require.NoError(t, err)
+
+		return keyDesc
+	}
+	outpoint := func(index uint32) wire.OutPoint {
+		return wire.OutPoint{
+			Hash:  chainhash.Hash{1, 2, 3},
+			Index: index,
+		}
+	}
+
+	// A to_remote output of a static remote key channel.
+	paymentDesc := deriveKey(keychain.KeyFamilyPaymentBase, 3)
+	p2wkh, err := lnd.P2WKHAddr(paymentDesc.PubKey, chainParams)
+	require.NoError(t, err)
+	remoteInputs, err := remoteClosedSweepInputs([]*targetAddr{{
+		addr:    p2wkh,
+		pubKey:  paymentDesc.PubKey,
+		keyDesc: &paymentDesc,
+		utxos: []*btc.UTXO{{
+			TXID:  chainhash.Hash{1, 2, 3}.String(),
+			Vout:  0,
+			Value: 100_000,
+		}},
+	}})
+	require.NoError(t, err)
+	require.Len(t, remoteInputs, 1)
+
+	// A time locked to_local output.
+	delayDesc := deriveKey(keychain.KeyFamilyDelayBase, 0)
+	commitPoint, err := otherKey.ECPubKey()
+	require.NoError(t, err)
+	revocationBase, err := extendedKey.ECPubKey()
+	require.NoError(t, err)
+	script, err := input.CommitScriptToSelf(
+		testWatchCsvDelay,
+		input.TweakPubKey(delayDesc.PubKey, commitPoint),
+		input.DeriveRevocationPubkey(revocationBase, commitPoint),
+	)
+	require.NoError(t, err)
+	pkScript, err := input.WitnessScriptHash(script)
+	require.NoError(t, err)
+	csvTimeout, witnessScript, scriptHash, err := (&sweepTarget{
+		lockScript:          pkScript,
+		commitPoint:         commitPoint,
+		revocationBasePoint: revocationBase,
+		delayBasePointDesc:  &delayDesc,
+	}).findDelay(20)
+	require.NoError(t, err)
+	timeLockedInput := &sweepInput{
+		class:    sweepClassTimeLocked,
+		outpoint: outpoint(1),
+		utxo:     &wire.TxOut{PkScript: scriptHash, Value: 200_000},
+		sequence: input.LockTimeToSequence(false, uint32(csvTimeout)),
+		addWeight: witnessInputWeight(
+			input.ToLocalTimeoutWitnessSize,
+		),
+		signDesc: &input.SignDescriptor{
+			KeyDesc: delayDesc,
+			SingleTweak: input.SingleTweakBytes(
+				commitPoint, delayDesc.PubKey,
+			),
+			WitnessScript: witnessScript,
+			HashType:      txscript.SigHashAll,
+		},
+		witness: input.CommitSpendTimeout,
+	}
+
+	// An on-chain wallet output.
+	account := btc.WalletAccountNP2WKH
+	walletPath := account.Path(chainParams, 0, 2)
+	walletKey, err := lnd.PrivKeyFromPath(extendedKey, walletPath)
+	require.NoError(t, err)
+	walletAddr, err := account.Address(walletKey.PubKey(), 0, chainParams)
+	require.NoError(t, err)
+	walletScript, err := txscript.PayToAddrScript(walletAddr)
+	require.NoError(t, err)
+	walletInputs := walletSweepInputs([]*btc.WalletBranchResult{{
+		UTXOs: []*btc.WalletUTXO{{
+			WalletAddress: &btc.WalletAddress{
+				Account:  account,
+				Path:     walletPath,
+				PkScript: walletScript,
+			},
+			OutPoint:  outpoint(2),
+			Value:     50_000,
+			Confirmed: true,
+		}, {
+			WalletAddress: &btc.WalletAddress{
+				Account:  account,
+				Path:     walletPath,
+				PkScript: walletScript,
+			},
+			OutPoint: outpoint(3),
+			Value:    50_000,
+		}},
+	}})
+	require.Len(t, walletInputs, 1)
+
+	// An anchor output of an anchor channel.
+	multiSigDesc := deriveKey(keychain.KeyFamilyMultiSig, 1)
+	anchorScript, err := input.CommitScriptAnchor(multiSigDesc.PubKey)
+	require.NoError(t, err)
+	anchorPkScript, err := input.WitnessScriptHash(anchorScript)
+	require.NoError(t, err)
+	anchorInput := &sweepInput{
+		class:    sweepClassAnchor,
+		outpoint: outpoint(4),
+		utxo: &wire.TxOut{
+			PkScript: anchorPkScript,
+			Value:    anchorOutputValue,
+		},
+		sequence:  wire.MaxTxInSequenceNum,
+		addWeight: witnessInputWeight(input.AnchorWitnessSize),
+		signDesc: &input.SignDescriptor{
+			KeyDesc:       multiSigDesc,
+			WitnessScript: anchorScript,
+			SignMethod:    input.WitnessV0SignMethod,
+			HashType:      txscript.SigHashAll,
+		},
+		witness: input.CommitSpendAnchor,
+	}
+
+	inputs := []*sweepInput{
+		anchorInput, walletInputs[0], timeLockedInput, remoteInputs[0],
+	}
+
+	// At a high fee rate, the anchor isn't worth sweeping.
+	highFeeRate := chainfee.SatPerKVByte(20_000).FeePerKWeight()
+	selected, summaries := planSweep(inputs, highFeeRate)
+	require.Len(t, selected, 3)
+	require.Equal(t, 1, summaries[sweepClassAnchor].numSkipped)
+
+	// At a low fee rate, everything is swept. The inputs are ordered by
+	// their class.
+	feeRate := chainfee.SatPerKVByte(1000).FeePerKWeight()
+	selected, summaries = planSweep(inputs, feeRate)
+	require.Len(t, selected, 4)
+	require.Equal(t, []*sweepInput{
+		remoteInputs[0], timeLockedInput, walletInputs[0], anchorInput,
+	}, selected)
+	require.EqualValues(
+		t, 200_000, summaries[sweepClassTimeLocked].value,
+	)
+
+	sweepAddr, err := lnd.P2TRAddr(revocationBase, chainParams)
+	require.NoError(t, err)
+	sweepDests, err := parseSweepAddrs([]string{sweepAddr.String()})
+	require.NoError(t, err)
+
+	signer := &lnd.Signer{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	sweepTx, fee, err := createSweepAllTx(
+		extendedKey, signer, selected, sweepDests, feeRate,
+	)
+	require.NoError(t, err)
+	require.Len(t, sweepTx.TxIn, 4)
+	require.Len(t, sweepTx.TxOut, 1)
+
+	var totalValue int64
+	prevOutFetcher := txscript.NewMultiPrevOutFetcher(nil)
+	for _, in := range selected {
+		prevOutFetcher.AddPrevOut(in.outpoint, in.utxo)
+		totalValue += in.utxo.Value
+	}
+	require.Equal(t, totalValue-int64(fee), sweepTx.TxOut[0].Value)
+
+	// Make sure all inputs are signed correctly.
+	sigHashes := txscript.NewTxSigHashes(sweepTx, prevOutFetcher)
+	for idx, in := range selected {
+		vm, err := txscript.NewEngine(
+			in.utxo.PkScript, sweepTx, idx,
+			txscript.StandardVerifyFlags, nil, sigHashes,
+			in.utxo.Value, prevOutFetcher,
+		)
+		require.NoError(t, err)
+		require.NoError(t, vm.Execute(), in.class)
+	}
+
+	// The fee matches the requested fee rate.
+	weight := blockchain.GetTransactionWeight(btcutil.NewTx(sweepTx))
+	require.InDelta(
+		t, int64(feeRate.FeeForWeight(lntypes.WeightUnit(weight))),
+		int64(fee), 5,
+	)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/hex"
 	"fmt"
+	"sync"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil"
@@ -20,15 +21,23 @@ import (
 )
 
 const (
-	sweepRemoteClosedDefaultRecoveryWindow = 200
-	sweepDustLimit                         = 600
+	// sweepRemoteClosedDefaultGapLimit is the default number of
+	// consecutive payment base key indices without any on-chain history
+	// after which we stop scanning. Cooperatively closed and still open
+	// channels leave gaps in the used indices, so this is much larger than
+	// the usual gap limit of an on-chain wallet.
+	sweepRemoteClosedDefaultGapLimit = 200
+
+	sweepDustLimit = 600
 )
 
 type sweepRemoteClosedCommand struct {
+	GapLimit       uint32
+	StartIndex     uint32
 	RecoveryWindow uint32
 	APIURL         string
 	Publish        bool
-	SweepAddr      string
+	SweepAddrs     []string
 	FeeRate        uint32
 
 	rootKey *rootKey
@@ -54,18 +63,36 @@ Supported remote force-closed channel types are:
  - STATIC_REMOTE_KEY (a.k.a. tweakless channels)
  - ANCHOR (a.k.a. anchor output channels)
  - SIMPLE_TAPROOT (a.k.a. simple taproot channels)
+
+The payment base keys of the node are scanned until --gaplimit consecutive
+key indices without any on-chain or mempool history are found. Each time an
+index with history is found, the scan is extended. The highest used index is
+reported at the end, so a later run can start close to it with --startindex.
 `,
 		Example: `chantools sweepremoteclosed \
-	--recoverywindow 300 \
+	--gaplimit 300 \
 	--feerate 20 \
 	--sweepaddr bc1q..... \
   	--publish`,
 		RunE: cc.Execute,
 	}
 	cc.cmd.Flags().Uint32Var(
-		&cc.RecoveryWindow, "recoverywindow",
-		sweepRemoteClosedDefaultRecoveryWindow, "number of keys to "+
-			"scan per derivation path",
+		&cc.GapLimit, "gaplimit", sweepRemoteClosedDefaultGapLimit,
+		"number of consecutive key indices without any on-chain "+
+			"history after which the scan stops",
+	)
+	cc.cmd.Flags().Uint32Var(
+		&cc.StartIndex, "startindex", 0, "the payment base key "+
+			"index to start scanning at; can be set to an index "+
+			"reported by a previous run to skip already swept "+
+			"keys",
+	)
+	cc.cmd.Flags().Uint32Var(
+		&cc.RecoveryWindow, "recoverywindow", 0, "deprecated alias "+
+			"for --gaplimit",
+	)
+	_ = cc.cmd.Flags().MarkDeprecated(
+		"recoverywindow", "use --gaplimit instead",
 	)
 	cc.cmd.Flags().StringVar(
 		&cc.APIURL, "apiurl", defaultAPIURL, "API URL to use (must "+
@@ -75,10 +102,8 @@ Supported remote force-closed channel types are:
 		&cc.Publish, "publish", false, "publish sweep TX to the chain "+
 			"API instead of just printing the TX",
 	)
-	cc.cmd.Flags().StringVar(
-		&cc.SweepAddr, "sweepaddr", "", "address to recover the funds "+
-			"to; specify '"+lnd.AddressDeriveFromWallet+"' to "+
-			"derive a new address from the seed automatically",
+	cc.cmd.Flags().StringArrayVar(
+		&cc.SweepAddrs, "sweepaddr", nil, sweepAddrFlagDesc,
 	)
 	cc.cmd.Flags().Uint32Var(
 		&cc.FeeRate, "feerate", defaultFeeSatPerVByte, "fee rate to "+
@@ -97,25 +122,25 @@ func (c *sweepRemoteClosedCommand) Execute(_ *cobra.Command, _ []string) error {
 	}
 
 	// Make sure sweep addr is set.
-	err = lnd.CheckAddress(
-		c.SweepAddr, chainParams, true, "sweep", lnd.AddrTypeP2WKH,
-		lnd.AddrTypeP2TR,
-	)
+	sweepDests, err := parseSweepAddrs(c.SweepAddrs)
 	if err != nil {
 		return err
 	}
 
 	// Set default values.
-	if c.RecoveryWindow == 0 {
-		c.RecoveryWindow = sweepRemoteClosedDefaultRecoveryWindow
+	if c.RecoveryWindow != 0 {
+		c.GapLimit = c.RecoveryWindow
+	}
+	if c.GapLimit == 0 {
+		c.GapLimit = sweepRemoteClosedDefaultGapLimit
 	}
 	if c.FeeRate == 0 {
 		c.FeeRate = defaultFeeSatPerVByte
 	}
 
 	return sweepRemoteClosed(
-		extendedKey, c.APIURL, c.SweepAddr, c.RecoveryWindow, c.FeeRate,
-		c.Publish,
+		extendedKey, c.APIURL, sweepDests, c.StartIndex, c.GapLimit,
+		c.FeeRate, c.Publish,
 	)
 }
 
@@ -124,63 +149,38 @@ type targetAddr struct {
 	pubKey     *btcec.PublicKey
 	path       string
 	keyDesc    *keychain.KeyDescriptor
-	vouts      []*btc.Vout
+	utxos      []*btc.UTXO
 	script     []byte
 	scriptTree *input.CommitScriptTree
 }
 
-func sweepRemoteClosed(extendedKey *hdkeychain.ExtendedKey, apiURL,
-	sweepAddr string, recoveryWindow uint32, feeRate uint32,
-	publish bool) error {
+func sweepRemoteClosed(extendedKey *hdkeychain.ExtendedKey, apiURL string,
+	sweepDests []*lnd.SweepDestination, startIndex, gapLimit uint32,
+	feeRate uint32, publish bool) error {
 
 	var estimator input.TxWeightEstimator
-	sweepScript, err := lnd.PrepareWalletAddress(
-		sweepAddr, chainParams, &estimator, extendedKey, "sweep",
+	sweepOutputs, err := lnd.PrepareSweepOutputs(
+		sweepDests, chainParams, &estimator, extendedKey, "sweep",
 	)
 	if err != nil {
 		return err
 	}
 
-	var (
-		targets []*targetAddr
-		api     = newExplorerAPI(apiURL)
+	api := newExplorerAPI(apiURL)
+	targets, highestUsed, err := scanRemoteClosed(
+		extendedKey, api, startIndex, gapLimit,
 	)
-	for index := range recoveryWindow {
-		path := fmt.Sprintf("m/1017'/%d'/%d'/0/%d",
-			chainParams.HDCoinType, keychain.KeyFamilyPaymentBase,
-			index)
-		parsedPath, err := lnd.ParsePath(path)
-		if err != nil {
-			return fmt.Errorf("error parsing path: %w", err)
-		}
-
-		hdKey, err := lnd.DeriveChildren(
-			extendedKey, parsedPath,
-		)
-		if err != nil {
-			return fmt.Errorf("eror deriving children: %w", err)
-		}
-
-		privKey, err := hdKey.ECPrivKey()
-		if err != nil {
-			return fmt.Errorf("could not derive private "+
-				"key: %w", err)
-		}
+	if err != nil {
+		return err
+	}
 
-		foundTargets, err := queryAddressBalances(
-			privKey.PubKey(), path, &keychain.KeyDescriptor{
-				PubKey: privKey.PubKey(),
-				KeyLocator: keychain.KeyLocator{
-					Family: keychain.KeyFamilyPaymentBase,
-					Index:  index,
-				},
-			}, api,
-		)
-		if err != nil {
-			return fmt.Errorf("could not query API for "+
-				"addresses with funds: %w", err)
-		}
-		targets = append(targets, foundTargets...)
+	if highestUsed < 0 {
+		log.Infof("No payment base key with on-chain history found "+
+			"starting at index %d", startIndex)
+	} else {
+		log.Infof("Highest payment base key index with on-chain "+
+			"history is %d, later runs can use --startindex %d",
+			highestUsed, highestUsed)
 	}
 
 	// Create estimator and transaction template.
@@ -193,12 +193,10 @@ func sweepRemoteClosed(extendedKey *hdkeychain.ExtendedKey, apiURL,
 
 	// Add all found target outputs.
 	for _, target := range targets {
-		for _, vout := range target.vouts {
-			totalOutputValue += vout.Value
+		for _, utxo := range target.utxos {
+			totalOutputValue += utxo.Value
 
-			txHash, err := chainhash.NewHashFromStr(
-				vout.Outspend.Txid,
-			)
+			txHash, err := chainhash.NewHashFromStr(utxo.TXID)
 			if err != nil {
 				return fmt.Errorf("error parsing tx hash: %w",
 					err)
@@ -213,11 +211,11 @@ func sweepRemoteClosed(extendedKey *hdkeychain.ExtendedKey, apiURL,
 
 			prevOutPoint := wire.OutPoint{
 				Hash:  *txHash,
-				Index: uint32(vout.Outspend.Vin),
+				Index: utxo.Vout,
 			}
 			prevTxOut := &wire.TxOut{
 				PkScript: pkScript,
-				Value:    int64(vout.Value),
+				Value:    int64(utxo.Value),
 			}
 			prevOutFetcher.AddPrevOut(prevOutPoint, prevTxOut)
 			txIn := &wire.TxIn{
@@ -307,10 +305,12 @@ func sweepRemoteClosed(extendedKey *hdkeychain.ExtendedKey, apiURL,
 	log.Infof("Fee %d sats of %d total amount (estimated weight %d)",
 		totalFee, totalOutputValue, estimator.Weight())
 
-	sweepTx.TxOut = []*wire.TxOut{{
-		Value:    int64(totalOutputValue) - int64(totalFee),
-		PkScript: sweepScript,
-	}}
+	sweepTx.TxOut, err = sweepOutputs.TxOuts(
+		int64(totalOutputValue) - int64(totalFee),
+	)
+	if err != nil {
+		return err
+	}
 
 	// Sign the transaction now.
 	var (
@@ -383,18 +383,157 @@ func sweepRemoteClosed(extendedKey *hdkeychain.ExtendedKey, apiURL,
 	return nil
 }
 
+// scanRemoteClosed scans the payment base keys starting at the given index
+// until gapLimit consecutive indices without any on-chain history are found.
+// All addresses with unspent outputs and the highest used index (or -1 if no
+// index was used) are returned.
+func scanRemoteClosed(extendedKey *hdkeychain.ExtendedKey,
+	api *btc.ExplorerAPI, startIndex,
+	gapLimit uint32) ([]*targetAddr, int64, error) {
+
+	// We derive the branch key once to speed up the derivation of the
+	// individual keys.
+	basePath := []uint32{
+		lnd.HardenedKey(keychain.BIP0043Purpose),
+		lnd.HardenedKey(chainParams.HDCoinType),
+		lnd.HardenedKey(uint32(keychain.KeyFamilyPaymentBase)), 0,
+	}
+	branchKey, err := lnd.DeriveChildren(extendedKey, basePath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error deriving children: %w", err)
+	}
+
+	var (
+		targets     []*targetAddr
+		highestUsed = int64(-1)
+		index       = startIndex
+		gap         uint32
+	)
+	for gap < gapLimit {
+		// We only need to look at as many keys as would be required to
+		// reach the gap limit if none of them is used.
+		batch := make([]uint32, gapLimit-gap)
+		for i := range batch {
+			batch[i] = index + uint32(i)
+		}
+
+		used, foundTargets, err := queryPaymentBaseKeys(
+			branchKey, batch, api,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not query API for "+
+				"addresses with funds: %w", err)
+		}
+
+		for i, keyIndex := range batch {
+			targets = append(targets, foundTargets[i]...)
+
+			if !used[i] {
+				gap++
+				continue
+			}
+
+			gap = 0
+			highestUsed = int64(keyIndex)
+		}
+
+		index += uint32(len(batch))
+	}
+
+	return targets, highestUsed, nil
+}
+
+// queryPaymentBaseKeys derives the payment base keys with the given indices and
+// queries their addresses in parallel.
+func queryPaymentBaseKeys(branchKey *hdkeychain.ExtendedKey, indices []uint32,
+	api *btc.ExplorerAPI) ([]bool, [][]*targetAddr, error) {
+
+	var (
+		used    = make([]bool, len(indices))
+		targets = make([][]*targetAddr, len(indices))
+		errs    = make([]error, len(indices))
+		jobs    = make(chan int)
+		wg      sync.WaitGroup
+	)
+	for range btc.DefaultScanWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				used[i], targets[i], errs[i] =
+					queryPaymentBaseKey(
+						branchKey, indices[i], api,
+					)
+			}
+		}()
+	}
+	for i := range indices {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return used, targets, nil
+}
+
+// queryPaymentBaseKey derives the payment base key with the given index and
+// returns whether any of its addresses has on-chain history and all addresses
+// with unspent outputs.
+func queryPaymentBaseKey(branchKey *hdkeychain.ExtendedKey, index uint32,
+	api *btc.ExplorerAPI) (bool, []*targetAddr, error) {
+
+	hdKey, err := branchKey.DeriveNonStandard(index)
+	if err != nil {
+		return false, nil, fmt.Errorf("error deriving key: %w", err)
+	}
+
+	privKey, err := hdKey.ECPrivKey()
+	if err != nil {
+		return false, nil, fmt.Errorf("could not derive private key: "+
+			"%w", err)
+	}
+
+	path := fmt.Sprintf("m/1017'/%d'/%d'/0/%d", chainParams.HDCoinType,
+		keychain.KeyFamilyPaymentBase, index)
+	keyDesc := &keychain.KeyDescriptor{
+		PubKey: privKey.PubKey(),
+		KeyLocator: keychain.KeyLocator{
+			Family: keychain.KeyFamilyPaymentBase,
+			Index:  index,
+		},
+	}
+
+	return queryAddressBalances(privKey.PubKey(), path, keyDesc, api)
+}
+
+// queryAddressBalances queries all addresses a remote force close could have
+// sent our funds to for the given key. It returns whether any of the addresses
+// has on-chain or mempool history and all addresses with unspent outputs.
 func queryAddressBalances(pubKey *btcec.PublicKey, path string,
-	keyDesc *keychain.KeyDescriptor, api *btc.ExplorerAPI) ([]*targetAddr,
-	error) {
+	keyDesc *keychain.KeyDescriptor, api *btc.ExplorerAPI) (bool,
+	[]*targetAddr, error) {
 
-	var targets []*targetAddr
+	var (
+		used    bool
+		targets []*targetAddr
+	)
 	queryAddr := func(address btcutil.Address, script []byte,
 		scriptTree *input.CommitScriptTree) error {
 
-		unspent, err := api.Unspent(address.EncodeAddress())
+		addrUsed, unspent, err := btc.QueryAddress(
+			api, address.EncodeAddress(),
+		)
 		if err != nil {
-			return fmt.Errorf("could not query unspent: %w", err)
+			return fmt.Errorf("could not query address: %w", err)
 		}
+		used = used || addrUsed
 
 		if len(unspent) > 0 {
 			log.Infof("Found %d unspent outputs for address %v",
@@ -404,7 +543,7 @@ func queryAddressBalances(pubKey *btcec.PublicKey, path string,
 				pubKey:     pubKey,
 				path:       path,
 				keyDesc:    keyDesc,
-				vouts:      unspent,
+				utxos:      unspent,
 				script:     script,
 				scriptTree: scriptTree,
 			})
@@ -415,27 +554,27 @@ func queryAddressBalances(pubKey *btcec.PublicKey, path string,
 
 	p2wkh, err := lnd.P2WKHAddr(pubKey, chainParams)
 	if err != nil {
-		return nil, err
+		return false, nil, err
 	}
 	if err := queryAddr(p2wkh, nil, nil); err != nil {
-		return nil, err
+		return false, nil, err
 	}
 
 	p2anchor, script, err := lnd.P2AnchorStaticRemote(pubKey, chainParams)
 	if err != nil {
-		return nil, err
+		return false, nil, err
 	}
 	if err := queryAddr(p2anchor, script, nil); err != nil {
-		return nil, err
+		return false, nil, err
 	}
 
 	p2tr, scriptTree, err := lnd.P2TaprootStaticRemote(pubKey, chainParams)
 	if err != nil {
-		return nil, err
+		return false, nil, err
 	}
 	if err := queryAddr(p2tr, nil, scriptTree); err != nil {
-		return nil, err
+		return false, nil, err
 	}
 
-	return targets, nil
+	return used, targets, nil
 }
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightninglabs/chantools/btc"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/stretchr/testify/require"
+)
+
+// testAddrStats describes the history of a single address known to the test
+// explorer.
+type testAddrStats struct {
+	funded uint64
+	spent  uint64
+}
+
+func newTestAddrExplorer(t *testing.T,
+	addrs map[string]*testAddrStats) *httptest.Server {
+
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			parts := strings.Split(r.URL.Path, "/")
+			require.GreaterOrEqual(t, len(parts), 3)
+			addrStats, used := addrs[parts[2]]
+
+			var response any
+			switch {
+			case len(parts) == 4 && parts[3] == "utxo":
+				require.True(t, used)
+				txid := chainhash.Hash{1, 2, 3}
+				value := addrStats.funded - addrStats.spent
+				response = []*btc.UTXO{{
+					TXID:  txid.String(),
+					Vout:  1,
+					Value: value,
+				}}
+
+			default:
+				stats := &btc.AddressStats{
+					ChainStats:   &btc.Stats{},
+					MempoolStats: &btc.Stats{},
+				}
+				if used {
+					stats.ChainStats.TXCount = 1
+					stats.ChainStats.FundedTXOSum =
+						addrStats.funded
+					stats.ChainStats.SpentTXOSum =
+						addrStats.spent
+				}
+				response = stats
+			}
+
+			require.NoError(t, json.NewEncoder(w).Encode(response))
+		},
+	))
+}
+
+func TestScanRemoteClosed(t *testing.T) {
+	_ = newHarness(t)
+
+	extendedKey, err := hdkeychain.NewKeyFromString(rootKeyAezeed)
+	require.NoError(t, err)
+
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	paymentAddrs := func(index uint32) (btcutil.Address, btcutil.Address) {
+		keyDesc, err := keyRing.DeriveKey(keychain.KeyLocator{
+			Family: keychain.KeyFamilyPaymentBase,
+			Index:  index,
+		})
+		require.NoError(t, err)
+
+		p2wkh, err := lnd.P2WKHAddr(keyDesc.PubKey, chainParams)
+		require.NoError(t, err)
+		p2anchor, _, err := lnd.P2AnchorStaticRemote(
+			keyDesc.PubKey, chainParams,
+		)
+		require.NoError(t, err)
+
+		return p2wkh, p2anchor
+	}
+
+	// Index 2 still has funds, index 5 was already swept and index 9 is
+	// beyond the gap limit.
+	p2wkh2, _ := paymentAddrs(2)
+	_, p2anchor5 := paymentAddrs(5)
+	p2wkh9, _ := paymentAddrs(9)
+	addrs := map[string]*testAddrStats{
+		p2wkh2.EncodeAddress():    {funded: 100_000},
+		p2anchor5.EncodeAddress(): {funded: 50_000, spent: 50_000},
+		p2wkh9.EncodeAddress():    {funded: 100_000},
+	}
+	server := newTestAddrExplorer(t, addrs)
+	defer server.Close()
+	api := &btc.ExplorerAPI{BaseURL: server.URL}
+
+	targets, highestUsed, err := scanRemoteClosed(extendedKey, api, 0, 3)
+	require.NoError(t, err)
+	require.EqualValues(t, 5, highestUsed)
+	require.Len(t, targets, 1)
+	require.Equal(t, p2wkh2.EncodeAddress(), targets[0].addr.String())
+	require.EqualValues(t, 2, targets[0].keyDesc.Index)
+	require.Len(t, targets[0].utxos, 1)
+	require.EqualValues(t, 100_000, targets[0].utxos[0].Value)
+
+	// A larger gap limit finds the last index as well.
+	targets, highestUsed, err = scanRemoteClosed(extendedKey, api, 0, 4)
+	require.NoError(t, err)
+	require.EqualValues(t, 9, highestUsed)
+	require.Len(t, targets, 2)
+
+	// Starting after all used indices finds nothing.
+	targets, highestUsed, err = scanRemoteClosed(extendedKey, api, 10, 3)
+	require.NoError(t, err)
+	require.EqualValues(t, -1, highestUsed)
+	require.Empty(t, targets)
+}
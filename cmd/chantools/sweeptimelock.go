@@ -26,7 +26,7 @@ const (
 type sweepTimeLockCommand struct {
 	APIURL      string
 	Publish     bool
-	SweepAddr   string
+	SweepAddrs  []string
 	MaxCsvLimit uint16
 	FeeRate     uint32
 
@@ -63,10 +63,8 @@ parameter to 144.`,
 		&cc.Publish, "publish", false, "publish sweep TX to the chain "+
 			"API instead of just printing the TX",
 	)
-	cc.cmd.Flags().StringVar(
-		&cc.SweepAddr, "sweepaddr", "", "address to recover the funds "+
-			"to; specify '"+lnd.AddressDeriveFromWallet+"' to "+
-			"derive a new address from the seed automatically",
+	cc.cmd.Flags().StringArrayVar(
+		&cc.SweepAddrs, "sweepaddr", nil, sweepAddrFlagDesc,
 	)
 	cc.cmd.Flags().Uint16Var(
 		&cc.MaxCsvLimit, "maxcsvlimit", defaultCsvLimit, "maximum CSV "+
@@ -90,10 +88,7 @@ func (c *sweepTimeLockCommand) Execute(_ *cobra.Command, _ []string) error {
 	}
 
 	// Make sure sweep addr is set.
-	err = lnd.CheckAddress(
-		c.SweepAddr, chainParams, true, "sweep", lnd.AddrTypeP2WKH,
-		lnd.AddrTypeP2TR,
-	)
+	sweepDests, err := parseSweepAddrs(c.SweepAddrs)
 	if err != nil {
 		return err
 	}
@@ -112,7 +107,7 @@ func (c *sweepTimeLockCommand) Execute(_ *cobra.Command, _ []string) error {
 		c.FeeRate = defaultFeeSatPerVByte
 	}
 	return sweepTimeLockFromSummary(
-		extendedKey, c.APIURL, entries, c.SweepAddr, c.MaxCsvLimit,
+		extendedKey, c.APIURL, entries, sweepDests, c.MaxCsvLimit,
 		c.Publish, c.FeeRate,
 	)
 }
@@ -129,9 +124,25 @@ type sweepTarget struct {
 }
 
 func sweepTimeLockFromSummary(extendedKey *hdkeychain.ExtendedKey, apiURL string,
-	entries []*dataformat.SummaryEntry, sweepAddr string,
+	entries []*dataformat.SummaryEntry, sweepDests []*lnd.SweepDestination,
 	maxCsvTimeout uint16, publish bool, feeRate uint32) error {
 
+	targets, err := sweepTargetsFromSummary(entries)
+	if err != nil {
+		return err
+	}
+
+	return sweepTimeLock(
+		extendedKey, apiURL, targets, sweepDests, maxCsvTimeout,
+		publish, feeRate,
+	)
+}
+
+// sweepTargetsFromSummary extracts the time locked outputs of all force closed
+// channels of the given summary entries that can still be swept.
+func sweepTargetsFromSummary(
+	entries []*dataformat.SummaryEntry) ([]*sweepTarget, error) {
+
 	targets := make([]*sweepTarget, 0, len(entries))
 	for _, entry := range entries {
 		// Skip entries that can't be swept.
@@ -173,29 +184,30 @@ func sweepTimeLockFromSummary(extendedKey *hdkeychain.ExtendedKey, apiURL string
 		// Prepare sweep script parameters.
 		commitPoint, err := pubKeyFromHex(fc.CommitPoint)
 		if err != nil {
-			return fmt.Errorf("error parsing commit point: %w", err)
+			return nil, fmt.Errorf("error parsing commit point: %w",
+				err)
 		}
 		revBase, err := pubKeyFromHex(fc.RevocationBasePoint.PubKey)
 		if err != nil {
-			return fmt.Errorf("error parsing revocation base "+
+			return nil, fmt.Errorf("error parsing revocation base "+
 				"point: %w", err)
 		}
 		delayDesc, err := fc.DelayBasePoint.Desc()
 		if err != nil {
-			return fmt.Errorf("error parsing delay base point: %w",
-				err)
+			return nil, fmt.Errorf("error parsing delay base "+
+				"point: %w", err)
 		}
 
 		lockScript, err := hex.DecodeString(fc.Outs[txindex].Script)
 		if err != nil {
-			return fmt.Errorf("error parsing target script: %w",
-				err)
+			return nil, fmt.Errorf("error parsing target "+
+				"script: %w", err)
 		}
 
 		// Create the transaction input.
 		txHash, err := chainhash.NewHashFromStr(fc.TXID)
 		if err != nil {
-			return fmt.Errorf("error parsing tx hash: %w", err)
+			return nil, fmt.Errorf("error parsing tx hash: %w", err)
 		}
 
 		targets = append(targets, &sweepTarget{
@@ -210,15 +222,48 @@ func sweepTimeLockFromSummary(extendedKey *hdkeychain.ExtendedKey, apiURL string
 		})
 	}
 
-	return sweepTimeLock(
-		extendedKey, apiURL, targets, sweepAddr, maxCsvTimeout, publish,
-		feeRate,
-	)
+	return targets, nil
 }
 
 func sweepTimeLock(extendedKey *hdkeychain.ExtendedKey, apiURL string,
-	targets []*sweepTarget, sweepAddr string, maxCsvTimeout uint16,
-	publish bool, feeRate uint32) error {
+	targets []*sweepTarget, sweepDests []*lnd.SweepDestination,
+	maxCsvTimeout uint16, publish bool, feeRate uint32) error {
+
+	sweepTx, err := createTimeLockSweepTx(
+		extendedKey, targets, sweepDests, maxCsvTimeout, feeRate,
+	)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	err = sweepTx.Serialize(&buf)
+	if err != nil {
+		return err
+	}
+
+	// Publish TX.
+	if publish {
+		api := newExplorerAPI(apiURL)
+		response, err := api.PublishTx(
+			hex.EncodeToString(buf.Bytes()),
+		)
+		if err != nil {
+			return err
+		}
+		log.Infof("Published TX %s, response: %s",
+			sweepTx.TxHash().String(), response)
+	}
+
+	log.Infof("Transaction: %x", buf.Bytes())
+	return nil
+}
+
+// createTimeLockSweepTx creates and signs a transaction that sweeps the given
+// time locked outputs to the sweep destinations.
+func createTimeLockSweepTx(extendedKey *hdkeychain.ExtendedKey,
+	targets []*sweepTarget, sweepDests []*lnd.SweepDestination,
+	maxCsvTimeout uint16, feeRate uint32) (*wire.MsgTx, error) {
 
 	// Create signer and transaction template.
 	var (
@@ -227,13 +272,12 @@ func sweepTimeLock(extendedKey *hdkeychain.ExtendedKey, apiURL string,
 			ExtendedKey: extendedKey,
 			ChainParams: chainParams,
 		}
-		api = newExplorerAPI(apiURL)
 	)
-	sweepScript, err := lnd.PrepareWalletAddress(
-		sweepAddr, chainParams, &estimator, extendedKey, "sweep",
+	sweepOutputs, err := lnd.PrepareSweepOutputs(
+		sweepDests, chainParams, &estimator, extendedKey, "sweep",
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var (
@@ -243,16 +287,8 @@ func sweepTimeLock(extendedKey *hdkeychain.ExtendedKey, apiURL string,
 		prevOutFetcher   = txscript.NewMultiPrevOutFetcher(nil)
 	)
 	for _, target := range targets {
-		// We can't rely on the CSV delay of the channel DB to be
-		// correct. But it doesn't cost us a lot to just brute force it.
-		csvTimeout, script, scriptHash, err := bruteForceDelay(
-			input.TweakPubKey(
-				target.delayBasePointDesc.PubKey,
-				target.commitPoint,
-			), input.DeriveRevocationPubkey(
-				target.revocationBasePoint,
-				target.commitPoint,
-			), target.lockScript, 0, maxCsvTimeout,
+		csvTimeout, script, scriptHash, err := target.findDelay(
+			maxCsvTimeout,
 		)
 		if err != nil {
 			log.Errorf("could not create matching script for %s "+
@@ -304,10 +340,12 @@ func sweepTimeLock(extendedKey *hdkeychain.ExtendedKey, apiURL string,
 	log.Infof("Fee %d sats of %d total amount (estimated weight %d)",
 		totalFee, totalOutputValue, estimator.Weight())
 
-	sweepTx.TxOut = []*wire.TxOut{{
-		Value:    totalOutputValue - int64(totalFee),
-		PkScript: sweepScript,
-	}}
+	sweepTx.TxOut, err = sweepOutputs.TxOuts(
+		totalOutputValue - int64(totalFee),
+	)
+	if err != nil {
+		return nil, err
+	}
 
 	// Sign the transaction now.
 	sigHashes := txscript.NewTxSigHashes(sweepTx, prevOutFetcher)
@@ -316,31 +354,27 @@ func sweepTimeLock(extendedKey *hdkeychain.ExtendedKey, apiURL string,
 		desc.InputIndex = idx
 		witness, err := input.CommitSpendTimeout(signer, desc, sweepTx)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		sweepTx.TxIn[idx].Witness = witness
 	}
 
-	var buf bytes.Buffer
-	err = sweepTx.Serialize(&buf)
-	if err != nil {
-		return err
-	}
-
-	// Publish TX.
-	if publish {
-		response, err := api.PublishTx(
-			hex.EncodeToString(buf.Bytes()),
-		)
-		if err != nil {
-			return err
-		}
-		log.Infof("Published TX %s, response: %s",
-			sweepTx.TxHash().String(), response)
-	}
+	return sweepTx, nil
+}
 
-	log.Infof("Transaction: %x", buf.Bytes())
-	return nil
+// findDelay brute forces the CSV delay of the target's time locked output and
+// returns it together with the witness script and the pk script.
+func (t *sweepTarget) findDelay(maxCsvTimeout uint16) (int32, []byte, []byte,
+	error) {
+
+	// We can't rely on the CSV delay of the channel DB to be correct. But
+	// it doesn't cost us a lot to just brute force it.
+	return bruteForceDelay(
+		input.TweakPubKey(t.delayBasePointDesc.PubKey, t.commitPoint),
+		input.DeriveRevocationPubkey(
+			t.revocationBasePoint, t.commitPoint,
+		), t.lockScript, 0, maxCsvTimeout,
+	)
 }
 
 func pubKeyFromHex(pubKeyHex string) (*btcec.PublicKey, error) {
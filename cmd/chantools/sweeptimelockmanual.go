@@ -28,7 +28,7 @@ const (
 type sweepTimeLockManualCommand struct {
 	APIURL                    string
 	Publish                   bool
-	SweepAddr                 string
+	SweepAddrs                []string
 	MaxCsvLimit               uint16
 	FeeRate                   uint32
 	TimeLockAddr              string
@@ -89,10 +89,8 @@ chantools sweeptimelockmanual \
 		&cc.Publish, "publish", false, "publish sweep TX to the chain "+
 			"API instead of just printing the TX",
 	)
-	cc.cmd.Flags().StringVar(
-		&cc.SweepAddr, "sweepaddr", "", "address to recover the funds "+
-			"to; specify '"+lnd.AddressDeriveFromWallet+"' to "+
-			"derive a new address from the seed automatically",
+	cc.cmd.Flags().StringArrayVar(
+		&cc.SweepAddrs, "sweepaddr", nil, sweepAddrFlagDesc,
 	)
 	cc.cmd.Flags().Uint16Var(
 		&cc.MaxCsvLimit, "maxcsvlimit", defaultCsvLimit, "maximum CSV "+
@@ -145,10 +143,7 @@ func (c *sweepTimeLockManualCommand) Execute(_ *cobra.Command, _ []string) error
 	}
 
 	// Make sure the sweep and time lock addrs are set.
-	err = lnd.CheckAddress(
-		c.SweepAddr, chainParams, true, "sweep", lnd.AddrTypeP2WKH,
-		lnd.AddrTypeP2TR,
-	)
+	sweepDests, err := parseSweepAddrs(c.SweepAddrs)
 	if err != nil {
 		return err
 	}
@@ -229,7 +224,7 @@ func (c *sweepTimeLockManualCommand) Execute(_ *cobra.Command, _ []string) error
 	}
 
 	return sweepTimeLockManual(
-		extendedKey, c.APIURL, c.SweepAddr, c.TimeLockAddr,
+		extendedKey, c.APIURL, sweepDests, c.TimeLockAddr,
 		remoteRevPoint, startCsvLimit, maxCsvLimit,
 		startNumChannelsTotal, maxNumChannelsTotal,
 		c.MaxNumChanUpdates, c.Publish, c.FeeRate,
@@ -237,7 +232,8 @@ func (c *sweepTimeLockManualCommand) Execute(_ *cobra.Command, _ []string) error
 }
 
 func sweepTimeLockManual(extendedKey *hdkeychain.ExtendedKey, apiURL string,
-	sweepAddr, timeLockAddr string, remoteRevPoint *btcec.PublicKey,
+	sweepDests []*lnd.SweepDestination, timeLockAddr string,
+	remoteRevPoint *btcec.PublicKey,
 	startCsvTimeout, maxCsvTimeout, startNumChannels, maxNumChannels uint16,
 	maxNumChanUpdates uint64, publish bool, feeRate uint32) error {
 
@@ -268,8 +264,8 @@ func sweepTimeLockManual(extendedKey *hdkeychain.ExtendedKey, apiURL string,
 	if err != nil {
 		return err
 	}
-	sweepScript, err := lnd.PrepareWalletAddress(
-		sweepAddr, chainParams, &estimator, extendedKey, "sweep",
+	sweepOutputs, err := lnd.PrepareSweepOutputs(
+		sweepDests, chainParams, &estimator, extendedKey, "sweep",
 	)
 	if err != nil {
 		return err
@@ -355,11 +351,11 @@ func sweepTimeLockManual(extendedKey *hdkeychain.ExtendedKey, apiURL string,
 	feeRateKWeight := chainfee.SatPerKVByte(1000 * feeRate).FeePerKWeight()
 	totalFee := feeRateKWeight.FeeForWeight(estimator.Weight())
 
-	// Add our sweep destination output.
-	sweepTx.TxOut = []*wire.TxOut{{
-		Value:    sweepValue - int64(totalFee),
-		PkScript: sweepScript,
-	}}
+	// Add our sweep destination outputs.
+	sweepTx.TxOut, err = sweepOutputs.TxOuts(sweepValue - int64(totalFee))
+	if err != nil {
+		return err
+	}
 
 	log.Infof("Fee %d sats of %d total amount (estimated weight %d)",
 		totalFee, sweepValue, estimator.Weight())
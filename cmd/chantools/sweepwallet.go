@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lightninglabs/chantools/btc"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+	"github.com/spf13/cobra"
+)
+
+type sweepWalletCommand struct {
+	APIURL     string
+	Publish    bool
+	SweepAddrs []string
+	FeeRate    uint32
+	GapLimit   uint32
+	Workers    int
+	MaxInputs  uint32
+
+	rootKey *rootKey
+	cmd     *cobra.Command
+}
+
+func newSweepWalletCommand() *cobra.Command {
+	cc := &sweepWalletCommand{}
+	cc.cmd = &cobra.Command{
+		Use: "sweepwallet",
+		Short: "Sweep all UTXOs of the on-chain wallet derived from " +
+			"the seed",
+		Long: `Discovers all unspent outputs of lnd's on-chain wallet by
+scanning the addresses derived from the seed (the np2wkh, p2wkh and p2tr
+accounts on both the external and internal branch and the keys of lnd's
+internal key families, see walletbalance) and sweeps them to the given
+destination(s).
+
+Unlike doublespendinputs, this doesn't need a list of outpoints and doesn't
+need lnd or its wallet.db at all, which makes it useful if lnd's wallet is
+unusable and all funds should be moved quickly. Unconfirmed outputs and outputs
+that are worth less than the fee to spend them are skipped. If there are more
+inputs than --maxinputs, multiple transactions are created.`,
+		Example: `chantools sweepwallet \
+	--sweepaddr bc1q..... \
+	--feerate 10 \
+	--publish`,
+		RunE: cc.Execute,
+	}
+	cc.cmd.Flags().StringVar(
+		&cc.APIURL, "apiurl", defaultAPIURL, "API URL to use (must "+
+			"be esplora compatible)",
+	)
+	cc.cmd.Flags().BoolVar(
+		&cc.Publish, "publish", false, "publish sweep TXs to the "+
+			"chain API instead of just printing them",
+	)
+	cc.cmd.Flags().StringArrayVar(
+		&cc.SweepAddrs, "sweepaddr", nil, sweepAddrFlagDesc,
+	)
+	cc.cmd.Flags().Uint32Var(
+		&cc.FeeRate, "feerate", defaultFeeSatPerVByte, "fee rate to "+
+			"use for the sweep transactions in sat/vByte",
+	)
+	cc.cmd.Flags().Uint32Var(
+		&cc.GapLimit, "gaplimit", btc.DefaultGapLimit, "number of "+
+			"consecutive unused addresses after which the scan of "+
+			"a branch is stopped",
+	)
+	cc.cmd.Flags().IntVar(
+		&cc.Workers, "workers", btc.DefaultScanWorkers, "number of "+
+			"addresses that are looked up in parallel",
+	)
+	cc.cmd.Flags().Uint32Var(
+		&cc.MaxInputs, "maxinputs", defaultSweepAllMaxInputs,
+		"maximum number of inputs per sweep transaction",
+	)
+
+	cc.rootKey = newRootKey(cc.cmd, "sweeping the wallet")
+
+	return cc.cmd
+}
+
+func (c *sweepWalletCommand) Execute(_ *cobra.Command, _ []string) error {
+	extendedKey, err := c.rootKey.read()
+	if err != nil {
+		return fmt.Errorf("error reading root key: %w", err)
+	}
+
+	// Make sure sweep addr is set.
+	sweepDests, err := parseSweepAddrs(c.SweepAddrs)
+	if err != nil {
+		return err
+	}
+
+	// Set default values.
+	if c.FeeRate == 0 {
+		c.FeeRate = defaultFeeSatPerVByte
+	}
+	if c.MaxInputs == 0 {
+		c.MaxInputs = defaultSweepAllMaxInputs
+	}
+
+	api := newExplorerAPI(c.APIURL)
+	scanner := &btc.WalletScanner{
+		API:         api,
+		RootKey:     extendedKey,
+		ChainParams: chainParams,
+		GapLimit:    c.GapLimit,
+		NumWorkers:  c.Workers,
+	}
+
+	log.Infof("Scanning on-chain wallet")
+	results, err := scanner.Scan(walletBalanceAccounts())
+	if err != nil {
+		return fmt.Errorf("error scanning wallet: %w", err)
+	}
+
+	feeRate := chainfee.SatPerKVByte(1000 * c.FeeRate).FeePerKWeight()
+	inputs, summaries := planSweep(walletSweepInputs(results), feeRate)
+	summary := summaries[sweepClassWallet]
+	log.Infof("Sweeping %d wallet UTXOs with a total value of %d sats "+
+		"at %d sat/vByte, %d skipped", summary.numInputs, summary.value,
+		c.FeeRate, summary.numSkipped)
+
+	if len(inputs) == 0 {
+		return errors.New("no wallet UTXOs found that can be swept")
+	}
+
+	return sweepInBatches(
+		extendedKey, api, inputs, sweepDests, feeRate, c.MaxInputs,
+		c.Publish,
+	)
+}
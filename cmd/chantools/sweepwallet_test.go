@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"regexp"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightninglabs/chantools/btc"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+	"github.com/stretchr/testify/require"
+)
+
+var sweepTxPattern = regexp.MustCompile(`Transaction: ([0-9a-f]+)`)
+
+func TestSweepWalletBatches(t *testing.T) {
+	h := newHarness(t)
+
+	extendedKey, err := hdkeychain.NewKeyFromString(rootKeyAezeed)
+	require.NoError(t, err)
+
+	var numUTXOs uint32
+	walletUTXO := func(account *btc.WalletAccount, branch, index uint32,
+		value uint64, confirmed bool) *btc.WalletUTXO {
+
+		path := account.Path(chainParams, branch, index)
+		privKey, err := lnd.PrivKeyFromPath(extendedKey, path)
+		require.NoError(t, err)
+		addr, err := account.Address(
+			privKey.PubKey(), branch, chainParams,
+		)
+		require.NoError(t, err)
+		pkScript, err := txscript.PayToAddrScript(addr)
+		require.NoError(t, err)
+
+		numUTXOs++
+		return &btc.WalletUTXO{
+			WalletAddress: &btc.WalletAddress{
+				Account:  account,
+				Branch:   branch,
+				Index:    index,
+				Path:     path,
+				PubKey:   privKey.PubKey(),
+				Address:  addr,
+				PkScript: pkScript,
+			},
+			OutPoint: wire.OutPoint{
+				Hash:  chainhash.Hash{4, 5, 6},
+				Index: numUTXOs,
+			},
+			Value:     value,
+			Confirmed: confirmed,
+		}
+	}
+
+	familyAccount := btc.NewLndFamilyAccount(
+		"payment base", keychain.KeyFamilyPaymentBase,
+	)
+	results := []*btc.WalletBranchResult{{
+		UTXOs: []*btc.WalletUTXO{
+			walletUTXO(btc.WalletAccountNP2WKH, 0, 0, 50_000, true),
+			walletUTXO(btc.WalletAccountNP2WKH, 1, 1, 40_000, true),
+		},
+	}, {
+		UTXOs: []*btc.WalletUTXO{
+			walletUTXO(btc.WalletAccountP2WKH, 0, 2, 30_000, true),
+			walletUTXO(btc.WalletAccountP2WKH, 0, 3, 10_000, false),
+		},
+	}, {
+		UTXOs: []*btc.WalletUTXO{
+			walletUTXO(btc.WalletAccountP2TR, 1, 0, 20_000, true),
+			walletUTXO(btc.WalletAccountP2TR, 0, 4, 200, true),
+		},
+	}, {
+		UTXOs: []*btc.WalletUTXO{
+			walletUTXO(familyAccount, 0, 5, 25_000, true),
+		},
+	}}
+
+	// The unconfirmed UTXO is never swept.
+	inputs := walletSweepInputs(results)
+	require.Len(t, inputs, 6)
+
+	// Spending the 200 sats taproot output costs more than it is worth.
+	feeRate := chainfee.SatPerKVByte(10_000).FeePerKWeight()
+	selected, summaries := planSweep(inputs, feeRate)
+	require.Len(t, selected, 5)
+	summary := summaries[sweepClassWallet]
+	require.Equal(t, 5, summary.numInputs)
+	require.Equal(t, 1, summary.numSkipped)
+	require.EqualValues(t, 165_000, summary.value)
+
+	sweepAddr, err := lnd.P2WKHAddr(
+		selected[0].walletUTXO.PubKey, chainParams,
+	)
+	require.NoError(t, err)
+	sweepDests, err := parseSweepAddrs([]string{sweepAddr.String()})
+	require.NoError(t, err)
+
+	// With at most two inputs per transaction, three transactions are
+	// created.
+	err = sweepInBatches(
+		extendedKey, nil, selected, sweepDests, feeRate, 2, false,
+	)
+	require.NoError(t, err)
+
+	prevOutFetcher := txscript.NewMultiPrevOutFetcher(nil)
+	for _, in := range selected {
+		prevOutFetcher.AddPrevOut(in.outpoint, in.utxo)
+	}
+
+	matches := sweepTxPattern.FindAllStringSubmatch(h.getLog(), -1)
+	require.Len(t, matches, 3)
+
+	var numInputs []int
+	for _, match := range matches {
+		txBytes, err := hex.DecodeString(match[1])
+		require.NoError(t, err)
+		sweepTx := &wire.MsgTx{}
+		require.NoError(t, sweepTx.Deserialize(bytes.NewReader(
+			txBytes,
+		)))
+		numInputs = append(numInputs, len(sweepTx.TxIn))
+
+		// All inputs must be signed correctly, including the native
+		// SegWit change output of the nested SegWit account.
+		sigHashes := txscript.NewTxSigHashes(sweepTx, prevOutFetcher)
+		for idx, txIn := range sweepTx.TxIn {
+			prevOut := prevOutFetcher.FetchPrevOutput(
+				txIn.PreviousOutPoint,
+			)
+			vm, err := txscript.NewEngine(
+				prevOut.PkScript, sweepTx, idx,
+				txscript.StandardVerifyFlags, nil, sigHashes,
+				prevOut.Value, prevOutFetcher,
+			)
+			require.NoError(t, err)
+			require.NoError(t, vm.Execute())
+		}
+	}
+	require.Equal(t, []int{2, 2, 1}, numInputs)
+}
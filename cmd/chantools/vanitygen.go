@@ -1,17 +1,21 @@
 package main
 
 import (
-	"bytes"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"math"
+	"os"
+	"regexp"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/lightninglabs/chantools/btc"
 	"github.com/lightninglabs/chantools/btc/fasthd"
 	"github.com/lightninglabs/chantools/lnd"
 	"github.com/lightningnetwork/lnd/aezeed"
@@ -19,13 +23,52 @@ import (
 	"github.com/spf13/cobra"
 )
 
+const (
+	vanityTargetPubKey = "pubkey"
+	vanityTargetP2WKH  = "p2wkh"
+	vanityTargetP2TR   = "p2tr"
+
+	// maxVanityBits is the maximum number of bits a pattern can fix. It
+	// would take billions of years to find a seed for a longer pattern.
+	maxVanityBits = 60
+
+	hexCharset    = "0123456789abcdef"
+	bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+)
+
+const vanityGenSeedFileFormat = `Seed generated by chantools vanitygen
+
+Network:		%s
+%s:	%s
+Birthday:		%s
+
+The seed is enciphered with the passphrase that was entered when running
+vanitygen. The passphrase is needed to restore the seed, for example in lnd.
+
+Cipher seed mnemonic:
+%s
+`
+
 var (
 	nodeKeyDerivationPath = "m/1017'/%d'/%d'/0/0"
+
+	// vanityTargetNames are the human readable names of what is matched
+	// for each target.
+	vanityTargetNames = map[string]string{
+		vanityTargetPubKey: "Node identity pubkey",
+		vanityTargetP2WKH:  "First P2WKH address",
+		vanityTargetP2TR:   "First P2TR address",
+	}
 )
 
 type vanityGenCommand struct {
-	Prefix  string
-	Threads uint8
+	Prefix     string
+	Suffix     string
+	Regex      string
+	Target     string
+	IgnoreCase bool
+	SeedFile   string
+	Threads    uint8
 
 	cmd *cobra.Command
 }
@@ -35,28 +78,70 @@ func newVanityGenCommand() *cobra.Command {
 	cc.cmd = &cobra.Command{
 		Use: "vanitygen",
 		Short: "Generate a seed with a custom lnd node identity " +
-			"public key that starts with the given prefix",
+			"public key or wallet address",
 		Long: `Try random lnd compatible seeds until one is found that
-produces a node identity public key that starts with the given prefix.
+produces a node identity public key or first wallet address that matches the
+given pattern.
+
+What is matched is selected with --target:
+  pubkey: The hex encoded node identity public key (the default).
+  p2wkh: The first receive address of the native SegWit (BIP84) account.
+  p2tr: The first receive address of the taproot (BIP86) account.
+
+The pattern consists of a --prefix, a --suffix and/or a --regex that all must
+match. A pubkey prefix must start with 02 or 03, an address prefix with the
+human readable part of the network and the witness version, for example bc1q
+for P2WKH or bc1p for P2TR addresses on mainnet. With --ignorecase, upper and
+lower case letters of the pattern are treated the same.
+
+The seed that is found is never shown on the terminal. Instead, it is
+enciphered with a passphrase and written to the file given with --seedfile.
+The passphrase is read from the AEZEED_NEW_PASSPHRASE environment variable, if
+set. Otherwise it is read from the terminal before the search starts.
 
 Example output:
 
 <pre>
-Running vanitygen on 8 threads. Prefix bit length is 17, expecting to approach
-probability p=1.0 after 131,072 seeds.
-Tested 185k seeds, p=1.41296, speed=14k/s, elapsed=13s                          
-Looking for 022222, found pubkey: 022222f015540ddde9bdf7c95b24f1d44f7ea6ab69bec83d6fbe622296d64b51d6
-with seed: [ability roast pear stomach wink cable tube trumpet shy caught hunt
-someone border organ spoon only prepare calm silent million tobacco chaos normal
-phone]
+Running vanitygen on 8 threads. Pattern bit length is 17, expecting to
+approach probability p=1.0 after 131,072 seeds.
+Tested 185k seeds, p=1.41296, speed=14k/s, elapsed=13s
+Found Node identity pubkey: 022222f015540ddde9bdf7c95b24f1d44f7ea6ab69bec83d6fbe622296d64b51d6
+Seed written to results/vanitygen-2024-01-01-12-00-00.txt, enciphered with the
+passphrase.
 </pre>
 `,
-		Example: `chantools vanitygen --prefix 022222 --threads 8`,
-		RunE:    cc.Execute,
+		Example: `chantools vanitygen --prefix 022222 --threads 8
+
+chantools vanitygen --target p2tr --prefix bc1pxyz --suffix 42 \
+	--seedfile vanity.txt`,
+		RunE: cc.Execute,
 	}
 	cc.cmd.Flags().StringVar(
-		&cc.Prefix, "prefix", "", "hex encoded prefix to find in node "+
-			"public key",
+		&cc.Prefix, "prefix", "", "prefix to find in the node public "+
+			"key (hex) or the address",
+	)
+	cc.cmd.Flags().StringVar(
+		&cc.Suffix, "suffix", "", "suffix to find in the node public "+
+			"key (hex) or the address",
+	)
+	cc.cmd.Flags().StringVar(
+		&cc.Regex, "regex", "", "regular expression the node public "+
+			"key (hex) or the address must match",
+	)
+	cc.cmd.Flags().StringVar(
+		&cc.Target, "target", vanityTargetPubKey, "what to match the "+
+			"pattern against; either '"+vanityTargetPubKey+"', '"+
+			vanityTargetP2WKH+"' or '"+vanityTargetP2TR+"'",
+	)
+	cc.cmd.Flags().BoolVar(
+		&cc.IgnoreCase, "ignorecase", false, "match the pattern case "+
+			"insensitively",
+	)
+	seedFileName := fmt.Sprintf("results/vanitygen-%s.txt",
+		time.Now().Format("2006-01-02-15-04-05"))
+	cc.cmd.Flags().StringVar(
+		&cc.SeedFile, "seedfile", seedFileName, "the file to write "+
+			"the enciphered seed to",
 	)
 	cc.cmd.Flags().Uint8Var(
 		&cc.Threads, "threads", 4, "number of parallel threads",
@@ -65,47 +150,207 @@ phone]
 	return cc.cmd
 }
 
+// vanityMatcher matches the hex encoded public keys or the addresses derived
+// from the seeds against a pattern.
+type vanityMatcher struct {
+	prefix string
+	suffix string
+	regex  *regexp.Regexp
+}
+
+// newVanityMatcher creates a matcher for the given target and pattern. It also
+// returns the number of seeds that need to be tested on average to find a
+// match, or zero if that can't be estimated.
+func newVanityMatcher(target, prefix, suffix, regex string,
+	ignoreCase bool) (*vanityMatcher, float64, error) {
+
+	if prefix == "" && suffix == "" && regex == "" {
+		return nil, 0, errors.New("at least one of --prefix, " +
+			"--suffix or --regex must be specified")
+	}
+
+	// Public keys are encoded as lower case hex and bech32 addresses are
+	// lower case too. So we only need to convert the pattern.
+	if ignoreCase {
+		prefix = strings.ToLower(prefix)
+		suffix = strings.ToLower(suffix)
+		if regex != "" {
+			regex = "(?i)" + regex
+		}
+	}
+
+	// The first characters of a public key or an address are fixed or
+	// almost fixed, so they don't count towards the pattern's length.
+	var (
+		charset      string
+		bitsPerChar  int
+		fixedStart   string
+		variablePart string
+		numBits      int
+	)
+	switch target {
+	case vanityTargetPubKey:
+		charset, bitsPerChar = hexCharset, 4
+		validStart := len(prefix) == 0 || prefix == "0" ||
+			strings.HasPrefix(prefix, "02") ||
+			strings.HasPrefix(prefix, "03")
+		if !validStart {
+			return nil, 0, errors.New("prefix must start with 02 " +
+				"or 03 because it's an EC public key")
+		}
+
+		// The first byte only contains a single bit of information.
+		if len(prefix) >= 2 {
+			numBits = 1
+			variablePart = prefix[2:]
+		}
+
+	case vanityTargetP2WKH, vanityTargetP2TR:
+		charset, bitsPerChar = bech32Charset, 5
+		fixedStart = chainParams.Bech32HRPSegwit + "1q"
+		if target == vanityTargetP2TR {
+			fixedStart = chainParams.Bech32HRPSegwit + "1p"
+		}
+
+		switch {
+		case strings.HasPrefix(prefix, fixedStart):
+			variablePart = prefix[len(fixedStart):]
+
+		case !strings.HasPrefix(fixedStart, prefix):
+			return nil, 0, fmt.Errorf("prefix of a %s address "+
+				"must start with %s", target, fixedStart)
+		}
+
+	default:
+		return nil, 0, fmt.Errorf("invalid target '%s'", target)
+	}
+
+	for _, part := range []string{variablePart, suffix} {
+		for _, char := range part {
+			if !strings.ContainsRune(charset, char) {
+				return nil, 0, fmt.Errorf("invalid character "+
+					"'%c' in pattern, must be one of '%s' "+
+					"(use --ignorecase for upper case "+
+					"characters)", char, charset)
+			}
+		}
+	}
+
+	numBits += (len(variablePart) + len(suffix)) * bitsPerChar
+	if numBits > maxVanityBits {
+		return nil, 0, errors.New("pattern too long, unlikely to " +
+			"find a seed within billions of years")
+	}
+
+	matcher := &vanityMatcher{
+		prefix: prefix,
+		suffix: suffix,
+	}
+	numTries := math.Pow(2, float64(numBits))
+	if regex != "" {
+		var err error
+		matcher.regex, err = regexp.Compile(regex)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid regex: %w", err)
+		}
+
+		// We can't know how likely a regular expression matches.
+		numTries = 0
+	}
+
+	return matcher, numTries, nil
+}
+
+// matches returns true if the given public key or address matches the
+// pattern.
+func (m *vanityMatcher) matches(candidate string) bool {
+	return strings.HasPrefix(candidate, m.prefix) &&
+		strings.HasSuffix(candidate, m.suffix) &&
+		(m.regex == nil || m.regex.MatchString(candidate))
+}
+
+// vanityResult is a seed that matches the pattern.
+type vanityResult struct {
+	entropy   [16]byte
+	candidate string
+}
+
 func (c *vanityGenCommand) Execute(_ *cobra.Command, _ []string) error {
-	prefixBytes, err := hex.DecodeString(c.Prefix)
+	matcher, numTries, err := newVanityMatcher(
+		c.Target, c.Prefix, c.Suffix, c.Regex, c.IgnoreCase,
+	)
 	if err != nil {
-		return fmt.Errorf("hex decoding of prefix failed: %w", err)
+		return err
+	}
+	if c.Threads == 0 {
+		return errors.New("at least one thread is required")
 	}
 
-	if len(prefixBytes) < 2 {
-		return errors.New("prefix must be at least 2 bytes")
+	var path []uint32
+	switch c.Target {
+	case vanityTargetPubKey:
+		path, err = lnd.ParsePath(fmt.Sprintf(
+			nodeKeyDerivationPath, chainParams.HDCoinType,
+			keychain.KeyFamilyNodeKey,
+		))
+		if err != nil {
+			return err
+		}
+
+	case vanityTargetP2WKH:
+		path = btc.WalletAccountP2WKH.Path(chainParams, 0, 0)
+
+	case vanityTargetP2TR:
+		path = btc.WalletAccountP2TR.Path(chainParams, 0, 0)
 	}
-	if len(prefixBytes) > 8 {
-		return errors.New("prefix too long, unlikely to find a key " +
-			"within billions of years")
+
+	// We never want to show the seed on the terminal, so we make sure we
+	// can write it to the file before we start the search.
+	if c.SeedFile == "" {
+		return errors.New("seed file is required")
 	}
-	if !(prefixBytes[0] == 0x02 || prefixBytes[0] == 0x03) {
-		return errors.New("prefix must start with 02 or 03 because " +
-			"it's an EC public key")
+	if _, err := os.Stat(c.SeedFile); err == nil {
+		return fmt.Errorf("seed file %s already exists", c.SeedFile)
 	}
-
-	path, err := lnd.ParsePath(fmt.Sprintf(
-		nodeKeyDerivationPath, chainParams.HDCoinType,
-		keychain.KeyFamilyNodeKey,
-	))
+	passphrase, err := readNewSeedPassphrase(
+		"Input passphrase to encipher the seed with: ",
+	)
 	if err != nil {
 		return err
 	}
+	if len(passphrase) == 0 {
+		return errors.New("a passphrase is required to encipher the " +
+			"seed that is written to the seed file")
+	}
 
-	numBits := ((len(prefixBytes) - 1) * 8) + 1
-	numTries := math.Pow(2, float64(numBits))
-	fmt.Printf("Running vanitygen on %d threads. Prefix bit length is %d, "+
-		"expecting to approach\nprobability p=1.0 after %s seeds.\n",
-		c.Threads, numBits, format(int64(numTries)))
+	if numTries > 0 {
+		fmt.Printf("Running vanitygen on %d threads. Pattern bit "+
+			"length is %d, expecting to\napproach probability "+
+			"p=1.0 after %s seeds.\n", c.Threads,
+			int(math.Log2(numTries)), format(int64(numTries)))
+	} else {
+		fmt.Printf("Running vanitygen on %d threads. The probability "+
+			"of a regex match can't be estimated.\n", c.Threads)
+	}
 	runtime.GOMAXPROCS(int(c.Threads))
 	var (
 		mtx         sync.Mutex
 		globalCount uint64
 		abort       = make(chan struct{})
+		results     = make(chan *vanityResult, c.Threads)
+		wg          sync.WaitGroup
 		start       = time.Now()
 	)
+	defer func() {
+		close(abort)
+		wg.Wait()
+	}()
 
 	for range c.Threads {
+		wg.Add(1)
 		go func() {
+			defer wg.Done()
+
 			var (
 				entropy [16]byte
 				count   uint64
@@ -119,37 +364,22 @@ func (c *vanityGenCommand) Execute(_ *cobra.Command, _ []string) error {
 
 				if _, err := rand.Read(entropy[:]); err != nil {
 					log.Error(err)
+					continue
 				}
-				rootKey, err := fasthd.NewFastDerivation(
-					entropy[:], chainParams,
+				candidate, err := c.deriveCandidate(
+					entropy[:], path,
 				)
 				if err != nil {
-					log.Error(err)
+					// Some seeds or children are invalid,
+					// we just try the next one.
+					continue
 				}
-				err = rootKey.ChildPath(path)
-				if err != nil {
-					log.Error(err)
-				}
-				pubKeyBytes := rootKey.PubKeyBytes()
-
-				if bytes.HasPrefix(pubKeyBytes, prefixBytes) {
-					seed, err := aezeed.New(
-						aezeed.CipherSeedVersion,
-						&entropy, time.Now(),
-					)
-					if err != nil {
-						log.Error(err)
-					}
-					mnemonic, err := seed.ToMnemonic(nil)
-					if err != nil {
-						log.Error(err)
-					}
-					fmt.Printf("\nLooking for %x, found "+
-						"pubkey: %x\nwith seed: %v\n",
-						prefixBytes, pubKeyBytes,
-						mnemonic)
 
-					close(abort)
+				if matcher.matches(candidate) {
+					results <- &vanityResult{
+						entropy:   entropy,
+						candidate: candidate,
+					}
 					return
 				}
 
@@ -167,17 +397,23 @@ func (c *vanityGenCommand) Execute(_ *cobra.Command, _ []string) error {
 	lastCount := uint64(0)
 	for {
 		select {
-		case <-abort:
-			return nil
+		case result := <-results:
+			return c.writeSeed(result, passphrase)
+
 		case <-time.After(1 * time.Second):
 			mtx.Lock()
 			currentCount := globalCount
 			mtx.Unlock()
 
-			msg := fmt.Sprintf("Tested %sk seeds, p=%.5f, "+
+			probability := "n/a"
+			if numTries > 0 {
+				probability = fmt.Sprintf(
+					"%.5f", float64(currentCount)/numTries,
+				)
+			}
+			msg := fmt.Sprintf("Tested %sk seeds, p=%s, "+
 				"speed=%dk/s, elapsed=%v",
-				format(int64(currentCount/1000)),
-				float64(currentCount)/numTries,
+				format(int64(currentCount/1000)), probability,
 				(currentCount-lastCount)/1000,
 				time.Since(start).Truncate(time.Second),
 			)
@@ -188,6 +424,90 @@ func (c *vanityGenCommand) Execute(_ *cobra.Command, _ []string) error {
 	}
 }
 
+// deriveCandidate derives the hex encoded public key or the address of the
+// target from the given entropy.
+func (c *vanityGenCommand) deriveCandidate(entropy []byte,
+	path []uint32) (string, error) {
+
+	key, err := fasthd.NewFastDerivation(entropy, chainParams)
+	if err != nil {
+		return "", err
+	}
+	if err := key.ChildPath(path); err != nil {
+		return "", err
+	}
+
+	switch c.Target {
+	case vanityTargetP2WKH:
+		addr, err := btcutil.NewAddressWitnessPubKeyHash(
+			btcutil.Hash160(key.PubKeyBytes()), chainParams,
+		)
+		if err != nil {
+			return "", err
+		}
+		return addr.EncodeAddress(), nil
+
+	case vanityTargetP2TR:
+		addr, err := btcutil.NewAddressTaproot(
+			key.TaprootPubKeyBytes(), chainParams,
+		)
+		if err != nil {
+			return "", err
+		}
+		return addr.EncodeAddress(), nil
+
+	default:
+		return hex.EncodeToString(key.PubKeyBytes()), nil
+	}
+}
+
+// writeSeed enciphers the seed of the result with the passphrase and writes it
+// to the seed file.
+func (c *vanityGenCommand) writeSeed(result *vanityResult,
+	passphrase []byte) error {
+
+	seed, err := aezeed.New(
+		aezeed.CipherSeedVersion, &result.entropy, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("error creating seed: %w", err)
+	}
+	mnemonic, err := seed.ToMnemonic(passphrase)
+	if err != nil {
+		return fmt.Errorf("error enciphering seed: %w", err)
+	}
+
+	content := fmt.Sprintf(
+		vanityGenSeedFileFormat, chainParams.Name,
+		vanityTargetNames[c.Target], result.candidate,
+		seed.BirthdayTime().Format("2006-01-02"),
+		strings.Join(mnemonic[:], " "),
+	)
+	file, err := os.OpenFile(
+		c.SeedFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating seed file: %w", err)
+	}
+	if _, err := file.WriteString(content); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("error writing seed file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error writing seed file: %w", err)
+	}
+
+	msg := fmt.Sprintf("\nFound %s: %s\nSeed written to %s, "+
+		"enciphered with the passphrase.",
+		vanityTargetNames[c.Target], result.candidate, c.SeedFile)
+	fmt.Println(msg)
+
+	// For the tests, also log as trace level which is disabled by default.
+	log.Tracef(msg)
+
+	return nil
+}
+
 func format(n int64) string {
 	in := strconv.FormatInt(n, 10)
 	numOfDigits := len(in)
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/hex"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/lightninglabs/chantools/btc"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/stretchr/testify/require"
+)
+
+var vanityGenMnemonicPattern = regexp.MustCompile(
+	`Cipher seed mnemonic:\n([a-z ]+)\n`,
+)
+
+func TestVanityGenMatcher(t *testing.T) {
+	// The harness switches to the regtest network.
+	_ = newHarness(t)
+
+	testCases := []struct {
+		name       string
+		target     string
+		prefix     string
+		suffix     string
+		regex      string
+		ignoreCase bool
+		numTries   float64
+		candidate  string
+		matches    bool
+		err        string
+	}{{
+		name:   "no pattern",
+		target: vanityTargetPubKey,
+		err:    "at least one of",
+	}, {
+		name:   "invalid pubkey prefix",
+		target: vanityTargetPubKey,
+		prefix: "04ab",
+		err:    "must start with 02 or 03",
+	}, {
+		name:   "invalid hex character",
+		target: vanityTargetPubKey,
+		prefix: "02xy",
+		err:    "invalid character 'x'",
+	}, {
+		name:   "upper case without ignorecase",
+		target: vanityTargetPubKey,
+		suffix: "AB",
+		err:    "use --ignorecase",
+	}, {
+		name:   "pattern too long",
+		target: vanityTargetPubKey,
+		prefix: "02" + strings.Repeat("a", 15),
+		err:    "pattern too long",
+	}, {
+		name:      "pubkey prefix",
+		target:    vanityTargetPubKey,
+		prefix:    "02ab",
+		numTries:  512,
+		candidate: "02abcdef",
+		matches:   true,
+	}, {
+		name:       "pubkey suffix ignore case",
+		target:     vanityTargetPubKey,
+		suffix:     "EF",
+		ignoreCase: true,
+		numTries:   256,
+		candidate:  "02abcdef",
+		matches:    true,
+	}, {
+		name:      "pubkey regex",
+		target:    vanityTargetPubKey,
+		regex:     "^03.*cafe",
+		candidate: "02abcafe",
+		matches:   false,
+	}, {
+		name:   "invalid address prefix",
+		target: vanityTargetP2TR,
+		prefix: "bcrt1qabc",
+		err:    "must start with bcrt1p",
+	}, {
+		name:   "invalid bech32 character",
+		target: vanityTargetP2WKH,
+		prefix: "bcrt1qb",
+		err:    "invalid character 'b'",
+	}, {
+		name:      "address prefix and suffix",
+		target:    vanityTargetP2WKH,
+		prefix:    "bcrt1qxy",
+		suffix:    "z",
+		numTries:  32768,
+		candidate: "bcrt1qxyabcz",
+		matches:   true,
+	}, {
+		name:      "short address prefix",
+		target:    vanityTargetP2TR,
+		prefix:    "bcrt",
+		numTries:  1,
+		candidate: "bcrt1pabc",
+		matches:   true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			matcher, numTries, err := newVanityMatcher(
+				tc.target, tc.prefix, tc.suffix, tc.regex,
+				tc.ignoreCase,
+			)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.numTries, numTries)
+			require.Equal(
+				t, tc.matches, matcher.matches(tc.candidate),
+			)
+		})
+	}
+}
+
+func TestVanityGen(t *testing.T) {
+	testCases := []struct {
+		target string
+		prefix string
+		suffix string
+	}{{
+		target: vanityTargetPubKey,
+		suffix: "a",
+	}, {
+		target: vanityTargetP2WKH,
+		prefix: "bcrt1qq",
+	}, {
+		target: vanityTargetP2TR,
+		prefix: "bcrt1pp",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.target, func(t *testing.T) {
+			h := newHarness(t)
+
+			vanityGen := &vanityGenCommand{
+				Prefix:   tc.prefix,
+				Suffix:   tc.suffix,
+				Target:   tc.target,
+				SeedFile: h.tempFile("vanity.txt"),
+				Threads:  1,
+			}
+
+			t.Setenv(lnd.NewPassphraseEnvName, testPassPhrase)
+
+			err := vanityGen.Execute(nil, nil)
+			require.NoError(t, err)
+
+			// The seed must never be logged, only written to the
+			// file.
+			content, err := os.ReadFile(vanityGen.SeedFile)
+			require.NoError(t, err)
+
+			matches := vanityGenMnemonicPattern.FindStringSubmatch(
+				string(content),
+			)
+			require.Len(t, matches, 2)
+			require.NotContains(t, h.getLog(), matches[1])
+
+			// The seed must produce a matching key or address.
+			seed := decipherTestSeed(t, matches[1], testPassPhrase)
+			rootKey, err := hdkeychain.NewMaster(
+				seed.Entropy[:], chainParams,
+			)
+			require.NoError(t, err)
+
+			var (
+				account  = btc.WalletAccountP2WKH
+				expected string
+			)
+			if tc.target == vanityTargetP2TR {
+				account = btc.WalletAccountP2TR
+			}
+
+			switch tc.target {
+			case vanityTargetPubKey:
+				pubKey, err := lnd.DeriveChildren(
+					rootKey, []uint32{
+						lnd.HardenedKeyStart + 1017,
+						lnd.HardenedKeyStart +
+							chainParams.HDCoinType,
+						lnd.HardenedKeyStart + 6,
+						0, 0,
+					},
+				)
+				require.NoError(t, err)
+				ecPubKey, err := pubKey.ECPubKey()
+				require.NoError(t, err)
+
+				expected = hex.EncodeToString(
+					ecPubKey.SerializeCompressed(),
+				)
+				require.True(t, strings.HasSuffix(
+					expected, tc.suffix,
+				))
+
+			default:
+				key, err := lnd.DeriveChildren(
+					rootKey,
+					account.Path(chainParams, 0, 0),
+				)
+				require.NoError(t, err)
+				pubKey, err := key.ECPubKey()
+				require.NoError(t, err)
+				addr, err := account.Address(
+					pubKey, 0, chainParams,
+				)
+				require.NoError(t, err)
+
+				expected = addr.String()
+				require.True(t, strings.HasPrefix(
+					expected, tc.prefix,
+				))
+			}
+
+			require.Contains(t, string(content), expected)
+			h.assertLogContains(expected)
+		})
+	}
+}
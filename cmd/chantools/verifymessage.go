@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightninglabs/chantools/btc"
+	"github.com/spf13/cobra"
+	"github.com/tv42/zbase32"
+)
+
+type verifyMessageCommand struct {
+	Msg     string
+	Sig     string
+	PubKey  string
+	Address string
+
+	cmd *cobra.Command
+}
+
+func newVerifyMessageCommand() *cobra.Command {
+	cc := &verifyMessageCommand{}
+	cc.cmd = &cobra.Command{
+		Use:   "verifymessage",
+		Short: "Verify a message signature of a node or an address",
+		Long: `Verifies the signature of a message that was created with
+the signmessage command of chantools or lnd.
+
+By default, the signature is expected in lnd's zbase32 encoded format. The
+public key that created the signature is recovered from it and printed. If
+--pubkey is specified, the command fails if the signature was created by a
+different key.
+
+If --address is specified, the signature is verified as a base64 encoded
+BIP-322 "simple" signature for that address instead.`,
+		Example: `chantools verifymessage --msg=foobar \
+	--sig=d6ydkz3p... --pubkey 03abcdef...
+
+chantools verifymessage --msg=foobar --sig=AkcwRAIg... \
+	--address bc1q.....`,
+		RunE: cc.Execute,
+	}
+	cc.cmd.Flags().StringVar(
+		&cc.Msg, "msg", "", "the message that was signed",
+	)
+	cc.cmd.Flags().StringVar(
+		&cc.Sig, "sig", "", "the signature to verify",
+	)
+	cc.cmd.Flags().StringVar(
+		&cc.PubKey, "pubkey", "", "the public key the signature is "+
+			"expected to be created with",
+	)
+	cc.cmd.Flags().StringVar(
+		&cc.Address, "address", "", "verify a BIP-322 signature for "+
+			"this address instead",
+	)
+
+	return cc.cmd
+}
+
+func (c *verifyMessageCommand) Execute(_ *cobra.Command, _ []string) error {
+	if c.Msg == "" {
+		return errors.New("please enter a valid msg")
+	}
+	if c.Sig == "" {
+		return errors.New("please enter a valid sig")
+	}
+
+	if c.Address != "" {
+		if c.PubKey != "" {
+			return errors.New("--pubkey cannot be used together " +
+				"with --address")
+		}
+
+		addr, err := btcutil.DecodeAddress(c.Address, chainParams)
+		if err != nil {
+			return fmt.Errorf("error decoding address: %w", err)
+		}
+
+		err = btc.VerifyBIP322Simple(addr, []byte(c.Msg), c.Sig)
+		if err != nil {
+			return err
+		}
+
+		result := fmt.Sprintf("Signature is valid for address %v",
+			addr)
+		fmt.Println(result)
+
+		// For the tests, also log as trace level which is disabled by
+		// default.
+		log.Tracef(result)
+
+		return nil
+	}
+
+	sigBytes, err := zbase32.DecodeString(c.Sig)
+	if err != nil {
+		return fmt.Errorf("error decoding signature: %w", err)
+	}
+
+	// The signature commits to the message with the special lnd prefix,
+	// see signmessage.
+	msg := append(append([]byte{}, signedMsgPrefix...), c.Msg...)
+	digest := chainhash.DoubleHashB(msg)
+	pubKey, _, err := ecdsa.RecoverCompact(sigBytes, digest)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	pubKeyBytes := pubKey.SerializeCompressed()
+
+	if c.PubKey != "" {
+		expectedPubKey, err := hex.DecodeString(c.PubKey)
+		if err != nil {
+			return fmt.Errorf("error decoding pubkey: %w", err)
+		}
+
+		if !bytes.Equal(expectedPubKey, pubKeyBytes) {
+			return fmt.Errorf("signature was created by public "+
+				"key %x, not by %x", pubKeyBytes,
+				expectedPubKey)
+		}
+	}
+
+	result := fmt.Sprintf("Signature is valid for public key %x",
+		pubKeyBytes)
+	fmt.Println(result)
+
+	// For the tests, also log as trace level which is disabled by default.
+	log.Tracef(result)
+
+	return nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/lightninglabs/chantools/btc"
+	"github.com/spf13/cobra"
+)
+
+type walletBalanceCommand struct {
+	APIURL   string
+	GapLimit uint32
+	Workers  int
+	JSON     bool
+
+	rootKey *rootKey
+	cmd     *cobra.Command
+}
+
+func newWalletBalanceCommand() *cobra.Command {
+	cc := &walletBalanceCommand{}
+	cc.cmd = &cobra.Command{
+		Use: "walletbalance",
+		Short: "Scan the on-chain wallet derived from a seed for its " +
+			"balance",
+		Long: `Derives the addresses of lnd's on-chain wallet (the
+np2wkh, p2wkh and p2tr accounts, both external and internal branches) and of
+lnd's internal key families from the root key and looks them up through the
+chain backend. Every branch is scanned until a number of consecutive unused
+addresses equal to the gap limit is found, the addresses of one batch are
+looked up in parallel.
+
+This doesn't need the wallet.db file of lnd, only the seed. The result contains
+all unspent outputs, the balance of each derivation path and the highest used
+index of each branch, which is useful for choosing the recovery window when
+restoring the wallet.`,
+		Example: `chantools walletbalance --gaplimit 50
+
+chantools walletbalance --json`,
+		RunE: cc.Execute,
+	}
+	cc.cmd.Flags().StringVar(
+		&cc.APIURL, "apiurl", defaultAPIURL, "API URL to use (must "+
+			"be esplora compatible)",
+	)
+	cc.cmd.Flags().Uint32Var(
+		&cc.GapLimit, "gaplimit", btc.DefaultGapLimit, "number of "+
+			"consecutive unused addresses after which the scan of "+
+			"a branch is stopped",
+	)
+	cc.cmd.Flags().IntVar(
+		&cc.Workers, "workers", btc.DefaultScanWorkers, "number of "+
+			"addresses that are looked up in parallel",
+	)
+	cc.cmd.Flags().BoolVar(
+		&cc.JSON, "json", false, "print the result as JSON instead "+
+			"of a human readable report",
+	)
+
+	cc.rootKey = newRootKey(cc.cmd, "deriving the wallet addresses")
+
+	return cc.cmd
+}
+
+func (c *walletBalanceCommand) Execute(_ *cobra.Command, _ []string) error {
+	extendedKey, err := c.rootKey.read()
+	if err != nil {
+		return fmt.Errorf("error reading root key: %w", err)
+	}
+
+	scanner := &btc.WalletScanner{
+		API:         newExplorerAPI(c.APIURL),
+		RootKey:     extendedKey,
+		ChainParams: chainParams,
+		GapLimit:    c.GapLimit,
+		NumWorkers:  c.Workers,
+	}
+	results, err := scanner.Scan(walletBalanceAccounts())
+	if err != nil {
+		return fmt.Errorf("error scanning wallet: %w", err)
+	}
+
+	report := newWalletBalanceReport(results)
+	if c.JSON {
+		reportBytes, err := json.MarshalIndent(report, "", " ")
+		if err != nil {
+			return fmt.Errorf("error encoding report: %w", err)
+		}
+		fmt.Println(string(reportBytes))
+
+		return nil
+	}
+
+	fmt.Print(report.String())
+
+	return nil
+}
+
+// walletBalanceAccounts returns the accounts of lnd's on-chain wallet followed
+// by one account for each of lnd's internal key families.
+func walletBalanceAccounts() []*btc.WalletAccount {
+	accounts := append([]*btc.WalletAccount{}, btc.DefaultWalletAccounts...)
+	for _, family := range lndKeyFamilies {
+		accounts = append(accounts, btc.NewLndFamilyAccount(
+			family.name, family.family,
+		))
+	}
+
+	return accounts
+}
+
+// walletBalanceUTXO is a single unspent output found in the wallet.
+type walletBalanceUTXO struct {
+	OutPoint    string `json:"outpoint"`
+	Address     string `json:"address"`
+	Path        string `json:"path"`
+	Value       uint64 `json:"value"`
+	Confirmed   bool   `json:"confirmed"`
+	BlockHeight int    `json:"block_height,omitempty"`
+}
+
+// walletBalanceBranch is the balance of a single branch of an account.
+type walletBalanceBranch struct {
+	Account            string               `json:"account"`
+	Path               string               `json:"path"`
+	HighestUsedIndex   int64                `json:"highest_used_index"`
+	NextUnusedAddress  string               `json:"next_unused_address"`
+	ConfirmedBalance   uint64               `json:"confirmed_balance"`
+	UnconfirmedBalance uint64               `json:"unconfirmed_balance"`
+	UTXOs              []*walletBalanceUTXO `json:"utxos"`
+}
+
+// walletBalanceReport is the result of scanning the whole wallet.
+type walletBalanceReport struct {
+	Branches           []*walletBalanceBranch `json:"branches"`
+	ConfirmedBalance   uint64                 `json:"confirmed_balance"`
+	UnconfirmedBalance uint64                 `json:"unconfirmed_balance"`
+	TotalBalance       uint64                 `json:"total_balance"`
+}
+
+// newWalletBalanceReport sums up the balances of the given scan results.
+func newWalletBalanceReport(
+	results []*btc.WalletBranchResult) *walletBalanceReport {
+
+	report := &walletBalanceReport{}
+	for _, result := range results {
+		// The path of the branch is the path of any of its addresses
+		// without the index.
+		path := result.NextUnused.PathString()
+		path = path[:strings.LastIndex(path, "/")]
+
+		branch := &walletBalanceBranch{
+			Account:           result.Account.Name,
+			Path:              path,
+			HighestUsedIndex:  result.HighestUsedIndex,
+			NextUnusedAddress: result.NextUnused.Address.String(),
+			UTXOs:             []*walletBalanceUTXO{},
+		}
+		for _, utxo := range result.UTXOs {
+			branch.UTXOs = append(branch.UTXOs, &walletBalanceUTXO{
+				OutPoint:    utxo.OutPoint.String(),
+				Address:     utxo.Address.String(),
+				Path:        utxo.PathString(),
+				Value:       utxo.Value,
+				Confirmed:   utxo.Confirmed,
+				BlockHeight: utxo.BlockHeight,
+			})
+
+			if utxo.Confirmed {
+				branch.ConfirmedBalance += utxo.Value
+			} else {
+				branch.UnconfirmedBalance += utxo.Value
+			}
+		}
+
+		report.Branches = append(report.Branches, branch)
+		report.ConfirmedBalance += branch.ConfirmedBalance
+		report.UnconfirmedBalance += branch.UnconfirmedBalance
+	}
+	report.TotalBalance = report.ConfirmedBalance +
+		report.UnconfirmedBalance
+
+	return report
+}
+
+// String returns a human readable representation of the report.
+func (r *walletBalanceReport) String() string {
+	var b strings.Builder
+	for _, branch := range r.Branches {
+		// Unused branches of lnd's key families are the norm, so we
+		// only list the unused branches of the on-chain wallet.
+		if branch.HighestUsedIndex < 0 {
+			if !strings.HasPrefix(branch.Path, "m/1017'") {
+				_, _ = fmt.Fprintf(&b, "%s (%s): unused\n",
+					branch.Path, branch.Account)
+			}
+			continue
+		}
+
+		_, _ = fmt.Fprintf(&b, "%s (%s): highest used index %d, "+
+			"next unused address %s\n", branch.Path,
+			branch.Account, branch.HighestUsedIndex,
+			branch.NextUnusedAddress)
+		_, _ = fmt.Fprintf(&b, "  Balance: %v confirmed, %v "+
+			"unconfirmed\n",
+			btcutil.Amount(branch.ConfirmedBalance),
+			btcutil.Amount(branch.UnconfirmedBalance))
+		for _, utxo := range branch.UTXOs {
+			status := "unconfirmed"
+			if utxo.Confirmed {
+				status = fmt.Sprintf("confirmed at height %d",
+					utxo.BlockHeight)
+			}
+			_, _ = fmt.Fprintf(&b, "  UTXO %s: %v at %s (%s), "+
+				"%s\n", utxo.OutPoint,
+				btcutil.Amount(utxo.Value), utxo.Address,
+				utxo.Path, status)
+		}
+	}
+
+	_, _ = fmt.Fprintf(&b, "\nTotal balance: %v (%v confirmed, %v "+
+		"unconfirmed)\n", btcutil.Amount(r.TotalBalance),
+		btcutil.Amount(r.ConfirmedBalance),
+		btcutil.Amount(r.UnconfirmedBalance))
+
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightninglabs/chantools/btc"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalletBalanceReport(t *testing.T) {
+	_ = newHarness(t)
+
+	extendedKey, err := hdkeychain.NewKeyFromString(rootKeyAezeed)
+	require.NoError(t, err)
+
+	walletAddress := func(account *btc.WalletAccount, branch,
+		index uint32) *btc.WalletAddress {
+
+		path := account.Path(chainParams, branch, index)
+		privKey, err := lnd.PrivKeyFromPath(extendedKey, path)
+		require.NoError(t, err)
+		addr, err := account.Address(
+			privKey.PubKey(), branch, chainParams,
+		)
+		require.NoError(t, err)
+
+		return &btc.WalletAddress{
+			Account: account,
+			Branch:  branch,
+			Index:   index,
+			Path:    path,
+			PubKey:  privKey.PubKey(),
+			Address: addr,
+		}
+	}
+
+	account := btc.WalletAccountP2TR
+	familyAccount := btc.NewLndFamilyAccount(
+		"payment base", keychain.KeyFamilyPaymentBase,
+	)
+	usedAddr := walletAddress(account, 0, 3)
+	results := []*btc.WalletBranchResult{{
+		Account:          account,
+		Branch:           0,
+		HighestUsedIndex: 3,
+		NextUnused:       walletAddress(account, 0, 4),
+		UTXOs: []*btc.WalletUTXO{{
+			WalletAddress: usedAddr,
+			OutPoint:      wire.OutPoint{Hash: chainhash.Hash{1}},
+			Value:         10_000,
+			Confirmed:     true,
+			BlockHeight:   100,
+		}, {
+			WalletAddress: usedAddr,
+			OutPoint:      wire.OutPoint{Hash: chainhash.Hash{2}},
+			Value:         5_000,
+		}},
+	}, {
+		Account:          account,
+		Branch:           1,
+		HighestUsedIndex: -1,
+		NextUnused:       walletAddress(account, 1, 0),
+	}, {
+		Account:          familyAccount,
+		Branch:           0,
+		HighestUsedIndex: -1,
+		NextUnused:       walletAddress(familyAccount, 0, 0),
+	}}
+
+	report := newWalletBalanceReport(results)
+	require.Len(t, report.Branches, 3)
+	require.Equal(t, "m/86'/1'/0'/0", report.Branches[0].Path)
+	require.Equal(t, "m/1017'/1'/3'/0", report.Branches[2].Path)
+	require.EqualValues(t, 10_000, report.Branches[0].ConfirmedBalance)
+	require.EqualValues(t, 5_000, report.Branches[0].UnconfirmedBalance)
+	require.Equal(
+		t, "m/86'/1'/0'/0/3", report.Branches[0].UTXOs[0].Path,
+	)
+	require.EqualValues(t, 15_000, report.TotalBalance)
+
+	// Unused lnd key families are left out of the human readable report.
+	output := report.String()
+	require.Contains(t, output, "m/86'/1'/0'/1 (p2tr): unused")
+	require.NotContains(t, output, "m/1017'")
+	require.Contains(t, output, "Total balance: 0.00015000 BTC")
+}
@@ -1,14 +1,22 @@
 package main
 
 import (
+	"cmp"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcwallet/waddrmgr"
 	"github.com/btcsuite/btcwallet/wallet"
 	"github.com/btcsuite/btcwallet/walletdb"
 	_ "github.com/btcsuite/btcwallet/walletdb/bdb"
+	"github.com/btcsuite/btcwallet/wtxmgr"
 	"github.com/lightninglabs/chantools/lnd"
 	"github.com/lightningnetwork/lnd/keychain"
 	"github.com/spf13/cobra"
@@ -20,23 +28,26 @@ Identity Pubkey:		%x
 BIP32 HD extended root key:	%s
 Wallet scopes:
 %s
+UTXOs:
+%s
+Transactions:
+%s
 `
 
 	keyScopeformat = `
-Scope:	m/%d'/%d'
+Scope:	m/%d'/%d', account %d (%s)
   Number of internal %s addresses:	%d
   Number of external %s addresses: 	%d
+  Number of imported addresses: 	%d
+  Balance:				%v
 `
 )
 
-var (
-	defaultAccount = uint32(waddrmgr.DefaultAccountNum)
-)
-
 type walletInfoCommand struct {
 	WalletDB    string
 	WithRootKey bool
 	DumpAddrs   bool
+	JSON        bool
 
 	cmd *cobra.Command
 }
@@ -56,7 +67,12 @@ extracted** because it is hashed into the extended HD root key before storing it
 in the wallet.db.
 In case lnd was started with "--noseedbackup=true" your wallet has the default
 password. To unlock the wallet set the environment variable WALLET_PASSWORD="-"
-or simply press <enter> without entering a password when being prompted.`,
+or simply press <enter> without entering a password when being prompted.
+
+All key scopes of the wallet are listed (np2wkh, p2wkh, p2tr and lnd's custom
+scopes), with all accounts of each scope, including imported ones. The unspent
+outputs and transactions the wallet knows about are listed as well. Use --json
+for an output that can be processed automatically.`,
 		Example: `chantools walletinfo --withrootkey \
 	--walletdb ~/.lnd/data/chain/bitcoin/mainnet/wallet.db`,
 		RunE: cc.Execute,
@@ -70,8 +86,12 @@ or simply press <enter> without entering a password when being prompted.`,
 			"key of wallet to standard out",
 	)
 	cc.cmd.Flags().BoolVar(
-		&cc.DumpAddrs, "dumpaddrs", false, "print all addresses, "+
-			"including private keys",
+		&cc.DumpAddrs, "dumpaddrs", false, "print all addresses of "+
+			"all accounts, including private keys",
+	)
+	cc.cmd.Flags().BoolVar(
+		&cc.JSON, "json", false, "print the result as JSON instead "+
+			"of a human readable report",
 	)
 
 	return cc.cmd
@@ -98,7 +118,7 @@ func (c *walletInfoCommand) Execute(_ *cobra.Command, _ []string) error {
 	}()
 
 	// Print the wallet info and if requested the root key.
-	identityKey, scopeInfo, err := walletInfo(w, c.DumpAddrs)
+	info, err := walletInfo(w, c.DumpAddrs)
 	if err != nil {
 		return err
 	}
@@ -111,12 +131,23 @@ func (c *walletInfoCommand) Execute(_ *cobra.Command, _ []string) error {
 			return err
 		}
 		rootKey = string(masterHDPrivKey)
+		info.RootKey = rootKey
 	}
 
-	result := fmt.Sprintf(
-		walletInfoFormat, identityKey.SerializeCompressed(), rootKey,
-		scopeInfo,
-	)
+	var result string
+	if c.JSON {
+		infoBytes, err := json.MarshalIndent(info, "", " ")
+		if err != nil {
+			return fmt.Errorf("error encoding wallet info: %w", err)
+		}
+		result = string(infoBytes)
+	} else {
+		result = fmt.Sprintf(
+			walletInfoFormat,
+			info.identityKey.SerializeCompressed(), rootKey,
+			info.scopeInfo(), info.utxoInfo(), info.txInfo(),
+		)
+	}
 
 	fmt.Println(result)
 
@@ -126,112 +157,394 @@ func (c *walletInfoCommand) Execute(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
-func walletInfo(w *wallet.Wallet, dumpAddrs bool) (*btcec.PublicKey, string,
+// walletInfoAccount is a single account of one of the wallet's key scopes.
+type walletInfoAccount struct {
+	Purpose          uint32 `json:"purpose"`
+	Coin             uint32 `json:"coin"`
+	AddressType      string `json:"address_type"`
+	Number           uint32 `json:"account_number"`
+	Name             string `json:"account_name"`
+	ExternalKeyCount uint32 `json:"external_key_count"`
+	InternalKeyCount uint32 `json:"internal_key_count"`
+	ImportedKeyCount uint32 `json:"imported_key_count"`
+	WatchOnly        bool   `json:"watch_only"`
+	Balance          int64  `json:"balance"`
+}
+
+// walletInfoAddress is a single address of the wallet.
+type walletInfoAddress struct {
+	Path    string `json:"path"`
+	PubKey  string `json:"pubkey"`
+	Address string `json:"address"`
+	Hash160 string `json:"hash160"`
+	PrivKey string `json:"privkey,omitempty"`
+}
+
+// walletInfoUTXO is an unspent output known to the wallet.
+type walletInfoUTXO struct {
+	OutPoint string `json:"outpoint"`
+	Address  string `json:"address"`
+	Scope    string `json:"scope"`
+	Account  uint32 `json:"account"`
+	Value    int64  `json:"value"`
+	Height   int32  `json:"height"`
+	Coinbase bool   `json:"coinbase"`
+}
+
+// walletInfoTx is a transaction known to the wallet.
+type walletInfoTx struct {
+	TxID     string `json:"txid"`
+	Height   int32  `json:"height"`
+	Received int64  `json:"received"`
+	Credits  int64  `json:"credits"`
+	Debits   int64  `json:"debits"`
+	Label    string `json:"label,omitempty"`
+}
+
+// walletInfoResult is all the information collected about a wallet.
+type walletInfoResult struct {
+	IdentityPubKey string               `json:"identity_pubkey"`
+	RootKey        string               `json:"root_key,omitempty"`
+	Accounts       []*walletInfoAccount `json:"accounts"`
+	UTXOs          []*walletInfoUTXO    `json:"utxos"`
+	Transactions   []*walletInfoTx      `json:"transactions"`
+	Addresses      []*walletInfoAddress `json:"addresses,omitempty"`
+
+	identityKey *btcec.PublicKey
+}
+
+func walletInfo(w *wallet.Wallet, dumpAddrs bool) (*walletInfoResult,
 	error) {
 
 	keyRing := keychain.NewBtcWalletKeyRing(w, chainParams.HDCoinType)
-	idPrivKey, err := keyRing.DerivePrivKey(keychain.KeyDescriptor{
-		KeyLocator: keychain.KeyLocator{
-			Family: keychain.KeyFamilyNodeKey,
-			Index:  0,
-		},
+	idPubKey, err := keyRing.DeriveKey(keychain.KeyLocator{
+		Family: keychain.KeyFamilyNodeKey,
+		Index:  0,
 	})
 	if err != nil {
-		return nil, "", fmt.Errorf("unable to open key ring for coin "+
-			"type %d: %v", chainParams.HDCoinType, err)
+		return nil, fmt.Errorf("unable to open key ring for coin "+
+			"type %d: %w", chainParams.HDCoinType, err)
 	}
 
-	// Collect information about the different addresses in use.
-	scopeNp2wkh, err := printScopeInfo(
-		"np2wkh", w, w.Manager.ScopesForExternalAddrType(
-			waddrmgr.NestedWitnessPubKey,
+	info := &walletInfoResult{
+		IdentityPubKey: hex.EncodeToString(
+			idPubKey.PubKey.SerializeCompressed(),
 		),
-	)
-	if err != nil {
-		return nil, "", err
+		identityKey: idPubKey.PubKey,
 	}
-	scopeP2wkh, err := printScopeInfo(
-		"p2wkh", w, w.Manager.ScopesForExternalAddrType(
-			waddrmgr.WitnessPubKey,
-		),
-	)
+
+	info.UTXOs, info.Transactions, err = walletTransactions(w)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
-	scopeAddrs := "\n"
-	if dumpAddrs {
-		printAddr := func(a waddrmgr.ManagedAddress) error {
-			pka, ok := a.(waddrmgr.ManagedPubKeyAddress)
-			if !ok {
-				return errors.New("key is not a managed pubkey")
+	// Collect information about all accounts of all scopes, including the
+	// taproot and lnd's custom scopes.
+	managers := w.Manager.ActiveScopedKeyManagers()
+	slices.SortFunc(managers, func(a, b *waddrmgr.ScopedKeyManager) int {
+		if a.Scope().Purpose != b.Scope().Purpose {
+			return cmp.Compare(a.Scope().Purpose, b.Scope().Purpose)
+		}
+
+		return cmp.Compare(a.Scope().Coin, b.Scope().Coin)
+	})
+	for _, mgr := range managers {
+		// Wallets created by old versions of lnd can contain
+		// experimental scopes btcwallet can't read anymore.
+		if !isKnownKeyScope(mgr.Scope()) {
+			log.Warnf("Skipping unknown key scope %v", mgr.Scope())
+			continue
+		}
+
+		accounts, err := w.Accounts(mgr.Scope())
+		if err != nil {
+			return nil, fmt.Errorf("error fetching accounts of "+
+				"scope %v: %w", mgr.Scope(), err)
+		}
+
+		addrType := addressTypeName(mgr.AddrSchema().ExternalAddrType)
+		for _, account := range accounts.Accounts {
+			// Every scope has an account for imported keys, we
+			// only list it if it's actually used.
+			imported := uint32(waddrmgr.ImportedAddrAccount)
+			if account.AccountNumber == imported &&
+				account.ImportedKeyCount == 0 {
+
+				continue
 			}
 
-			privKey, err := pka.PrivKey()
+			a := &walletInfoAccount{
+				Purpose:          mgr.Scope().Purpose,
+				Coin:             mgr.Scope().Coin,
+				AddressType:      addrType,
+				Number:           account.AccountNumber,
+				Name:             account.AccountName,
+				ExternalKeyCount: account.ExternalKeyCount,
+				InternalKeyCount: account.InternalKeyCount,
+				ImportedKeyCount: account.ImportedKeyCount,
+				WatchOnly:        account.IsWatchOnly,
+			}
+
+			// The balance btcwallet reports for an account
+			// includes the outputs of the accounts with the same
+			// number in all other scopes, so we sum it up
+			// ourselves.
+			scope := scopeString(mgr.Scope())
+			for _, utxo := range info.UTXOs {
+				if utxo.Scope == scope &&
+					utxo.Account == a.Number {
+
+					a.Balance += utxo.Value
+				}
+			}
+			info.Accounts = append(info.Accounts, a)
+		}
+
+		if dumpAddrs {
+			addrs, err := accountAddresses(w, mgr)
 			if err != nil {
-				return fmt.Errorf("error deriving priv key: %w",
-					err)
+				return nil, err
 			}
+			info.Addresses = append(info.Addresses, addrs...)
+		}
+	}
 
-			scope, path, _ := pka.DerivationInfo()
-			scopeAddrs += fmt.Sprintf(
-				"path=m/%d'/%d'/%d'/%d/%d, pubkey=%x, "+
-					"addr=%s, hash160=%x, priv=%x\n",
-				scope.Purpose, scope.Coin, path.InternalAccount,
-				path.Branch, path.Index,
-				pka.PubKey().SerializeCompressed(),
-				pka.Address().String(), a.AddrHash(),
-				privKey.Serialize(),
-			)
+	return info, nil
+}
+
+// scopeString returns the derivation path prefix of the given key scope.
+func scopeString(scope waddrmgr.KeyScope) string {
+	return fmt.Sprintf("m/%d'/%d'", scope.Purpose, scope.Coin)
+}
+
+// isKnownKeyScope returns true if the given scope is one of btcwallet's default
+// scopes or lnd's custom scope.
+func isKnownKeyScope(scope waddrmgr.KeyScope) bool {
+	if scope.Purpose == keychain.BIP0043Purpose {
+		return true
+	}
+
+	return slices.Contains(waddrmgr.DefaultKeyScopes, scope)
+}
+
+// addressTypeName returns the short name of the given address type.
+func addressTypeName(addrType waddrmgr.AddressType) string {
+	switch addrType {
+	case waddrmgr.NestedWitnessPubKey:
+		return "np2wkh"
+
+	case waddrmgr.WitnessPubKey:
+		return "p2wkh"
+
+	case waddrmgr.TaprootPubKey:
+		return "p2tr"
+
+	case waddrmgr.PubKeyHash:
+		return "p2pkh"
+
+	default:
+		return fmt.Sprintf("type %d", addrType)
+	}
+}
+
+// accountAddresses returns all addresses of all accounts of the given scoped
+// key manager. The private keys are only added if they're available.
+func accountAddresses(w *wallet.Wallet,
+	mgr *waddrmgr.ScopedKeyManager) ([]*walletInfoAddress, error) {
+
+	var managedAddrs []waddrmgr.ManagedAddress
+	err := walletdb.View(w.Database(), func(tx walletdb.ReadTx) error {
+		waddrmgrNs := tx.ReadBucket(lnd.WaddrmgrNamespaceKey)
+
+		addAddr := func(a waddrmgr.ManagedAddress) error {
+			managedAddrs = append(managedAddrs, a)
 			return nil
 		}
-		for _, mgr := range w.Manager.ActiveScopedKeyManagers() {
-			var addrs []waddrmgr.ManagedAddress
-			err = walletdb.View(
-				w.Database(), func(tx walletdb.ReadTx) error {
-					waddrmgrNs := tx.ReadBucket(
-						lnd.WaddrmgrNamespaceKey,
-					)
-
-					return mgr.ForEachAccountAddress(
-						waddrmgrNs, 0,
-						func(a waddrmgr.ManagedAddress) error {
-							addrs = append(addrs, a)
-							return nil
-						},
-					)
-				},
+
+		return mgr.ForEachAccount(waddrmgrNs, func(acct uint32) error {
+			return mgr.ForEachAccountAddress(
+				waddrmgrNs, acct, addAddr,
 			)
-			if err != nil {
-				return nil, "", err
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading addresses: %w", err)
+	}
+
+	addrs := make([]*walletInfoAddress, 0, len(managedAddrs))
+	for _, a := range managedAddrs {
+		pka, ok := a.(waddrmgr.ManagedPubKeyAddress)
+		if !ok {
+			return nil, errors.New("key is not a managed pubkey")
+		}
+
+		addr := &walletInfoAddress{
+			Path: "imported",
+			PubKey: hex.EncodeToString(
+				pka.PubKey().SerializeCompressed(),
+			),
+			Address: pka.Address().String(),
+			Hash160: hex.EncodeToString(a.AddrHash()),
+		}
+
+		scope, path, ok := pka.DerivationInfo()
+		if ok {
+			addr.Path = fmt.Sprintf("m/%d'/%d'/%d'/%d/%d",
+				scope.Purpose, scope.Coin,
+				path.InternalAccount, path.Branch, path.Index)
+		}
+
+		// Watch-only accounts don't have any private keys.
+		privKey, err := pka.PrivKey()
+		switch {
+		case err == nil:
+			addr.PrivKey = hex.EncodeToString(privKey.Serialize())
+
+		case !waddrmgr.IsError(err, waddrmgr.ErrWatchingOnly):
+			return nil, fmt.Errorf("error deriving priv key: %w",
+				err)
+		}
+
+		addrs = append(addrs, addr)
+	}
+
+	return addrs, nil
+}
+
+// walletTransactions returns the unspent outputs and transactions stored in
+// the wallet's transaction manager. Outputs that are currently leased aren't
+// included in the unspent outputs.
+func walletTransactions(w *wallet.Wallet) ([]*walletInfoUTXO,
+	[]*walletInfoTx, error) {
+
+	var (
+		utxos = []*walletInfoUTXO{}
+		txs   = []*walletInfoTx{}
+	)
+	err := walletdb.View(w.Database(), func(tx walletdb.ReadTx) error {
+		waddrmgrNs := tx.ReadBucket(lnd.WaddrmgrNamespaceKey)
+		wtxmgrNs := tx.ReadBucket(lnd.WtxmgrNamespaceKey)
+
+		credits, err := w.TxStore.UnspentOutputs(wtxmgrNs)
+		if err != nil {
+			return err
+		}
+		for _, credit := range credits {
+			utxo := &walletInfoUTXO{
+				OutPoint: credit.OutPoint.String(),
+				Value:    int64(credit.Amount),
+				Height:   credit.Height,
+				Coinbase: credit.FromCoinBase,
 			}
+			_, addrs, _, err := txscript.ExtractPkScriptAddrs(
+				credit.PkScript, chainParams,
+			)
+			if err == nil && len(addrs) > 0 {
+				utxo.Address = addrs[0].String()
 
-			for _, addr := range addrs {
-				if err := printAddr(addr); err != nil {
-					return nil, "", err
+				mgr, account, err := w.Manager.AddrAccount(
+					waddrmgrNs, addrs[0],
+				)
+				if err == nil {
+					utxo.Scope = scopeString(mgr.Scope())
+					utxo.Account = account
 				}
 			}
+			utxos = append(utxos, utxo)
 		}
+
+		return w.TxStore.RangeTransactions(
+			wtxmgrNs, 0, -1,
+			func(details []wtxmgr.TxDetails) (bool, error) {
+				for _, detail := range details {
+					txs = append(txs, newWalletInfoTx(
+						&detail,
+					))
+				}
+
+				return false, nil
+			},
+		)
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading transactions: %w",
+			err)
 	}
 
-	return idPrivKey.PubKey(), scopeNp2wkh + scopeP2wkh + scopeAddrs, nil
+	return utxos, txs, nil
 }
 
-func printScopeInfo(name string, w *wallet.Wallet,
-	scopes []waddrmgr.KeyScope) (string, error) {
+// newWalletInfoTx sums up the credits and debits of a wallet transaction.
+func newWalletInfoTx(details *wtxmgr.TxDetails) *walletInfoTx {
+	tx := &walletInfoTx{
+		TxID:     details.Hash.String(),
+		Height:   details.Block.Height,
+		Received: details.Received.Unix(),
+		Label:    details.Label,
+	}
+	for _, credit := range details.Credits {
+		tx.Credits += int64(credit.Amount)
+	}
+	for _, debit := range details.Debits {
+		tx.Debits += int64(debit.Amount)
+	}
 
-	scopeInfo := ""
-	for _, scope := range scopes {
-		props, err := w.AccountProperties(scope, defaultAccount)
-		if err != nil {
-			return "", fmt.Errorf("error fetching account "+
-				"properties: %w", err)
+	return tx
+}
+
+// scopeInfo returns a human readable summary of all accounts and, if they were
+// collected, all addresses of the wallet.
+func (r *walletInfoResult) scopeInfo() string {
+	var b strings.Builder
+	for _, a := range r.Accounts {
+		_, _ = fmt.Fprintf(
+			&b, keyScopeformat, a.Purpose, a.Coin, a.Number, a.Name,
+			a.AddressType, a.InternalKeyCount, a.AddressType,
+			a.ExternalKeyCount, a.ImportedKeyCount,
+			btcutil.Amount(a.Balance),
+		)
+	}
+
+	b.WriteString("\n")
+	for _, a := range r.Addresses {
+		privKey := a.PrivKey
+		if privKey == "" {
+			privKey = na
 		}
-		scopeInfo += fmt.Sprintf(
-			keyScopeformat, scope.Purpose, scope.Coin, name,
-			props.InternalKeyCount, name, props.ExternalKeyCount,
+		_, _ = fmt.Fprintf(
+			&b, "path=%s, pubkey=%s, addr=%s, hash160=%s, "+
+				"priv=%s\n", a.Path, a.PubKey, a.Address,
+			a.Hash160, privKey,
 		)
 	}
 
-	return scopeInfo, nil
+	return b.String()
+}
+
+// utxoInfo returns a human readable list of the wallet's unspent outputs.
+func (r *walletInfoResult) utxoInfo() string {
+	var b strings.Builder
+	for _, u := range r.UTXOs {
+		_, _ = fmt.Fprintf(&b, "%s: %v at %s (%s, account %d), "+
+			"height %d\n", u.OutPoint, btcutil.Amount(u.Value),
+			u.Address, u.Scope, u.Account, u.Height)
+	}
+
+	return b.String()
+}
+
+// txInfo returns a human readable list of the wallet's transactions.
+func (r *walletInfoResult) txInfo() string {
+	var b strings.Builder
+	for _, t := range r.Transactions {
+		_, _ = fmt.Fprintf(&b, "%s: height %d, credits %v, debits %v",
+			t.TxID, t.Height, btcutil.Amount(t.Credits),
+			btcutil.Amount(t.Debits))
+		if t.Label != "" {
+			_, _ = fmt.Fprintf(&b, ", label %q", t.Label)
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
 }
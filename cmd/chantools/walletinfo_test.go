@@ -10,6 +10,8 @@ import (
 const (
 	walletContent = "03b99ab108e39e9e4cf565c1b706480180a70a4fdc4828e44c50" +
 		"4530c056be5b5f"
+	walletUTXO = "a30980d0c88acd36a515dde37da615d6713a422ac1d4fb9bb47823" +
+		"de95bd1094:1"
 )
 
 func TestWalletInfo(t *testing.T) {
@@ -29,3 +31,26 @@ func TestWalletInfo(t *testing.T) {
 	h.assertLogContains(walletContent)
 	h.assertLogContains(rootKeyAezeed)
 }
+
+func TestWalletInfoJSON(t *testing.T) {
+	h := newHarness(t)
+
+	// Dump the wallet information, including the accounts of all scopes
+	// and the transactions of the wallet.
+	info := &walletInfoCommand{
+		WalletDB:  h.testdataFile("wallet.db"),
+		DumpAddrs: true,
+		JSON:      true,
+	}
+
+	t.Setenv(lnd.PasswordEnvName, testPassPhrase)
+
+	err := info.Execute(nil, nil)
+	require.NoError(t, err)
+
+	h.assertLogContains(walletContent)
+	h.assertLogContains(walletUTXO)
+	h.assertLogContains(`"scope": "m/84'/0'"`)
+	h.assertLogContains(`"purpose": 1017`)
+	h.assertLogContains("0:openchannel:shortchanid-144036023304192")
+}
@@ -0,0 +1,472 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightninglabs/chantools/btc"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultWatchPollInterval = 10 * time.Minute
+	defaultWatchJournal      = "results/watch-journal.json"
+
+	// watchStateUnconfirmed means the force close transaction isn't
+	// confirmed yet.
+	watchStateUnconfirmed = "unconfirmed"
+
+	// watchStateTimeLocked means the force close transaction is confirmed
+	// and we wait for the CSV delay to expire.
+	watchStateTimeLocked = "time_locked"
+
+	// watchStateSweepPublished means the sweep transaction was published
+	// and we wait for it to confirm.
+	watchStateSweepPublished = "sweep_published"
+
+	// watchStateSpent means the output was spent by a confirmed
+	// transaction, nothing left to do.
+	watchStateSpent = "spent"
+)
+
+type watchCommand struct {
+	APIURL       string
+	SweepAddrs   []string
+	MaxCsvLimit  uint16
+	FeeRate      uint32
+	Journal      string
+	PollInterval time.Duration
+
+	rootKey *rootKey
+	inputs  *inputFlags
+	cmd     *cobra.Command
+}
+
+func newWatchCommand() *cobra.Command {
+	cc := &watchCommand{}
+	cc.cmd = &cobra.Command{
+		Use: "watch",
+		Short: "Watch force-closed channels and sweep the time " +
+			"locked outputs as soon as they mature",
+		Long: `Use this command after force-closing channels with the
+forceclose command to sweep the time locked outputs automatically. Instead of
+having to remember running the sweeptimelock command after up to 2016 blocks,
+this command keeps running and polls the chain API for the confirmation of the
+force close transactions. As soon as the CSV delay of an output has expired, the
+output is swept to the given address and the sweep transaction is published.
+
+The state of each output is written to a journal file after every change, so the
+command can be stopped and restarted at any time with the same journal file. If
+a published sweep transaction disappears from the mempool, it is published
+again. The command exits once all outputs are spent by a confirmed transaction.
+
+You **MUST** use the result file that was created with the forceclose command,
+otherwise it won't work.`,
+		Example: `chantools watch \
+	--fromsummary results/forceclose-xxxx-yyyy.json \
+	--sweepaddr bc1q..... \
+	--feerate 10 \
+	--journal results/watch-journal.json \
+	--pollinterval 10m`,
+		RunE: cc.Execute,
+	}
+	cc.cmd.Flags().StringVar(
+		&cc.APIURL, "apiurl", defaultAPIURL, "API URL to use (must "+
+			"be esplora compatible)",
+	)
+	cc.cmd.Flags().StringArrayVar(
+		&cc.SweepAddrs, "sweepaddr", nil, sweepAddrFlagDesc,
+	)
+	cc.cmd.Flags().Uint16Var(
+		&cc.MaxCsvLimit, "maxcsvlimit", defaultCsvLimit, "maximum CSV "+
+			"limit to use",
+	)
+	cc.cmd.Flags().Uint32Var(
+		&cc.FeeRate, "feerate", defaultFeeSatPerVByte, "fee rate to "+
+			"use for the sweep transactions in sat/vByte",
+	)
+	cc.cmd.Flags().StringVar(
+		&cc.Journal, "journal", defaultWatchJournal, "the file to "+
+			"keep the state of the watched outputs in; use the "+
+			"same file when restarting the command",
+	)
+	cc.cmd.Flags().DurationVar(
+		&cc.PollInterval, "pollinterval", defaultWatchPollInterval,
+		"the interval in which the chain API is polled for new blocks",
+	)
+
+	cc.rootKey = newRootKey(cc.cmd, "deriving keys")
+	cc.inputs = newInputFlags(cc.cmd)
+
+	return cc.cmd
+}
+
+func (c *watchCommand) Execute(_ *cobra.Command, _ []string) error {
+	extendedKey, err := c.rootKey.read()
+	if err != nil {
+		return fmt.Errorf("error reading root key: %w", err)
+	}
+
+	// Make sure sweep addr is set.
+	sweepDests, err := parseSweepAddrs(c.SweepAddrs)
+	if err != nil {
+		return err
+	}
+
+	// Parse channel entries from any of the possible input files.
+	entries, err := c.inputs.parseInputType()
+	if err != nil {
+		return err
+	}
+
+	// Set default values.
+	if c.MaxCsvLimit == 0 {
+		c.MaxCsvLimit = defaultCsvLimit
+	}
+	if c.FeeRate == 0 {
+		c.FeeRate = defaultFeeSatPerVByte
+	}
+	if c.Journal == "" {
+		c.Journal = defaultWatchJournal
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = defaultWatchPollInterval
+	}
+
+	targets, err := sweepTargetsFromSummary(entries)
+	if err != nil {
+		return err
+	}
+
+	watcher, err := newTimeLockWatcher(
+		extendedKey, newExplorerAPI(c.APIURL), targets, sweepDests,
+		c.MaxCsvLimit, c.FeeRate, c.Journal,
+	)
+	if err != nil {
+		return err
+	}
+
+	for {
+		done, err := watcher.poll()
+		if err != nil {
+			return err
+		}
+		if done {
+			log.Infof("All watched outputs are spent, exiting")
+			return nil
+		}
+
+		log.Infof("Checking again in %v", c.PollInterval)
+		time.Sleep(c.PollInterval)
+	}
+}
+
+// watchJournal is the state of all watched outputs that is persisted to disk.
+type watchJournal struct {
+	BlockHeight int            `json:"block_height"`
+	Outputs     []*watchOutput `json:"outputs"`
+}
+
+// watchOutput is the state of a single watched time locked output.
+type watchOutput struct {
+	ChannelPoint string `json:"channel_point"`
+	Outpoint     string `json:"outpoint"`
+	Value        int64  `json:"value"`
+	CSVDelay     int32  `json:"csv_delay"`
+	State        string `json:"state"`
+	ConfHeight   int    `json:"conf_height,omitempty"`
+	MatureHeight int    `json:"mature_height,omitempty"`
+	SweepTXID    string `json:"sweep_txid,omitempty"`
+	SpendTXID    string `json:"spend_txid,omitempty"`
+}
+
+// timeLockWatcher polls the chain API for the state of time locked outputs
+// and sweeps them once they mature.
+type timeLockWatcher struct {
+	extendedKey *hdkeychain.ExtendedKey
+	api         *btc.ExplorerAPI
+	sweepDests  []*lnd.SweepDestination
+	maxCsvLimit uint16
+	feeRate     uint32
+	journalFile string
+
+	targets map[string]*sweepTarget
+	journal *watchJournal
+}
+
+// newTimeLockWatcher creates a new watcher for the given targets. If the
+// journal file exists, the state of the outputs is restored from it.
+func newTimeLockWatcher(extendedKey *hdkeychain.ExtendedKey,
+	api *btc.ExplorerAPI, targets []*sweepTarget,
+	sweepDests []*lnd.SweepDestination,
+	maxCsvLimit uint16, feeRate uint32,
+	journalFile string) (*timeLockWatcher, error) {
+
+	w := &timeLockWatcher{
+		extendedKey: extendedKey,
+		api:         api,
+		sweepDests:  sweepDests,
+		maxCsvLimit: maxCsvLimit,
+		feeRate:     feeRate,
+		journalFile: journalFile,
+		targets:     make(map[string]*sweepTarget, len(targets)),
+		journal:     &watchJournal{},
+	}
+
+	journalBytes, err := os.ReadFile(journalFile)
+	switch {
+	case err == nil:
+		err = json.Unmarshal(journalBytes, w.journal)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing journal file "+
+				"%s: %w", journalFile, err)
+		}
+
+		log.Infof("Restored state of %d outputs from journal file %s",
+			len(w.journal.Outputs), journalFile)
+
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("error reading journal file %s: %w",
+			journalFile, err)
+	}
+
+	outputs := make(map[string]*watchOutput, len(w.journal.Outputs))
+	for _, output := range w.journal.Outputs {
+		outputs[output.Outpoint] = output
+	}
+
+	for _, target := range targets {
+		outpoint := wire.OutPoint{
+			Hash:  target.txid,
+			Index: target.index,
+		}.String()
+		w.targets[outpoint] = target
+
+		if _, ok := outputs[outpoint]; ok {
+			continue
+		}
+
+		csvDelay, _, _, err := target.findDelay(maxCsvLimit)
+		if err != nil {
+			log.Errorf("Could not create matching script for %s "+
+				"or csv too high, not watching it: %v",
+				target.channelPoint, err)
+			delete(w.targets, outpoint)
+			continue
+		}
+
+		w.journal.Outputs = append(w.journal.Outputs, &watchOutput{
+			ChannelPoint: target.channelPoint,
+			Outpoint:     outpoint,
+			Value:        target.value,
+			CSVDelay:     csvDelay,
+			State:        watchStateUnconfirmed,
+		})
+	}
+
+	return w, w.writeJournal()
+}
+
+// poll checks the state of all watched outputs, sweeps the ones that matured
+// and writes the journal. It returns true if there are no outputs left to
+// watch. Errors of the chain API are logged and retried in the next poll.
+func (w *timeLockWatcher) poll() (bool, error) {
+	height, err := w.api.BlockHeight()
+	if err != nil {
+		log.Errorf("Error fetching block height: %v", err)
+		return false, nil
+	}
+	w.journal.BlockHeight = height
+
+	var (
+		matureTargets []*sweepTarget
+		matureOutputs []*watchOutput
+		numPending    int
+	)
+	for _, output := range w.journal.Outputs {
+		target, ok := w.targets[output.Outpoint]
+		if !ok || output.State == watchStateSpent {
+			continue
+		}
+
+		err := w.updateOutput(output, target, height)
+		if err != nil {
+			log.Errorf("Error checking output %s of channel %s: %v",
+				output.Outpoint, output.ChannelPoint, err)
+		}
+
+		if output.State == watchStateSpent {
+			continue
+		}
+		numPending++
+
+		// The sweep transaction can be included in the next block if
+		// the CSV delay expires with it.
+		if output.State == watchStateTimeLocked &&
+			output.SpendTXID == "" &&
+			height+1 >= output.MatureHeight {
+
+			matureTargets = append(matureTargets, target)
+			matureOutputs = append(matureOutputs, output)
+		}
+	}
+
+	if len(matureTargets) > 0 {
+		w.sweep(matureTargets, matureOutputs)
+	}
+
+	return numPending == 0, w.writeJournal()
+}
+
+// updateOutput fetches the force close transaction of the output and updates
+// the output's state accordingly.
+func (w *timeLockWatcher) updateOutput(output *watchOutput,
+	target *sweepTarget, height int) error {
+
+	tx, err := w.api.Transaction(target.txid.String())
+	switch {
+	case errors.Is(err, btc.ErrTxNotFound):
+		log.Infof("Force close transaction %v of channel %s not found "+
+			"yet", target.txid, output.ChannelPoint)
+		output.State = watchStateUnconfirmed
+		return nil
+
+	case err != nil:
+		return err
+	}
+
+	if int(target.index) >= len(tx.Vout) {
+		return fmt.Errorf("output index %d out of range",
+			target.index)
+	}
+
+	if tx.Status == nil || !tx.Status.Confirmed {
+		if output.State != watchStateUnconfirmed {
+			log.Warnf("Force close transaction %v of channel %s "+
+				"is no longer confirmed", target.txid,
+				output.ChannelPoint)
+		}
+		output.State = watchStateUnconfirmed
+		output.ConfHeight = 0
+		output.MatureHeight = 0
+		return nil
+	}
+
+	if output.State == watchStateUnconfirmed {
+		output.State = watchStateTimeLocked
+		output.ConfHeight = tx.Status.BlockHeight
+		output.MatureHeight = tx.Status.BlockHeight +
+			int(output.CSVDelay)
+
+		log.Infof("Force close transaction %v of channel %s "+
+			"confirmed at height %d, output %s matures at height "+
+			"%d", target.txid, output.ChannelPoint,
+			output.ConfHeight, output.Outpoint,
+			output.MatureHeight)
+	}
+
+	outspend := tx.Vout[target.index].Outspend
+	switch {
+	// The output is spent by a confirmed transaction, we're done.
+	case outspend != nil && outspend.Spent && outspend.Status != nil &&
+		outspend.Status.Confirmed:
+
+		output.State = watchStateSpent
+		output.SpendTXID = outspend.Txid
+
+		log.Infof("Output %s of channel %s spent by confirmed "+
+			"transaction %s", output.Outpoint, output.ChannelPoint,
+			outspend.Txid)
+
+	// The output is spent by an unconfirmed transaction, we wait for it to
+	// confirm.
+	case outspend != nil && outspend.Spent:
+		if outspend.Txid != output.SweepTXID &&
+			outspend.Txid != output.SpendTXID {
+
+			log.Warnf("Output %s of channel %s spent by "+
+				"unconfirmed transaction %s", output.Outpoint,
+				output.ChannelPoint, outspend.Txid)
+		}
+		output.SpendTXID = outspend.Txid
+
+	// Our sweep transaction was dropped from the mempool, it needs to be
+	// published again.
+	case output.State == watchStateSweepPublished:
+		log.Warnf("Sweep transaction %s of output %s is no longer "+
+			"known, publishing again", output.SweepTXID,
+			output.Outpoint)
+		output.State = watchStateTimeLocked
+		output.SpendTXID = ""
+
+	default:
+		output.SpendTXID = ""
+		if height+1 < output.MatureHeight {
+			log.Infof("Output %s of channel %s matures in %d "+
+				"blocks", output.Outpoint, output.ChannelPoint,
+				output.MatureHeight-height-1)
+		}
+	}
+
+	return nil
+}
+
+// sweep creates and publishes a transaction that sweeps all given targets.
+// Errors are only logged, publishing is attempted again in the next poll.
+func (w *timeLockWatcher) sweep(targets []*sweepTarget,
+	outputs []*watchOutput) {
+
+	sweepTx, err := createTimeLockSweepTx(
+		w.extendedKey, targets, w.sweepDests, w.maxCsvLimit, w.feeRate,
+	)
+	if err != nil {
+		log.Errorf("Error creating sweep transaction: %v", err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := sweepTx.Serialize(&buf); err != nil {
+		log.Errorf("Error serializing sweep transaction: %v", err)
+		return
+	}
+
+	response, err := w.api.PublishTx(hex.EncodeToString(buf.Bytes()))
+	if err != nil {
+		log.Errorf("Error publishing sweep transaction %v: %v",
+			sweepTx.TxHash(), err)
+		return
+	}
+
+	log.Infof("Published sweep transaction %v for %d outputs, "+
+		"response: %s", sweepTx.TxHash(), len(targets), response)
+	log.Infof("Transaction: %x", buf.Bytes())
+
+	for _, output := range outputs {
+		output.State = watchStateSweepPublished
+		output.SweepTXID = sweepTx.TxHash().String()
+	}
+}
+
+// writeJournal atomically writes the current state to the journal file.
+func (w *timeLockWatcher) writeJournal() error {
+	journalBytes, err := json.MarshalIndent(w.journal, "", " ")
+	if err != nil {
+		return err
+	}
+
+	tempFile := w.journalFile + ".tmp"
+	err = os.WriteFile(tempFile, journalBytes, 0644)
+	if err != nil {
+		return fmt.Errorf("error writing journal file: %w", err)
+	}
+
+	return os.Rename(tempFile, w.journalFile)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightninglabs/chantools/btc"
+	"github.com/lightninglabs/chantools/lnd"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testWatchCsvDelay = 10
+	testWatchValue    = 50_000
+)
+
+// testWatchAPI is a minimal esplora compatible API that knows about a single
+// force close transaction.
+type testWatchAPI struct {
+	sync.Mutex
+
+	closeTXID    string
+	height       int
+	closeKnown   bool
+	closeHeight  int
+	spendTXID    string
+	spendConfirm bool
+	published    []string
+}
+
+func (a *testWatchAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.Lock()
+	defer a.Unlock()
+
+	var response any
+	switch {
+	case r.URL.Path == "/blocks/tip/height":
+		response = a.height
+
+	case r.Method == http.MethodPost && r.URL.Path == "/tx":
+		body, _ := io.ReadAll(r.Body)
+		a.published = append(a.published, string(body))
+		_, _ = w.Write([]byte("ok"))
+		return
+
+	case !a.closeKnown:
+		_, _ = w.Write([]byte("Transaction not found"))
+		return
+
+	case r.URL.Path == "/tx/"+a.closeTXID:
+		tx := &btc.TX{
+			TXID:   a.closeTXID,
+			Vout:   []*btc.Vout{{Value: testWatchValue}},
+			Status: &btc.Status{},
+		}
+		if a.closeHeight > 0 {
+			tx.Status.Confirmed = true
+			tx.Status.BlockHeight = a.closeHeight
+		}
+		response = tx
+
+	case r.URL.Path == "/tx/"+a.closeTXID+"/outspend/0":
+		response = &btc.Outspend{
+			Spent: a.spendTXID != "",
+			Txid:  a.spendTXID,
+			Status: &btc.Status{
+				Confirmed: a.spendConfirm,
+			},
+		}
+
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (a *testWatchAPI) set(fn func()) {
+	a.Lock()
+	defer a.Unlock()
+
+	fn()
+}
+
+func TestTimeLockWatcher(t *testing.T) {
+	h := newHarness(t)
+
+	extendedKey, err := hdkeychain.NewKeyFromString(rootKeyAezeed)
+	require.NoError(t, err)
+	otherKey, err := hdkeychain.NewKeyFromString(rootKeyBip39)
+	require.NoError(t, err)
+
+	// Create a time locked output that belongs to our key.
+	keyRing := &lnd.HDKeyRing{
+		ExtendedKey: extendedKey,
+		ChainParams: chainParams,
+	}
+	delayDesc, err := keyRing.DeriveKey(keychain.KeyLocator{
+		Family: keychain.KeyFamilyDelayBase,
+	})
+	require.NoError(t, err)
+	commitPoint, err := otherKey.ECPubKey()
+	require.NoError(t, err)
+	revocationBase, err := extendedKey.ECPubKey()
+	require.NoError(t, err)
+
+	script, err := input.CommitScriptToSelf(
+		testWatchCsvDelay,
+		input.TweakPubKey(delayDesc.PubKey, commitPoint),
+		input.DeriveRevocationPubkey(revocationBase, commitPoint),
+	)
+	require.NoError(t, err)
+	pkScript, err := input.WitnessScriptHash(script)
+	require.NoError(t, err)
+
+	closeTxHash := chainhash.Hash{1, 2, 3}
+	target := &sweepTarget{
+		channelPoint:        "test:0",
+		txid:                closeTxHash,
+		lockScript:          pkScript,
+		value:               testWatchValue,
+		commitPoint:         commitPoint,
+		revocationBasePoint: revocationBase,
+		delayBasePointDesc:  &delayDesc,
+	}
+
+	sweepAddr, err := lnd.P2WKHAddr(revocationBase, chainParams)
+	require.NoError(t, err)
+	sweepDests, err := parseSweepAddrs([]string{sweepAddr.String()})
+	require.NoError(t, err)
+
+	api := &testWatchAPI{
+		closeTXID: closeTxHash.String(),
+		height:    100,
+	}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	journalFile := h.tempFile("watch-journal.json")
+	newWatcher := func() *timeLockWatcher {
+		w, err := newTimeLockWatcher(
+			extendedKey, &btc.ExplorerAPI{BaseURL: server.URL},
+			[]*sweepTarget{target}, sweepDests, 20, 10,
+			journalFile,
+		)
+		require.NoError(t, err)
+		require.Len(t, w.journal.Outputs, 1)
+
+		return w
+	}
+	watcher := newWatcher()
+	output := watcher.journal.Outputs[0]
+	require.EqualValues(t, testWatchCsvDelay, output.CSVDelay)
+
+	// The close transaction isn't known yet.
+	done, err := watcher.poll()
+	require.NoError(t, err)
+	require.False(t, done)
+	require.Equal(t, watchStateUnconfirmed, output.State)
+
+	// It confirms, but the output is still time locked.
+	api.set(func() {
+		api.closeKnown = true
+		api.closeHeight = 101
+		api.height = 105
+	})
+	done, err = watcher.poll()
+	require.NoError(t, err)
+	require.False(t, done)
+	require.Equal(t, watchStateTimeLocked, output.State)
+	require.Equal(t, 111, output.MatureHeight)
+	require.Empty(t, api.published)
+
+	// Once the sweep can be included in the next block, it's published.
+	api.set(func() {
+		api.height = 110
+	})
+	done, err = watcher.poll()
+	require.NoError(t, err)
+	require.False(t, done)
+	require.Equal(t, watchStateSweepPublished, output.State)
+	require.NotEmpty(t, output.SweepTXID)
+	require.Len(t, api.published, 1)
+
+	// A restarted watcher picks up the state from the journal. The sweep
+	// transaction was dropped from the mempool, so it's published again.
+	watcher = newWatcher()
+	output = watcher.journal.Outputs[0]
+	require.Equal(t, watchStateSweepPublished, output.State)
+	done, err = watcher.poll()
+	require.NoError(t, err)
+	require.False(t, done)
+	require.Equal(t, watchStateSweepPublished, output.State)
+	require.Len(t, api.published, 2)
+
+	// Once the sweep confirms, we're done.
+	api.set(func() {
+		api.spendTXID = output.SweepTXID
+		api.spendConfirm = true
+	})
+	done, err = watcher.poll()
+	require.NoError(t, err)
+	require.True(t, done)
+	require.Equal(t, watchStateSpent, output.State)
+	require.Equal(t, output.SweepTXID, output.SpendTXID)
+}
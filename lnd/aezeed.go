@@ -27,6 +27,14 @@ const (
 	MnemonicEnvName   = "AEZEED_MNEMONIC"
 	PassphraseEnvName = "AEZEED_PASSPHRASE"
 	PasswordEnvName   = "WALLET_PASSWORD"
+
+	// NewPasswordEnvName is the environment variable the new password of a
+	// wallet is read from when changing the password.
+	NewPasswordEnvName = "WALLET_NEW_PASSWORD"
+
+	// NewPassphraseEnvName is the environment variable the new passphrase
+	// of an aezeed is read from when changing the passphrase.
+	NewPassphraseEnvName = "AEZEED_NEW_PASSPHRASE"
 )
 
 var (
@@ -38,8 +46,9 @@ var (
 		ObtainPrivatePass: noConsole,
 	}
 
-	// Namespace from github.com/btcsuite/btcwallet/wallet/wallet.go.
+	// Namespaces from github.com/btcsuite/btcwallet/wallet/wallet.go.
 	WaddrmgrNamespaceKey = []byte("waddrmgr")
+	WtxmgrNamespaceKey   = []byte("wtxmgr")
 
 	// Bucket names from github.com/btcsuite/btcwallet/waddrmgr/db.go.
 	mainBucketName    = []byte("main")
@@ -56,6 +65,55 @@ func noConsole() ([]byte, error) {
 func ReadAezeed(params *chaincfg.Params) (*hdkeychain.ExtendedKey, time.Time,
 	error) {
 
+	cipherSeed, err := ReadCipherSeed()
+	if err != nil {
+		return nil, time.Unix(0, 0), err
+	}
+	rootKey, err := hdkeychain.NewMaster(cipherSeed.Entropy[:], params)
+	if err != nil {
+		return nil, time.Unix(0, 0), errors.New("failed to derive " +
+			"master extended key")
+	}
+	return rootKey, cipherSeed.BirthdayTime(), nil
+}
+
+// ReadCipherSeed reads an aezeed and its passphrase from the console or the
+// environment variables and deciphers it.
+func ReadCipherSeed() (*aezeed.CipherSeed, error) {
+	cipherSeedMnemonic, err := ReadMnemonicWords()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cipherSeedMnemonic) != 24 {
+		return nil, fmt.Errorf("wrong cipher seed mnemonic length: "+
+			"got %v words, expecting %v words",
+			len(cipherSeedMnemonic), 24)
+	}
+
+	passphraseBytes, err := ReadPassphrase("doesn't have")
+	if err != nil {
+		return nil, err
+	}
+
+	var mnemonic aezeed.Mnemonic
+	copy(mnemonic[:], cipherSeedMnemonic)
+
+	// If we're unable to map it back into the ciphertext, then either the
+	// mnemonic is wrong, or the passphrase is wrong.
+	cipherSeed, err := mnemonic.ToCipherSeed(passphraseBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt seed with "+
+			"passphrase: %w", err)
+	}
+
+	return cipherSeed, nil
+}
+
+// ReadMnemonicWords reads the words of an aezeed mnemonic from the console or
+// the environment variable and cleans them up. The number of words is not
+// checked.
+func ReadMnemonicWords() ([]string, error) {
 	// To automate things with chantools, we also offer reading the seed
 	// from environment variables.
 	mnemonicStr := strings.TrimSpace(os.Getenv(MnemonicEnvName))
@@ -69,7 +127,7 @@ func ReadAezeed(params *chaincfg.Params) (*hdkeychain.ExtendedKey, time.Time,
 		reader := bufio.NewReader(os.Stdin)
 		mnemonicStr, err = reader.ReadString('\n')
 		if err != nil {
-			return nil, time.Unix(0, 0), err
+			return nil, err
 		}
 	}
 
@@ -85,37 +143,9 @@ func ReadAezeed(params *chaincfg.Params) (*hdkeychain.ExtendedKey, time.Time,
 	mnemonicStr = multipleSpaces.ReplaceAllString(mnemonicStr, " ")
 	mnemonicStr = strings.TrimSpace(mnemonicStr)
 
-	cipherSeedMnemonic := strings.Split(mnemonicStr, " ")
-
 	fmt.Println()
 
-	if len(cipherSeedMnemonic) != 24 {
-		return nil, time.Unix(0, 0), fmt.Errorf("wrong cipher seed "+
-			"mnemonic length: got %v words, expecting %v words",
-			len(cipherSeedMnemonic), 24)
-	}
-
-	passphraseBytes, err := ReadPassphrase("doesn't have")
-	if err != nil {
-		return nil, time.Unix(0, 0), err
-	}
-
-	var mnemonic aezeed.Mnemonic
-	copy(mnemonic[:], cipherSeedMnemonic)
-
-	// If we're unable to map it back into the ciphertext, then either the
-	// mnemonic is wrong, or the passphrase is wrong.
-	cipherSeed, err := mnemonic.ToCipherSeed(passphraseBytes)
-	if err != nil {
-		return nil, time.Unix(0, 0), fmt.Errorf("failed to decrypt "+
-			"seed with passphrase: %w", err)
-	}
-	rootKey, err := hdkeychain.NewMaster(cipherSeed.Entropy[:], params)
-	if err != nil {
-		return nil, time.Unix(0, 0), errors.New("failed to derive " +
-			"master extended key")
-	}
-	return rootKey, cipherSeed.BirthdayTime(), nil
+	return strings.Split(mnemonicStr, " "), nil
 }
 
 // ReadPassphrase reads a cipher seed passphrase from the console or the
@@ -180,12 +210,12 @@ func PasswordFromConsole(userQuery string) ([]byte, error) {
 	return pw, nil
 }
 
-// OpenWallet opens a lnd compatible wallet and returns it, along with the
-// private wallet password.
-func OpenWallet(walletDbPath string,
-	chainParams *chaincfg.Params) (*wallet.Wallet, []byte, func() error,
-	error) {
-
+// ReadWalletPassword reads a wallet password from the given environment
+// variable or, if that is empty, from the console and returns the public and
+// private wallet passphrases that lnd derives from it. A single dash (-) in the
+// environment variable or an empty password on the console means that the
+// wallet uses lnd's default passphrases.
+func ReadWalletPassword(envName, userQuery string) ([]byte, []byte, error) {
 	var (
 		publicWalletPw  = lnwallet.DefaultPublicPassphrase
 		privateWalletPw = lnwallet.DefaultPrivatePassphrase
@@ -194,7 +224,7 @@ func OpenWallet(walletDbPath string,
 
 	// To automate things with chantools, we also offer reading the wallet
 	// password from environment variables.
-	pw := []byte(strings.TrimSpace(os.Getenv(PasswordEnvName)))
+	pw := []byte(strings.TrimSpace(os.Getenv(envName)))
 
 	// Because we cannot differentiate between an empty and a non-existent
 	// environment variable, we need a special character that indicates that
@@ -208,9 +238,9 @@ func OpenWallet(walletDbPath string,
 	// The environment variable didn't contain anything, we'll read the
 	// passphrase from the terminal.
 	case len(pw) == 0:
-		pw, err = PasswordFromConsole("Input wallet password: ")
+		pw, err = PasswordFromConsole(userQuery)
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, err
 		}
 		if len(pw) > 0 {
 			publicWalletPw = pw
@@ -223,25 +253,57 @@ func OpenWallet(walletDbPath string,
 		privateWalletPw = pw
 	}
 
+	return publicWalletPw, privateWalletPw, nil
+}
+
+// OpenWallet opens a lnd compatible wallet and returns it, along with the
+// private wallet password.
+func OpenWallet(walletDbPath string,
+	chainParams *chaincfg.Params) (*wallet.Wallet, []byte, func() error,
+	error) {
+
+	publicWalletPw, privateWalletPw, err := ReadWalletPassword(
+		PasswordEnvName, "Input wallet password: ",
+	)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	w, cleanup, err := OpenWalletWithPassphrases(
+		walletDbPath, chainParams, publicWalletPw, privateWalletPw,
+	)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return w, privateWalletPw, cleanup, nil
+}
+
+// OpenWalletWithPassphrases opens and unlocks a lnd compatible wallet with the
+// given public and private passphrases.
+func OpenWalletWithPassphrases(walletDbPath string,
+	chainParams *chaincfg.Params, publicWalletPw,
+	privateWalletPw []byte) (*wallet.Wallet, func() error, error) {
+
 	// Try to load and open the wallet.
 	db, err := walletdb.Open(
 		"bdb", lncfg.CleanAndExpandPath(walletDbPath), false,
 		DefaultOpenTimeout,
 	)
 	if errors.Is(err, bbolt.ErrTimeout) {
-		return nil, nil, nil, errors.New("error opening wallet " +
-			"database, make sure lnd is not running and holding " +
-			"the exclusive lock on the wallet")
+		return nil, nil, errors.New("error opening wallet database, " +
+			"make sure lnd is not running and holding the " +
+			"exclusive lock on the wallet")
 	}
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("error opening wallet "+
-			"database: %w", err)
+		return nil, nil, fmt.Errorf("error opening wallet database: "+
+			"%w", err)
 	}
 
 	w, err := wallet.Open(db, publicWalletPw, openCallbacks, chainParams, 0)
 	if err != nil {
 		_ = db.Close()
-		return nil, nil, nil, fmt.Errorf("error opening wallet %w", err)
+		return nil, nil, fmt.Errorf("error opening wallet %w", err)
 	}
 
 	// Start and unlock the wallet.
@@ -250,7 +312,7 @@ func OpenWallet(walletDbPath string,
 	if err != nil {
 		w.Stop()
 		_ = db.Close()
-		return nil, nil, nil, err
+		return nil, nil, err
 	}
 
 	cleanup := func() error {
@@ -262,7 +324,7 @@ func OpenWallet(walletDbPath string,
 		return nil
 	}
 
-	return w, privateWalletPw, cleanup, nil
+	return w, cleanup, nil
 }
 
 // DecryptWalletRootKey decrypts a lnd compatible wallet's root key.
@@ -0,0 +1,749 @@
+package lnd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math/bits"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lightningnetwork/lnd/aezeed"
+)
+
+var (
+	// maskCharsets are the character sets of the placeholders that can be
+	// used in a passphrase mask. They follow the built-in charsets of
+	// hashcat.
+	maskCharsets = map[byte]string{
+		'l': "abcdefghijklmnopqrstuvwxyz",
+		'u': "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+		'd': "0123456789",
+		's': " !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~",
+	}
+
+	// leetSubstitutions are the characters that are commonly replaced by
+	// similar looking digits or symbols in passphrases.
+	leetSubstitutions = map[rune][]rune{
+		'a': {'4', '@'},
+		'e': {'3'},
+		'i': {'1', '!'},
+		'l': {'1'},
+		'o': {'0'},
+		's': {'5', '$'},
+		't': {'7'},
+	}
+)
+
+func init() {
+	maskCharsets['a'] = maskCharsets['l'] + maskCharsets['u'] +
+		maskCharsets['d'] + maskCharsets['s']
+}
+
+// PassphraseSource produces passphrase candidates, always in the same order.
+type PassphraseSource interface {
+	// Total returns the number of candidates of the source.
+	Total() uint64
+
+	// Next returns the next candidate or false if there are no more
+	// candidates.
+	Next() (string, bool, error)
+}
+
+// ListSource is a passphrase source that returns the candidates of a list.
+type ListSource struct {
+	candidates []string
+	pos        int
+}
+
+// NewListSource returns a passphrase source for the given candidates.
+func NewListSource(candidates []string) *ListSource {
+	return &ListSource{
+		candidates: candidates,
+	}
+}
+
+// Total returns the number of candidates of the source.
+func (s *ListSource) Total() uint64 {
+	return uint64(len(s.candidates))
+}
+
+// Next returns the next candidate or false if there are no more candidates.
+func (s *ListSource) Next() (string, bool, error) {
+	if s.pos >= len(s.candidates) {
+		return "", false, nil
+	}
+
+	s.pos++
+	return s.candidates[s.pos-1], true, nil
+}
+
+// MutationSource is a passphrase source that returns the case and leet
+// mutations of a list of base guesses.
+type MutationSource struct {
+	guesses []string
+	total   uint64
+
+	mutator *passphraseMutator
+}
+
+// NewMutationSource returns a passphrase source that returns the case and leet
+// mutations of all the given base guesses.
+func NewMutationSource(guesses []string) (*MutationSource, error) {
+	s := &MutationSource{
+		guesses: guesses,
+	}
+	for _, guess := range guesses {
+		num, err := newPassphraseMutator(guess).count()
+		if err != nil {
+			return nil, fmt.Errorf("error counting mutations of "+
+				"guess %q: %w", guess, err)
+		}
+
+		var carry uint64
+		s.total, carry = bits.Add64(s.total, num, 0)
+		if carry != 0 {
+			return nil, errors.New("guesses have too many " +
+				"candidates")
+		}
+	}
+
+	return s, nil
+}
+
+// Total returns the number of candidates of the source.
+func (s *MutationSource) Total() uint64 {
+	return s.total
+}
+
+// Next returns the next candidate or false if there are no more candidates.
+func (s *MutationSource) Next() (string, bool, error) {
+	for {
+		if s.mutator != nil {
+			if candidate, ok := s.mutator.next(); ok {
+				return candidate, true, nil
+			}
+			s.mutator = nil
+		}
+
+		if len(s.guesses) == 0 {
+			return "", false, nil
+		}
+		s.mutator = newPassphraseMutator(s.guesses[0])
+		s.guesses = s.guesses[1:]
+	}
+}
+
+// WordlistSource is a passphrase source that reads the candidates from a file
+// with one candidate per line.
+type WordlistSource struct {
+	file    *os.File
+	scanner *bufio.Scanner
+	mutate  bool
+	total   uint64
+
+	mutator *passphraseMutator
+}
+
+// NewWordlistSource opens the given wordlist file. If mutate is true, the case
+// and leet mutations of each line are returned instead of only the line
+// itself.
+func NewWordlistSource(fileName string, mutate bool) (*WordlistSource,
+	error) {
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	// We need to go through the whole file once to know the number of
+	// candidates, which is cheap compared to testing them. The mutations
+	// are only counted, not produced.
+	s := &WordlistSource{
+		file:    file,
+		scanner: bufio.NewScanner(file),
+		mutate:  mutate,
+	}
+	for s.scanner.Scan() {
+		num := uint64(1)
+		if mutate {
+			mutator := newPassphraseMutator(s.scanner.Text())
+			num, err = mutator.count()
+			if err != nil {
+				_ = file.Close()
+				return nil, fmt.Errorf("error counting "+
+					"mutations of line %q: %w",
+					s.scanner.Text(), err)
+			}
+		}
+
+		var carry uint64
+		s.total, carry = bits.Add64(s.total, num, 0)
+		if carry != 0 {
+			_ = file.Close()
+			return nil, errors.New("wordlist has too many " +
+				"candidates")
+		}
+	}
+	if err := s.scanner.Err(); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	if _, err := file.Seek(0, 0); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	s.scanner = bufio.NewScanner(file)
+
+	return s, nil
+}
+
+// Total returns the number of candidates of the source.
+func (s *WordlistSource) Total() uint64 {
+	return s.total
+}
+
+// Next returns the next candidate or false if there are no more candidates.
+func (s *WordlistSource) Next() (string, bool, error) {
+	for {
+		if s.mutator != nil {
+			if candidate, ok := s.mutator.next(); ok {
+				return candidate, true, nil
+			}
+			s.mutator = nil
+		}
+
+		if !s.scanner.Scan() {
+			return "", false, s.scanner.Err()
+		}
+		if !s.mutate {
+			return s.scanner.Text(), true, nil
+		}
+		s.mutator = newPassphraseMutator(s.scanner.Text())
+	}
+}
+
+// Close closes the wordlist file.
+func (s *WordlistSource) Close() error {
+	return s.file.Close()
+}
+
+// MaskSource is a passphrase source that returns all passphrases matching a
+// mask. Each position of the mask is either a literal character or one of the
+// placeholders ?l (lower case letter), ?u (upper case letter), ?d (digit), ?s
+// (special character) or ?a (any of those). ?? stands for a literal question
+// mark.
+type MaskSource struct {
+	positions []string
+	digits    []int
+	total     uint64
+	done      bool
+}
+
+// NewMaskSource parses the given mask.
+func NewMaskSource(mask string) (*MaskSource, error) {
+	s := &MaskSource{
+		total: 1,
+	}
+	for i := 0; i < len(mask); i++ {
+		charset := mask[i : i+1]
+		if mask[i] == '?' {
+			if i+1 >= len(mask) {
+				return nil, errors.New("mask must not end " +
+					"with a single ?")
+			}
+
+			i++
+			switch {
+			case mask[i] == '?':
+				charset = "?"
+
+			case maskCharsets[mask[i]] != "":
+				charset = maskCharsets[mask[i]]
+
+			default:
+				return nil, fmt.Errorf("unknown mask "+
+					"placeholder ?%c", mask[i])
+			}
+		}
+
+		hi, lo := bits.Mul64(s.total, uint64(len(charset)))
+		if hi != 0 {
+			return nil, errors.New("mask has too many candidates")
+		}
+		s.total = lo
+		s.positions = append(s.positions, charset)
+	}
+	if len(s.positions) == 0 {
+		return nil, errors.New("mask must not be empty")
+	}
+	s.digits = make([]int, len(s.positions))
+
+	return s, nil
+}
+
+// Total returns the number of candidates of the source.
+func (s *MaskSource) Total() uint64 {
+	return s.total
+}
+
+// Next returns the next candidate or false if there are no more candidates.
+func (s *MaskSource) Next() (string, bool, error) {
+	if s.done {
+		return "", false, nil
+	}
+
+	var b strings.Builder
+	for i, digit := range s.digits {
+		b.WriteByte(s.positions[i][digit])
+	}
+
+	// Move on to the next candidate, the last position changes fastest.
+	s.done = true
+	for i := len(s.digits) - 1; i >= 0; i-- {
+		s.digits[i]++
+		if s.digits[i] < len(s.positions[i]) {
+			s.done = false
+			break
+		}
+		s.digits[i] = 0
+	}
+
+	return b.String(), true, nil
+}
+
+// PassphraseMutations returns the given guess together with its common case
+// and leet variations, without duplicates.
+func PassphraseMutations(guess string) []string {
+	var (
+		mutations []string
+		mutator   = newPassphraseMutator(guess)
+	)
+	for {
+		mutation, ok := mutator.next()
+		if !ok {
+			return mutations
+		}
+		mutations = append(mutations, mutation)
+	}
+}
+
+// passphraseMutator lazily produces the case and leet variations of a guess.
+// The number of leet variations grows exponentially with the length of the
+// guess, so they are never all kept in memory at the same time.
+type passphraseMutator struct {
+	// variants are the case variations of the guess.
+	variants [][]rune
+
+	// keys are the lower case characters of each variant, which are used
+	// to look up the leet substitutions.
+	keys [][]rune
+
+	// base is the index of the next variant that is returned unchanged.
+	base int
+
+	// variant is the index of the variant that is currently substituted.
+	variant int
+
+	// digits select the substitution of each character of the current
+	// variant, zero means the character is not substituted.
+	digits []int
+}
+
+// newPassphraseMutator returns a mutator for the given guess.
+func newPassphraseMutator(guess string) *passphraseMutator {
+	caseVariants := []string{
+		guess, strings.ToLower(guess), strings.ToUpper(guess),
+		capitalize(guess), swapFirstCase(guess),
+	}
+
+	m := &passphraseMutator{}
+	for _, variant := range caseVariants {
+		runes := []rune(variant)
+		keys := make([]rune, len(runes))
+		for idx, r := range runes {
+			keys[idx] = []rune(strings.ToLower(string(r)))[0]
+		}
+
+		m.variants = append(m.variants, runes)
+		m.keys = append(m.keys, keys)
+	}
+
+	return m
+}
+
+// next returns the next mutation or false if there are no more mutations. All
+// case variations are returned first, followed by their leet variations.
+func (m *passphraseMutator) next() (string, bool) {
+	for m.base < len(m.variants) {
+		m.base++
+		if !m.isDuplicateBase(m.base - 1) {
+			return string(m.variants[m.base-1]), true
+		}
+	}
+
+	for m.variant < len(m.variants) {
+		if m.digits == nil {
+			m.digits = make([]int, len(m.variants[m.variant]))
+		}
+
+		// Once all substitutions of a variant were returned, we move
+		// on to the next one.
+		if !m.increment() {
+			m.variant++
+			m.digits = nil
+			continue
+		}
+		if m.isDuplicateLeet() {
+			continue
+		}
+
+		runes := make([]rune, len(m.digits))
+		for idx, digit := range m.digits {
+			runes[idx] = m.variants[m.variant][idx]
+			if digit > 0 {
+				key := m.keys[m.variant][idx]
+				runes[idx] = leetSubstitutions[key][digit-1]
+			}
+		}
+
+		return string(runes), true
+	}
+
+	return "", false
+}
+
+// increment selects the next substitution of the current variant. It returns
+// false once all substitutions were selected.
+func (m *passphraseMutator) increment() bool {
+	for idx := len(m.digits) - 1; idx >= 0; idx-- {
+		m.digits[idx]++
+		key := m.keys[m.variant][idx]
+		if m.digits[idx] <= len(leetSubstitutions[key]) {
+			return true
+		}
+		m.digits[idx] = 0
+	}
+
+	return false
+}
+
+// isDuplicateBase returns true if the variant with the given index is equal to
+// one of the variants before it.
+func (m *passphraseMutator) isDuplicateBase(variant int) bool {
+	for prev := 0; prev < variant; prev++ {
+		if string(m.variants[prev]) == string(m.variants[variant]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isDuplicateLeet returns true if the current substitution was already
+// returned for one of the previous variants. That is the case if the previous
+// variant only differs from the current one in substituted characters.
+func (m *passphraseMutator) isDuplicateLeet() bool {
+	for prev := 0; prev < m.variant; prev++ {
+		diff, ok := m.diff(prev, m.variant)
+		if !ok {
+			continue
+		}
+
+		covered := true
+		for _, idx := range diff {
+			if m.digits[idx] == 0 {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			return true
+		}
+	}
+
+	return false
+}
+
+// diff returns the positions in which the two variants differ. It returns
+// false if the leet variations of the variants can never be equal.
+func (m *passphraseMutator) diff(a, b int) ([]int, bool) {
+	if len(m.variants[a]) != len(m.variants[b]) {
+		return nil, false
+	}
+
+	var diff []int
+	for idx := range m.variants[a] {
+		if m.variants[a][idx] == m.variants[b][idx] {
+			continue
+		}
+
+		key := m.keys[b][idx]
+		if m.keys[a][idx] != key || len(leetSubstitutions[key]) == 0 {
+			return nil, false
+		}
+		diff = append(diff, idx)
+	}
+
+	return diff, true
+}
+
+// count returns the number of mutations without producing them.
+func (m *passphraseMutator) count() (uint64, error) {
+	var total uint64
+	for variant := range m.variants {
+		if !m.isDuplicateBase(variant) {
+			total++
+		}
+	}
+
+	for variant := range m.variants {
+		// A substitution is a duplicate if it covers all the
+		// differences to one of the previous variants. We use the
+		// inclusion-exclusion principle to count the substitutions
+		// that don't cover any of them.
+		var diffs [][]int
+		for prev := 0; prev < variant; prev++ {
+			if diff, ok := m.diff(prev, variant); ok {
+				diffs = append(diffs, diff)
+			}
+		}
+
+		var added, removed uint64
+		for subset := 0; subset < 1<<len(diffs); subset++ {
+			covered := make(map[int]struct{})
+			for idx, diff := range diffs {
+				if subset&(1<<idx) == 0 {
+					continue
+				}
+				for _, pos := range diff {
+					covered[pos] = struct{}{}
+				}
+			}
+
+			num, err := m.countCovering(variant, covered)
+			if err != nil {
+				return 0, err
+			}
+
+			var carry uint64
+			if bits.OnesCount(uint(subset))%2 == 0 {
+				added, carry = bits.Add64(added, num, 0)
+			} else {
+				removed, carry = bits.Add64(removed, num, 0)
+			}
+			if carry != 0 {
+				return 0, errors.New("guess has too many " +
+					"mutations")
+			}
+		}
+
+		var carry uint64
+		total, carry = bits.Add64(total, added-removed, 0)
+		if carry != 0 {
+			return 0, errors.New("guess has too many mutations")
+		}
+	}
+
+	return total, nil
+}
+
+// countCovering returns the number of substitutions of the given variant that
+// substitute at least one character and at least all the covered positions.
+func (m *passphraseMutator) countCovering(variant int,
+	covered map[int]struct{}) (uint64, error) {
+
+	num := uint64(1)
+	for idx, key := range m.keys[variant] {
+		options := uint64(len(leetSubstitutions[key]))
+		if _, ok := covered[idx]; !ok {
+			options++
+		}
+
+		hi, lo := bits.Mul64(num, options)
+		if hi != 0 {
+			return 0, errors.New("guess has too many mutations")
+		}
+		num = lo
+	}
+
+	// Not substituting any character is the variant itself, which is not
+	// a leet variation.
+	if len(covered) == 0 {
+		num--
+	}
+
+	return num, nil
+}
+
+// capitalize returns the word with its first letter in upper case and all
+// others in lower case.
+func capitalize(word string) string {
+	if word == "" {
+		return word
+	}
+
+	runes := []rune(strings.ToLower(word))
+	runes[0] = []rune(strings.ToUpper(string(runes[0])))[0]
+
+	return string(runes)
+}
+
+// swapFirstCase returns the word with the case of its first letter swapped.
+func swapFirstCase(word string) string {
+	if word == "" {
+		return word
+	}
+
+	runes := []rune(word)
+	first := string(runes[0])
+	if strings.ToUpper(first) == first {
+		runes[0] = []rune(strings.ToLower(first))[0]
+	} else {
+		runes[0] = []rune(strings.ToUpper(first))[0]
+	}
+
+	return string(runes)
+}
+
+// PassphraseRecovery tries the candidates of a passphrase source to decipher
+// an aezeed.
+type PassphraseRecovery struct {
+	// Mnemonic is the mnemonic of the seed.
+	Mnemonic aezeed.Mnemonic
+
+	// Source produces the passphrase candidates.
+	Source PassphraseSource
+
+	// NumWorkers is the number of candidates that are tested in parallel.
+	// If it is zero, one worker per CPU is used.
+	NumWorkers int
+
+	// Skip is the number of candidates of the source that were already
+	// tested in a previous run.
+	Skip uint64
+
+	// Progress is an optional function that is called after each batch of
+	// candidates with the number of candidates tested so far, including
+	// the skipped ones. All candidates up to that number were tested.
+	Progress func(tested uint64) error
+}
+
+// Run tests the candidates of the source until one of them deciphers the seed.
+// If none of them does, an empty passphrase and a nil seed are returned.
+func (r *PassphraseRecovery) Run() (string, *aezeed.CipherSeed, error) {
+	// Before we test any passphrase, we make sure the words of the
+	// mnemonic are correct. Otherwise, no passphrase would ever work.
+	cipherSeed, err := r.Mnemonic.ToCipherSeed(nil)
+	switch {
+	case err == nil:
+		return "", cipherSeed, nil
+
+	case !errors.Is(err, aezeed.ErrInvalidPass):
+		return "", nil, fmt.Errorf("invalid mnemonic, use recoverseed "+
+			"to recover the correct words first: %w", err)
+	}
+
+	tested := uint64(0)
+	for ; tested < r.Skip; tested++ {
+		_, ok, err := r.Source.Next()
+		if err != nil {
+			return "", nil, err
+		}
+		if !ok {
+			break
+		}
+	}
+
+	numWorkers := r.NumWorkers
+	if numWorkers <= 0 {
+		numWorkers = runtime.NumCPU()
+	}
+
+	// We test the candidates in batches, so we always know up to which
+	// candidate all of them were tested.
+	batchSize := numWorkers * 4
+	for {
+		batch := make([]string, 0, batchSize)
+		for len(batch) < batchSize {
+			candidate, ok, err := r.Source.Next()
+			if err != nil {
+				return "", nil, err
+			}
+			if !ok {
+				break
+			}
+			batch = append(batch, candidate)
+		}
+		if len(batch) == 0 {
+			return "", nil, nil
+		}
+
+		passphrase, cipherSeed, err := r.testBatch(batch, numWorkers)
+		if err != nil || cipherSeed != nil {
+			return passphrase, cipherSeed, err
+		}
+
+		tested += uint64(len(batch))
+		if r.Progress != nil {
+			if err := r.Progress(tested); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+}
+
+// testBatch tests all candidates of the batch in parallel.
+func (r *PassphraseRecovery) testBatch(batch []string,
+	numWorkers int) (string, *aezeed.CipherSeed, error) {
+
+	var (
+		wg         sync.WaitGroup
+		mtx        sync.Mutex
+		next       atomic.Int64
+		found      string
+		foundSeed  *aezeed.CipherSeed
+		firstError error
+	)
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				idx := int(next.Add(1) - 1)
+				if idx >= len(batch) {
+					return
+				}
+
+				cipherSeed, err := r.Mnemonic.ToCipherSeed(
+					[]byte(batch[idx]),
+				)
+				if errors.Is(err, aezeed.ErrInvalidPass) {
+					continue
+				}
+
+				mtx.Lock()
+				if err != nil && firstError == nil {
+					firstError = err
+				}
+				if err == nil {
+					found, foundSeed = batch[idx],
+						cipherSeed
+				}
+				mtx.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstError != nil {
+		return "", nil, firstError
+	}
+
+	return found, foundSeed, nil
+}
@@ -0,0 +1,209 @@
+package lnd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/aezeed"
+	"github.com/stretchr/testify/require"
+)
+
+func readAllCandidates(t *testing.T, source PassphraseSource) []string {
+	var candidates []string
+	for {
+		candidate, ok, err := source.Next()
+		require.NoError(t, err)
+		if !ok {
+			return candidates
+		}
+		candidates = append(candidates, candidate)
+	}
+}
+
+func TestMaskSource(t *testing.T) {
+	source, err := NewMaskSource("a??b?d")
+	require.NoError(t, err)
+	require.EqualValues(t, 10, source.Total())
+
+	candidates := readAllCandidates(t, source)
+	require.Len(t, candidates, 10)
+	require.Equal(t, "a?b0", candidates[0])
+	require.Equal(t, "a?b9", candidates[9])
+
+	source, err = NewMaskSource("?u?l")
+	require.NoError(t, err)
+	require.EqualValues(t, 26*26, source.Total())
+	candidates = readAllCandidates(t, source)
+	require.Equal(t, "Ab", candidates[1])
+	require.Equal(t, "Zz", candidates[len(candidates)-1])
+
+	_, err = NewMaskSource("abc?")
+	require.Error(t, err)
+	_, err = NewMaskSource("?x")
+	require.Error(t, err)
+	_, err = NewMaskSource("")
+	require.Error(t, err)
+	_, err = NewMaskSource("?a?a?a?a?a?a?a?a?a?a?a")
+	require.ErrorContains(t, err, "too many candidates")
+}
+
+func TestPassphraseMutations(t *testing.T) {
+	mutations := PassphraseMutations("pass")
+	require.Equal(t, "pass", mutations[0])
+	require.Contains(t, mutations, "PASS")
+	require.Contains(t, mutations, "Pass")
+	require.Contains(t, mutations, "p4$5")
+	require.Contains(t, mutations, "P@ss")
+
+	seen := make(map[string]struct{})
+	for _, mutation := range mutations {
+		require.NotContains(t, seen, mutation)
+		seen[mutation] = struct{}{}
+	}
+
+	require.Equal(t, []string{"123"}, PassphraseMutations("123"))
+}
+
+// referenceMutations naively builds all mutations of a guess in memory.
+func referenceMutations(guess string) map[string]struct{} {
+	mutations := make(map[string]struct{})
+	caseVariants := []string{
+		guess, strings.ToLower(guess), strings.ToUpper(guess),
+		capitalize(guess), swapFirstCase(guess),
+	}
+	for _, variant := range caseVariants {
+		leets := []string{""}
+		for _, r := range variant {
+			options := []rune{r}
+			options = append(options, leetSubstitutions[[]rune(
+				strings.ToLower(string(r)),
+			)[0]]...)
+
+			var next []string
+			for _, leet := range leets {
+				for _, option := range options {
+					next = append(next, leet+string(option))
+				}
+			}
+			leets = next
+		}
+
+		for _, leet := range leets {
+			mutations[leet] = struct{}{}
+		}
+	}
+
+	return mutations
+}
+
+func TestPassphraseMutationsCount(t *testing.T) {
+	guesses := []string{
+		"", "123", "pass", "Pass", "aBc", "Toast", "sAlt4ever", "öl",
+		"ISLAST", "ooooo",
+	}
+	for _, guess := range guesses {
+		mutations := PassphraseMutations(guess)
+
+		expected := referenceMutations(guess)
+		require.Len(t, mutations, len(expected), guess)
+		for _, mutation := range mutations {
+			require.Contains(t, expected, mutation, guess)
+		}
+
+		count, err := newPassphraseMutator(guess).count()
+		require.NoError(t, err)
+		require.EqualValues(t, len(mutations), count, guess)
+	}
+
+	// Long guesses are counted without producing the mutations. The three
+	// distinct case variants ooo..., OOO... and Ooo... lead to 2^40-1,
+	// 2^40-2 and 2^39-2 new leet variations.
+	count, err := newPassphraseMutator(strings.Repeat("o", 40)).count()
+	require.NoError(t, err)
+	require.EqualValues(t, 3+(1<<40-1)+(1<<40-2)+(1<<39-2), count)
+
+	_, err = newPassphraseMutator(strings.Repeat("a", 50)).count()
+	require.ErrorContains(t, err, "too many mutations")
+}
+
+func TestMutationSource(t *testing.T) {
+	source, err := NewMutationSource([]string{"pass", "word"})
+	require.NoError(t, err)
+
+	candidates := readAllCandidates(t, source)
+	require.EqualValues(t, len(candidates), source.Total())
+	require.Equal(t, append(
+		PassphraseMutations("pass"), PassphraseMutations("word")...,
+	), candidates)
+
+	_, err = NewMutationSource([]string{strings.Repeat("a", 50)})
+	require.ErrorContains(t, err, "too many mutations")
+}
+
+func TestWordlistSource(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "wordlist.txt")
+	require.NoError(t, os.WriteFile(fileName, []byte("foo\nbar\n"), 0600))
+
+	source, err := NewWordlistSource(fileName, false)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, source.Total())
+	require.Equal(t, []string{"foo", "bar"}, readAllCandidates(t, source))
+	require.NoError(t, source.Close())
+
+	source, err = NewWordlistSource(fileName, true)
+	require.NoError(t, err)
+	candidates := readAllCandidates(t, source)
+	require.EqualValues(t, len(candidates), source.Total())
+	require.Contains(t, candidates, "F00")
+	require.Contains(t, candidates, "B@r")
+	require.NoError(t, source.Close())
+}
+
+func TestPassphraseRecovery(t *testing.T) {
+	cipherSeed, err := aezeed.New(0, nil, time.Now())
+	require.NoError(t, err)
+	mnemonic, err := cipherSeed.ToMnemonic([]byte("pass7"))
+	require.NoError(t, err)
+
+	source, err := NewMaskSource("pass?d")
+	require.NoError(t, err)
+
+	var progress []uint64
+	recovery := &PassphraseRecovery{
+		Mnemonic:   mnemonic,
+		Source:     source,
+		NumWorkers: 1,
+		Skip:       4,
+		Progress: func(tested uint64) error {
+			progress = append(progress, tested)
+			return nil
+		},
+	}
+	passphrase, recovered, err := recovery.Run()
+	require.NoError(t, err)
+	require.Equal(t, "pass7", passphrase)
+	require.Equal(t, cipherSeed.Entropy, recovered.Entropy)
+	require.Empty(t, progress)
+
+	// A source without the right passphrase finds nothing.
+	recovery.Source = NewListSource([]string{"pass1", "pass2"})
+	recovery.Skip = 0
+	passphrase, recovered, err = recovery.Run()
+	require.NoError(t, err)
+	require.Empty(t, passphrase)
+	require.Nil(t, recovered)
+	require.Equal(t, []uint64{2}, progress)
+
+	// A wrong word can't be fixed by any passphrase.
+	wrongWord := aezeed.DefaultWordList[0]
+	if mnemonic[0] == wrongWord {
+		wrongWord = aezeed.DefaultWordList[1]
+	}
+	mnemonic[0] = wrongWord
+	recovery.Mnemonic = mnemonic
+	_, _, err = recovery.Run()
+	require.ErrorContains(t, err, "invalid mnemonic")
+}
@@ -0,0 +1,315 @@
+package lnd
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math/bits"
+	"runtime"
+	"sync"
+
+	"github.com/lightningnetwork/lnd/aezeed"
+)
+
+const (
+	// UnknownWordPlaceholder marks a word of an aezeed mnemonic that is
+	// unknown and can be any word of the word list.
+	UnknownWordPlaceholder = "?"
+
+	// recoveryChunkSize is the number of word combinations a worker tests
+	// in one go.
+	recoveryChunkSize = 1 << 16
+
+	// checksumOffset is the offset of the checksum within an enciphered
+	// aezeed, see the aezeed package.
+	checksumOffset = aezeed.EncipheredCipherSeedSize - 4
+)
+
+var (
+	// aezeedCRCTable is the table aezeed uses for its checksum.
+	aezeedCRCTable = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// MnemonicWordCandidates returns the words of the aezeed word list the given
+// word could stand for. The placeholder stands for every word of the list, a
+// word that is in the list only for itself and any other word for all words of
+// the list that are at most maxDistance edits away from it.
+func MnemonicWordCandidates(word string, maxDistance int) ([]string, error) {
+	if word == UnknownWordPlaceholder {
+		return aezeed.DefaultWordList, nil
+	}
+
+	if _, ok := aezeed.ReverseWordMap[word]; ok {
+		return []string{word}, nil
+	}
+
+	var candidates []string
+	for _, listWord := range aezeed.DefaultWordList {
+		if editDistance(word, listWord) <= maxDistance {
+			candidates = append(candidates, listWord)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no word in the word list is similar "+
+			"to '%s'", word)
+	}
+
+	return candidates, nil
+}
+
+// editDistance returns the Levenshtein distance between the two words.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+// RecoveredSeed is an aezeed that was found by a seed recovery.
+type RecoveredSeed struct {
+	// Mnemonic is the complete mnemonic of the seed.
+	Mnemonic aezeed.Mnemonic
+
+	// CipherSeed is the deciphered seed.
+	CipherSeed *aezeed.CipherSeed
+}
+
+// SeedRecovery tries all combinations of candidate words of an aezeed mnemonic
+// to find the ones that can be deciphered with the given passphrase.
+type SeedRecovery struct {
+	// Candidates are the candidate words for each of the 24 words of the
+	// mnemonic.
+	Candidates [][]string
+
+	// Passphrase is the passphrase of the seed.
+	Passphrase []byte
+
+	// NumWorkers is the number of combinations that are tested in
+	// parallel. If it is zero, one worker per CPU is used.
+	NumWorkers int
+
+	// Confirm is an optional function that is called for each deciphered
+	// seed. Only seeds it returns true for are part of the result.
+	Confirm func(*aezeed.CipherSeed) (bool, error)
+}
+
+// NumCombinations returns the number of word combinations the recovery needs
+// to test.
+func (r *SeedRecovery) NumCombinations() (uint64, error) {
+	if len(r.Candidates) != aezeed.NumMnemonicWords {
+		return 0, fmt.Errorf("need candidates for %d words, got %d",
+			aezeed.NumMnemonicWords, len(r.Candidates))
+	}
+
+	total := uint64(1)
+	for idx, candidates := range r.Candidates {
+		if len(candidates) == 0 {
+			return 0, fmt.Errorf("no candidates for word %d",
+				idx+1)
+		}
+
+		hi, lo := bits.Mul64(total, uint64(len(candidates)))
+		if hi != 0 {
+			return 0, errors.New("too many unknown words")
+		}
+		total = lo
+	}
+
+	return total, nil
+}
+
+// Run tests all combinations of the candidate words. The cheap checks of the
+// version and the checksum of the mnemonic are done first, only the
+// combinations that pass them are deciphered with the passphrase.
+func (r *SeedRecovery) Run() ([]*RecoveredSeed, error) {
+	total, err := r.NumCombinations()
+	if err != nil {
+		return nil, err
+	}
+
+	// We work with the indexes of the words in the word list, that's what
+	// is encoded in the mnemonic.
+	wordIndexes := make([][]uint16, len(r.Candidates))
+	for i, candidates := range r.Candidates {
+		wordIndexes[i] = make([]uint16, len(candidates))
+		for j, word := range candidates {
+			index, ok := aezeed.ReverseWordMap[word]
+			if !ok {
+				return nil, fmt.Errorf("word '%s' is not in "+
+					"the word list", word)
+			}
+			wordIndexes[i][j] = uint16(index)
+		}
+	}
+
+	numWorkers := r.NumWorkers
+	if numWorkers <= 0 {
+		numWorkers = runtime.NumCPU()
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mtx      sync.Mutex
+		results  []*RecoveredSeed
+		firstErr error
+		chunks   = make(chan uint64)
+	)
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for start := range chunks {
+				end := min(start+recoveryChunkSize, total)
+				seeds, err := r.testRange(
+					wordIndexes, start, end,
+				)
+
+				mtx.Lock()
+				results = append(results, seeds...)
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				mtx.Unlock()
+			}
+		}()
+	}
+
+	for start := uint64(0); start < total; start += recoveryChunkSize {
+		chunks <- start
+	}
+	close(chunks)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return results, nil
+}
+
+// testRange tests the word combinations with the numbers from start up to but
+// not including end.
+func (r *SeedRecovery) testRange(wordIndexes [][]uint16, start,
+	end uint64) ([]*RecoveredSeed, error) {
+
+	// The number of a combination is a mixed radix number with one digit
+	// per word, each digit selecting one of the word's candidates.
+	var (
+		digits  [aezeed.NumMnemonicWords]int
+		indexes [aezeed.NumMnemonicWords]uint16
+		results []*RecoveredSeed
+	)
+	remainder := start
+	for i := len(digits) - 1; i >= 0; i-- {
+		numCandidates := uint64(len(wordIndexes[i]))
+		digits[i] = int(remainder % numCandidates)
+		remainder /= numCandidates
+	}
+
+	for number := start; number < end; number++ {
+		for i, digit := range digits {
+			indexes[i] = wordIndexes[i][digit]
+		}
+
+		if mnemonicChecksumValid(&indexes) {
+			seed, err := r.decipher(&indexes)
+			if err != nil {
+				return results, err
+			}
+			if seed != nil {
+				results = append(results, seed)
+			}
+		}
+
+		// Move on to the next combination.
+		for i := len(digits) - 1; i >= 0; i-- {
+			digits[i]++
+			if digits[i] < len(wordIndexes[i]) {
+				break
+			}
+			digits[i] = 0
+		}
+	}
+
+	return results, nil
+}
+
+// decipher deciphers the mnemonic with the given word indexes. If the
+// passphrase is wrong for the mnemonic or the seed isn't confirmed, nil is
+// returned.
+func (r *SeedRecovery) decipher(
+	indexes *[aezeed.NumMnemonicWords]uint16) (*RecoveredSeed, error) {
+
+	var mnemonic aezeed.Mnemonic
+	for i, index := range indexes {
+		mnemonic[i] = aezeed.DefaultWordList[index]
+	}
+
+	cipherSeed, err := mnemonic.ToCipherSeed(r.Passphrase)
+	if errors.Is(err, aezeed.ErrInvalidPass) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Confirm != nil {
+		confirmed, err := r.Confirm(cipherSeed)
+		if err != nil {
+			return nil, err
+		}
+		if !confirmed {
+			return nil, nil
+		}
+	}
+
+	return &RecoveredSeed{
+		Mnemonic:   mnemonic,
+		CipherSeed: cipherSeed,
+	}, nil
+}
+
+// mnemonicChecksumValid returns true if the mnemonic with the given word
+// indexes has the current version and a valid checksum.
+func mnemonicChecksumValid(indexes *[aezeed.NumMnemonicWords]uint16) bool {
+	// Each word encodes 11 bits of the enciphered seed.
+	var (
+		cipherText [aezeed.EncipheredCipherSeedSize]byte
+		acc        uint64
+		numBits    uint
+		pos        int
+	)
+	for _, index := range indexes {
+		acc = acc<<aezeed.BitsPerWord | uint64(index)
+		numBits += aezeed.BitsPerWord
+		for numBits >= 8 {
+			numBits -= 8
+			cipherText[pos] = byte(acc >> numBits)
+			pos++
+		}
+	}
+
+	if cipherText[0] != aezeed.CipherSeedVersion {
+		return false
+	}
+
+	checksum := crc32.Checksum(cipherText[:checksumOffset], aezeedCRCTable)
+	return checksum == binary.BigEndian.Uint32(cipherText[checksumOffset:])
+}
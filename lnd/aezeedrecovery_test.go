@@ -0,0 +1,54 @@
+package lnd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/aezeed"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMnemonicWordCandidates(t *testing.T) {
+	candidates, err := MnemonicWordCandidates(UnknownWordPlaceholder, 2)
+	require.NoError(t, err)
+	require.Len(t, candidates, 2048)
+
+	candidates, err = MnemonicWordCandidates("abandon", 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"abandon"}, candidates)
+
+	candidates, err = MnemonicWordCandidates("abandn", 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"abandon"}, candidates)
+
+	_, err = MnemonicWordCandidates("xxxxxxxxxxx", 2)
+	require.Error(t, err)
+}
+
+func TestSeedRecovery(t *testing.T) {
+	passphrase := []byte("testnet3")
+	cipherSeed, err := aezeed.New(0, nil, time.Now())
+	require.NoError(t, err)
+	mnemonic, err := cipherSeed.ToMnemonic(passphrase)
+	require.NoError(t, err)
+
+	// We don't know the 3rd word and misspelled the 10th.
+	candidates := make([][]string, aezeed.NumMnemonicWords)
+	for i, word := range mnemonic {
+		candidates[i] = []string{word}
+	}
+	candidates[2] = aezeed.DefaultWordList
+	candidates[9], err = MnemonicWordCandidates(mnemonic[9][1:], 1)
+	require.NoError(t, err)
+
+	recovery := &SeedRecovery{
+		Candidates: candidates,
+		Passphrase: passphrase,
+	}
+	results, err := recovery.Run()
+	require.NoError(t, err)
+
+	require.Len(t, results, 1)
+	require.Equal(t, mnemonic, results[0].Mnemonic)
+	require.Equal(t, cipherSeed.Entropy, results[0].CipherSeed.Entropy)
+}
@@ -0,0 +1,407 @@
+package lnd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/lightningnetwork/lnd/input"
+)
+
+var (
+	inputCharset = "0123456789()[],'/*abcdefgh@:$%{}IJKLMNOPQRSTUVWXYZ" +
+		"&+-.;<=>?!^_|~ijklmnopqrstuvwxyzABCDEFGH`#\\\"\\\\ "
+	checksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	generator       = []uint64{
+		0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a,
+		0x644d626ffd,
+	}
+)
+
+func descriptorSumPolymod(symbols []uint64) uint64 {
+	chk := uint64(1)
+	for _, value := range symbols {
+		top := chk >> 35
+		chk = (chk&0x7ffffffff)<<5 ^ value
+		for i := range 5 {
+			if (top>>i)&1 != 0 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func descriptorSumExpand(s string) []uint64 {
+	groups := []uint64{}
+	symbols := []uint64{}
+	for _, c := range s {
+		v := strings.IndexRune(inputCharset, c)
+		if v < 0 {
+			return nil
+		}
+		symbols = append(symbols, uint64(v&31))
+		groups = append(groups, uint64(v>>5))
+		if len(groups) == 3 {
+			symbols = append(
+				symbols, groups[0]*9+groups[1]*3+groups[2],
+			)
+			groups = []uint64{}
+		}
+	}
+	if len(groups) == 1 {
+		symbols = append(symbols, groups[0])
+	} else if len(groups) == 2 {
+		symbols = append(symbols, groups[0]*3+groups[1])
+	}
+	return symbols
+}
+
+// DescriptorSumCreate appends the checksum to the given descriptor.
+func DescriptorSumCreate(s string) string {
+	symbols := append(descriptorSumExpand(s), 0, 0, 0, 0, 0, 0, 0, 0)
+	checksum := descriptorSumPolymod(symbols) ^ 1
+	builder := strings.Builder{}
+	for i := range 8 {
+		builder.WriteByte(checksumCharset[(checksum>>(5*(7-i)))&31])
+	}
+	return s + "#" + builder.String()
+}
+
+// DescriptorSumCheck verifies the checksum of the given descriptor. If require
+// is false, a descriptor without checksum is also accepted.
+func DescriptorSumCheck(s string, require bool) bool {
+	if !strings.Contains(s, "#") {
+		return !require
+	}
+	if len(s) < 9 || s[len(s)-9] != '#' {
+		return false
+	}
+	symbols := descriptorSumExpand(s[:len(s)-9])
+	for _, c := range s[len(s)-8:] {
+		v := strings.IndexRune(checksumCharset, c)
+		if v < 0 {
+			return false
+		}
+		symbols = append(symbols, uint64(v))
+	}
+	return descriptorSumPolymod(symbols) == 1
+}
+
+// descriptorKey is a single key expression of an output descriptor. It is
+// either a static public key or an extended key with a derivation path that
+// can end in a wildcard.
+type descriptorKey struct {
+	pubKey           *btcec.PublicKey
+	extendedKey      *hdkeychain.ExtendedKey
+	path             []uint32
+	wildcard         bool
+	wildcardHardened bool
+}
+
+// derive returns the public key at the given index. The index is only used if
+// the key ends in a wildcard.
+func (k *descriptorKey) derive(index uint32) (*btcec.PublicKey, error) {
+	if k.pubKey != nil {
+		return k.pubKey, nil
+	}
+
+	path := append([]uint32{}, k.path...)
+	if k.wildcard {
+		if k.wildcardHardened {
+			index += HardenedKeyStart
+		}
+		path = append(path, index)
+	}
+
+	key := k.extendedKey
+	for _, pathPart := range path {
+		var err error
+		key, err = key.Derive(pathPart)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return key.ECPubKey()
+}
+
+// OutputDescriptor is a parsed output script descriptor. The following
+// descriptors are supported: wpkh(KEY), tr(KEY), wsh(multi(k,KEY,...)) and
+// wsh(sortedmulti(k,KEY,...)).
+type OutputDescriptor struct {
+	// Type is the address type of the outputs the descriptor describes.
+	Type AddrType
+
+	threshold int
+	sorted    bool
+	keys      []*descriptorKey
+}
+
+// ParseOutputDescriptor parses the given output descriptor. The checksum is
+// optional but verified if present.
+func ParseOutputDescriptor(descriptor string,
+	chainParams *chaincfg.Params) (*OutputDescriptor, error) {
+
+	desc := strings.TrimSpace(descriptor)
+	if idx := strings.LastIndex(desc, "#"); idx >= 0 {
+		if !DescriptorSumCheck(desc, true) {
+			return nil, fmt.Errorf("invalid checksum in "+
+				"descriptor %s", descriptor)
+		}
+		desc = desc[:idx]
+	}
+
+	if inner, ok := unwrapDescriptor(desc, "wpkh"); ok {
+		key, err := parseDescriptorKey(inner, chainParams)
+		if err != nil {
+			return nil, err
+		}
+
+		return &OutputDescriptor{
+			Type: AddrTypeP2WKH,
+			keys: []*descriptorKey{key},
+		}, nil
+	}
+
+	if inner, ok := unwrapDescriptor(desc, "tr"); ok {
+		if strings.Contains(inner, ",") {
+			return nil, errors.New("tr() descriptors with script " +
+				"paths are not supported")
+		}
+
+		key, err := parseDescriptorKey(inner, chainParams)
+		if err != nil {
+			return nil, err
+		}
+
+		return &OutputDescriptor{
+			Type: AddrTypeP2TR,
+			keys: []*descriptorKey{key},
+		}, nil
+	}
+
+	if inner, ok := unwrapDescriptor(desc, "wsh"); ok {
+		sorted := false
+		args, ok := unwrapDescriptor(inner, "multi")
+		if !ok {
+			args, ok = unwrapDescriptor(inner, "sortedmulti")
+			sorted = true
+		}
+		if !ok {
+			return nil, fmt.Errorf("only multi() and "+
+				"sortedmulti() are supported in wsh() "+
+				"descriptors, got %s", inner)
+		}
+
+		parts := strings.Split(args, ",")
+		threshold, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid multisig threshold: %w",
+				err)
+		}
+		numKeys := len(parts) - 1
+		if threshold < 1 || threshold > numKeys ||
+			numKeys > txscript.MaxPubKeysPerMultiSig {
+
+			return nil, fmt.Errorf("invalid multisig threshold "+
+				"%d of %d keys", threshold, numKeys)
+		}
+
+		result := &OutputDescriptor{
+			Type:      AddrTypeP2WSH,
+			threshold: threshold,
+			sorted:    sorted,
+		}
+		for _, part := range parts[1:] {
+			key, err := parseDescriptorKey(part, chainParams)
+			if err != nil {
+				return nil, err
+			}
+			result.keys = append(result.keys, key)
+		}
+
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("unsupported descriptor %s, only wpkh(), tr() "+
+		"and wsh(multi()) or wsh(sortedmulti()) are supported",
+		descriptor)
+}
+
+// IsRange returns true if the descriptor contains a wildcard and therefore
+// describes a range of output scripts.
+func (d *OutputDescriptor) IsRange() bool {
+	for _, key := range d.keys {
+		if key.wildcard {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PkScript returns the output script at the given index. The index is ignored
+// if the descriptor doesn't contain a wildcard.
+func (d *OutputDescriptor) PkScript(index uint32) ([]byte, error) {
+	pubKeys := make([]*btcec.PublicKey, len(d.keys))
+	for idx, key := range d.keys {
+		var err error
+		pubKeys[idx], err = key.derive(index)
+		if err != nil {
+			return nil, fmt.Errorf("error deriving descriptor "+
+				"key: %w", err)
+		}
+	}
+
+	switch d.Type {
+	case AddrTypeP2WKH:
+		return input.WitnessPubKeyHash(pubKeys[0].SerializeCompressed())
+
+	case AddrTypeP2TR:
+		taprootKey := txscript.ComputeTaprootKeyNoScript(pubKeys[0])
+		return input.PayToTaprootScript(taprootKey)
+
+	case AddrTypeP2WSH:
+		witnessScript, err := d.multiSigScript(pubKeys)
+		if err != nil {
+			return nil, err
+		}
+
+		return input.WitnessScriptHash(witnessScript)
+
+	default:
+		return nil, fmt.Errorf("unsupported descriptor type %d", d.Type)
+	}
+}
+
+// multiSigScript creates the k-of-n multisig script of the given keys.
+func (d *OutputDescriptor) multiSigScript(
+	pubKeys []*btcec.PublicKey) ([]byte, error) {
+
+	serializedKeys := make([][]byte, len(pubKeys))
+	for idx, pubKey := range pubKeys {
+		serializedKeys[idx] = pubKey.SerializeCompressed()
+	}
+	if d.sorted {
+		sort.Slice(serializedKeys, func(i, j int) bool {
+			return bytes.Compare(
+				serializedKeys[i], serializedKeys[j],
+			) < 0
+		})
+	}
+
+	builder := txscript.NewScriptBuilder()
+	builder.AddInt64(int64(d.threshold))
+	for _, serializedKey := range serializedKeys {
+		builder.AddData(serializedKey)
+	}
+	builder.AddInt64(int64(len(serializedKeys)))
+	builder.AddOp(txscript.OP_CHECKMULTISIG)
+
+	return builder.Script()
+}
+
+// unwrapDescriptor returns the arguments of the given descriptor function if
+// the descriptor is a call to that function.
+func unwrapDescriptor(desc, function string) (string, bool) {
+	prefix := function + "("
+	if !strings.HasPrefix(desc, prefix) || !strings.HasSuffix(desc, ")") {
+		return "", false
+	}
+
+	return desc[len(prefix) : len(desc)-1], true
+}
+
+// parseDescriptorKey parses a key expression of a descriptor. Key origin
+// information is ignored as it's not needed to derive the keys.
+func parseDescriptorKey(keyExpr string,
+	chainParams *chaincfg.Params) (*descriptorKey, error) {
+
+	keyExpr = strings.TrimSpace(keyExpr)
+	if strings.HasPrefix(keyExpr, "[") {
+		end := strings.Index(keyExpr, "]")
+		if end < 0 {
+			return nil, fmt.Errorf("invalid key origin in %s",
+				keyExpr)
+		}
+		keyExpr = keyExpr[end+1:]
+	}
+
+	parts := strings.Split(keyExpr, "/")
+
+	// A static public key in hex.
+	isHexKey := len(parts[0]) == 2*btcec.PubKeyBytesLenCompressed
+	if len(parts) == 1 && isHexKey {
+		pubKeyBytes, err := hex.DecodeString(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key %s: %w",
+				parts[0], err)
+		}
+		pubKey, err := btcec.ParsePubKey(pubKeyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key %s: %w",
+				parts[0], err)
+		}
+
+		return &descriptorKey{pubKey: pubKey}, nil
+	}
+
+	extendedKey, err := hdkeychain.NewKeyFromString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid extended key %s: %w", parts[0],
+			err)
+	}
+	if !extendedKey.IsForNet(chainParams) {
+		return nil, fmt.Errorf("extended key %s is not valid for "+
+			"network %s", parts[0], chainParams.Name)
+	}
+
+	key := &descriptorKey{
+		extendedKey: extendedKey,
+	}
+	needsPrivate := false
+	for idx, part := range parts[1:] {
+		hardened := strings.HasSuffix(part, "'") ||
+			strings.HasSuffix(part, "h")
+		if hardened {
+			part = part[:len(part)-1]
+			needsPrivate = true
+		}
+
+		if part == "*" {
+			if idx != len(parts)-2 {
+				return nil, fmt.Errorf("wildcard must be the "+
+					"last path element in %s", keyExpr)
+			}
+			key.wildcard = true
+			key.wildcardHardened = hardened
+			continue
+		}
+
+		index, err := strconv.ParseUint(part, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path element %s in "+
+				"%s: %w", part, keyExpr, err)
+		}
+		if hardened {
+			index += uint64(HardenedKeyStart)
+		}
+		key.path = append(key.path, uint32(index))
+	}
+
+	if needsPrivate && !extendedKey.IsPrivate() {
+		return nil, fmt.Errorf("hardened derivation requires a "+
+			"private key in %s", keyExpr)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,248 @@
+package lnd
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/input"
+)
+
+const (
+	// destinationWeightSeparator separates the optional weight from the
+	// address or descriptor of a sweep destination.
+	destinationWeightSeparator = ":"
+
+	// destinationIndexSeparator separates the optional start index from a
+	// ranged descriptor of a sweep destination.
+	destinationIndexSeparator = "@"
+)
+
+// SweepDestination is a single destination the funds of a sweep transaction
+// are sent to. A destination is specified as
+// <address|descriptor>[@<start index>][:<weight>], where the start index is
+// only allowed for ranged descriptors and the weight defines the share of the
+// swept funds the destination receives relative to all other destinations.
+type SweepDestination struct {
+	// Addr is the address of the destination or the special value
+	// AddressDeriveFromWallet. It is empty if the destination is a
+	// descriptor.
+	Addr string
+
+	// Descriptor is the output descriptor of the destination.
+	Descriptor *OutputDescriptor
+
+	// NextIndex is the index at which the next output script of a ranged
+	// descriptor is derived.
+	NextIndex uint32
+
+	// Weight is the share of the swept funds the destination receives.
+	Weight uint32
+}
+
+// ParseSweepDestinations parses and validates the given sweep destinations.
+// At least one destination is required.
+func ParseSweepDestinations(destinations []string,
+	chainParams *chaincfg.Params, allowDerive bool, hint string,
+	allowedTypes ...AddrType) ([]*SweepDestination, error) {
+
+	if len(destinations) == 0 {
+		return nil, fmt.Errorf("%s address cannot be empty", hint)
+	}
+
+	result := make([]*SweepDestination, 0, len(destinations))
+	for _, destination := range destinations {
+		dest, err := parseSweepDestination(
+			destination, chainParams, allowDerive, hint,
+			allowedTypes...,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, dest)
+	}
+
+	return result, nil
+}
+
+func parseSweepDestination(destination string, chainParams *chaincfg.Params,
+	allowDerive bool, hint string,
+	allowedTypes ...AddrType) (*SweepDestination, error) {
+
+	dest := &SweepDestination{
+		Weight: 1,
+	}
+
+	target := strings.TrimSpace(destination)
+	idx := strings.LastIndex(target, destinationWeightSeparator)
+	if idx >= 0 {
+		weight, err := strconv.ParseUint(target[idx+1:], 10, 32)
+		if err != nil || weight == 0 {
+			return nil, fmt.Errorf("invalid weight in %s "+
+				"destination %s, must be a positive integer",
+				hint, destination)
+		}
+
+		dest.Weight = uint32(weight)
+		target = target[:idx]
+	}
+
+	// Anything that isn't a descriptor must be an address.
+	if !strings.Contains(target, "(") {
+		err := CheckAddress(
+			target, chainParams, allowDerive, hint, allowedTypes...,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		dest.Addr = target
+		return dest, nil
+	}
+
+	idx = strings.LastIndex(target, destinationIndexSeparator)
+	if idx >= 0 {
+		index, err := strconv.ParseUint(target[idx+1:], 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start index in %s "+
+				"destination %s: %w", hint, destination, err)
+		}
+
+		dest.NextIndex = uint32(index)
+		target = target[:idx]
+	}
+
+	descriptor, err := ParseOutputDescriptor(target, chainParams)
+	if err != nil {
+		return nil, fmt.Errorf("%s descriptor is invalid: %w", hint,
+			err)
+	}
+
+	typeAllowed := false
+	for _, allowedType := range allowedTypes {
+		if descriptor.Type == allowedType {
+			typeAllowed = true
+		}
+	}
+	if !typeAllowed {
+		return nil, fmt.Errorf("%s descriptor is of wrong type, "+
+			"allowed types: %s", hint,
+			addrTypesToString(allowedTypes))
+	}
+
+	if dest.NextIndex != 0 && !descriptor.IsRange() {
+		return nil, fmt.Errorf("start index in %s destination %s is "+
+			"only allowed for ranged descriptors", hint,
+			destination)
+	}
+
+	dest.Descriptor = descriptor
+	return dest, nil
+}
+
+// SweepOutputs are the output scripts of a sweep transaction together with
+// the share of the swept funds each of them receives.
+type SweepOutputs struct {
+	PkScripts [][]byte
+	Weights   []uint32
+}
+
+// PrepareSweepOutputs creates the output scripts of all given destinations and
+// adds their weight to the estimator. A ranged descriptor is advanced to the
+// next index, so the next call returns a fresh output script.
+func PrepareSweepOutputs(destinations []*SweepDestination,
+	chainParams *chaincfg.Params, estimator *input.TxWeightEstimator,
+	rootKey *hdkeychain.ExtendedKey, hint string) (*SweepOutputs, error) {
+
+	outputs := &SweepOutputs{
+		PkScripts: make([][]byte, len(destinations)),
+		Weights:   make([]uint32, len(destinations)),
+	}
+	for idx, dest := range destinations {
+		outputs.Weights[idx] = dest.Weight
+
+		if dest.Descriptor == nil {
+			pkScript, err := PrepareWalletAddress(
+				dest.Addr, chainParams, estimator, rootKey,
+				hint,
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			outputs.PkScripts[idx] = pkScript
+			continue
+		}
+
+		pkScript, err := dest.Descriptor.PkScript(dest.NextIndex)
+		if err != nil {
+			return nil, fmt.Errorf("error deriving %s output "+
+				"script at index %d: %w", hint, dest.NextIndex,
+				err)
+		}
+		if estimator != nil {
+			err := AddOutputWeight(estimator, pkScript)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if dest.Descriptor.IsRange() {
+			dest.NextIndex++
+		}
+
+		outputs.PkScripts[idx] = pkScript
+	}
+
+	return outputs, nil
+}
+
+// TxOuts splits the given amount between the outputs according to their
+// weights. Any remainder of the division goes to the first output. An error is
+// returned if any of the outputs would be dust.
+func (o *SweepOutputs) TxOuts(amount int64) ([]*wire.TxOut, error) {
+	var totalWeight int64
+	for _, weight := range o.Weights {
+		totalWeight += int64(weight)
+	}
+	if totalWeight == 0 {
+		return nil, errors.New("no sweep outputs")
+	}
+
+	var (
+		txOuts    = make([]*wire.TxOut, len(o.PkScripts))
+		remaining = amount
+	)
+	for idx, pkScript := range o.PkScripts {
+		// We use big integers to make sure the multiplication can't
+		// overflow.
+		value := new(big.Int).Mul(
+			big.NewInt(amount), big.NewInt(int64(o.Weights[idx])),
+		)
+		value.Quo(value, big.NewInt(totalWeight))
+
+		txOuts[idx] = &wire.TxOut{
+			Value:    value.Int64(),
+			PkScript: pkScript,
+		}
+		remaining -= value.Int64()
+	}
+	txOuts[0].Value += remaining
+
+	for idx, txOut := range txOuts {
+		if mempool.IsDust(txOut, mempool.DefaultMinRelayTxFee) {
+			return nil, fmt.Errorf("sweep output %d with value %v "+
+				"would be dust", idx,
+				btcutil.Amount(txOut.Value))
+		}
+	}
+
+	return txOuts, nil
+}
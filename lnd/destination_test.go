@@ -0,0 +1,195 @@
+package lnd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOutputDescriptor(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	extendedKey, err := hdkeychain.NewKeyFromString(rootKey)
+	require.NoError(t, err)
+	xpub, err := extendedKey.Neuter()
+	require.NoError(t, err)
+
+	deriveKey := func(path ...uint32) *btcec.PublicKey {
+		key := xpub
+		for _, index := range path {
+			key, err = key.Derive(index)
+			require.NoError(t, err)
+		}
+		pubKey, err := key.ECPubKey()
+		require.NoError(t, err)
+
+		return pubKey
+	}
+
+	// A ranged wpkh descriptor derives a new key for each index.
+	desc, err := ParseOutputDescriptor(
+		fmt.Sprintf("wpkh([deadbeef/84h/1h/0h]%s/0/*)", xpub), params,
+	)
+	require.NoError(t, err)
+	require.Equal(t, AddrTypeP2WKH, desc.Type)
+	require.True(t, desc.IsRange())
+
+	pkScript, err := desc.PkScript(7)
+	require.NoError(t, err)
+	addr, err := P2WKHAddr(deriveKey(0, 7), params)
+	require.NoError(t, err)
+	expected, err := txscript.PayToAddrScript(addr)
+	require.NoError(t, err)
+	require.Equal(t, expected, pkScript)
+
+	// A taproot descriptor with a fixed key isn't ranged.
+	desc, err = ParseOutputDescriptor(
+		fmt.Sprintf("tr(%s/1)", xpub), params,
+	)
+	require.NoError(t, err)
+	require.Equal(t, AddrTypeP2TR, desc.Type)
+	require.False(t, desc.IsRange())
+
+	pkScript, err = desc.PkScript(0)
+	require.NoError(t, err)
+	trAddr, err := P2TRAddr(deriveKey(1), params)
+	require.NoError(t, err)
+	expected, err = txscript.PayToAddrScript(trAddr)
+	require.NoError(t, err)
+	require.Equal(t, expected, pkScript)
+
+	// Sorted multisig doesn't depend on the order of the keys.
+	key1 := deriveKey(2)
+	key2 := deriveKey(3)
+	desc, err = ParseOutputDescriptor(
+		fmt.Sprintf("wsh(sortedmulti(1,%x,%x))",
+			key1.SerializeCompressed(), key2.SerializeCompressed()),
+		params,
+	)
+	require.NoError(t, err)
+	require.Equal(t, AddrTypeP2WSH, desc.Type)
+	desc2, err := ParseOutputDescriptor(
+		fmt.Sprintf("wsh(sortedmulti(1,%x,%x))",
+			key2.SerializeCompressed(), key1.SerializeCompressed()),
+		params,
+	)
+	require.NoError(t, err)
+
+	pkScript, err = desc.PkScript(0)
+	require.NoError(t, err)
+	pkScript2, err := desc2.PkScript(0)
+	require.NoError(t, err)
+	require.Equal(t, pkScript, pkScript2)
+	require.Len(t, pkScript, input.P2WSHSize)
+
+	// A valid checksum is accepted, an invalid one is rejected.
+	withSum := DescriptorSumCreate(fmt.Sprintf("wpkh(%s/0/*)", xpub))
+	_, err = ParseOutputDescriptor(withSum, params)
+	require.NoError(t, err)
+
+	tampered := withSum[:len(withSum)-1] + "q"
+	if withSum[len(withSum)-1] == 'q' {
+		tampered = withSum[:len(withSum)-1] + "p"
+	}
+	_, err = ParseOutputDescriptor(tampered, params)
+	require.ErrorContains(t, err, "checksum")
+
+	// Keys of the wrong network and unsupported descriptors are rejected.
+	_, err = ParseOutputDescriptor(
+		fmt.Sprintf("wpkh(%s/0/*)", xpub), &chaincfg.MainNetParams,
+	)
+	require.Error(t, err)
+	_, err = ParseOutputDescriptor(
+		fmt.Sprintf("pkh(%s/0/*)", xpub), params,
+	)
+	require.Error(t, err)
+	_, err = ParseOutputDescriptor(
+		fmt.Sprintf("wpkh(%s/*/0)", xpub), params,
+	)
+	require.Error(t, err)
+}
+
+func TestParseSweepDestinations(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	extendedKey, err := hdkeychain.NewKeyFromString(rootKey)
+	require.NoError(t, err)
+	xpub, err := extendedKey.Neuter()
+	require.NoError(t, err)
+	pubKey, err := extendedKey.ECPubKey()
+	require.NoError(t, err)
+	addr, err := P2WKHAddr(pubKey, params)
+	require.NoError(t, err)
+
+	allowedTypes := []AddrType{AddrTypeP2WKH, AddrTypeP2WSH, AddrTypeP2TR}
+	dests, err := ParseSweepDestinations([]string{
+		addr.String() + ":70",
+		fmt.Sprintf("wpkh(%s/0/*)@5:30", xpub),
+		AddressDeriveFromWallet,
+	}, params, true, "sweep", allowedTypes...)
+	require.NoError(t, err)
+	require.Len(t, dests, 3)
+
+	require.Equal(t, addr.String(), dests[0].Addr)
+	require.EqualValues(t, 70, dests[0].Weight)
+	require.NotNil(t, dests[1].Descriptor)
+	require.EqualValues(t, 5, dests[1].NextIndex)
+	require.EqualValues(t, 30, dests[1].Weight)
+	require.Equal(t, AddressDeriveFromWallet, dests[2].Addr)
+	require.EqualValues(t, 1, dests[2].Weight)
+
+	// Preparing the outputs advances the ranged descriptor and accounts for
+	// the weight of all outputs.
+	var estimator input.TxWeightEstimator
+	outputs, err := PrepareSweepOutputs(
+		dests[:2], params, &estimator, extendedKey, "sweep",
+	)
+	require.NoError(t, err)
+	require.Len(t, outputs.PkScripts, 2)
+	require.EqualValues(t, 6, dests[1].NextIndex)
+
+	var expected input.TxWeightEstimator
+	expected.AddP2WKHOutput()
+	expected.AddP2WKHOutput()
+	require.Equal(t, expected.Weight(), estimator.Weight())
+
+	txOuts, err := outputs.TxOuts(100_001)
+	require.NoError(t, err)
+	require.Len(t, txOuts, 2)
+	require.EqualValues(t, 70_001, txOuts[0].Value)
+	require.EqualValues(t, 30_000, txOuts[1].Value)
+
+	// An output that would be dust is rejected.
+	_, err = outputs.TxOuts(500)
+	require.ErrorContains(t, err, "dust")
+
+	invalid := []string{
+		addr.String() + ":0",
+		addr.String() + ":abc",
+		addr.String() + "@5",
+		fmt.Sprintf("wpkh(%s/0)@5", xpub),
+		fmt.Sprintf("pkh(%s/0/*)", xpub),
+	}
+	for _, dest := range invalid {
+		_, err := ParseSweepDestinations(
+			[]string{dest}, params, true, "sweep", allowedTypes...,
+		)
+		require.Error(t, err, dest)
+	}
+
+	// Descriptors of a type that isn't allowed are rejected.
+	_, err = ParseSweepDestinations(
+		[]string{fmt.Sprintf("tr(%s/0/*)", xpub)}, params, true,
+		"sweep", AddrTypeP2WKH,
+	)
+	require.ErrorContains(t, err, "wrong type")
+
+	_, err = ParseSweepDestinations(
+		nil, params, true, "sweep", allowedTypes...,
+	)
+	require.Error(t, err)
+}
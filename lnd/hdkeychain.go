@@ -105,6 +105,85 @@ func ParsePath(path string) ([]uint32, error) {
 	return indices, nil
 }
 
+// ExpandPathRange expands a BIP32 derivation path in which any part can be a
+// range of indexes like "0-499" into the list of all paths it stands for. A
+// range is hardened if its start or end index is marked as hardened, for
+// example "0'-9'". At most maxPaths paths are returned.
+func ExpandPathRange(path string, maxPaths int) ([]string, error) {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "m/") {
+		return nil, errors.New("path must start with m/")
+	}
+
+	paths := []string{"m"}
+	for _, part := range strings.Split(path, "/")[1:] {
+		start, end, found := strings.Cut(part, "-")
+		if !found {
+			paths = appendPathPart(paths, part)
+			continue
+		}
+
+		hardened := ""
+		if strings.HasSuffix(start, "'") ||
+			strings.HasSuffix(end, "'") {
+
+			hardened = "'"
+		}
+		first, err := strconv.ParseUint(
+			strings.TrimSuffix(start, "'"), 10, 31,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse range start "+
+				"\"%s\": %w", start, err)
+		}
+		last, err := strconv.ParseUint(
+			strings.TrimSuffix(end, "'"), 10, 31,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse range end "+
+				"\"%s\": %w", end, err)
+		}
+		if last < first {
+			return nil, fmt.Errorf("invalid range \"%s\", end "+
+				"must not be smaller than start", part)
+		}
+
+		numIndexes := last - first + 1
+		if numIndexes*uint64(len(paths)) > uint64(maxPaths) {
+			return nil, fmt.Errorf("path range contains more than "+
+				"%d paths", maxPaths)
+		}
+
+		expanded := make([]string, 0, len(paths)*int(numIndexes))
+		for _, prefix := range paths {
+			for index := first; index <= last; index++ {
+				expanded = append(expanded, fmt.Sprintf(
+					"%s/%d%s", prefix, index, hardened,
+				))
+			}
+		}
+		paths = expanded
+	}
+
+	// Make sure all expanded paths are valid.
+	for _, expandedPath := range paths {
+		if _, err := ParsePath(expandedPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return paths, nil
+}
+
+// appendPathPart appends the given part to all the paths.
+func appendPathPart(paths []string, part string) []string {
+	for i := range paths {
+		paths[i] = paths[i] + "/" + part
+	}
+
+	return paths
+}
+
 func HardenedKey(key uint32) uint32 {
 	return key + HardenedKeyStart
 }
@@ -452,6 +531,11 @@ func PrepareWalletAddress(addr string, chainParams *chaincfg.Params,
 	estimator *input.TxWeightEstimator, rootKey *hdkeychain.ExtendedKey,
 	hint string) ([]byte, error) {
 
+	var (
+		pkScript []byte
+		err      error
+	)
+
 	// We already checked if deriving a new address is allowed in a previous
 	// step, so we can just go ahead and do it now if requested.
 	if addr == AddressDeriveFromWallet {
@@ -477,36 +561,63 @@ func PrepareWalletAddress(addr string, chainParams *chaincfg.Params,
 			return nil, err
 		}
 
-		return txscript.PayToAddrScript(p2wkhAddr)
-	}
+		pkScript, err = txscript.PayToAddrScript(p2wkhAddr)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		parsedAddr, err := ParseAddress(addr, chainParams)
+		if err != nil {
+			return nil, fmt.Errorf("%s address is invalid: %w",
+				hint, err)
+		}
 
-	parsedAddr, err := ParseAddress(addr, chainParams)
-	if err != nil {
-		return nil, fmt.Errorf("%s address is invalid: %w", hint, err)
+		pkScript, err = txscript.PayToAddrScript(parsedAddr)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Exit early if we don't need to estimate the weight.
 	if estimator == nil {
-		return txscript.PayToAddrScript(parsedAddr)
+		return pkScript, nil
 	}
 
-	// These are the three address types that we support in general. We
-	// should have checked that we get the correct type in a previous step.
-	switch parsedAddr.(type) {
-	case *btcutil.AddressWitnessPubKeyHash:
+	err = AddOutputWeight(estimator, pkScript)
+	if err != nil {
+		return nil, fmt.Errorf("%s address is of wrong type: %w", hint,
+			err)
+	}
+
+	return pkScript, nil
+}
+
+// AddOutputWeight adds the weight of an output with the given pk script to the
+// estimator.
+func AddOutputWeight(estimator *input.TxWeightEstimator,
+	pkScript []byte) error {
+
+	switch class := txscript.GetScriptClass(pkScript); class {
+	case txscript.WitnessV0PubKeyHashTy:
 		estimator.AddP2WKHOutput()
 
-	case *btcutil.AddressWitnessScriptHash:
+	case txscript.WitnessV0ScriptHashTy:
 		estimator.AddP2WSHOutput()
 
-	case *btcutil.AddressTaproot:
+	case txscript.WitnessV1TaprootTy:
 		estimator.AddP2TROutput()
 
+	case txscript.ScriptHashTy:
+		estimator.AddP2SHOutput()
+
+	case txscript.PubKeyHashTy:
+		estimator.AddP2PKHOutput()
+
 	default:
-		return nil, fmt.Errorf("%s address is of wrong type", hint)
+		return fmt.Errorf("unsupported output script type %v", class)
 	}
 
-	return txscript.PayToAddrScript(parsedAddr)
+	return nil
 }
 
 func matchAddrType(addr btcutil.Address, allowedTypes ...AddrType) bool {
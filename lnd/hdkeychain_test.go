@@ -0,0 +1,32 @@
+package lnd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandPathRange(t *testing.T) {
+	paths, err := ExpandPathRange("m/1017'/0'/6'/0/0", 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"m/1017'/0'/6'/0/0"}, paths)
+
+	paths, err = ExpandPathRange("m/1017'/0'/0-1'/0/3-4", 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"m/1017'/0'/0'/0/3", "m/1017'/0'/0'/0/4",
+		"m/1017'/0'/1'/0/3", "m/1017'/0'/1'/0/4",
+	}, paths)
+
+	_, err = ExpandPathRange("m/0/0-10", 10)
+	require.ErrorContains(t, err, "more than 10 paths")
+
+	_, err = ExpandPathRange("m/0/5-4", 10)
+	require.ErrorContains(t, err, "must not be smaller")
+
+	_, err = ExpandPathRange("m/0/a-4", 10)
+	require.Error(t, err)
+
+	_, err = ExpandPathRange("0/1", 10)
+	require.Error(t, err)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
 	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
 	"github.com/btcsuite/btcd/btcutil/psbt"
 	"github.com/btcsuite/btcd/chaincfg"
@@ -277,6 +278,86 @@ func (s *Signer) AddTaprootSignature(packet *psbt.Packet, inputIndex int,
 	return nil
 }
 
+// SignWalletInput signs the input at the given index of the packet that spends
+// a P2WKH, NP2WKH or P2TR (BIP86 key spend) output of the on-chain wallet with
+// the given private key. The final witness (and signature script for NP2WKH)
+// is added to the packet directly. The witness UTXO of all inputs must be set.
+func (s *Signer) SignWalletInput(packet *psbt.Packet, inputIndex int,
+	privKey *btcec.PrivateKey) error {
+
+	pIn := &packet.Inputs[inputIndex]
+	utxo := pIn.WitnessUtxo
+	if utxo == nil {
+		return fmt.Errorf("witness utxo of input %d missing",
+			inputIndex)
+	}
+
+	pkScript, err := txscript.ParsePkScript(utxo.PkScript)
+	if err != nil {
+		return fmt.Errorf("error parsing pk script: %w", err)
+	}
+
+	// The signature hash for both native and nested P2WKH inputs commits
+	// to the P2WKH witness program.
+	pubKey := privKey.PubKey()
+	witnessProgram, err := txscript.NewScriptBuilder().AddOp(
+		txscript.OP_0,
+	).AddData(btcutil.Hash160(pubKey.SerializeCompressed())).Script()
+	if err != nil {
+		return fmt.Errorf("error creating witness program: %w", err)
+	}
+
+	switch pkScript.Class() {
+	case txscript.WitnessV1TaprootTy:
+		return s.AddTaprootSignature(packet, inputIndex, utxo, privKey)
+
+	case txscript.WitnessV0PubKeyHashTy:
+
+	case txscript.ScriptHashTy:
+		pIn.RedeemScript = witnessProgram
+		sigScript, err := txscript.NewScriptBuilder().AddData(
+			witnessProgram,
+		).Script()
+		if err != nil {
+			return fmt.Errorf("error creating sig script: %w", err)
+		}
+		pIn.FinalScriptSig = sigScript
+
+	default:
+		return fmt.Errorf("unsupported wallet input type: %v",
+			pkScript.Class())
+	}
+
+	prevOutFetcher := wallet.PsbtPrevOutputFetcher(packet)
+	signDesc := &input.SignDescriptor{
+		WitnessScript:     witnessProgram,
+		Output:            utxo,
+		InputIndex:        inputIndex,
+		HashType:          txscript.SigHashAll,
+		PrevOutputFetcher: prevOutFetcher,
+	}
+	sig, err := s.SignOutputRawWithPrivateKey(
+		packet.UnsignedTx, signDesc, privKey,
+	)
+	if err != nil {
+		return fmt.Errorf("error signing with our key: %w", err)
+	}
+
+	witness := wire.TxWitness{
+		append(sig.Serialize(), byte(txscript.SigHashAll)),
+		pubKey.SerializeCompressed(),
+	}
+	var witnessBuf bytes.Buffer
+	err = psbt.WriteTxWitness(&witnessBuf, witness)
+	if err != nil {
+		return fmt.Errorf("error serializing witness: %w", err)
+	}
+
+	pIn.FinalScriptWitness = witnessBuf.Bytes()
+
+	return nil
+}
+
 // maybeTweakPrivKey examines the single tweak parameters on the passed sign
 // descriptor and may perform a mapping on the passed private key in order to
 // utilize the tweaks, if populated.
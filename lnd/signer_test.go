@@ -0,0 +1,105 @@
+package lnd
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcwallet/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignWalletInput(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	extendedKey, err := hdkeychain.NewKeyFromString(rootKey)
+	require.NoError(t, err)
+
+	signer := &Signer{
+		ExtendedKey: extendedKey,
+		ChainParams: params,
+	}
+
+	mkAddrs := []func(*btcec.PublicKey, *chaincfg.Params) (btcutil.Address,
+		error){
+
+		func(k *btcec.PublicKey,
+			p *chaincfg.Params) (btcutil.Address, error) {
+
+			return P2WKHAddr(k, p)
+		},
+		func(k *btcec.PublicKey,
+			p *chaincfg.Params) (btcutil.Address, error) {
+
+			return NP2WKHAddr(k, p)
+		},
+		func(k *btcec.PublicKey,
+			p *chaincfg.Params) (btcutil.Address, error) {
+
+			return P2TRAddr(k, p)
+		},
+	}
+
+	tx := wire.NewMsgTx(2)
+	packet, err := psbt.NewFromUnsignedTx(tx)
+	require.NoError(t, err)
+
+	privKeys := make([]*btcec.PrivateKey, len(mkAddrs))
+	for idx, mkAddr := range mkAddrs {
+		privKeys[idx], err = PrivKeyFromPath(extendedKey, []uint32{
+			HardenedKey(84), HardenedKey(1), HardenedKey(0), 0,
+			uint32(idx),
+		})
+		require.NoError(t, err)
+
+		addr, err := mkAddr(privKeys[idx].PubKey(), params)
+		require.NoError(t, err)
+		pkScript, err := txscript.PayToAddrScript(addr)
+		require.NoError(t, err)
+
+		packet.UnsignedTx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: wire.OutPoint{
+				Hash:  chainhash.Hash{byte(idx + 1)},
+				Index: uint32(idx),
+			},
+		})
+		packet.Inputs = append(packet.Inputs, psbt.PInput{
+			WitnessUtxo: &wire.TxOut{
+				Value:    100_000,
+				PkScript: pkScript,
+			},
+		})
+	}
+	packet.UnsignedTx.AddTxOut(&wire.TxOut{
+		Value:    250_000,
+		PkScript: packet.Inputs[0].WitnessUtxo.PkScript,
+	})
+	packet.Outputs = append(packet.Outputs, psbt.POutput{})
+
+	for idx := range mkAddrs {
+		err := signer.SignWalletInput(packet, idx, privKeys[idx])
+		require.NoError(t, err)
+	}
+
+	finalTx, err := psbt.Extract(packet)
+	require.NoError(t, err)
+
+	// Every input must now be valid according to the script engine.
+	prevOutFetcher := wallet.PsbtPrevOutputFetcher(packet)
+	sigHashes := txscript.NewTxSigHashes(finalTx, prevOutFetcher)
+	for idx := range finalTx.TxIn {
+		utxo := packet.Inputs[idx].WitnessUtxo
+		vm, err := txscript.NewEngine(
+			utxo.PkScript, finalTx, idx,
+			txscript.StandardVerifyFlags, nil, sigHashes,
+			utxo.Value, prevOutFetcher,
+		)
+		require.NoError(t, err)
+		require.NoError(t, vm.Execute())
+	}
+}